@@ -138,3 +138,30 @@ func (r Ranges) MergedBuckets(maxBucketSize uint64) (out Ranges) {
 	}
 	return out
 }
+
+// Gaps returns, sorted, the spans of `target` not covered by any of the ranges,
+// which can be unsorted and overlapping. It is the work remaining to cover
+// `target` when `r` are the ranges already processed.
+func (r Ranges) Gaps(target *Range) (out Ranges) {
+	sorted := make(Ranges, len(r))
+	copy(sorted, r)
+	sort.Sort(sorted)
+
+	cursor := target.StartBlock
+	for _, rng := range sorted {
+		if cursor >= target.ExclusiveEndBlock {
+			break
+		}
+		if rng.ExclusiveEndBlock <= cursor {
+			continue
+		}
+		if rng.StartBlock > cursor {
+			out = append(out, NewRange(cursor, min(rng.StartBlock, target.ExclusiveEndBlock)))
+		}
+		cursor = max(cursor, rng.ExclusiveEndBlock)
+	}
+	if cursor < target.ExclusiveEndBlock {
+		out = append(out, NewRange(cursor, target.ExclusiveEndBlock))
+	}
+	return out
+}
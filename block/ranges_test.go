@@ -51,3 +51,22 @@ func TestRangeMergedBuckets(t *testing.T) {
 		ParseRanges("1-2,2-3,3-4,4-5,10-12,13-14").MergedBuckets(3).String(),
 	)
 }
+
+func TestRangesGaps(t *testing.T) {
+	target := NewRange(0, 100)
+	assert.Equal(t, ParseRanges("0-100").String(), Ranges(nil).Gaps(target).String())
+	assert.Equal(t, "", ParseRanges("0-100").Gaps(target).String())
+	assert.Equal(t, "", ParseRanges("0-150").Gaps(NewRange(10, 100)).String())
+	assert.Equal(t,
+		ParseRanges("0-10,30-40,45-50,80-100").String(),
+		ParseRanges("50-60,10-20,40-45,55-80,20-30").Gaps(target).String(),
+	)
+	assert.Equal(t,
+		ParseRanges("20-30,60-70").String(),
+		ParseRanges("0-20,30-60,70-200").Gaps(NewRange(15, 75)).String(),
+	)
+	assert.Equal(t,
+		ParseRanges("10-20").String(),
+		ParseRanges("100-200").Gaps(NewRange(10, 20)).String(),
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -98,6 +99,13 @@ func mustGetBool(cmd *cobra.Command, flagName string) bool {
 	}
 	return val
 }
+func mustGetDuration(cmd *cobra.Command, flagName string) time.Duration {
+	val, err := cmd.Flags().GetDuration(flagName)
+	if err != nil {
+		panic(fmt.Sprintf("flags: couldn't find flag %q", flagName))
+	}
+	return val
+}
 
 func maybeGetString(cmd *cobra.Command, flagName string) string {
 	val, _ := cmd.Flags().GetString(flagName)
@@ -14,6 +14,8 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc/metadata"
 	"io"
+	"strings"
+	"time"
 )
 
 func init() {
@@ -26,6 +28,7 @@ func init() {
 	runCmd.Flags().Bool("insecure", false, "Skip certificate validation on GRPC connection")
 	runCmd.Flags().Bool("plaintext", false, "Establish GRPC connection in plaintext")
 	runCmd.Flags().StringP("output", "o", "", "Output mode. Defaults to 'ui' when in a TTY is present, and 'json' otherwise")
+	runCmd.Flags().Duration("ui-flush-interval", 100*time.Millisecond, "In 'ui' output mode, print the blocks in batches at most once per interval, 0 prints each block as it comes at the cost of flickering on fast streams")
 	runCmd.Flags().StringSlice("debug-modules-initial-snapshot", nil, "List of 'store' modules from which to print the initial data snapshot (Unavailable in Production Mode)")
 	runCmd.Flags().StringSlice("debug-modules-output", nil, "List of modules from which to print outputs, deltas and logs (Unavailable in Production Mode)")
 	runCmd.Flags().StringSliceP("header", "H", nil, "Additional headers to be sent in the substreams request")
@@ -163,6 +166,7 @@ func runRun(cmd *cobra.Command, args []string) error {
 	}
 
 	ui := tui.New(req, pkg, toPrint)
+	ui.SetFlushInterval(mustGetDuration(cmd, "ui-flush-interval"))
 	if err := ui.Init(outputMode); err != nil {
 		return fmt.Errorf("TUI initialization: %w", err)
 	}
@@ -188,6 +192,9 @@ func runRun(cmd *cobra.Command, args []string) error {
 		}
 		streamCtx = metadata.AppendToOutgoingContext(streamCtx, headerArray...)
 	}
+	if uncached := manifestReader.UncachedModules(); len(uncached) != 0 {
+		streamCtx = metadata.AppendToOutgoingContext(streamCtx, "X-Sf-Substreams-Uncached-Modules", strings.Join(uncached, ","))
+	}
 
 	ui.SetRequest(req)
 	ui.Connecting()
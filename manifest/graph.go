@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/streamingfast/bstream"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	"github.com/yourbasic/graph"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 type ModuleGraph struct {
@@ -20,6 +22,7 @@ type ModuleGraph struct {
 	moduleIndex     map[string]int
 	indexIndex      map[int]*pbsubstreams.Module
 	inputOrderIndex map[string]map[string]int
+	order           []int // topological sort, consumers before their inputs
 }
 
 func NewModuleGraph(modules []*pbsubstreams.Module) (*ModuleGraph, error) {
@@ -63,11 +66,14 @@ func NewModuleGraph(modules []*pbsubstreams.Module) (*ModuleGraph, error) {
 		}
 	}
 
-	if !graph.Acyclic(g) {
+	order, acyclic := graph.TopSort(g)
+	if !acyclic {
 		return nil, fmt.Errorf("modules graph has a cycle")
 	}
 
-	if err := computeInitialBlock(modules, g); err != nil {
+	g.order = order
+
+	if err := computeInitialBlock(order, g); err != nil {
 		return nil, err
 	}
 
@@ -101,11 +107,14 @@ func (g *ModuleGraph) GetSources() []string {
 	return sources
 }
 
-func computeInitialBlock(modules []*pbsubstreams.Module, g *ModuleGraph) error {
-	for _, module := range modules {
+// computeInitialBlock sets the initial block of the modules that don't have one, going
+// through `order`, a topological sort of the graph, backward: the inputs of a module
+// have their initial block resolved before it.
+func computeInitialBlock(order []int, g *ModuleGraph) error {
+	for i := len(order) - 1; i >= 0; i-- {
+		module := g.modules[order[i]]
 		if module.InitialBlock == UNSET {
-			moduleIndex := g.moduleIndex[module.Name]
-			startBlock, err := startBlockForModule(moduleIndex, g)
+			startBlock, err := startBlockForModule(order[i], g)
 			if err != nil {
 				return err
 			}
@@ -117,21 +126,110 @@ func computeInitialBlock(modules []*pbsubstreams.Module, g *ModuleGraph) error {
 	return nil
 }
 
+// ValidateDepth fails when `moduleName` is at the end of an input chain of more than
+// `maxDepth` modules, 0 meaning no limit. Only the modules it depends on are considered,
+// it is the deepest of them. Depths are computed iteratively, inputs first, following
+// the topological sort of the graph backward.
+func (g *ModuleGraph) ValidateDepth(moduleName string, maxDepth uint64) error {
+	if maxDepth == 0 {
+		return nil
+	}
+	target, found := g.moduleIndex[moduleName]
+	if !found {
+		return fmt.Errorf("could not find module %s in graph", moduleName)
+	}
+
+	// the inputs of a module come after it in the sort
+	inClosure := make([]bool, g.Order())
+	inClosure[target] = true
+	for _, v := range g.order {
+		if !inClosure[v] {
+			continue
+		}
+		g.Visit(v, func(w int, _ int64) bool {
+			inClosure[w] = true
+			return false
+		})
+	}
+
+	depths := make([]uint64, g.Order())
+	deepestInput := make([]int, g.Order())
+	for i := len(g.order) - 1; i >= 0; i-- {
+		v := g.order[i]
+		if !inClosure[v] {
+			continue
+		}
+		depths[v] = 1
+		deepestInput[v] = -1
+		g.Visit(v, func(w int, _ int64) bool {
+			if depths[w]+1 > depths[v] {
+				depths[v] = depths[w] + 1
+				deepestInput[v] = w
+			}
+			return false
+		})
+	}
+
+	if depths[target] > maxDepth {
+		return fmt.Errorf("module %q is at depth %d of its input chain, more than the maximum of %d: %s", moduleName, depths[target], maxDepth, chainTail(target, deepestInput, g))
+	}
+	return nil
+}
+
+// chainTail renders the last modules of the deepest input chain ending at `v`.
+func chainTail(v int, deepestInput []int, g *ModuleGraph) string {
+	const tailLength = 5
+
+	var tail []string
+	for ; v != -1 && len(tail) < tailLength; v = deepestInput[v] {
+		tail = append([]string{g.modules[v].Name}, tail...)
+	}
+	out := strings.Join(tail, " -> ")
+	if v != -1 {
+		out = "... -> " + out
+	}
+	return out
+}
+
+// ValidateInitialBlocks fails when a module starts before a module whose output it
+// consumes, it would be asked for its output at blocks where its input doesn't exist.
+// Reading a store that has not started yet is fine, the store is simply empty. The
+// modules are left untouched, the inherited initial blocks are resolved on copies.
+func ValidateInitialBlocks(modules []*pbsubstreams.Module) error {
+	resolved := make([]*pbsubstreams.Module, len(modules))
+	for i, module := range modules {
+		resolved[i] = proto.Clone(module).(*pbsubstreams.Module)
+	}
+	g, err := NewModuleGraph(resolved)
+	if err != nil {
+		return err
+	}
+	for _, module := range g.modules {
+		for _, input := range module.Inputs {
+			mapInput := input.GetMap()
+			if mapInput == nil {
+				continue
+			}
+			inputIndex, found := g.moduleIndex[mapInput.ModuleName]
+			if !found {
+				continue
+			}
+			inputModule := g.modules[inputIndex]
+			if module.InitialBlock < inputModule.InitialBlock {
+				return fmt.Errorf("module %q has initial block %d, before the initial block %d of its input %q", module.Name, module.InitialBlock, inputModule.InitialBlock, inputModule.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// startBlockForModule returns the initial block a module inherits from its inputs, which
+// must have theirs resolved already.
 func startBlockForModule(moduleIndex int, g *ModuleGraph) (out uint64, err error) {
 	parentsInitialBlock := int64(-1)
 	g.Visit(moduleIndex, func(w int, c int64) bool {
 		parent := g.modules[w]
-		currentInitialBlock := int64(-1)
-		if parent.InitialBlock == UNSET {
-			var newVal uint64
-			newVal, err = startBlockForModule(w, g)
-			if err != nil {
-				return true
-			}
-			currentInitialBlock = int64(newVal)
-		} else {
-			currentInitialBlock = int64(parent.GetInitialBlock())
-		}
+		currentInitialBlock := int64(parent.GetInitialBlock())
 
 		if parentsInitialBlock == -1 {
 			if currentInitialBlock != -1 {
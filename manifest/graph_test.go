@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"fmt"
 	"sort"
 	"testing"
 
@@ -477,3 +478,77 @@ func TestModuleGraph_ComputeInitialBlocks_WithThreeParentsEachContainingAInitial
 	_, err := NewModuleGraph(testModules)
 	assert.Equal(t, `cannot deterministically determine the initialBlock for module "D"; multiple inputs have conflicting initial blocks defined or inherited`, err.Error())
 }
+
+func TestModuleGraph_InitialBlockBeforeMapInput(t *testing.T) {
+	var testModules = []*pbsubstreams.Module{
+		{
+			Name:         "dependency",
+			InitialBlock: twenty,
+		},
+		{
+			Name:         "consumer",
+			InitialBlock: ten,
+			Inputs: []*pbsubstreams.Module_Input{
+				{
+					Input: &pbsubstreams.Module_Input_Map_{
+						Map: &pbsubstreams.Module_Input_Map{
+							ModuleName: "dependency",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	_, err := NewModuleGraph(testModules)
+	assert.NoError(t, err, "published packages are not rejected")
+
+	err = ValidateInitialBlocks(testModules)
+	assert.EqualError(t, err, `module "consumer" has initial block 10, before the initial block 20 of its input "dependency"`)
+
+	// a store input not started yet is read empty
+	testModules[1].Inputs[0].Input = &pbsubstreams.Module_Input_Store_{
+		Store: &pbsubstreams.Module_Input_Store{ModuleName: "dependency"},
+	}
+	err = ValidateInitialBlocks(testModules)
+	assert.NoError(t, err)
+}
+
+func TestModuleGraph_MaxDepth(t *testing.T) {
+	chain := func(length int) []*pbsubstreams.Module {
+		modules := make([]*pbsubstreams.Module, length)
+		for i := range modules {
+			modules[i] = &pbsubstreams.Module{Name: fmt.Sprintf("mod%d", i), InitialBlock: UNSET}
+			if i > 0 {
+				modules[i].Inputs = []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Map_{
+					Map: &pbsubstreams.Module_Input_Map{ModuleName: modules[i-1].Name},
+				}}}
+			}
+		}
+		return modules
+	}
+
+	validateDepth := func(modules []*pbsubstreams.Module, moduleName string, maxDepth uint64) error {
+		graph, err := NewModuleGraph(modules)
+		require.NoError(t, err)
+		return graph.ValidateDepth(moduleName, maxDepth)
+	}
+
+	assert.NoError(t, validateDepth(chain(8), "mod7", 8))
+
+	err := validateDepth(chain(9), "mod8", 8)
+	assert.EqualError(t, err, `module "mod8" is at depth 9 of its input chain, more than the maximum of 8: ... -> mod4 -> mod5 -> mod6 -> mod7 -> mod8`)
+
+	err = validateDepth(chain(3), "mod2", 2)
+	assert.EqualError(t, err, `module "mod2" is at depth 3 of its input chain, more than the maximum of 2: mod0 -> mod1 -> mod2`)
+
+	// only the output module's inputs count
+	assert.NoError(t, validateDepth(chain(9), "mod1", 2))
+
+	// no limit
+	graph, err := NewModuleGraph(chain(1000))
+	require.NoError(t, err)
+	initialBlock, err := graph.ModuleInitialBlock("mod999")
+	require.NoError(t, err)
+	assert.Equal(t, bstream.GetProtocolFirstStreamableBlock, initialBlock)
+}
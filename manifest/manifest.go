@@ -84,8 +84,16 @@ type Module struct {
 	ValueType    string `yaml:"valueType"`
 	Binary       string `yaml:"binary"`
 
+	// InitialValues, for 'store' modules, are the keys the store holds at its initial
+	// block, before the module first executes. Binary values use the `!!binary` tag.
+	InitialValues map[string]string `yaml:"initialValues"`
+
 	Inputs []*Input     `yaml:"inputs"`
 	Output StreamOutput `yaml:"output"`
+
+	// Cache, when set to false, asks the servers to always recompute the module
+	// outputs instead of caching them, for modules cheap to run.
+	Cache *bool `yaml:"cache"`
 }
 
 type Input struct {
@@ -182,11 +190,13 @@ func validateStoreBuilder(module *Module) error {
 		"max:bigdecimal",
 		"max:bigfloat",
 		"max:float64",
+		"max:scored",
 		"min:bigint",
 		"min:int64",
 		"min:bigdecimal",
 		"min:bigfloat",
 		"min:float64",
+		"min:scored",
 		"add:bigint",
 		"add:int64",
 		"add:bigdecimal",
@@ -200,6 +210,7 @@ func validateStoreBuilder(module *Module) error {
 		"set:bigint",
 		"set:int64",
 		"set:float64",
+		"set:versioned",
 		"set_if_not_exists:bytes",
 		"set_if_not_exists:string",
 		"set_if_not_exists:proto",
@@ -210,6 +221,10 @@ func validateStoreBuilder(module *Module) error {
 		"set_if_not_exists:float64",
 		"append:bytes",
 		"append:string",
+		"bit_or:bigint",
+		"bit_or:int64",
+		"bit_and:bigint",
+		"bit_and:int64",
 	}
 	found := false
 	var lastCombination string
@@ -337,6 +352,14 @@ const (
 	// Deprecated: bigfloat value type replaced with bigdecimal
 	OutputValueTypeBigFloat = "bigfloat"
 	OutputValueTypeString   = "string"
+
+	// OutputValueTypeScored values carry a score alongside an arbitrary payload,
+	// `min` and `max` stores of that type compare on the score only.
+	OutputValueTypeScored = "scored"
+
+	// OutputValueTypeVersioned values carry the block they were written for alongside
+	// an arbitrary payload, `set` stores of that type keep the value of the highest block.
+	OutputValueTypeVersioned = "versioned"
 )
 
 const (
@@ -346,6 +369,8 @@ const (
 	UpdatePolicyMax            = "max"
 	UpdatePolicyMin            = "min"
 	UpdatePolicyAppend         = "append"
+	UpdatePolicyBitOr          = "bit_or"
+	UpdatePolicyBitAnd         = "bit_and"
 )
 
 func (m *Module) setKindToProto(pbModule *pbsubstreams.Module) {
@@ -371,18 +396,34 @@ func (m *Module) setKindToProto(pbModule *pbsubstreams.Module) {
 			updatePolicy = pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN
 		case UpdatePolicyAppend:
 			updatePolicy = pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND
+		case UpdatePolicyBitOr:
+			updatePolicy = pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR
+		case UpdatePolicyBitAnd:
+			updatePolicy = pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND
 		default:
 			panic(fmt.Sprintf("invalid update policy %s", m.UpdatePolicy))
 		}
 		pbModule.Kind = &pbsubstreams.Module_KindStore_{
 			KindStore: &pbsubstreams.Module_KindStore{
-				UpdatePolicy: updatePolicy,
-				ValueType:    m.ValueType,
+				UpdatePolicy:  updatePolicy,
+				ValueType:     m.ValueType,
+				InitialValues: m.initialValuesToProto(),
 			},
 		}
 	}
 }
 
+func (m *Module) initialValuesToProto() map[string][]byte {
+	if len(m.InitialValues) == 0 {
+		return nil
+	}
+	out := make(map[string][]byte, len(m.InitialValues))
+	for key, value := range m.InitialValues {
+		out[key] = []byte(value)
+	}
+	return out
+}
+
 func (m *Module) setOutputToProto(pbModule *pbsubstreams.Module) {
 	if m.Output.Type != "" {
 		pbModule.Output = &pbsubstreams.Module_Output{
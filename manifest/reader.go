@@ -77,6 +77,7 @@ type Reader struct {
 	protoDefinitions         []*desc.FileDescriptor
 	sinkConfigJSON           string
 	sinkConfigDynamicMessage *dynamic.Message
+	uncachedModules          []string
 
 	//options
 	skipSourceCodeImportValidation bool
@@ -207,6 +208,13 @@ func (r *Reader) read(workingDir string) (*pbsubstreams.Package, error) {
 
 // IsRemotePackage determines if reader's input to read the manifest is a remote file accessible over
 // HTTP/HTTPS, Google Cloud Storage, S3 or Azure Storage.
+// UncachedModules returns the modules declared with `cache: false` in the manifest read,
+// which is only known after `Read` for local manifests. The flag is not part of packages,
+// clients send it along with the request in the `X-Sf-Substreams-Uncached-Modules` header.
+func (r *Reader) UncachedModules() []string {
+	return r.uncachedModules
+}
+
 func (r *Reader) IsRemotePackage() bool {
 	return hasRemotePackagePrefix(r.resolvedInput)
 }
@@ -365,6 +373,12 @@ func (r *Reader) newPkgFromManifest(inputPath string) (pkg *pbsubstreams.Package
 		return nil, nil, fmt.Errorf("failed validation: %w", err)
 	}
 
+	// Only enforced on manifests being authored, published packages starting a module
+	// before its map inputs keep loading and are executed from their inputs' start.
+	if err := ValidateInitialBlocks(pkg.Modules.Modules); err != nil {
+		return nil, nil, fmt.Errorf("failed validation: %w", err)
+	}
+
 	return pkg, protoDefinitions, nil
 }
 
@@ -518,6 +532,18 @@ func ValidateModules(mods *pbsubstreams.Modules) error {
 			return fmt.Errorf("limit of 30 inputs for a given module (%q) reached", mod.Name)
 		}
 
+		// Map modules carry an output type instead, only stores need a value type to
+		// know how their values are merged.
+		if store, ok := mod.Kind.(*pbsubstreams.Module_KindStore_); ok {
+			valueType := store.KindStore.GetValueType()
+			if valueType == "" {
+				return fmt.Errorf("module %q: store value type is missing", mod.Name)
+			}
+			if !strings.HasPrefix(valueType, "proto:") && !storeValidTypes[valueType] {
+				return fmt.Errorf("module %q: unknown store value type %q", mod.Name, valueType)
+			}
+		}
+
 		for idx, in := range mod.Inputs {
 			switch i := in.Input.(type) {
 			case *pbsubstreams.Module_Input_Params_:
@@ -607,6 +633,9 @@ func LoadManifestFile(inputPath string) (*Manifest, error) {
 			if s.Output.Type == "" {
 				return nil, fmt.Errorf("stream %q: missing 'output.type' for kind 'map'", s.Name)
 			}
+			if len(s.InitialValues) != 0 {
+				return nil, fmt.Errorf("stream %q: 'initialValues' is only valid for kind 'store'", s.Name)
+			}
 		case ModuleKindStore:
 			if err := validateStoreBuilder(s); err != nil {
 				return nil, fmt.Errorf("stream %q: %w", s.Name, err)
@@ -752,6 +781,9 @@ func (r *Reader) convertToPkg(m *Manifest) (pkg *pbsubstreams.Package, err error
 		pbmeta := &pbsubstreams.ModuleMetadata{
 			Doc: mod.Doc,
 		}
+		if mod.Cache != nil && !*mod.Cache {
+			r.uncachedModules = append(r.uncachedModules, mod.Name)
+		}
 		var pbmod *pbsubstreams.Module
 
 		binaryName := "default"
@@ -827,4 +859,6 @@ var storeValidTypes = map[string]bool{
 	"bytes":      true,
 	"string":     true,
 	"proto":      true,
+	"scored":     true,
+	"versioned":  true,
 }
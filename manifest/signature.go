@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
 
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
@@ -66,6 +67,19 @@ func (m *ModuleHashes) HashModule(modules *pbsubstreams.Modules, module *pbsubst
 		buf.WriteString("map")
 	case *pbsubstreams.Module_KindStore_:
 		buf.WriteString("store")
+		// only hashed when declared, leaving the hash of the other stores unchanged
+		if initialValues := module.GetKindStore().InitialValues; len(initialValues) != 0 {
+			buf.WriteString("initial_values")
+			keys := make([]string, 0, len(initialValues))
+			for key := range initialValues {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				writeLengthPrefixed(buf, key)
+				writeLengthPrefixed(buf, string(initialValues[key]))
+			}
+		}
 	default:
 		return nil, fmt.Errorf("invalid module file %T", module.Kind)
 	}
@@ -141,3 +155,12 @@ func inputValue(input *pbsubstreams.Module_Input) (string, error) {
 		return "", fmt.Errorf("invalid input %T", input.Input)
 	}
 }
+
+// writeLengthPrefixed writes `s` preceded by its length, so that consecutive strings
+// cannot be shifted into one another without changing the hash.
+func writeLengthPrefixed(buf *bytes.Buffer, s string) {
+	lengthBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(lengthBytes, uint64(len(s)))
+	buf.Write(lengthBytes)
+	buf.WriteString(s)
+}
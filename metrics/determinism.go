@@ -0,0 +1,6 @@
+package metrics
+
+// NondeterministicModuleOutputs counts the blocks for which executing a module twice
+// gave two different outputs. Such a module corrupts the outputs cache, whose content
+// depends on which execution wrote it. The module is named in the logs.
+var NondeterministicModuleOutputs = MetricSet.NewCounter("substreams_nondeterministic_module_outputs", "Number of blocks for which two executions of a module gave different outputs")
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// MaxModuleLabels bounds the number of distinct values of the `module` label of the
+// module metrics, module names being chosen by the users. The modules seen once it is
+// reached are all reported under `OtherModulesLabel`.
+const MaxModuleLabels = 1000
+
+// OtherModulesLabel is the `module` label of the modules past `MaxModuleLabels`.
+const OtherModulesLabel = "other"
+
+var moduleLabels = struct {
+	lock sync.Mutex
+	seen map[string]bool
+}{seen: make(map[string]bool)}
+
+// moduleLabel returns the `module` label value of `module`, shared by all the module
+// metrics so that they name the same modules.
+func moduleLabel(module string) string {
+	moduleLabels.lock.Lock()
+	defer moduleLabels.lock.Unlock()
+
+	if moduleLabels.seen[module] {
+		return module
+	}
+	if len(moduleLabels.seen) >= MaxModuleLabels {
+		return OtherModulesLabel
+	}
+	moduleLabels.seen[module] = true
+	return module
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleLabel_Bounded(t *testing.T) {
+	seen := moduleLabels.seen
+	moduleLabels.seen = make(map[string]bool)
+	defer func() { moduleLabels.seen = seen }()
+
+	for i := 0; i < MaxModuleLabels; i++ {
+		assert.Equal(t, fmt.Sprintf("module_%d", i), moduleLabel(fmt.Sprintf("module_%d", i)))
+	}
+	assert.Equal(t, OtherModulesLabel, moduleLabel("one_too_many"))
+	assert.Equal(t, "module_0", moduleLabel("module_0"), "modules seen before keep their label")
+}
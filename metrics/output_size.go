@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// ModuleOutputMebibytes is the size of the output of each module for a block, to find
+// the modules bloating their outputs. It is in MiB for the default buckets to go from
+// 5 KiB to 10 MiB. The `module` label is bounded, see `MaxModuleLabels`.
+var ModuleOutputMebibytes = MetricSet.NewHistogramVec("substreams_module_output_mebibytes", []string{"module"}, "Size in MiB of the output of a module for a block")
+
+// moduleOutputObservers are the histograms of ModuleOutputMebibytes, by module name,
+// resolved once per module having its own label
+var moduleOutputObservers sync.Map
+
+func moduleOutputObserver(module string) prometheus.Observer {
+	if observer, found := moduleOutputObservers.Load(module); found {
+		return observer.(prometheus.Observer)
+	}
+	label := moduleLabel(module)
+	observer := ModuleOutputMebibytes.Native().WithLabelValues(label)
+	if label == module {
+		moduleOutputObservers.Store(module, observer)
+	}
+	return observer
+}
+
+// ObserveModuleOutputSize records the size of the output of `module` for a block, logging
+// a warning when it is larger than `warnThreshold`, if not 0.
+func ObserveModuleOutputSize(logger *zap.Logger, module string, blockNum uint64, size int, warnThreshold uint64) {
+	moduleOutputObserver(module).Observe(float64(size) / (1 << 20))
+
+	if warnThreshold != 0 && uint64(size) > warnThreshold {
+		logger.Warn("module output exceeds size threshold",
+			zap.String("module", module),
+			zap.Uint64("block_num", blockNum),
+			zap.Int("size_bytes", size),
+			zap.Uint64("threshold_bytes", warnThreshold),
+		)
+	}
+}
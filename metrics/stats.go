@@ -35,7 +35,8 @@ type Stats struct {
 	// counter is used to get the next jobIdx
 	counter uint64
 
-	logger *zap.Logger
+	logger      *zap.Logger
+	stopLogging chan struct{}
 }
 
 type runningJobs map[uint64]*extendedJob
@@ -143,6 +144,7 @@ type extendedStats struct {
 	processedBlocksInCompleteJobs uint64
 	storeOperationTime            time.Duration
 	processingTime                time.Duration
+	executedBlocks                uint64
 	externalCallMetrics           map[string]*extendedCallMetric
 }
 
@@ -304,6 +306,31 @@ func (s *Stats) RecordModuleWasmStoreDeletePrefix(moduleName string, sizeBytes u
 	mod.storeOperationTime += elapsed
 }
 
+// RecordModuleBlock should be called once per module per block it completes, whether its
+// output was computed, read from the cache or its execution skipped. It is a no-op on
+// tier2, the blocks processed by sub-requests being reported through their jobs.
+func (s *Stats) RecordModuleBlock(moduleName string) {
+	if s.config.Tier2 {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.moduleStats(moduleName).executedBlocks++
+}
+
+// ModuleBlockCounts returns, per module, the number of blocks it executed locally.
+func (s *Stats) ModuleBlockCounts() map[string]uint64 {
+	s.Lock()
+	defer s.Unlock()
+	out := make(map[string]uint64, len(s.modulesStats))
+	for name, mod := range s.modulesStats {
+		if mod.executedBlocks != 0 {
+			out[name] = mod.executedBlocks
+		}
+	}
+	return out
+}
+
 func (s *Stats) RecordBlock(ref bstream.BlockRef) {
 	s.blockRate.Add(1)
 }
@@ -564,7 +591,42 @@ func toRPCExternalCallMetrics(in []*pbssinternal.ExternalCallMetric) []*pbsubstr
 	return out
 }
 
+// Start logs the blocks executed by each module every `each`, until LogAndClose is
+// called, pointing out the modules that hold back the throughput. It is a no-op on tier2.
+func (s *Stats) Start(each time.Duration) {
+	if s.config.Tier2 {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.stopLogging != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.stopLogging = stop
+
+	go func() {
+		ticker := time.NewTicker(each)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.logger.Info("substreams request module blocks", zap.Any("module_block_counts", s.ModuleBlockCounts()))
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
 func (s *Stats) LogAndClose() {
+	s.Lock()
+	if s.stopLogging != nil {
+		close(s.stopLogging)
+		s.stopLogging = nil
+	}
+	s.Unlock()
+
 	s.blockRate.SyncNow()
 	s.blockRate.Stop()
 	s.logger.Info("substreams request stats", s.getZapFields()...)
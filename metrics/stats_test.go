@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestStats_RecordModuleBlock(t *testing.T) {
+	stats := NewReqStats(&Config{}, zap.NewNop())
+
+	for i := 0; i < 10; i++ {
+		stats.RecordModuleBlock("map_transfers")
+		if i%2 == 0 {
+			stats.RecordModuleBlock("store_balances")
+		}
+	}
+	// modules with stats but no block executed are left out
+	stats.RecordModuleWasmStoreRead("store_totals", time.Millisecond)
+	stats.RecordModuleWasmBlock("store_totals", time.Millisecond)
+
+	assert.Equal(t, map[string]uint64{
+		"map_transfers":  10,
+		"store_balances": 5,
+	}, stats.ModuleBlockCounts())
+
+	stats.Start(time.Millisecond)
+	stats.RecordModuleBlock("store_balances")
+	stats.LogAndClose()
+	assert.Equal(t, uint64(6), stats.ModuleBlockCounts()["store_balances"])
+
+	tier2Stats := NewReqStats(&Config{Tier2: true}, zap.NewNop())
+	tier2Stats.Start(time.Millisecond)
+	tier2Stats.RecordModuleBlock("map_transfers")
+	tier2Stats.LogAndClose()
+	assert.Empty(t, tier2Stats.ModuleBlockCounts())
+}
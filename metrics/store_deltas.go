@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+)
+
+// StoreDeltaOperations counts the deltas written by each module to its store, by
+// operation. A module only ever creating keys, never updating them, usually has a bug in
+// its key scheme. The `module` label is bounded, see `MaxModuleLabels`.
+var StoreDeltaOperations = MetricSet.NewCounterVec("substreams_store_delta_operations", []string{"module", "operation"}, "Number of deltas written by a module to its store, by operation (create, update, delete)")
+
+// storeDeltaCounters are the counters of StoreDeltaOperations, by module name then
+// operation, resolved once per module having its own label, deltas being counted on
+// the hot path
+var storeDeltaCounters sync.Map
+
+func moduleStoreDeltaCounters(module string) map[pbssinternal.StoreDelta_Operation]prometheus.Counter {
+	if counters, found := storeDeltaCounters.Load(module); found {
+		return counters.(map[pbssinternal.StoreDelta_Operation]prometheus.Counter)
+	}
+	label := moduleLabel(module)
+	counters := make(map[pbssinternal.StoreDelta_Operation]prometheus.Counter)
+	for value, name := range pbssinternal.StoreDelta_Operation_name {
+		counters[pbssinternal.StoreDelta_Operation(value)] = StoreDeltaOperations.Native().WithLabelValues(label, strings.ToLower(name))
+	}
+	if label == module {
+		storeDeltaCounters.Store(module, counters)
+	}
+	return counters
+}
+
+// CountStoreDelta records a delta of `operation` written by `module` to its store.
+func CountStoreDelta(module string, operation pbssinternal.StoreDelta_Operation) {
+	moduleStoreDeltaCounters(module)[operation].Inc()
+}
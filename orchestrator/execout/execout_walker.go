@@ -29,6 +29,10 @@ type Walker struct {
 	module     *pbsubstreams.Module
 	logger     *zap.Logger
 	working    bool
+
+	// skipEmptyOutputs leaves out the blocks whose cached output is empty, the
+	// progress heartbeats keep being sent meanwhile
+	skipEmptyOutputs bool
 }
 
 func NewWalker(
@@ -40,12 +44,13 @@ func NewWalker(
 ) *Walker {
 	logger := reqctx.Logger(ctx)
 	return &Walker{
-		ctx:        ctx,
-		module:     module,
-		fileWalker: fileWalker,
-		Range:      walkRange,
-		streamOut:  stream,
-		logger:     logger,
+		ctx:              ctx,
+		module:           module,
+		fileWalker:       fileWalker,
+		Range:            walkRange,
+		streamOut:        stream,
+		logger:           logger,
+		skipEmptyOutputs: reqctx.Details(ctx).SkipEmptyCachedOutputs,
 	}
 }
 
@@ -95,6 +100,13 @@ func computeNewWait(previousWait time.Duration) time.Duration {
 }
 
 func (r *Walker) sendItems(sortedItems []*pboutput.Item) error {
+	skipped := 0
+	defer func() {
+		if skipped > 0 {
+			r.logger.Debug("skipped blocks with empty cached output", zap.String("module", r.module.Name), zap.Int("block_count", skipped))
+		}
+	}()
+
 	for _, item := range sortedItems {
 		if item == nil {
 			continue // why would that happen?!
@@ -103,19 +115,23 @@ func (r *Walker) sendItems(sortedItems []*pboutput.Item) error {
 			continue
 		}
 
-		blockScopedData, err := toBlockScopedData(r.module, item)
-		if err != nil {
-			return fmt.Errorf("converting to block scoped data: %w", err)
-		}
-
-		if err = r.streamOut.BlockScopedData(blockScopedData); err != nil {
-			return fmt.Errorf("calling response func: %w", err)
+		if r.skipEmptyOutputs && len(item.Payload) == 0 {
+			skipped++
+		} else {
+			blockScopedData, err := toBlockScopedData(r.module, item)
+			if err != nil {
+				return fmt.Errorf("converting to block scoped data: %w", err)
+			}
+
+			if err = r.streamOut.BlockScopedData(blockScopedData); err != nil {
+				return fmt.Errorf("calling response func: %w", err)
+			}
 		}
 
-		if blockScopedData.Clock.Number >= r.ExclusiveEndBlock {
+		if item.BlockNum >= r.ExclusiveEndBlock {
 			r.logger.Info("stop pulling block scoped data, end block reach",
 				zap.Uint64("exclusive_end_block_num", r.ExclusiveEndBlock),
-				zap.Uint64("cache_item_block_num", blockScopedData.Clock.Number),
+				zap.Uint64("cache_item_block_num", item.BlockNum),
 			)
 			return nil
 		}
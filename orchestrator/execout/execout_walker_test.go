@@ -0,0 +1,53 @@
+package execout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams"
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/orchestrator/response"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	pboutput "github.com/streamingfast/substreams/storage/execout/pb"
+)
+
+func TestWalker_sendItemsSkipsEmptyCachedOutputs(t *testing.T) {
+	items := []*pboutput.Item{
+		{BlockNum: 10, BlockId: "10a", Payload: []byte("first")},
+		{BlockNum: 11, BlockId: "11a"},
+		{BlockNum: 12, BlockId: "12a", Payload: []byte{}},
+		{BlockNum: 13, BlockId: "13a", Payload: []byte("second")},
+		{BlockNum: 14, BlockId: "14a"},
+	}
+
+	tests := []struct {
+		name         string
+		skipEmpty    bool
+		expectBlocks []uint64
+	}{
+		{"all blocks by default", false, []uint64{10, 11, 12, 13, 14}},
+		{"empty ones skipped", true, []uint64{10, 13}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var blocks []uint64
+			stream := response.New(func(resp substreams.ResponseFromAnyTier) error {
+				blocks = append(blocks, resp.(*pbsubstreamsrpc.Response).GetBlockScopedData().Clock.Number)
+				return nil
+			})
+
+			ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{SkipEmptyCachedOutputs: test.skipEmpty})
+			module := &pbsubstreams.Module{Name: "map_sparse", Output: &pbsubstreams.Module_Output{Type: "proto:sf.test.Output"}}
+			walker := NewWalker(ctx, module, nil, block.NewRange(10, 14), stream)
+
+			require.NoError(t, walker.sendItems(items))
+			assert.Equal(t, test.expectBlocks, blocks)
+		})
+	}
+}
@@ -2,10 +2,13 @@ package orchestrator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/streamingfast/substreams"
+	"github.com/streamingfast/substreams/block"
 	orchestratorExecout "github.com/streamingfast/substreams/orchestrator/execout"
 	"github.com/streamingfast/substreams/orchestrator/plan"
 	"github.com/streamingfast/substreams/orchestrator/response"
@@ -56,11 +59,40 @@ func BuildParallelProcessor(
 		}
 	}
 
+	if len(runtimeConfig.CompletedRanges) != 0 {
+		stages.MarkCompletedRanges(completedRangesByName(runtimeConfig.CompletedRanges, outputGraph))
+	}
+
 	if os.Getenv("SUBSTREAMS_DEBUG_SCHEDULER_STATE") == "true" {
 		fmt.Println("Initial state:")
 		fmt.Print(stages.StatesString())
 	}
 
+	// For debugging, the jobs needed by the request can be dumped to a file, and a
+	// dumped plan fed back to replay the same sub-requests in the same order.
+	if path := os.Getenv("SUBSTREAMS_DEBUG_DUMP_JOB_PLAN"); path != "" {
+		cnt, err := json.MarshalIndent(stages.ComputeJobPlan(), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("marshalling job plan: %w", err)
+		}
+		if err := os.WriteFile(path, cnt, 0644); err != nil {
+			return nil, fmt.Errorf("dumping job plan: %w", err)
+		}
+	}
+	if path := os.Getenv("SUBSTREAMS_DEBUG_JOB_PLAN"); path != "" {
+		cnt, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading job plan: %w", err)
+		}
+		jobPlan, err := stage.ParseJobPlan(cnt)
+		if err != nil {
+			return nil, err
+		}
+		if err := stages.SetJobPlan(jobPlan); err != nil {
+			return nil, fmt.Errorf("job plan %q: %w", path, err)
+		}
+	}
+
 	// OPTIMIZATION: We should fetch the ExecOut files too, and see if they
 	// cover some of the ranges that we're after.
 	// We don't need to plan work for ranges where we have ExecOut
@@ -124,6 +156,16 @@ func BuildParallelProcessor(
 	workerPool := work.NewWorkerPool(ctx, maxParallelJobs, runtimeConfig.WorkerFactory)
 	sched.WorkerPool = workerPool
 
+	// For debugging stuck backprocessing, the queued and in-flight sub-requests can be
+	// logged periodically.
+	if interval := os.Getenv("SUBSTREAMS_DEBUG_SCHEDULER_JOBS_INTERVAL"); interval != "" {
+		dumpInterval, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SUBSTREAMS_DEBUG_SCHEDULER_JOBS_INTERVAL: %w", err)
+		}
+		sched.JobsDumpInterval = dumpInterval
+	}
+
 	return &ParallelProcessor{
 		scheduler: sched,
 		reqPlan:   reqPlan,
@@ -149,3 +191,15 @@ func (b *ParallelProcessor) Run(ctx context.Context) (storeMap store.Map, err er
 
 	return nil, nil
 }
+
+// completedRangesByName maps the completed ranges, keyed by module hash, to the names of
+// the modules of the request.
+func completedRangesByName(completedRanges map[string]block.Ranges, outputGraph *outputmodules.Graph) map[string]block.Ranges {
+	out := make(map[string]block.Ranges)
+	for _, mod := range outputGraph.UsedModules() {
+		if ranges, found := completedRanges[outputGraph.ModuleHashes().Get(mod.Name)]; found {
+			out[mod.Name] = ranges
+		}
+	}
+	return out
+}
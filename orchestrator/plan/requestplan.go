@@ -107,6 +107,42 @@ func BuildTier1RequestPlan(productionMode bool, segmentInterval uint64, graphIni
 	return plan, nil
 }
 
+// BackprocessSpan returns the number of blocks covered by the parallel backprocessing
+// of the plan, from the earliest block needed by the stores or the mapper to the
+// linear handoff. It is 0 when no backprocessing is needed.
+func (p *RequestPlan) BackprocessSpan() uint64 {
+	var start, end uint64
+	first := true
+	for _, rng := range []*block.Range{p.BuildStores, p.WriteExecOut} {
+		if rng == nil {
+			continue
+		}
+		if first || rng.StartBlock < start {
+			start = rng.StartBlock
+		}
+		if first || rng.ExclusiveEndBlock > end {
+			end = rng.ExclusiveEndBlock
+		}
+		first = false
+	}
+	if end < start {
+		return 0
+	}
+	return end - start
+}
+
+// ValidateBackprocessSpan ensures the backprocessing of the plan does not span more
+// than `maxBlocks` blocks, 0 meaning no limit.
+func (p *RequestPlan) ValidateBackprocessSpan(maxBlocks uint64) error {
+	if maxBlocks == 0 {
+		return nil
+	}
+	if span := p.BackprocessSpan(); span > maxBlocks {
+		return fmt.Errorf("request requires backprocessing %d blocks, over the maximum of %d allowed: use a narrower block range, a later start block or resume from a cursor", span, maxBlocks)
+	}
+	return nil
+}
+
 func (p *RequestPlan) StoresSegmenter() *block.Segmenter {
 	return block.NewSegmenter(p.segmentInterval, p.BuildStores.StartBlock, p.BuildStores.ExclusiveEndBlock)
 }
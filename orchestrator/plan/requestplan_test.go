@@ -239,3 +239,18 @@ func tostr(s *block.Range) string {
 	}
 	return fmt.Sprintf("%d-%d", s.StartBlock, s.ExclusiveEndBlock)
 }
+
+func TestRequestPlan_ValidateBackprocessSpan(t *testing.T) {
+	res, err := BuildTier1RequestPlan(true, 100, 100, 1000, 5000, 0, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4900), res.BackprocessSpan())
+
+	assert.NoError(t, res.ValidateBackprocessSpan(0))
+	assert.NoError(t, res.ValidateBackprocessSpan(4900))
+	assert.EqualError(t, res.ValidateBackprocessSpan(4899), "request requires backprocessing 4900 blocks, over the maximum of 4899 allowed: use a narrower block range, a later start block or resume from a cursor")
+
+	res, err = BuildTier1RequestPlan(true, 100, 5000, 5000, 5000, 0, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), res.BackprocessSpan())
+	assert.NoError(t, res.ValidateBackprocessSpan(1))
+}
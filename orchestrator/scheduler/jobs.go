@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/orchestrator/loop"
+	"github.com/streamingfast/substreams/orchestrator/stage"
+	"github.com/streamingfast/substreams/orchestrator/work"
+)
+
+// JobsListing is what the scheduler is doing: the sub-requests still to be
+// dispatched, in the order they will be, and those dispatched but not completed yet.
+type JobsListing struct {
+	Queued   stage.JobPlan
+	InFlight []*work.InFlightJob
+}
+
+// MsgListJobs asks the scheduler for its JobsListing, sent on `reply`, or logged when
+// `reply` is nil.
+type MsgListJobs struct {
+	reply chan *JobsListing
+}
+
+// ListJobs returns the JobsListing of the running scheduler. Built from within its
+// event loop, it is consistent with the scheduling decisions.
+func (s *Scheduler) ListJobs(ctx context.Context) (*JobsListing, error) {
+	reply := make(chan *JobsListing, 1)
+	s.Send(MsgListJobs{reply: reply})
+	select {
+	case listing := <-reply:
+		return listing, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Scheduler) listJobs() *JobsListing {
+	return &JobsListing{
+		Queued:   s.Stages.QueuedJobs(),
+		InFlight: s.WorkerPool.InFlight(),
+	}
+}
+
+// cmdDumpJobs logs the JobsListing every `JobsDumpInterval`.
+func (s *Scheduler) cmdDumpJobs() loop.Cmd {
+	return func() loop.Msg {
+		select {
+		case <-time.After(s.JobsDumpInterval):
+		case <-s.ctx.Done():
+		}
+		return MsgListJobs{}
+	}
+}
+
+func (s *Scheduler) dumpJobs(listing *JobsListing) {
+	s.logger.Info("scheduler jobs",
+		zap.Int("queued_count", len(listing.Queued)),
+		zap.Array("queued", listing.Queued),
+		zap.Objects("in_flight", listing.InFlight),
+	)
+}
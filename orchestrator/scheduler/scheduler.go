@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -28,6 +29,10 @@ type Scheduler struct {
 
 	logger *zap.Logger
 
+	// JobsDumpInterval, when not 0, is the interval at which the queued and in-flight
+	// jobs are logged, to debug stuck backprocessing.
+	JobsDumpInterval time.Duration
+
 	// Final state:
 	outputStreamCompleted bool
 	storesSyncCompleted   bool
@@ -63,6 +68,10 @@ func (s *Scheduler) Init() loop.Cmd {
 
 	cmds = append(cmds, s.Stages.CmdStartMerge())
 
+	if s.JobsDumpInterval != 0 {
+		cmds = append(cmds, s.cmdDumpJobs())
+	}
+
 	return loop.Batch(cmds...)
 }
 
@@ -104,6 +113,7 @@ func (s *Scheduler) Update(msg loop.Msg) loop.Cmd {
 
 		s.logger.Info("scheduling work", zap.Object("unit", workUnit))
 		modules := s.Stages.StageModules(workUnit.Stage)
+		s.WorkerPool.Dispatched(worker, workUnit, workRange, modules)
 		return loop.Batch(
 			worker.Work(s.ctx, workUnit, workRange, modules, s.stream),
 			work.CmdScheduleNextJob(),
@@ -151,6 +161,15 @@ func (s *Scheduler) Update(msg loop.Msg) loop.Cmd {
 		}
 		cmds = append(cmds, s.ExecOutWalker.CmdDownloadCurrentSegment(msg.Wait))
 
+	case MsgListJobs:
+		listing := s.listJobs()
+		if msg.reply != nil {
+			msg.reply <- listing
+			return nil
+		}
+		s.dumpJobs(listing)
+		return s.cmdDumpJobs()
+
 	case execout.MsgWalkerCompleted:
 		s.outputStreamCompleted = true
 		return s.cmdShutdownWhenComplete()
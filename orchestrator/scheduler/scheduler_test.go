@@ -1,12 +1,24 @@
 package scheduler
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/metrics"
 	"github.com/streamingfast/substreams/orchestrator/execout"
 	"github.com/streamingfast/substreams/orchestrator/loop"
+	"github.com/streamingfast/substreams/orchestrator/plan"
+	"github.com/streamingfast/substreams/orchestrator/response"
+	"github.com/streamingfast/substreams/orchestrator/stage"
+	"github.com/streamingfast/substreams/orchestrator/work"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
 )
 
 func TestSched2_JobFinished(t *testing.T) {
@@ -35,3 +47,43 @@ func TestSched2_JobFinished(t *testing.T) {
 	//  * NextSegment()
 
 }
+
+func TestScheduler_ListJobs(t *testing.T) {
+	ctx := reqctx.WithReqStats(context.Background(), metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+	reqPlan, err := plan.BuildTier1RequestPlan(true, 10, 5, 5, 50, 50, true)
+	require.NoError(t, err)
+
+	s := New(ctx, nil)
+	s.Stages = stage.NewStages(ctx, outputmodules.TestGraphStagedModules(5, 5, 5, 5, 5), reqPlan, nil, "trace")
+	s.WorkerPool = work.NewWorkerPool(ctx, 1, func(logger *zap.Logger) work.Worker {
+		return work.NewWorkerFactoryFromFunc(func(ctx context.Context, unit stage.Unit, workRange *block.Range, moduleNames []string, upstream *response.Stream) loop.Cmd {
+			return func() loop.Msg { select {} } // never completes
+		})
+	})
+
+	list := func() *JobsListing {
+		reply := make(chan *JobsListing, 1)
+		assert.Nil(t, s.Update(MsgListJobs{reply: reply}))
+		return <-reply
+	}
+
+	listing := list()
+	assert.Empty(t, listing.InFlight)
+	require.NotEmpty(t, listing.Queued)
+	next := listing.Queued[0]
+
+	// the job is dispatched, the command running it is left pending
+	assert.NotNil(t, s.Update(work.MsgScheduleNextJob{}))
+
+	listing = list()
+	require.Len(t, listing.InFlight, 1)
+	inFlight := listing.InFlight[0]
+	assert.Equal(t, stage.Unit{Stage: next.Stage, Segment: next.Segment}, inFlight.Unit)
+	assert.Equal(t, block.NewRange(next.StartBlock, next.ExclusiveEndBlock), inFlight.Range)
+	assert.Equal(t, next.Modules, inFlight.Modules)
+	assert.NotEmpty(t, inFlight.WorkerID)
+	assert.GreaterOrEqual(t, inFlight.Elapsed, time.Duration(0))
+	for _, queued := range listing.Queued {
+		assert.False(t, queued.Stage == next.Stage && queued.Segment == next.Segment, "dispatched job is not queued anymore")
+	}
+}
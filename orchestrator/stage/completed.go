@@ -0,0 +1,48 @@
+package stage
+
+import (
+	"github.com/streamingfast/substreams/block"
+)
+
+// MarkCompletedRanges marks as completed the units whose segment is, for every module of
+// the stage, covered by one of the module's ranges in `completed`, keyed by module name.
+// The ranges are trusted as is, the storage is not looked up: it is meant for snapshots
+// known to exist, for example produced by another deployment. Called after
+// `FetchStoresState`, units it already found completed are left untouched.
+func (s *Stages) MarkCompletedRanges(completed map[string]block.Ranges) {
+	for stageIdx, stage := range s.stages {
+		for segmentIdx := stage.segmenter.FirstIndex(); segmentIdx <= stage.segmenter.LastIndex(); segmentIdx++ {
+			unit := Unit{Stage: stageIdx, Segment: segmentIdx}
+			if s.getState(unit) != UnitPending {
+				continue
+			}
+			if !stage.segmentCovered(segmentIdx, completed) {
+				continue
+			}
+			s.markSegmentCompleted(unit)
+		}
+
+		if stage.kind == KindStore {
+			s.MoveSegmentCompletedForward(stageIdx)
+		}
+	}
+}
+
+func (s *Stage) segmentCovered(segmentIdx int, completed map[string]block.Ranges) bool {
+	for _, mod := range s.moduleStates {
+		rng := mod.segmenter.Range(segmentIdx)
+		if rng == nil || !rangesCover(completed[mod.name], rng) {
+			return false
+		}
+	}
+	return true
+}
+
+func rangesCover(ranges block.Ranges, rng *block.Range) bool {
+	for _, r := range ranges {
+		if r.StartBlock <= rng.StartBlock && rng.ExclusiveEndBlock <= r.ExclusiveEndBlock {
+			return true
+		}
+	}
+	return false
+}
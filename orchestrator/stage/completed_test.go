@@ -0,0 +1,49 @@
+package stage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/orchestrator/plan"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+)
+
+func TestStages_MarkCompletedRanges(t *testing.T) {
+	reqPlan, err := plan.BuildTier1RequestPlan(true, 10, 5, 5, 50, 50, true)
+	require.NoError(t, err)
+	graph := outputmodules.TestGraphStagedModules(5, 5, 5, 5, 5)
+	for i, name := range []string{"store_a", "store_b"} {
+		graph.StagedUsedModules()[i].LastLayer()[0].Name = name
+	}
+
+	computed := NewStages(context.Background(), graph, reqPlan, nil, "trace").ComputeJobPlan()
+
+	stages := NewStages(context.Background(), graph, reqPlan, nil, "trace")
+	stages.MarkCompletedRanges(map[string]block.Ranges{
+		"store_a": {block.NewRange(5, 30)},
+		"store_b": {block.NewRange(5, 15)}, // only covers the first segment entirely
+	})
+	resumed := stages.ComputeJobPlan()
+
+	skipped := map[Unit]bool{
+		{Stage: 0, Segment: 0}: true,
+		{Stage: 0, Segment: 1}: true,
+		{Stage: 0, Segment: 2}: true,
+		{Stage: 1, Segment: 0}: true,
+	}
+	var expected JobPlan
+	for _, job := range computed {
+		if !skipped[job.unit()] {
+			expected = append(expected, job)
+		}
+	}
+	assert.ElementsMatch(t, expected, resumed)
+	for _, job := range resumed {
+		assert.False(t, skipped[job.unit()], "stage %d segment %d was completed externally", job.Stage, job.Segment)
+	}
+	assert.Equal(t, 2, stages.stages[0].segmentCompleted)
+}
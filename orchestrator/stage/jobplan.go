@@ -0,0 +1,158 @@
+package stage
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/streamingfast/substreams/block"
+)
+
+// PlannedJob is a sub-request of a JobPlan.
+type PlannedJob struct {
+	Stage             int      `json:"stage"`
+	Segment           int      `json:"segment"`
+	StartBlock        uint64   `json:"start_block"`
+	ExclusiveEndBlock uint64   `json:"exclusive_end_block"`
+	Modules           []string `json:"modules"`
+}
+
+func (j *PlannedJob) unit() Unit {
+	return Unit{Stage: j.Stage, Segment: j.Segment}
+}
+
+// JobPlan is the ordered list of sub-requests needed to backprocess a request. Computed
+// with `Stages.ComputeJobPlan`, it can be dumped and fed back with `Stages.SetJobPlan` to
+// replay the exact same sub-requests, in the same order, for debugging.
+type JobPlan []*PlannedJob
+
+func ParseJobPlan(data []byte) (JobPlan, error) {
+	var out JobPlan
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing job plan: %w", err)
+	}
+	return out, nil
+}
+
+func (p JobPlan) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, job := range p {
+		enc.AppendString(fmt.Sprintf("stage %d segment %d [%d, %d) %v", job.Stage, job.Segment, job.StartBlock, job.ExclusiveEndBlock, job.Modules))
+	}
+	return nil
+}
+
+// ComputeJobPlan returns the jobs that `NextJob` hands out, in the order it does when each
+// job, and the merge of its output, completes before the next one is scheduled. The
+// stages are left untouched.
+func (s *Stages) ComputeJobPlan() JobPlan {
+	simulated := &Stages{
+		globalSegmenter: s.globalSegmenter,
+		stages:          s.stages,
+		segmentOffset:   s.segmentOffset,
+		segmentStates:   make([]stageStates, len(s.segmentStates)),
+	}
+	for i, states := range s.segmentStates {
+		simulated.segmentStates[i] = slices.Clone(states)
+	}
+
+	var out JobPlan
+	for {
+		unit, rng := simulated.nextComputedJob()
+		if rng == nil {
+			return out
+		}
+		out = append(out, &PlannedJob{
+			Stage:             unit.Stage,
+			Segment:           unit.Segment,
+			StartBlock:        rng.StartBlock,
+			ExclusiveEndBlock: rng.ExclusiveEndBlock,
+			Modules:           s.StageModules(unit.Stage),
+		})
+		simulated.setState(unit, UnitCompleted)
+	}
+}
+
+// QueuedJobs returns the jobs `NextJob` has yet to hand out, in order: the rest of the
+// fixed plan when one is set, the computed ones otherwise.
+func (s *Stages) QueuedJobs() JobPlan {
+	if s.jobPlan != nil {
+		return slices.Clone(s.jobPlan)
+	}
+	return s.ComputeJobPlan()
+}
+
+// SetJobPlan makes `NextJob` hand out the jobs of `jobPlan`, in order, instead of
+// computing them. The plan must hold the jobs `ComputeJobPlan` would return, in any
+// order compatible with their dependencies.
+func (s *Stages) SetJobPlan(jobPlan JobPlan) error {
+	expected := make(map[Unit]*PlannedJob)
+	for _, job := range s.ComputeJobPlan() {
+		expected[job.unit()] = job
+	}
+
+	seen := make(map[Unit]bool)
+	for i, job := range jobPlan {
+		unit := job.unit()
+		want, found := expected[unit]
+		if !found {
+			return fmt.Errorf("planned job %d: stage %d segment %d does not need processing", i, job.Stage, job.Segment)
+		}
+		if seen[unit] {
+			return fmt.Errorf("planned job %d: stage %d segment %d is planned more than once", i, job.Stage, job.Segment)
+		}
+		seen[unit] = true
+		if job.StartBlock != want.StartBlock || job.ExclusiveEndBlock != want.ExclusiveEndBlock {
+			return fmt.Errorf("planned job %d: range [%d, %d) does not match the range of stage %d segment %d, [%d, %d)", i, job.StartBlock, job.ExclusiveEndBlock, job.Stage, job.Segment, want.StartBlock, want.ExclusiveEndBlock)
+		}
+		if !slices.Equal(job.Modules, want.Modules) {
+			return fmt.Errorf("planned job %d: modules %v do not match the modules of stage %d, %v", i, job.Modules, job.Stage, want.Modules)
+		}
+	}
+	if len(seen) != len(expected) {
+		return fmt.Errorf("job plan is missing %d of the %d jobs needed", len(expected)-len(seen), len(expected))
+	}
+
+	s.jobPlan = jobPlan
+	s.logger.Info("using fixed job plan", zap.Array("jobs", jobPlan))
+	return nil
+}
+
+// nextPlannedJob returns the next job of the fixed plan once its dependencies are
+// completed, nil until then or when the plan is exhausted.
+func (s *Stages) nextPlannedJob() (Unit, *block.Range) {
+	s.completeEmptyUnits()
+	if len(s.jobPlan) == 0 {
+		return Unit{}, nil
+	}
+	job := s.jobPlan[0]
+	unit := job.unit()
+	if !s.dependenciesCompleted(unit) {
+		return Unit{}, nil
+	}
+
+	s.jobPlan = s.jobPlan[1:]
+	s.markSegmentScheduled(unit)
+	return unit, block.NewRange(job.StartBlock, job.ExclusiveEndBlock)
+}
+
+// completeEmptyUnits marks as completed the units that cover no blocks, as `NextJob` does
+// when computing jobs, since they are never part of a plan.
+func (s *Stages) completeEmptyUnits() {
+	for segmentIdx := s.globalSegmenter.FirstIndex(); segmentIdx <= s.globalSegmenter.LastIndex(); segmentIdx++ {
+		for stageIdx, stage := range s.stages {
+			unit := Unit{Segment: segmentIdx, Stage: stageIdx}
+			if segmentIdx < stage.segmenter.FirstIndex() || segmentIdx > stage.segmenter.LastIndex() {
+				continue
+			}
+			if s.getState(unit) != UnitPending || !s.dependenciesCompleted(unit) {
+				continue
+			}
+			if stage.segmenter.Range(segmentIdx).Len() == 0 {
+				s.markSegmentCompleted(unit)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/orchestrator/plan"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+)
+
+func newJobPlanTestStages(t *testing.T) *Stages {
+	t.Helper()
+	reqPlan, err := plan.BuildTier1RequestPlan(true, 10, 5, 5, 50, 50, true)
+	require.NoError(t, err)
+	return NewStages(context.Background(), outputmodules.TestGraphStagedModules(5, 5, 5, 5, 5), reqPlan, nil, "trace")
+}
+
+// runJobs hands out jobs until none is left, completing the scheduled ones in
+// batches, like a worker pool would with enough workers.
+func runJobs(stages *Stages) (out JobPlan) {
+	for {
+		var scheduled []Unit
+		for {
+			unit, rng := stages.NextJob()
+			if rng == nil {
+				break
+			}
+			scheduled = append(scheduled, unit)
+			out = append(out, &PlannedJob{
+				Stage:             unit.Stage,
+				Segment:           unit.Segment,
+				StartBlock:        rng.StartBlock,
+				ExclusiveEndBlock: rng.ExclusiveEndBlock,
+				Modules:           stages.StageModules(unit.Stage),
+			})
+		}
+		if len(scheduled) == 0 {
+			return out
+		}
+		for _, unit := range scheduled {
+			stages.setState(unit, UnitCompleted)
+		}
+	}
+}
+
+func TestStages_JobPlanReplay(t *testing.T) {
+	dumped := newJobPlanTestStages(t).ComputeJobPlan()
+	require.NotEmpty(t, dumped)
+
+	cnt, err := json.Marshal(dumped)
+	require.NoError(t, err)
+	jobPlan, err := ParseJobPlan(cnt)
+	require.NoError(t, err)
+
+	// a plan in a different order than the computed one is followed as is
+	reversedSegments := make(JobPlan, 0, len(jobPlan))
+	for i := len(jobPlan) - 1; i >= 0; i-- {
+		if jobPlan[i].Segment == 0 {
+			reversedSegments = append(reversedSegments, jobPlan[i])
+		}
+	}
+	for _, job := range jobPlan {
+		if job.Segment != 0 {
+			reversedSegments = append(reversedSegments, job)
+		}
+	}
+
+	for _, replayed := range []JobPlan{jobPlan, reversedSegments} {
+		stages := newJobPlanTestStages(t)
+		require.NoError(t, stages.SetJobPlan(replayed))
+		assert.Equal(t, replayed, runJobs(stages))
+	}
+}
+
+func TestStages_SetJobPlanValidation(t *testing.T) {
+	stages := newJobPlanTestStages(t)
+	jobPlan := stages.ComputeJobPlan()
+
+	badRange := *jobPlan[0]
+	badRange.ExclusiveEndBlock++
+	assert.ErrorContains(t, stages.SetJobPlan(append(JobPlan{&badRange}, jobPlan[1:]...)), "does not match the range")
+	assert.ErrorContains(t, stages.SetJobPlan(jobPlan[1:]), "job plan is missing 1 of the")
+	assert.ErrorContains(t, stages.SetJobPlan(append(jobPlan, jobPlan[0])), "planned more than once")
+	assert.Nil(t, stages.jobPlan)
+}
@@ -17,19 +17,38 @@ type ModuleState struct {
 
 	segmenter *block.Segmenter
 
-	storeConfig *store.Config
+	storeConfig   *store.Config
+	partialLoader *store.PartialLoader // nil when partials are loaded right before their merge
 
 	cachedStore      *store.FullKV
 	lastBlockInStore uint64
+
+	// audit of the full snapshots, see `store.Config.SetSnapshotAudit`
+	auditBase        *store.FileInfo   // full snapshot the partials merged since are replayed on, nil for an empty store
+	auditBaseWritten <-chan struct{}   // closed once auditBase is in storage
+	auditPartials    []*store.FileInfo // partials merged on top of auditBase
+	auditDeletions   []*store.FileInfo // partials whose deletion waits for the next audit
 }
 
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
 func NewModuleState(logger *zap.Logger, name string, segmenter *block.Segmenter, storeConfig *store.Config) *ModuleState {
-	return &ModuleState{
+	s := &ModuleState{
 		name:        name,
 		segmenter:   segmenter,
 		logger:      logger,
 		storeConfig: storeConfig,
+
+		auditBaseWritten: closedChan,
 	}
+	if storeConfig != nil && storeConfig.MergeConcurrency() > 0 {
+		s.partialLoader = store.NewPartialLoader(storeConfig, storeConfig.MergeConcurrency(), logger)
+	}
+	return s
 }
 
 func (s *ModuleState) getStore(ctx context.Context, exclusiveEndBlock uint64) (*store.FullKV, error) {
@@ -38,20 +57,42 @@ func (s *ModuleState) getStore(ctx context.Context, exclusiveEndBlock uint64) (*
 	}
 	loadStore := s.storeConfig.NewFullKV(s.logger)
 	moduleInitBlock := s.storeConfig.ModuleInitialBlock()
+	var fullKVFile *store.FileInfo
 	if moduleInitBlock != exclusiveEndBlock {
-		fullKVFile := store.NewCompleteFileInfo(s.name, moduleInitBlock, exclusiveEndBlock)
+		fullKVFile = store.NewCompleteFileInfo(s.name, moduleInitBlock, exclusiveEndBlock)
 		err := loadStore.Load(ctx, fullKVFile)
 		if err != nil {
 			return nil, fmt.Errorf("load store %q: %w", s.name, err)
 		}
 	}
+	if s.cachedStore != nil {
+		s.cachedStore.Release()
+	}
 	s.cachedStore = loadStore
 	s.lastBlockInStore = exclusiveEndBlock
+	s.auditBase, s.auditBaseWritten, s.auditPartials = fullKVFile, closedChan, nil
 	return loadStore, nil
 }
 
-func (s *ModuleState) derivePartialKV(initialBlock uint64) *store.PartialKV {
-	return s.storeConfig.NewPartialKV(initialBlock, s.logger)
+// prefetchPartial starts loading the partial of `segment` so it is ready when its turn
+// to be merged comes, if merge concurrency is enabled.
+func (s *ModuleState) prefetchPartial(ctx context.Context, segment int, traceID string) {
+	if s.partialLoader == nil || segment < s.segmenter.FirstIndex() || segment > s.segmenter.LastIndex() {
+		return
+	}
+	rng := s.segmenter.Range(segment)
+	s.partialLoader.Prefetch(ctx, store.NewPartialFileInfo(s.name, rng.StartBlock, rng.ExclusiveEndBlock, traceID))
+}
+
+func (s *ModuleState) loadPartialKV(ctx context.Context, file *store.FileInfo) (*store.PartialKV, error) {
+	if s.partialLoader != nil {
+		return s.partialLoader.Take(ctx, file)
+	}
+	partialKV := s.storeConfig.NewPartialKV(file.Range.StartBlock, s.logger)
+	if err := partialKV.Load(ctx, file); err != nil {
+		return nil, err
+	}
+	return partialKV, nil
 }
 
 //type MergeState int
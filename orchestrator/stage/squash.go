@@ -59,7 +59,6 @@ func (s *Stages) singleSquash(stage *Stage, modState *ModuleState, mergeUnit Uni
 
 	rng := modState.segmenter.Range(mergeUnit.Segment)
 	partialFile := store.NewPartialFileInfo(modState.name, rng.StartBlock, rng.ExclusiveEndBlock, s.traceID)
-	partialKV := modState.derivePartialKV(rng.StartBlock)
 	segmentEndsOnInterval := modState.segmenter.EndsOnInterval(mergeUnit.Segment)
 
 	// Retrieve store to merge, from cache or load from storage. Allows skipping of segments
@@ -71,21 +70,31 @@ func (s *Stages) singleSquash(stage *Stage, modState *ModuleState, mergeUnit Uni
 
 	// Load
 	metrics.loadStart = time.Now()
-	if err := partialKV.Load(s.ctx, partialFile); err != nil {
+	partialKV, err := modState.loadPartialKV(s.ctx, partialFile)
+	if err != nil {
 		return fmt.Errorf("loading partial: %q: %w", partialFile.Filename, err)
 	}
 	metrics.loadEnd = time.Now()
 
 	// Merge
 	metrics.mergeStart = time.Now()
-	if err := fullKV.Merge(partialKV); err != nil {
+	err = fullKV.Merge(partialKV)
+	partialKV.Release() // merged, its content only lives on in the full store
+	if err != nil {
 		return fmt.Errorf("merging: %w", err)
 	}
 	modState.lastBlockInStore = rng.ExclusiveEndBlock
 	metrics.mergeEnd = time.Now()
 
-	// Delete partial store
-	if reqctx.Details(s.ctx).ProductionMode || segmentEndsOnInterval { /* FIXME: compute this elsewhere? */
+	// Delete partial store, once audited when the full snapshots are
+	deletePartial := reqctx.Details(s.ctx).ProductionMode || segmentEndsOnInterval /* FIXME: compute this elsewhere? */
+	auditEnabled := modState.storeConfig.SnapshotAuditEnabled()
+	if auditEnabled {
+		modState.auditPartials = append(modState.auditPartials, partialFile)
+		if deletePartial {
+			modState.auditDeletions = append(modState.auditDeletions, partialFile)
+		}
+	} else if deletePartial {
 		s.logger.Info("deleting store", zap.Stringer("store", partialKV))
 		stage.asyncWork.Go(func() error {
 			return partialKV.DeleteStore(s.ctx, partialFile)
@@ -95,14 +104,42 @@ func (s *Stages) singleSquash(stage *Stage, modState *ModuleState, mergeUnit Uni
 	// Flush full store
 	if segmentEndsOnInterval {
 		metrics.saveStart = time.Now()
-		_, writer, err := fullKV.Save(rng.ExclusiveEndBlock)
+		file, writer, err := fullKV.Save(rng.ExclusiveEndBlock)
 		if err != nil {
 			return fmt.Errorf("save full store: %w", err)
 		}
 		metrics.saveEnd = time.Now()
 
+		var audit *store.SnapshotAudit
+		var auditBaseWritten <-chan struct{}
+		var auditDeletions []*store.FileInfo
+		written := make(chan struct{})
+		if auditEnabled {
+			audit = store.NewSnapshotAudit(modState.storeConfig, file, modState.auditBase, modState.auditPartials)
+			auditBaseWritten, auditDeletions = modState.auditBaseWritten, modState.auditDeletions
+			modState.auditBase, modState.auditBaseWritten = file, written
+			modState.auditPartials, modState.auditDeletions = nil, nil
+		}
+
 		stage.asyncWork.Go(func() error {
-			return writer.Write(context.Background()) // always write files here even if the request was cancelled.
+			err := writer.Write(context.Background()) // always write files here even if the request was cancelled.
+			close(written)
+			if err != nil {
+				return err
+			}
+			if audit == nil {
+				return nil
+			}
+
+			<-auditBaseWritten
+			s.auditSnapshot(audit, modState.name, file)
+			for _, deletion := range auditDeletions {
+				s.logger.Info("deleting audited store", zap.String("file", deletion.Filename))
+				if err := partialKV.DeleteStore(s.ctx, deletion); err != nil {
+					return err
+				}
+			}
+			return nil
 		})
 	}
 
@@ -110,3 +147,22 @@ func (s *Stages) singleSquash(stage *Stage, modState *ModuleState, mergeUnit Uni
 
 	return nil
 }
+
+// auditSnapshot verifies that the snapshot just written matches the replay of the
+// partials it was produced from. Mismatches are only reported, they never fail the request.
+func (s *Stages) auditSnapshot(audit *store.SnapshotAudit, moduleName string, file *store.FileInfo) {
+	diffs, err := audit.Run(context.Background())
+	if err != nil {
+		s.logger.Warn("cannot audit store snapshot", zap.String("module_name", moduleName), zap.String("file", file.Filename), zap.Error(err))
+		return
+	}
+	if len(diffs) == 0 {
+		return
+	}
+
+	divergent := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		divergent = append(divergent, diff.String())
+	}
+	s.logger.Error("store snapshot does not match the replay of its partials", zap.String("module_name", moduleName), zap.String("file", file.Filename), zap.Int("divergent_key_count", len(diffs)), zap.Strings("divergent_keys", divergent))
+}
@@ -48,6 +48,9 @@ type Stages struct {
 	// Any previous segment is assumed to have completed successfully, and any stores that we sync'd prior to this offset
 	// are assumed to have been either fully loaded, or merged up until this offset.
 	segmentOffset int
+
+	// jobPlan, when set, holds the remaining jobs of a fixed plan, see `SetJobPlan`.
+	jobPlan JobPlan
 }
 type stageStates []UnitState
 
@@ -334,6 +337,13 @@ func (s *Stages) WaitAsyncWork() error {
 }
 
 func (s *Stages) NextJob() (Unit, *block.Range) {
+	if s.jobPlan != nil {
+		return s.nextPlannedJob()
+	}
+	return s.nextComputedJob()
+}
+
+func (s *Stages) nextComputedJob() (Unit, *block.Range) {
 	// OPTIMIZATION: before calling NextJob, keep a small reserve (10% ?) of workers
 	//  so that when a job finishes, it can start immediately a potentially
 	//  higher priority one (we'll go do all those first-level jobs
@@ -421,6 +431,10 @@ func (s *Stages) FinalStoreMap(exclusiveEndBlock uint64) (store.Map, error) {
 			continue
 		}
 		for _, modState := range stage.moduleStates {
+			// all the partials were merged, those still prefetched are not needed
+			if modState.partialLoader != nil {
+				modState.partialLoader.Close()
+			}
 			fullKV, err := modState.getStore(s.ctx, exclusiveEndBlock)
 			if err != nil {
 				return nil, fmt.Errorf("stores didn't sync up properly, expected store %q to be at block %d but was at %d: %w", modState.name, exclusiveEndBlock, modState.lastBlockInStore, err)
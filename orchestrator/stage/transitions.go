@@ -112,6 +112,11 @@ func (s *Stages) MarkSegmentPartialPresent(u Unit) {
 		UnitScheduled, // reported by working completing its generation of a partial
 		UnitPending,   // from initial storage state snapshot
 	)
+	if stage := s.stages[u.Stage]; stage.kind == KindStore {
+		for _, modState := range stage.moduleStates {
+			modState.prefetchPartial(s.ctx, u.Segment, s.traceID)
+		}
+	}
 }
 
 func (s *Stages) markSegmentScheduled(u Unit) {
@@ -8,8 +8,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/sethvargo/go-retry"
 	"github.com/streamingfast/dauth"
-	"github.com/streamingfast/derr"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -63,11 +63,24 @@ func (f SimpleWorkerFactory) ID() string {
 // The tracer will be provided by the worker pool, on worker creation
 type WorkerFactory = func(logger *zap.Logger) Worker
 
+const (
+	// DefaultMaxRetries is the number of times a job failing with a retryable error is
+	// retried before failing the request.
+	DefaultMaxRetries = 3
+	// DefaultRetryBackoff is the initial delay between the attempts of a job.
+	DefaultRetryBackoff = time.Second
+
+	maxRetryBackoff = 5 * time.Second
+)
+
 type RemoteWorker struct {
 	clientFactory client.InternalClientFactory
 	tracer        ttrace.Tracer
 	logger        *zap.Logger
 	id            uint64
+
+	maxRetries   uint64
+	retryBackoff time.Duration // initial delay between attempts, growing on each retry
 }
 
 func NewRemoteWorker(clientFactory client.InternalClientFactory, logger *zap.Logger) *RemoteWorker {
@@ -76,21 +89,36 @@ func NewRemoteWorker(clientFactory client.InternalClientFactory, logger *zap.Log
 		tracer:        otel.GetTracerProvider().Tracer("worker"),
 		logger:        logger,
 		id:            atomic.AddUint64(&lastWorkerID, 1),
+		maxRetries:    DefaultMaxRetries,
+		retryBackoff:  DefaultRetryBackoff,
 	}
 }
 
+// SetRetryPolicy configures how many times a job failing with a retryable error, a
+// tier2 becoming unavailable for example, is retried, and the initial backoff between
+// attempts. The backoff follows a Fibonacci sequence, up to 5 seconds unless it starts higher.
+func (w *RemoteWorker) SetRetryPolicy(maxRetries uint64, backoff time.Duration) {
+	w.maxRetries = maxRetries
+	w.retryBackoff = backoff
+}
+
 func (w *RemoteWorker) ID() string {
 	return fmt.Sprintf("%d", w.id)
 }
 
 func NewRequest(req *reqctx.RequestDetails, stageIndex int, workRange *block.Range) *pbssinternal.ProcessRangeRequest {
-	return &pbssinternal.ProcessRangeRequest{
+	out := &pbssinternal.ProcessRangeRequest{
 		StartBlockNum: workRange.StartBlock,
 		StopBlockNum:  workRange.ExclusiveEndBlock,
 		Modules:       req.Modules,
 		OutputModule:  req.OutputModule,
 		Stage:         uint32(stageIndex),
 	}
+	if req.SubrequestModulesHash != "" {
+		out.Modules = nil
+		out.ModulesHash = req.SubrequestModulesHash
+	}
+	return out
 }
 
 func (w *RemoteWorker) Work(ctx context.Context, unit stage.Unit, workRange *block.Range, moduleNames []string, upstream *response.Stream) loop.Cmd {
@@ -98,23 +126,9 @@ func (w *RemoteWorker) Work(ctx context.Context, unit stage.Unit, workRange *blo
 	logger := reqctx.Logger(ctx)
 
 	return func() loop.Msg {
-		var res *Result
-		retryIdx := 0
 		startTime := time.Now()
-		err := derr.RetryContext(ctx, 3, func(ctx context.Context) error {
-			res = w.work(ctx, request, moduleNames, upstream)
-			err := res.Error
-			switch err.(type) {
-			case *RetryableErr:
-				logger.Debug("worker failed with retryable error", zap.Error(err))
-				retryIdx++
-				return err
-			default:
-				if err != nil {
-					return derr.NewFatalError(err)
-				}
-				return nil
-			}
+		retryIdx, err := w.retry(ctx, logger, unit, func(ctx context.Context) error {
+			return w.work(ctx, request, moduleNames, upstream).Error
 		})
 
 		if err != nil {
@@ -159,6 +173,30 @@ func (w *RemoteWorker) Work(ctx context.Context, unit stage.Unit, workRange *blo
 	}
 }
 
+// retry runs `attempt` until it succeeds or fails with an error other than a
+// *RetryableErr, at most `maxRetries` more times, and returns the number of retries. It
+// backs off like derr.RetryContext, following a Fibonacci sequence capped at 5 seconds,
+// from the configured initial backoff.
+func (w *RemoteWorker) retry(ctx context.Context, logger *zap.Logger, unit stage.Unit, attempt func(ctx context.Context) error) (retries int, err error) {
+	backoff := retry.NewFibonacci(w.retryBackoff)
+	backoff = retry.WithMaxRetries(w.maxRetries, backoff)
+	backoff = retry.WithCappedDuration(max(maxRetryBackoff, w.retryBackoff), backoff)
+
+	attempts := 0
+	err = retry.Do(ctx, backoff, func(ctx context.Context) error {
+		attempts++
+		err := attempt(ctx)
+		switch err.(type) {
+		case *RetryableErr:
+			logger.Debug("worker failed with retryable error", zap.Object("unit", unit), zap.Int("attempt", attempts), zap.Error(err))
+			return retry.RetryableError(err)
+		default:
+			return err
+		}
+	})
+	return attempts - 1, err
+}
+
 func (w *RemoteWorker) work(ctx context.Context, request *pbssinternal.ProcessRangeRequest, moduleNames []string, upstream *response.Stream) *Result {
 	var err error
 
@@ -246,6 +284,9 @@ func (w *RemoteWorker) work(ctx context.Context, request *pbssinternal.ProcessRa
 
 				err := fmt.Errorf("work failed on remote host: %s", r.Failed.Reason)
 				span.SetStatus(codes.Error, err.Error())
+				if r.Failed.Retryable {
+					return &Result{Error: NewRetryableErr(err)}
+				}
 				return &Result{Error: err}
 
 			case *pbssinternal.ProcessRangeResponse_Completed:
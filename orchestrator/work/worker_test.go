@@ -0,0 +1,112 @@
+package work
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpcCodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/metrics"
+	"github.com/streamingfast/substreams/orchestrator/stage"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+// flakyClient fails the first `failures` sub-requests as an unavailable tier2 would,
+// then completes them.
+type flakyClient struct {
+	failures int
+	attempts int
+}
+
+func (c *flakyClient) ProcessRange(ctx context.Context, in *pbssinternal.ProcessRangeRequest, opts ...grpc.CallOption) (pbssinternal.Substreams_ProcessRangeClient, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return nil, status.Error(grpcCodes.Unavailable, "tier2 unavailable")
+	}
+	return &completedStream{}, nil
+}
+
+type completedStream struct {
+	grpc.ClientStream
+}
+
+func (s *completedStream) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (s *completedStream) CloseSend() error             { return nil }
+func (s *completedStream) Recv() (*pbssinternal.ProcessRangeResponse, error) {
+	return &pbssinternal.ProcessRangeResponse{
+		Type: &pbssinternal.ProcessRangeResponse_Completed{Completed: &pbssinternal.Completed{}},
+	}, nil
+}
+
+func TestRemoteWorker_RetriesTransientFailures(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{OutputModule: "test_map"})
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+	stats.RecordStages([]*pbsubstreamsrpc.Stage{{Modules: []string{"test_map"}}})
+	ctx = reqctx.WithReqStats(ctx, stats)
+
+	run := func(failures int, maxRetries uint64) (*flakyClient, any) {
+		cli := &flakyClient{failures: failures}
+		worker := NewRemoteWorker(func() (pbssinternal.SubstreamsClient, func() error, []grpc.CallOption, error) {
+			return cli, func() error { return nil }, nil, nil
+		}, zap.NewNop())
+		worker.SetRetryPolicy(maxRetries, time.Millisecond)
+
+		msg := worker.Work(ctx, stage.Unit{Segment: 1}, block.NewRange(100, 200), []string{"test_map"}, nil)()
+		return cli, msg
+	}
+
+	cli, msg := run(2, 3)
+	assert.Equal(t, 3, cli.attempts)
+	assert.IsType(t, MsgJobSucceeded{}, msg)
+
+	cli, msg = run(2, 1)
+	assert.Equal(t, 2, cli.attempts)
+	require.IsType(t, MsgJobFailed{}, msg)
+	assert.ErrorContains(t, msg.(MsgJobFailed).Error, "tier2 unavailable")
+}
+
+// failingClient fails every sub-request as a module failing deterministically would.
+type failingClient struct {
+	attempts int
+}
+
+func (c *failingClient) ProcessRange(ctx context.Context, in *pbssinternal.ProcessRangeRequest, opts ...grpc.CallOption) (pbssinternal.Substreams_ProcessRangeClient, error) {
+	c.attempts++
+	return &failingStream{}, nil
+}
+
+type failingStream struct {
+	completedStream
+}
+
+func (s *failingStream) Recv() (*pbssinternal.ProcessRangeResponse, error) {
+	return nil, status.Error(grpcCodes.InvalidArgument, "wasm execution failed deterministically")
+}
+
+func TestRemoteWorker_DoesNotRetryDeterministicFailures(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{OutputModule: "test_map"})
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+	stats.RecordStages([]*pbsubstreamsrpc.Stage{{Modules: []string{"test_map"}}})
+	ctx = reqctx.WithReqStats(ctx, stats)
+
+	cli := &failingClient{}
+	worker := NewRemoteWorker(func() (pbssinternal.SubstreamsClient, func() error, []grpc.CallOption, error) {
+		return cli, func() error { return nil }, nil, nil
+	}, zap.NewNop())
+	worker.SetRetryPolicy(3, time.Millisecond)
+
+	msg := worker.Work(ctx, stage.Unit{Segment: 1}, block.NewRange(100, 200), []string{"test_map"}, nil)()
+	assert.Equal(t, 1, cli.attempts)
+	require.IsType(t, MsgJobFailed{}, msg)
+	assert.ErrorContains(t, msg.(MsgJobFailed).Error, "failed deterministically")
+}
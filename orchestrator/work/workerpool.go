@@ -2,9 +2,14 @@ package work
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/orchestrator/stage"
 	"github.com/streamingfast/substreams/reqctx"
 )
 
@@ -22,6 +27,27 @@ const (
 type WorkerStatus struct {
 	State  WorkerState
 	Worker Worker
+	Job    *InFlightJob // set while working, when dispatched with `Dispatched`
+}
+
+// InFlightJob is a sub-request dispatched to a worker and not completed yet.
+type InFlightJob struct {
+	WorkerID string
+	Unit     stage.Unit
+	Range    *block.Range
+	Modules  []string
+	Started  time.Time
+	Elapsed  time.Duration // filled by `InFlight`
+}
+
+func (j *InFlightJob) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("worker", j.WorkerID)
+	enc.AddInt("stage", j.Unit.Stage)
+	enc.AddInt("segment", j.Unit.Segment)
+	enc.AddString("range", j.Range.String())
+	enc.AddString("modules", strings.Join(j.Modules, ","))
+	enc.AddDuration("elapsed", j.Elapsed)
+	return nil
 }
 
 func NewWorkerPool(ctx context.Context, workerCount int, workerFactory WorkerFactory) *WorkerPool {
@@ -78,7 +104,39 @@ func (p *WorkerPool) Return(worker Worker) {
 				panic("returned worker was already free")
 			}
 			status.State = WorkerFree
+			status.Job = nil
 			return
 		}
 	}
 }
+
+// Dispatched records that `worker`, borrowed, was handed the job for `unit`.
+func (p *WorkerPool) Dispatched(worker Worker, unit stage.Unit, workRange *block.Range, modules []string) {
+	for _, status := range p.workers {
+		if status.Worker == worker {
+			status.Job = &InFlightJob{
+				WorkerID: worker.ID(),
+				Unit:     unit,
+				Range:    workRange,
+				Modules:  modules,
+				Started:  time.Now(),
+			}
+			return
+		}
+	}
+}
+
+// InFlight returns the jobs dispatched to workers that did not complete yet, in the
+// order of the workers.
+func (p *WorkerPool) InFlight() (out []*InFlightJob) {
+	now := time.Now()
+	for _, status := range p.workers {
+		if status.State != WorkerWorking || status.Job == nil {
+			continue
+		}
+		job := *status.Job
+		job.Elapsed = now.Sub(job.Started)
+		out = append(out, &job)
+	}
+	return out
+}
@@ -22,6 +22,57 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type StoreDeltaFormat int32
+
+const (
+	StoreDeltaFormat_STORE_DELTA_FORMAT_PROTOBUF StoreDeltaFormat = 0
+	// The keys and values are decoded according to the store's value type.
+	StoreDeltaFormat_STORE_DELTA_FORMAT_JSON StoreDeltaFormat = 1
+	// A compact binary layout, one column per field, with the values left as is.
+	StoreDeltaFormat_STORE_DELTA_FORMAT_COLUMNAR StoreDeltaFormat = 2
+)
+
+// Enum value maps for StoreDeltaFormat.
+var (
+	StoreDeltaFormat_name = map[int32]string{
+		0: "STORE_DELTA_FORMAT_PROTOBUF",
+		1: "STORE_DELTA_FORMAT_JSON",
+		2: "STORE_DELTA_FORMAT_COLUMNAR",
+	}
+	StoreDeltaFormat_value = map[string]int32{
+		"STORE_DELTA_FORMAT_PROTOBUF": 0,
+		"STORE_DELTA_FORMAT_JSON":     1,
+		"STORE_DELTA_FORMAT_COLUMNAR": 2,
+	}
+)
+
+func (x StoreDeltaFormat) Enum() *StoreDeltaFormat {
+	p := new(StoreDeltaFormat)
+	*p = x
+	return p
+}
+
+func (x StoreDeltaFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StoreDeltaFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_sf_substreams_rpc_v2_service_proto_enumTypes[0].Descriptor()
+}
+
+func (StoreDeltaFormat) Type() protoreflect.EnumType {
+	return &file_sf_substreams_rpc_v2_service_proto_enumTypes[0]
+}
+
+func (x StoreDeltaFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StoreDeltaFormat.Descriptor instead.
+func (StoreDeltaFormat) EnumDescriptor() ([]byte, []int) {
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{0}
+}
+
 type StoreDelta_Operation int32
 
 const (
@@ -58,11 +109,11 @@ func (x StoreDelta_Operation) String() string {
 }
 
 func (StoreDelta_Operation) Descriptor() protoreflect.EnumDescriptor {
-	return file_sf_substreams_rpc_v2_service_proto_enumTypes[0].Descriptor()
+	return file_sf_substreams_rpc_v2_service_proto_enumTypes[1].Descriptor()
 }
 
 func (StoreDelta_Operation) Type() protoreflect.EnumType {
-	return &file_sf_substreams_rpc_v2_service_proto_enumTypes[0]
+	return &file_sf_substreams_rpc_v2_service_proto_enumTypes[1]
 }
 
 func (x StoreDelta_Operation) Number() protoreflect.EnumNumber {
@@ -71,7 +122,7 @@ func (x StoreDelta_Operation) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use StoreDelta_Operation.Descriptor instead.
 func (StoreDelta_Operation) EnumDescriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{17, 0}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{22, 0}
 }
 
 type Request struct {
@@ -211,6 +262,8 @@ type Response struct {
 	//	*Response_BlockScopedData
 	//	*Response_BlockUndoSignal
 	//	*Response_FatalError
+	//	*Response_FailureSummary
+	//	*Response_StreamStart
 	//	*Response_DebugSnapshotData
 	//	*Response_DebugSnapshotComplete
 	Message isResponse_Message `protobuf_oneof:"message"`
@@ -290,6 +343,20 @@ func (x *Response) GetFatalError() *Error {
 	return nil
 }
 
+func (x *Response) GetFailureSummary() *FailureSummary {
+	if x, ok := x.GetMessage().(*Response_FailureSummary); ok {
+		return x.FailureSummary
+	}
+	return nil
+}
+
+func (x *Response) GetStreamStart() *StreamStart {
+	if x, ok := x.GetMessage().(*Response_StreamStart); ok {
+		return x.StreamStart
+	}
+	return nil
+}
+
 func (x *Response) GetDebugSnapshotData() *InitialSnapshotData {
 	if x, ok := x.GetMessage().(*Response_DebugSnapshotData); ok {
 		return x.DebugSnapshotData
@@ -328,6 +395,16 @@ type Response_FatalError struct {
 	FatalError *Error `protobuf:"bytes,5,opt,name=fatal_error,json=fatalError,proto3,oneof"`
 }
 
+type Response_FailureSummary struct {
+	// Sent last, when modules failed on some blocks without ending the stream.
+	FailureSummary *FailureSummary `protobuf:"bytes,6,opt,name=failure_summary,json=failureSummary,proto3,oneof"`
+}
+
+type Response_StreamStart struct {
+	// Sent first when requested, see `X-Sf-Substreams-Start-Ack`.
+	StreamStart *StreamStart `protobuf:"bytes,7,opt,name=stream_start,json=streamStart,proto3,oneof"`
+}
+
 type Response_DebugSnapshotData struct {
 	// Available only in developer mode, and only if `debug_initial_store_snapshot_for_modules` is set.
 	DebugSnapshotData *InitialSnapshotData `protobuf:"bytes,10,opt,name=debug_snapshot_data,json=debugSnapshotData,proto3,oneof"`
@@ -348,10 +425,72 @@ func (*Response_BlockUndoSignal) isResponse_Message() {}
 
 func (*Response_FatalError) isResponse_Message() {}
 
+func (*Response_FailureSummary) isResponse_Message() {}
+
+func (*Response_StreamStart) isResponse_Message() {}
+
 func (*Response_DebugSnapshotData) isResponse_Message() {}
 
 func (*Response_DebugSnapshotComplete) isResponse_Message() {}
 
+// StreamStart acknowledges the stream is established, before any processing. Resuming
+// from `cursor` restarts the stream at `start_block_num`, it is empty only when the
+// stream starts at the first streamable block of the chain.
+type StreamStart struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StartBlockNum uint64 `protobuf:"varint,1,opt,name=start_block_num,json=startBlockNum,proto3" json:"start_block_num,omitempty"`
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+}
+
+func (x *StreamStart) Reset() {
+	*x = StreamStart{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamStart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStart) ProtoMessage() {}
+
+func (x *StreamStart) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStart.ProtoReflect.Descriptor instead.
+func (*StreamStart) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamStart) GetStartBlockNum() uint64 {
+	if x != nil {
+		return x.StartBlockNum
+	}
+	return 0
+}
+
+func (x *StreamStart) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
 // BlockUndoSignal informs you that every bit of data
 // with a block number above 'last_valid_block' has been reverted
 // on-chain. Delete that data and restart from 'last_valid_cursor'
@@ -367,7 +506,7 @@ type BlockUndoSignal struct {
 func (x *BlockUndoSignal) Reset() {
 	*x = BlockUndoSignal{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[2]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -380,7 +519,7 @@ func (x *BlockUndoSignal) String() string {
 func (*BlockUndoSignal) ProtoMessage() {}
 
 func (x *BlockUndoSignal) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[2]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -393,7 +532,7 @@ func (x *BlockUndoSignal) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BlockUndoSignal.ProtoReflect.Descriptor instead.
 func (*BlockUndoSignal) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{2}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *BlockUndoSignal) GetLastValidBlock() *v1.BlockRef {
@@ -419,15 +558,25 @@ type BlockScopedData struct {
 	Clock  *v1.Clock        `protobuf:"bytes,2,opt,name=clock,proto3" json:"clock,omitempty"`
 	Cursor string           `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
 	// Non-deterministic, allows substreams-sink to let go of their undo data.
-	FinalBlockHeight  uint64               `protobuf:"varint,4,opt,name=final_block_height,json=finalBlockHeight,proto3" json:"final_block_height,omitempty"`
+	FinalBlockHeight uint64 `protobuf:"varint,4,opt,name=final_block_height,json=finalBlockHeight,proto3" json:"final_block_height,omitempty"`
+	// Set on the first block sent from the live handoff block: the blocks before it were
+	// backprocessed, it and the ones after it come from live processing and may be reorged.
+	LiveTransition bool `protobuf:"varint,5,opt,name=live_transition,json=liveTransition,proto3" json:"live_transition,omitempty"`
+	// Available only when requested with `X-Sf-Substreams-Errors-As-Data`, the errors of
+	// the modules that failed on the block without ending the stream. The modules
+	// depending on them were not run.
+	ModuleErrors      []*Error             `protobuf:"bytes,6,rep,name=module_errors,json=moduleErrors,proto3" json:"module_errors,omitempty"`
 	DebugMapOutputs   []*MapModuleOutput   `protobuf:"bytes,10,rep,name=debug_map_outputs,json=debugMapOutputs,proto3" json:"debug_map_outputs,omitempty"`
 	DebugStoreOutputs []*StoreModuleOutput `protobuf:"bytes,11,rep,name=debug_store_outputs,json=debugStoreOutputs,proto3" json:"debug_store_outputs,omitempty"`
+	// Available only when requested with `X-Sf-Substreams-Stream-Module-Logs`, the logs of
+	// the modules executed on the block, whether they failed or not.
+	DebugModuleLogs []*ModuleLogs `protobuf:"bytes,12,rep,name=debug_module_logs,json=debugModuleLogs,proto3" json:"debug_module_logs,omitempty"`
 }
 
 func (x *BlockScopedData) Reset() {
 	*x = BlockScopedData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[3]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -440,7 +589,7 @@ func (x *BlockScopedData) String() string {
 func (*BlockScopedData) ProtoMessage() {}
 
 func (x *BlockScopedData) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[3]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -453,7 +602,7 @@ func (x *BlockScopedData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BlockScopedData.ProtoReflect.Descriptor instead.
 func (*BlockScopedData) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{3}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *BlockScopedData) GetOutput() *MapModuleOutput {
@@ -484,6 +633,20 @@ func (x *BlockScopedData) GetFinalBlockHeight() uint64 {
 	return 0
 }
 
+func (x *BlockScopedData) GetLiveTransition() bool {
+	if x != nil {
+		return x.LiveTransition
+	}
+	return false
+}
+
+func (x *BlockScopedData) GetModuleErrors() []*Error {
+	if x != nil {
+		return x.ModuleErrors
+	}
+	return nil
+}
+
 func (x *BlockScopedData) GetDebugMapOutputs() []*MapModuleOutput {
 	if x != nil {
 		return x.DebugMapOutputs
@@ -498,6 +661,77 @@ func (x *BlockScopedData) GetDebugStoreOutputs() []*StoreModuleOutput {
 	return nil
 }
 
+func (x *BlockScopedData) GetDebugModuleLogs() []*ModuleLogs {
+	if x != nil {
+		return x.DebugModuleLogs
+	}
+	return nil
+}
+
+type ModuleLogs struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ModuleName string   `protobuf:"bytes,1,opt,name=module_name,json=moduleName,proto3" json:"module_name,omitempty"`
+	Logs       []string `protobuf:"bytes,2,rep,name=logs,proto3" json:"logs,omitempty"`
+	// See `OutputDebugInfo.logs_truncated`.
+	LogsTruncated bool `protobuf:"varint,3,opt,name=logs_truncated,json=logsTruncated,proto3" json:"logs_truncated,omitempty"`
+}
+
+func (x *ModuleLogs) Reset() {
+	*x = ModuleLogs{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ModuleLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModuleLogs) ProtoMessage() {}
+
+func (x *ModuleLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModuleLogs.ProtoReflect.Descriptor instead.
+func (*ModuleLogs) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ModuleLogs) GetModuleName() string {
+	if x != nil {
+		return x.ModuleName
+	}
+	return ""
+}
+
+func (x *ModuleLogs) GetLogs() []string {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *ModuleLogs) GetLogsTruncated() bool {
+	if x != nil {
+		return x.LogsTruncated
+	}
+	return false
+}
+
 type SessionInit struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -512,7 +746,7 @@ type SessionInit struct {
 func (x *SessionInit) Reset() {
 	*x = SessionInit{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[4]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -525,7 +759,7 @@ func (x *SessionInit) String() string {
 func (*SessionInit) ProtoMessage() {}
 
 func (x *SessionInit) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[4]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -538,7 +772,7 @@ func (x *SessionInit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionInit.ProtoReflect.Descriptor instead.
 func (*SessionInit) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{4}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *SessionInit) GetTraceId() string {
@@ -580,7 +814,7 @@ type InitialSnapshotComplete struct {
 func (x *InitialSnapshotComplete) Reset() {
 	*x = InitialSnapshotComplete{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[5]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -593,7 +827,7 @@ func (x *InitialSnapshotComplete) String() string {
 func (*InitialSnapshotComplete) ProtoMessage() {}
 
 func (x *InitialSnapshotComplete) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[5]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -606,7 +840,7 @@ func (x *InitialSnapshotComplete) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InitialSnapshotComplete.ProtoReflect.Descriptor instead.
 func (*InitialSnapshotComplete) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{5}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *InitialSnapshotComplete) GetCursor() string {
@@ -630,7 +864,7 @@ type InitialSnapshotData struct {
 func (x *InitialSnapshotData) Reset() {
 	*x = InitialSnapshotData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[6]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -643,7 +877,7 @@ func (x *InitialSnapshotData) String() string {
 func (*InitialSnapshotData) ProtoMessage() {}
 
 func (x *InitialSnapshotData) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[6]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -656,7 +890,7 @@ func (x *InitialSnapshotData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InitialSnapshotData.ProtoReflect.Descriptor instead.
 func (*InitialSnapshotData) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{6}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *InitialSnapshotData) GetModuleName() string {
@@ -701,7 +935,7 @@ type MapModuleOutput struct {
 func (x *MapModuleOutput) Reset() {
 	*x = MapModuleOutput{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[7]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -714,7 +948,7 @@ func (x *MapModuleOutput) String() string {
 func (*MapModuleOutput) ProtoMessage() {}
 
 func (x *MapModuleOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[7]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -727,7 +961,7 @@ func (x *MapModuleOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MapModuleOutput.ProtoReflect.Descriptor instead.
 func (*MapModuleOutput) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{7}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *MapModuleOutput) GetName() string {
@@ -760,15 +994,18 @@ type StoreModuleOutput struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name             string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	DebugStoreDeltas []*StoreDelta    `protobuf:"bytes,2,rep,name=debug_store_deltas,json=debugStoreDeltas,proto3" json:"debug_store_deltas,omitempty"`
-	DebugInfo        *OutputDebugInfo `protobuf:"bytes,10,opt,name=debug_info,json=debugInfo,proto3" json:"debug_info,omitempty"`
+	Name             string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DebugStoreDeltas []*StoreDelta `protobuf:"bytes,2,rep,name=debug_store_deltas,json=debugStoreDeltas,proto3" json:"debug_store_deltas,omitempty"`
+	// Set instead of `debug_store_deltas` when the deltas were requested in another
+	// format than protobuf, with `X-Sf-Substreams-Store-Delta-Format`.
+	DebugEncodedStoreDeltas *EncodedStoreDeltas `protobuf:"bytes,3,opt,name=debug_encoded_store_deltas,json=debugEncodedStoreDeltas,proto3" json:"debug_encoded_store_deltas,omitempty"`
+	DebugInfo               *OutputDebugInfo    `protobuf:"bytes,10,opt,name=debug_info,json=debugInfo,proto3" json:"debug_info,omitempty"`
 }
 
 func (x *StoreModuleOutput) Reset() {
 	*x = StoreModuleOutput{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[8]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -781,7 +1018,7 @@ func (x *StoreModuleOutput) String() string {
 func (*StoreModuleOutput) ProtoMessage() {}
 
 func (x *StoreModuleOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[8]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -794,7 +1031,7 @@ func (x *StoreModuleOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StoreModuleOutput.ProtoReflect.Descriptor instead.
 func (*StoreModuleOutput) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{8}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *StoreModuleOutput) GetName() string {
@@ -811,6 +1048,13 @@ func (x *StoreModuleOutput) GetDebugStoreDeltas() []*StoreDelta {
 	return nil
 }
 
+func (x *StoreModuleOutput) GetDebugEncodedStoreDeltas() *EncodedStoreDeltas {
+	if x != nil {
+		return x.DebugEncodedStoreDeltas
+	}
+	return nil
+}
+
 func (x *StoreModuleOutput) GetDebugInfo() *OutputDebugInfo {
 	if x != nil {
 		return x.DebugInfo
@@ -818,6 +1062,61 @@ func (x *StoreModuleOutput) GetDebugInfo() *OutputDebugInfo {
 	return nil
 }
 
+type EncodedStoreDeltas struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Format StoreDeltaFormat `protobuf:"varint,1,opt,name=format,proto3,enum=sf.substreams.rpc.v2.StoreDeltaFormat" json:"format,omitempty"`
+	Data   []byte           `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *EncodedStoreDeltas) Reset() {
+	*x = EncodedStoreDeltas{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncodedStoreDeltas) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodedStoreDeltas) ProtoMessage() {}
+
+func (x *EncodedStoreDeltas) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodedStoreDeltas.ProtoReflect.Descriptor instead.
+func (*EncodedStoreDeltas) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *EncodedStoreDeltas) GetFormat() StoreDeltaFormat {
+	if x != nil {
+		return x.Format
+	}
+	return StoreDeltaFormat_STORE_DELTA_FORMAT_PROTOBUF
+}
+
+func (x *EncodedStoreDeltas) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 type OutputDebugInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -833,7 +1132,7 @@ type OutputDebugInfo struct {
 func (x *OutputDebugInfo) Reset() {
 	*x = OutputDebugInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[9]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -846,7 +1145,7 @@ func (x *OutputDebugInfo) String() string {
 func (*OutputDebugInfo) ProtoMessage() {}
 
 func (x *OutputDebugInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[9]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -859,7 +1158,7 @@ func (x *OutputDebugInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use OutputDebugInfo.ProtoReflect.Descriptor instead.
 func (*OutputDebugInfo) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{9}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *OutputDebugInfo) GetLogs() []string {
@@ -901,7 +1200,7 @@ type ModulesProgress struct {
 func (x *ModulesProgress) Reset() {
 	*x = ModulesProgress{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[10]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -914,7 +1213,7 @@ func (x *ModulesProgress) String() string {
 func (*ModulesProgress) ProtoMessage() {}
 
 func (x *ModulesProgress) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[10]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -927,7 +1226,7 @@ func (x *ModulesProgress) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ModulesProgress.ProtoReflect.Descriptor instead.
 func (*ModulesProgress) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{10}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ModulesProgress) GetRunningJobs() []*Job {
@@ -970,7 +1269,7 @@ type ProcessedBytes struct {
 func (x *ProcessedBytes) Reset() {
 	*x = ProcessedBytes{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[11]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -983,7 +1282,7 @@ func (x *ProcessedBytes) String() string {
 func (*ProcessedBytes) ProtoMessage() {}
 
 func (x *ProcessedBytes) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[11]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -996,7 +1295,7 @@ func (x *ProcessedBytes) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProcessedBytes.ProtoReflect.Descriptor instead.
 func (*ProcessedBytes) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{11}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *ProcessedBytes) GetTotalBytesRead() uint64 {
@@ -1029,7 +1328,7 @@ type Error struct {
 func (x *Error) Reset() {
 	*x = Error{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[12]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1042,7 +1341,7 @@ func (x *Error) String() string {
 func (*Error) ProtoMessage() {}
 
 func (x *Error) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[12]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1055,7 +1354,7 @@ func (x *Error) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Error.ProtoReflect.Descriptor instead.
 func (*Error) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{12}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *Error) GetModule() string {
@@ -1086,6 +1385,136 @@ func (x *Error) GetLogsTruncated() bool {
 	return false
 }
 
+// FailureSummary gathers, per module, the errors returned as data over the whole stream,
+// see `X-Sf-Substreams-Errors-As-Data`.
+type FailureSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Modules []*ModuleFailures `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"`
+}
+
+func (x *FailureSummary) Reset() {
+	*x = FailureSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FailureSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FailureSummary) ProtoMessage() {}
+
+func (x *FailureSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FailureSummary.ProtoReflect.Descriptor instead.
+func (*FailureSummary) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FailureSummary) GetModules() []*ModuleFailures {
+	if x != nil {
+		return x.Modules
+	}
+	return nil
+}
+
+type ModuleFailures struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Module string `protobuf:"bytes,1,opt,name=module,proto3" json:"module,omitempty"`
+	// number of blocks on which the module failed
+	Count uint64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// the first distinct reasons of the module's failures, at most a few of them
+	SampleReasons    []string `protobuf:"bytes,3,rep,name=sample_reasons,json=sampleReasons,proto3" json:"sample_reasons,omitempty"`
+	FirstFailedBlock uint64   `protobuf:"varint,4,opt,name=first_failed_block,json=firstFailedBlock,proto3" json:"first_failed_block,omitempty"`
+	LastFailedBlock  uint64   `protobuf:"varint,5,opt,name=last_failed_block,json=lastFailedBlock,proto3" json:"last_failed_block,omitempty"`
+}
+
+func (x *ModuleFailures) Reset() {
+	*x = ModuleFailures{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ModuleFailures) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModuleFailures) ProtoMessage() {}
+
+func (x *ModuleFailures) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModuleFailures.ProtoReflect.Descriptor instead.
+func (*ModuleFailures) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ModuleFailures) GetModule() string {
+	if x != nil {
+		return x.Module
+	}
+	return ""
+}
+
+func (x *ModuleFailures) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ModuleFailures) GetSampleReasons() []string {
+	if x != nil {
+		return x.SampleReasons
+	}
+	return nil
+}
+
+func (x *ModuleFailures) GetFirstFailedBlock() uint64 {
+	if x != nil {
+		return x.FirstFailedBlock
+	}
+	return 0
+}
+
+func (x *ModuleFailures) GetLastFailedBlock() uint64 {
+	if x != nil {
+		return x.LastFailedBlock
+	}
+	return 0
+}
+
 type Job struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1101,7 +1530,7 @@ type Job struct {
 func (x *Job) Reset() {
 	*x = Job{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[13]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1114,7 +1543,7 @@ func (x *Job) String() string {
 func (*Job) ProtoMessage() {}
 
 func (x *Job) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[13]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1127,7 +1556,7 @@ func (x *Job) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Job.ProtoReflect.Descriptor instead.
 func (*Job) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{13}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *Job) GetStage() uint32 {
@@ -1177,7 +1606,7 @@ type Stage struct {
 func (x *Stage) Reset() {
 	*x = Stage{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[14]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1190,7 +1619,7 @@ func (x *Stage) String() string {
 func (*Stage) ProtoMessage() {}
 
 func (x *Stage) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[14]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1203,7 +1632,7 @@ func (x *Stage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Stage.ProtoReflect.Descriptor instead.
 func (*Stage) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{14}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *Stage) GetModules() []string {
@@ -1257,7 +1686,7 @@ type ModuleStats struct {
 func (x *ModuleStats) Reset() {
 	*x = ModuleStats{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[15]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1270,7 +1699,7 @@ func (x *ModuleStats) String() string {
 func (*ModuleStats) ProtoMessage() {}
 
 func (x *ModuleStats) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[15]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1283,7 +1712,7 @@ func (x *ModuleStats) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ModuleStats.ProtoReflect.Descriptor instead.
 func (*ModuleStats) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{15}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *ModuleStats) GetName() string {
@@ -1383,7 +1812,7 @@ type ExternalCallMetric struct {
 func (x *ExternalCallMetric) Reset() {
 	*x = ExternalCallMetric{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[16]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1396,7 +1825,7 @@ func (x *ExternalCallMetric) String() string {
 func (*ExternalCallMetric) ProtoMessage() {}
 
 func (x *ExternalCallMetric) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[16]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1409,7 +1838,7 @@ func (x *ExternalCallMetric) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExternalCallMetric.ProtoReflect.Descriptor instead.
 func (*ExternalCallMetric) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{16}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *ExternalCallMetric) GetName() string {
@@ -1448,7 +1877,7 @@ type StoreDelta struct {
 func (x *StoreDelta) Reset() {
 	*x = StoreDelta{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[17]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1461,7 +1890,7 @@ func (x *StoreDelta) String() string {
 func (*StoreDelta) ProtoMessage() {}
 
 func (x *StoreDelta) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[17]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1474,7 +1903,7 @@ func (x *StoreDelta) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StoreDelta.ProtoReflect.Descriptor instead.
 func (*StoreDelta) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{17}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *StoreDelta) GetOperation() StoreDelta_Operation {
@@ -1524,7 +1953,7 @@ type BlockRange struct {
 func (x *BlockRange) Reset() {
 	*x = BlockRange{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[18]
+		mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1537,7 +1966,7 @@ func (x *BlockRange) String() string {
 func (*BlockRange) ProtoMessage() {}
 
 func (x *BlockRange) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[18]
+	mi := &file_sf_substreams_rpc_v2_service_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1550,7 +1979,7 @@ func (x *BlockRange) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BlockRange.ProtoReflect.Descriptor instead.
 func (*BlockRange) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{18}
+	return file_sf_substreams_rpc_v2_service_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *BlockRange) GetStartBlock() uint64 {
@@ -1603,7 +2032,7 @@ var file_sf_substreams_rpc_v2_service_proto_rawDesc = []byte{
 	0x66, 0x6f, 0x72, 0x5f, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28,
 	0x09, 0x52, 0x23, 0x64, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53,
 	0x74, 0x6f, 0x72, 0x65, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x46, 0x6f, 0x72, 0x4d,
-	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x22, 0xc9, 0x04, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x22, 0xe2, 0x05, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f,
 	0x6e, 0x73, 0x65, 0x12, 0x3d, 0x0a, 0x07, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72,
 	0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x65, 0x73, 0x73,
@@ -1627,138 +2056,201 @@ var file_sf_substreams_rpc_v2_service_proto_rawDesc = []byte{
 	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73,
 	0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x45, 0x72,
 	0x72, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x0a, 0x66, 0x61, 0x74, 0x61, 0x6c, 0x45, 0x72, 0x72, 0x6f,
-	0x72, 0x12, 0x5b, 0x0a, 0x13, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x73, 0x6e, 0x61, 0x70, 0x73,
-	0x68, 0x6f, 0x74, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29,
+	0x72, 0x12, 0x4f, 0x0a, 0x0f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x5f, 0x73, 0x75, 0x6d,
+	0x6d, 0x61, 0x72, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x73, 0x66, 0x2e,
+	0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76,
+	0x32, 0x2e, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79,
+	0x48, 0x00, 0x52, 0x0e, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61,
+	0x72, 0x79, 0x12, 0x46, 0x0a, 0x0c, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x73, 0x74, 0x61,
+	0x72, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75,
+	0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x48, 0x00, 0x52, 0x0b, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x5b, 0x0a, 0x13, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x5f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x5f, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x49,
+	0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x44, 0x61,
+	0x74, 0x61, 0x48, 0x00, 0x52, 0x11, 0x64, 0x65, 0x62, 0x75, 0x67, 0x53, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x44, 0x61, 0x74, 0x61, 0x12, 0x67, 0x0a, 0x17, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x5f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65,
+	0x74, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75,
+	0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e,
+	0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43,
+	0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x48, 0x00, 0x52, 0x15, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65,
+	0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x4d, 0x0a, 0x0b, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0d, 0x73, 0x74, 0x61, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4e,
+	0x75, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0x83, 0x01, 0x0a, 0x0f, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x55, 0x6e, 0x64, 0x6f, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x12, 0x44,
+	0x0a, 0x10, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x5f, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75,
+	0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x52, 0x65, 0x66, 0x52, 0x0e, 0x6c, 0x61, 0x73, 0x74, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x2a, 0x0a, 0x11, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x61, 0x6c,
+	0x69, 0x64, 0x5f, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0f, 0x6c, 0x61, 0x73, 0x74, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72,
+	0x22, 0xaa, 0x04, 0x0a, 0x0f, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x64,
+	0x44, 0x61, 0x74, 0x61, 0x12, 0x3d, 0x0a, 0x06, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4d, 0x61, 0x70, 0x4d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x52, 0x06, 0x6f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x12, 0x2d, 0x0a, 0x05, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x05, 0x63, 0x6c, 0x6f,
+	0x63, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x2c, 0x0a, 0x12, 0x66, 0x69,
+	0x6e, 0x61, 0x6c, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x27, 0x0a, 0x0f, 0x6c, 0x69, 0x76, 0x65,
+	0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0e, 0x6c, 0x69, 0x76, 0x65, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x40, 0x0a, 0x0d, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x5f, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75,
+	0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x0c, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x45, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x12, 0x51, 0x0a, 0x11, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x6d, 0x61, 0x70,
+	0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25,
 	0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72,
-	0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61,
-	0x70, 0x73, 0x68, 0x6f, 0x74, 0x44, 0x61, 0x74, 0x61, 0x48, 0x00, 0x52, 0x11, 0x64, 0x65, 0x62,
-	0x75, 0x67, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x44, 0x61, 0x74, 0x61, 0x12, 0x67,
-	0x0a, 0x17, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74,
-	0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x2d, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e,
-	0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e,
-	0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x48, 0x00,
-	0x52, 0x15, 0x64, 0x65, 0x62, 0x75, 0x67, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43,
-	0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
-	0x67, 0x65, 0x22, 0x83, 0x01, 0x0a, 0x0f, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x55, 0x6e, 0x64, 0x6f,
-	0x53, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x12, 0x44, 0x0a, 0x10, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76,
-	0x61, 0x6c, 0x69, 0x64, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x1a, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
-	0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x66, 0x52, 0x0e, 0x6c, 0x61,
-	0x73, 0x74, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x2a, 0x0a, 0x11,
-	0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x5f, 0x63, 0x75, 0x72, 0x73, 0x6f,
-	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x6c, 0x61, 0x73, 0x74, 0x56, 0x61, 0x6c,
-	0x69, 0x64, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0xf1, 0x02, 0x0a, 0x0f, 0x42, 0x6c, 0x6f,
-	0x63, 0x6b, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x64, 0x44, 0x61, 0x74, 0x61, 0x12, 0x3d, 0x0a, 0x06,
-	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x73,
-	0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63,
-	0x2e, 0x76, 0x32, 0x2e, 0x4d, 0x61, 0x70, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74,
-	0x70, 0x75, 0x74, 0x52, 0x06, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x2d, 0x0a, 0x05, 0x63,
-	0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x73, 0x66, 0x2e,
-	0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c,
-	0x6f, 0x63, 0x6b, 0x52, 0x05, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75,
-	0x72, 0x73, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73,
-	0x6f, 0x72, 0x12, 0x2c, 0x0a, 0x12, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x62, 0x6c, 0x6f, 0x63,
-	0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10,
-	0x66, 0x69, 0x6e, 0x61, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74,
-	0x12, 0x51, 0x0a, 0x11, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x6d, 0x61, 0x70, 0x5f, 0x6f, 0x75,
-	0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x73, 0x66,
+	0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4d, 0x61, 0x70, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f,
+	0x75, 0x74, 0x70, 0x75, 0x74, 0x52, 0x0f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x4d, 0x61, 0x70, 0x4f,
+	0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x57, 0x0a, 0x13, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f,
+	0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x0b, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65,
+	0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x52, 0x11, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12,
+	0x4c, 0x0a, 0x11, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x5f,
+	0x6c, 0x6f, 0x67, 0x73, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x66, 0x2e,
+	0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76,
+	0x32, 0x2e, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4c, 0x6f, 0x67, 0x73, 0x52, 0x0f, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4c, 0x6f, 0x67, 0x73, 0x22, 0x68, 0x0a,
+	0x0a, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4c, 0x6f, 0x67, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x6c, 0x6f, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x6f, 0x67, 0x73,
+	0x12, 0x25, 0x0a, 0x0e, 0x6c, 0x6f, 0x67, 0x73, 0x5f, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74,
+	0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x6c, 0x6f, 0x67, 0x73, 0x54, 0x72,
+	0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x22, 0xbe, 0x01, 0x0a, 0x0b, 0x53, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x74, 0x72, 0x61, 0x63, 0x65,
+	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x74, 0x72, 0x61, 0x63, 0x65,
+	0x49, 0x64, 0x12, 0x30, 0x0a, 0x14, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x5f, 0x73,
+	0x74, 0x61, 0x72, 0x74, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x12, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x53, 0x74, 0x61, 0x72, 0x74, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x30, 0x0a, 0x14, 0x6c, 0x69, 0x6e, 0x65, 0x61, 0x72, 0x5f, 0x68,
+	0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x12, 0x6c, 0x69, 0x6e, 0x65, 0x61, 0x72, 0x48, 0x61, 0x6e, 0x64, 0x6f, 0x66,
+	0x66, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x30, 0x0a, 0x14, 0x6d, 0x61, 0x78, 0x5f, 0x70, 0x61,
+	0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x5f, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x73, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x12, 0x6d, 0x61, 0x78, 0x50, 0x61, 0x72, 0x61, 0x6c, 0x6c, 0x65,
+	0x6c, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x73, 0x22, 0x31, 0x0a, 0x17, 0x49, 0x6e, 0x69, 0x74,
+	0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x6f, 0x6d, 0x70, 0x6c,
+	0x65, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0xac, 0x01, 0x0a, 0x13,
+	0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x44,
+	0x61, 0x74, 0x61, 0x12, 0x1f, 0x0a, 0x0b, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x5f, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x38, 0x0a, 0x06, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74, 0x6f, 0x72,
+	0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x06, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x12, 0x1b,
+	0x0a, 0x09, 0x73, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x08, 0x73, 0x65, 0x6e, 0x74, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x74,
+	0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x4b, 0x65, 0x79, 0x73, 0x22, 0xa0, 0x01, 0x0a, 0x0f, 0x4d,
+	0x61, 0x70, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x33, 0x0a, 0x0a, 0x6d, 0x61, 0x70, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79, 0x52, 0x09, 0x6d, 0x61,
+	0x70, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x44, 0x0a, 0x0a, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x73, 0x66,
 	0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e,
-	0x76, 0x32, 0x2e, 0x4d, 0x61, 0x70, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74, 0x70,
-	0x75, 0x74, 0x52, 0x0f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x4d, 0x61, 0x70, 0x4f, 0x75, 0x74, 0x70,
-	0x75, 0x74, 0x73, 0x12, 0x57, 0x0a, 0x13, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x73, 0x74, 0x6f,
-	0x72, 0x65, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x27, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
-	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x4d, 0x6f, 0x64,
-	0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x52, 0x11, 0x64, 0x65, 0x62, 0x75, 0x67,
-	0x53, 0x74, 0x6f, 0x72, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x22, 0xbe, 0x01, 0x0a,
-	0x0b, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x19, 0x0a, 0x08,
-	0x74, 0x72, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
-	0x74, 0x72, 0x61, 0x63, 0x65, 0x49, 0x64, 0x12, 0x30, 0x0a, 0x14, 0x72, 0x65, 0x73, 0x6f, 0x6c,
-	0x76, 0x65, 0x64, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x12, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x64, 0x53,
-	0x74, 0x61, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x30, 0x0a, 0x14, 0x6c, 0x69, 0x6e,
-	0x65, 0x61, 0x72, 0x5f, 0x68, 0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x5f, 0x62, 0x6c, 0x6f, 0x63,
-	0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x12, 0x6c, 0x69, 0x6e, 0x65, 0x61, 0x72, 0x48,
-	0x61, 0x6e, 0x64, 0x6f, 0x66, 0x66, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x30, 0x0a, 0x14, 0x6d,
-	0x61, 0x78, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x5f, 0x77, 0x6f, 0x72, 0x6b,
-	0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x12, 0x6d, 0x61, 0x78, 0x50, 0x61,
-	0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x57, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x73, 0x22, 0x31, 0x0a,
-	0x17, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74,
-	0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73,
-	0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
-	0x22, 0xac, 0x01, 0x0a, 0x13, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x53, 0x6e, 0x61, 0x70,
-	0x73, 0x68, 0x6f, 0x74, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1f, 0x0a, 0x0b, 0x6d, 0x6f, 0x64, 0x75,
-	0x6c, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6d,
-	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x38, 0x0a, 0x06, 0x64, 0x65, 0x6c,
-	0x74, 0x61, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x66, 0x2e, 0x73,
-	0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32,
-	0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x06, 0x64, 0x65, 0x6c,
-	0x74, 0x61, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x79, 0x73,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x6e, 0x74, 0x4b, 0x65, 0x79, 0x73,
-	0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x4b, 0x65, 0x79, 0x73, 0x22,
-	0xa0, 0x01, 0x0a, 0x0f, 0x4d, 0x61, 0x70, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74,
+	0x76, 0x32, 0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e,
+	0x66, 0x6f, 0x52, 0x09, 0x64, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0xa4, 0x02,
+	0x0a, 0x11, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4f, 0x75, 0x74,
 	0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x33, 0x0a, 0x0a, 0x6d, 0x61, 0x70, 0x5f, 0x6f,
-	0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e,
-	0x79, 0x52, 0x09, 0x6d, 0x61, 0x70, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x44, 0x0a, 0x0a,
-	0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x25, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
-	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x65,
-	0x62, 0x75, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x64, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e,
-	0x66, 0x6f, 0x22, 0xbd, 0x01, 0x0a, 0x11, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x4d, 0x6f, 0x64, 0x75,
-	0x6c, 0x65, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x4e, 0x0a, 0x12,
-	0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x64, 0x65, 0x6c, 0x74,
-	0x61, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75,
-	0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e,
-	0x53, 0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x10, 0x64, 0x65, 0x62, 0x75,
-	0x67, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x12, 0x44, 0x0a, 0x0a,
-	0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x25, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
-	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x65,
-	0x62, 0x75, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x64, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e,
-	0x66, 0x6f, 0x22, 0x64, 0x0a, 0x0f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x65, 0x62, 0x75,
-	0x67, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x6f, 0x67, 0x73, 0x18, 0x01, 0x20,
-	0x03, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x6f, 0x67, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x6c, 0x6f, 0x67,
-	0x73, 0x5f, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x08, 0x52, 0x0d, 0x6c, 0x6f, 0x67, 0x73, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64,
-	0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x06, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x22, 0xa1, 0x02, 0x0a, 0x0f, 0x4d, 0x6f, 0x64,
-	0x75, 0x6c, 0x65, 0x73, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x3c, 0x0a, 0x0c,
-	0x72, 0x75, 0x6e, 0x6e, 0x69, 0x6e, 0x67, 0x5f, 0x6a, 0x6f, 0x62, 0x73, 0x18, 0x02, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4a, 0x6f, 0x62, 0x52, 0x0b, 0x72,
-	0x75, 0x6e, 0x6e, 0x69, 0x6e, 0x67, 0x4a, 0x6f, 0x62, 0x73, 0x12, 0x46, 0x0a, 0x0d, 0x6d, 0x6f,
-	0x64, 0x75, 0x6c, 0x65, 0x73, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x21, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
-	0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x53,
-	0x74, 0x61, 0x74, 0x73, 0x52, 0x0c, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x53, 0x74, 0x61,
-	0x74, 0x73, 0x12, 0x33, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x67, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x52,
-	0x06, 0x73, 0x74, 0x61, 0x67, 0x65, 0x73, 0x12, 0x4d, 0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x63, 0x65,
-	0x73, 0x73, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x24, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
-	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x50, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65,
-	0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x52, 0x0e, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65,
-	0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x4a, 0x04, 0x08, 0x01, 0x10, 0x02, 0x22, 0x6a, 0x0a, 0x0e,
-	0x50, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x28,
-	0x0a, 0x10, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x72, 0x65,
-	0x61, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x42,
-	0x79, 0x74, 0x65, 0x73, 0x52, 0x65, 0x61, 0x64, 0x12, 0x2e, 0x0a, 0x13, 0x74, 0x6f, 0x74, 0x61,
-	0x6c, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x42, 0x79, 0x74, 0x65,
-	0x73, 0x57, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x22, 0x72, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f,
-	0x72, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61,
-	0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f,
-	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x6f, 0x67, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x4e, 0x0a, 0x12, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x5f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65,
+	0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x10, 0x64, 0x65, 0x62, 0x75, 0x67, 0x53, 0x74, 0x6f, 0x72,
+	0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x12, 0x65, 0x0a, 0x1a, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x5f, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64, 0x5f, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x64,
+	0x65, 0x6c, 0x74, 0x61, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x73, 0x66,
+	0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e,
+	0x76, 0x32, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x44,
+	0x65, 0x6c, 0x74, 0x61, 0x73, 0x52, 0x17, 0x64, 0x65, 0x62, 0x75, 0x67, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x65, 0x64, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x12, 0x44,
+	0x0a, 0x0a, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x25, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74,
+	0x44, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x49, 0x6e, 0x66, 0x6f, 0x22, 0x68, 0x0a, 0x12, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64, 0x53,
+	0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x73, 0x12, 0x3e, 0x0a, 0x06, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x26, 0x2e, 0x73, 0x66, 0x2e,
+	0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76,
+	0x32, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x46, 0x6f, 0x72, 0x6d,
+	0x61, 0x74, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x64,
+	0x0a, 0x0f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x65, 0x62, 0x75, 0x67, 0x49, 0x6e, 0x66,
+	0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x6f, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
 	0x04, 0x6c, 0x6f, 0x67, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x6c, 0x6f, 0x67, 0x73, 0x5f, 0x74, 0x72,
-	0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x6c,
-	0x6f, 0x67, 0x73, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x22, 0xa7, 0x01, 0x0a,
+	0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x6c,
+	0x6f, 0x67, 0x73, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06,
+	0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x63, 0x61,
+	0x63, 0x68, 0x65, 0x64, 0x22, 0xa1, 0x02, 0x0a, 0x0f, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73,
+	0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x3c, 0x0a, 0x0c, 0x72, 0x75, 0x6e, 0x6e,
+	0x69, 0x6e, 0x67, 0x5f, 0x6a, 0x6f, 0x62, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19,
+	0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4a, 0x6f, 0x62, 0x52, 0x0b, 0x72, 0x75, 0x6e, 0x6e, 0x69,
+	0x6e, 0x67, 0x4a, 0x6f, 0x62, 0x73, 0x12, 0x46, 0x0a, 0x0d, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65,
+	0x73, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e,
+	0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70,
+	0x63, 0x2e, 0x76, 0x32, 0x2e, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x52, 0x0c, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x33,
+	0x0a, 0x06, 0x73, 0x74, 0x61, 0x67, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b,
+	0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x52, 0x06, 0x73, 0x74, 0x61,
+	0x67, 0x65, 0x73, 0x12, 0x4d, 0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65, 0x64,
+	0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x73,
+	0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x76, 0x32, 0x2e, 0x50, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65, 0x64, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x52, 0x0e, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x65, 0x64, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x4a, 0x04, 0x08, 0x01, 0x10, 0x02, 0x22, 0x6a, 0x0a, 0x0e, 0x50, 0x72, 0x6f, 0x63,
+	0x65, 0x73, 0x73, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x74, 0x6f,
+	0x74, 0x61, 0x6c, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x42, 0x79, 0x74, 0x65, 0x73,
+	0x52, 0x65, 0x61, 0x64, 0x12, 0x2e, 0x0a, 0x13, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x62, 0x79,
+	0x74, 0x65, 0x73, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x11, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x42, 0x79, 0x74, 0x65, 0x73, 0x57, 0x72, 0x69,
+	0x74, 0x74, 0x65, 0x6e, 0x22, 0x72, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x16, 0x0a,
+	0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x12, 0x0a,
+	0x04, 0x6c, 0x6f, 0x67, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x6f, 0x67,
+	0x73, 0x12, 0x25, 0x0a, 0x0e, 0x6c, 0x6f, 0x67, 0x73, 0x5f, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61,
+	0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x6c, 0x6f, 0x67, 0x73, 0x54,
+	0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x22, 0x50, 0x0a, 0x0e, 0x46, 0x61, 0x69, 0x6c,
+	0x75, 0x72, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x3e, 0x0a, 0x07, 0x6d, 0x6f,
+	0x64, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x73, 0x66,
+	0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e,
+	0x76, 0x32, 0x2e, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65,
+	0x73, 0x52, 0x07, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x22, 0xbf, 0x01, 0x0a, 0x0e, 0x4d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x12, 0x16, 0x0a,
+	0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x73,
+	0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52, 0x65, 0x61, 0x73, 0x6f,
+	0x6e, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x66, 0x61, 0x69, 0x6c,
+	0x65, 0x64, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10,
+	0x66, 0x69, 0x72, 0x73, 0x74, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x12, 0x2a, 0x0a, 0x11, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x5f,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x6c, 0x61, 0x73,
+	0x74, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0xa7, 0x01, 0x0a,
 	0x03, 0x4a, 0x6f, 0x62, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x0d, 0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x74,
 	0x61, 0x72, 0x74, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
@@ -1846,18 +2338,25 @@ var file_sf_substreams_rpc_v2_service_proto_rawDesc = []byte{
 	0x1f, 0x0a, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
 	0x12, 0x1b, 0x0a, 0x09, 0x65, 0x6e, 0x64, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x04, 0x52, 0x08, 0x65, 0x6e, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x32, 0x53, 0x0a,
-	0x06, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x49, 0x0a, 0x06, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x73, 0x12, 0x1d, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
-	0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x1e, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
-	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x30, 0x01, 0x42, 0x4d, 0x5a, 0x4b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x73, 0x74, 0x2f, 0x73,
-	0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x70, 0x62, 0x2f, 0x73, 0x66, 0x2f,
-	0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x72, 0x70, 0x63, 0x2f, 0x76,
-	0x32, 0x3b, 0x70, 0x62, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x72, 0x70,
-	0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x01, 0x28, 0x04, 0x52, 0x08, 0x65, 0x6e, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x2a, 0x71, 0x0a,
+	0x10, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x46, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x12, 0x1f, 0x0a, 0x1b, 0x53, 0x54, 0x4f, 0x52, 0x45, 0x5f, 0x44, 0x45, 0x4c, 0x54, 0x41,
+	0x5f, 0x46, 0x4f, 0x52, 0x4d, 0x41, 0x54, 0x5f, 0x50, 0x52, 0x4f, 0x54, 0x4f, 0x42, 0x55, 0x46,
+	0x10, 0x00, 0x12, 0x1b, 0x0a, 0x17, 0x53, 0x54, 0x4f, 0x52, 0x45, 0x5f, 0x44, 0x45, 0x4c, 0x54,
+	0x41, 0x5f, 0x46, 0x4f, 0x52, 0x4d, 0x41, 0x54, 0x5f, 0x4a, 0x53, 0x4f, 0x4e, 0x10, 0x01, 0x12,
+	0x1f, 0x0a, 0x1b, 0x53, 0x54, 0x4f, 0x52, 0x45, 0x5f, 0x44, 0x45, 0x4c, 0x54, 0x41, 0x5f, 0x46,
+	0x4f, 0x52, 0x4d, 0x41, 0x54, 0x5f, 0x43, 0x4f, 0x4c, 0x55, 0x4d, 0x4e, 0x41, 0x52, 0x10, 0x02,
+	0x32, 0x53, 0x0a, 0x06, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x49, 0x0a, 0x06, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x73, 0x12, 0x1d, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x73, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x76, 0x32, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x30, 0x01, 0x42, 0x4d, 0x5a, 0x4b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x73,
+	0x74, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x70, 0x62, 0x2f,
+	0x73, 0x66, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x72, 0x70,
+	0x63, 0x2f, 0x76, 0x32, 0x3b, 0x70, 0x62, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x73, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1872,67 +2371,80 @@ func file_sf_substreams_rpc_v2_service_proto_rawDescGZIP() []byte {
 	return file_sf_substreams_rpc_v2_service_proto_rawDescData
 }
 
-var file_sf_substreams_rpc_v2_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_sf_substreams_rpc_v2_service_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_sf_substreams_rpc_v2_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_sf_substreams_rpc_v2_service_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_sf_substreams_rpc_v2_service_proto_goTypes = []interface{}{
-	(StoreDelta_Operation)(0),       // 0: sf.substreams.rpc.v2.StoreDelta.Operation
-	(*Request)(nil),                 // 1: sf.substreams.rpc.v2.Request
-	(*Response)(nil),                // 2: sf.substreams.rpc.v2.Response
-	(*BlockUndoSignal)(nil),         // 3: sf.substreams.rpc.v2.BlockUndoSignal
-	(*BlockScopedData)(nil),         // 4: sf.substreams.rpc.v2.BlockScopedData
-	(*SessionInit)(nil),             // 5: sf.substreams.rpc.v2.SessionInit
-	(*InitialSnapshotComplete)(nil), // 6: sf.substreams.rpc.v2.InitialSnapshotComplete
-	(*InitialSnapshotData)(nil),     // 7: sf.substreams.rpc.v2.InitialSnapshotData
-	(*MapModuleOutput)(nil),         // 8: sf.substreams.rpc.v2.MapModuleOutput
-	(*StoreModuleOutput)(nil),       // 9: sf.substreams.rpc.v2.StoreModuleOutput
-	(*OutputDebugInfo)(nil),         // 10: sf.substreams.rpc.v2.OutputDebugInfo
-	(*ModulesProgress)(nil),         // 11: sf.substreams.rpc.v2.ModulesProgress
-	(*ProcessedBytes)(nil),          // 12: sf.substreams.rpc.v2.ProcessedBytes
-	(*Error)(nil),                   // 13: sf.substreams.rpc.v2.Error
-	(*Job)(nil),                     // 14: sf.substreams.rpc.v2.Job
-	(*Stage)(nil),                   // 15: sf.substreams.rpc.v2.Stage
-	(*ModuleStats)(nil),             // 16: sf.substreams.rpc.v2.ModuleStats
-	(*ExternalCallMetric)(nil),      // 17: sf.substreams.rpc.v2.ExternalCallMetric
-	(*StoreDelta)(nil),              // 18: sf.substreams.rpc.v2.StoreDelta
-	(*BlockRange)(nil),              // 19: sf.substreams.rpc.v2.BlockRange
-	(*v1.Modules)(nil),              // 20: sf.substreams.v1.Modules
-	(*v1.BlockRef)(nil),             // 21: sf.substreams.v1.BlockRef
-	(*v1.Clock)(nil),                // 22: sf.substreams.v1.Clock
-	(*anypb.Any)(nil),               // 23: google.protobuf.Any
+	(StoreDeltaFormat)(0),           // 0: sf.substreams.rpc.v2.StoreDeltaFormat
+	(StoreDelta_Operation)(0),       // 1: sf.substreams.rpc.v2.StoreDelta.Operation
+	(*Request)(nil),                 // 2: sf.substreams.rpc.v2.Request
+	(*Response)(nil),                // 3: sf.substreams.rpc.v2.Response
+	(*StreamStart)(nil),             // 4: sf.substreams.rpc.v2.StreamStart
+	(*BlockUndoSignal)(nil),         // 5: sf.substreams.rpc.v2.BlockUndoSignal
+	(*BlockScopedData)(nil),         // 6: sf.substreams.rpc.v2.BlockScopedData
+	(*ModuleLogs)(nil),              // 7: sf.substreams.rpc.v2.ModuleLogs
+	(*SessionInit)(nil),             // 8: sf.substreams.rpc.v2.SessionInit
+	(*InitialSnapshotComplete)(nil), // 9: sf.substreams.rpc.v2.InitialSnapshotComplete
+	(*InitialSnapshotData)(nil),     // 10: sf.substreams.rpc.v2.InitialSnapshotData
+	(*MapModuleOutput)(nil),         // 11: sf.substreams.rpc.v2.MapModuleOutput
+	(*StoreModuleOutput)(nil),       // 12: sf.substreams.rpc.v2.StoreModuleOutput
+	(*EncodedStoreDeltas)(nil),      // 13: sf.substreams.rpc.v2.EncodedStoreDeltas
+	(*OutputDebugInfo)(nil),         // 14: sf.substreams.rpc.v2.OutputDebugInfo
+	(*ModulesProgress)(nil),         // 15: sf.substreams.rpc.v2.ModulesProgress
+	(*ProcessedBytes)(nil),          // 16: sf.substreams.rpc.v2.ProcessedBytes
+	(*Error)(nil),                   // 17: sf.substreams.rpc.v2.Error
+	(*FailureSummary)(nil),          // 18: sf.substreams.rpc.v2.FailureSummary
+	(*ModuleFailures)(nil),          // 19: sf.substreams.rpc.v2.ModuleFailures
+	(*Job)(nil),                     // 20: sf.substreams.rpc.v2.Job
+	(*Stage)(nil),                   // 21: sf.substreams.rpc.v2.Stage
+	(*ModuleStats)(nil),             // 22: sf.substreams.rpc.v2.ModuleStats
+	(*ExternalCallMetric)(nil),      // 23: sf.substreams.rpc.v2.ExternalCallMetric
+	(*StoreDelta)(nil),              // 24: sf.substreams.rpc.v2.StoreDelta
+	(*BlockRange)(nil),              // 25: sf.substreams.rpc.v2.BlockRange
+	(*v1.Modules)(nil),              // 26: sf.substreams.v1.Modules
+	(*v1.BlockRef)(nil),             // 27: sf.substreams.v1.BlockRef
+	(*v1.Clock)(nil),                // 28: sf.substreams.v1.Clock
+	(*anypb.Any)(nil),               // 29: google.protobuf.Any
 }
 var file_sf_substreams_rpc_v2_service_proto_depIdxs = []int32{
-	20, // 0: sf.substreams.rpc.v2.Request.modules:type_name -> sf.substreams.v1.Modules
-	5,  // 1: sf.substreams.rpc.v2.Response.session:type_name -> sf.substreams.rpc.v2.SessionInit
-	11, // 2: sf.substreams.rpc.v2.Response.progress:type_name -> sf.substreams.rpc.v2.ModulesProgress
-	4,  // 3: sf.substreams.rpc.v2.Response.block_scoped_data:type_name -> sf.substreams.rpc.v2.BlockScopedData
-	3,  // 4: sf.substreams.rpc.v2.Response.block_undo_signal:type_name -> sf.substreams.rpc.v2.BlockUndoSignal
-	13, // 5: sf.substreams.rpc.v2.Response.fatal_error:type_name -> sf.substreams.rpc.v2.Error
-	7,  // 6: sf.substreams.rpc.v2.Response.debug_snapshot_data:type_name -> sf.substreams.rpc.v2.InitialSnapshotData
-	6,  // 7: sf.substreams.rpc.v2.Response.debug_snapshot_complete:type_name -> sf.substreams.rpc.v2.InitialSnapshotComplete
-	21, // 8: sf.substreams.rpc.v2.BlockUndoSignal.last_valid_block:type_name -> sf.substreams.v1.BlockRef
-	8,  // 9: sf.substreams.rpc.v2.BlockScopedData.output:type_name -> sf.substreams.rpc.v2.MapModuleOutput
-	22, // 10: sf.substreams.rpc.v2.BlockScopedData.clock:type_name -> sf.substreams.v1.Clock
-	8,  // 11: sf.substreams.rpc.v2.BlockScopedData.debug_map_outputs:type_name -> sf.substreams.rpc.v2.MapModuleOutput
-	9,  // 12: sf.substreams.rpc.v2.BlockScopedData.debug_store_outputs:type_name -> sf.substreams.rpc.v2.StoreModuleOutput
-	18, // 13: sf.substreams.rpc.v2.InitialSnapshotData.deltas:type_name -> sf.substreams.rpc.v2.StoreDelta
-	23, // 14: sf.substreams.rpc.v2.MapModuleOutput.map_output:type_name -> google.protobuf.Any
-	10, // 15: sf.substreams.rpc.v2.MapModuleOutput.debug_info:type_name -> sf.substreams.rpc.v2.OutputDebugInfo
-	18, // 16: sf.substreams.rpc.v2.StoreModuleOutput.debug_store_deltas:type_name -> sf.substreams.rpc.v2.StoreDelta
-	10, // 17: sf.substreams.rpc.v2.StoreModuleOutput.debug_info:type_name -> sf.substreams.rpc.v2.OutputDebugInfo
-	14, // 18: sf.substreams.rpc.v2.ModulesProgress.running_jobs:type_name -> sf.substreams.rpc.v2.Job
-	16, // 19: sf.substreams.rpc.v2.ModulesProgress.modules_stats:type_name -> sf.substreams.rpc.v2.ModuleStats
-	15, // 20: sf.substreams.rpc.v2.ModulesProgress.stages:type_name -> sf.substreams.rpc.v2.Stage
-	12, // 21: sf.substreams.rpc.v2.ModulesProgress.processed_bytes:type_name -> sf.substreams.rpc.v2.ProcessedBytes
-	19, // 22: sf.substreams.rpc.v2.Stage.completed_ranges:type_name -> sf.substreams.rpc.v2.BlockRange
-	17, // 23: sf.substreams.rpc.v2.ModuleStats.external_call_metrics:type_name -> sf.substreams.rpc.v2.ExternalCallMetric
-	0,  // 24: sf.substreams.rpc.v2.StoreDelta.operation:type_name -> sf.substreams.rpc.v2.StoreDelta.Operation
-	1,  // 25: sf.substreams.rpc.v2.Stream.Blocks:input_type -> sf.substreams.rpc.v2.Request
-	2,  // 26: sf.substreams.rpc.v2.Stream.Blocks:output_type -> sf.substreams.rpc.v2.Response
-	26, // [26:27] is the sub-list for method output_type
-	25, // [25:26] is the sub-list for method input_type
-	25, // [25:25] is the sub-list for extension type_name
-	25, // [25:25] is the sub-list for extension extendee
-	0,  // [0:25] is the sub-list for field type_name
+	26, // 0: sf.substreams.rpc.v2.Request.modules:type_name -> sf.substreams.v1.Modules
+	8,  // 1: sf.substreams.rpc.v2.Response.session:type_name -> sf.substreams.rpc.v2.SessionInit
+	15, // 2: sf.substreams.rpc.v2.Response.progress:type_name -> sf.substreams.rpc.v2.ModulesProgress
+	6,  // 3: sf.substreams.rpc.v2.Response.block_scoped_data:type_name -> sf.substreams.rpc.v2.BlockScopedData
+	5,  // 4: sf.substreams.rpc.v2.Response.block_undo_signal:type_name -> sf.substreams.rpc.v2.BlockUndoSignal
+	17, // 5: sf.substreams.rpc.v2.Response.fatal_error:type_name -> sf.substreams.rpc.v2.Error
+	18, // 6: sf.substreams.rpc.v2.Response.failure_summary:type_name -> sf.substreams.rpc.v2.FailureSummary
+	4,  // 7: sf.substreams.rpc.v2.Response.stream_start:type_name -> sf.substreams.rpc.v2.StreamStart
+	10, // 8: sf.substreams.rpc.v2.Response.debug_snapshot_data:type_name -> sf.substreams.rpc.v2.InitialSnapshotData
+	9,  // 9: sf.substreams.rpc.v2.Response.debug_snapshot_complete:type_name -> sf.substreams.rpc.v2.InitialSnapshotComplete
+	27, // 10: sf.substreams.rpc.v2.BlockUndoSignal.last_valid_block:type_name -> sf.substreams.v1.BlockRef
+	11, // 11: sf.substreams.rpc.v2.BlockScopedData.output:type_name -> sf.substreams.rpc.v2.MapModuleOutput
+	28, // 12: sf.substreams.rpc.v2.BlockScopedData.clock:type_name -> sf.substreams.v1.Clock
+	17, // 13: sf.substreams.rpc.v2.BlockScopedData.module_errors:type_name -> sf.substreams.rpc.v2.Error
+	11, // 14: sf.substreams.rpc.v2.BlockScopedData.debug_map_outputs:type_name -> sf.substreams.rpc.v2.MapModuleOutput
+	12, // 15: sf.substreams.rpc.v2.BlockScopedData.debug_store_outputs:type_name -> sf.substreams.rpc.v2.StoreModuleOutput
+	7,  // 16: sf.substreams.rpc.v2.BlockScopedData.debug_module_logs:type_name -> sf.substreams.rpc.v2.ModuleLogs
+	24, // 17: sf.substreams.rpc.v2.InitialSnapshotData.deltas:type_name -> sf.substreams.rpc.v2.StoreDelta
+	29, // 18: sf.substreams.rpc.v2.MapModuleOutput.map_output:type_name -> google.protobuf.Any
+	14, // 19: sf.substreams.rpc.v2.MapModuleOutput.debug_info:type_name -> sf.substreams.rpc.v2.OutputDebugInfo
+	24, // 20: sf.substreams.rpc.v2.StoreModuleOutput.debug_store_deltas:type_name -> sf.substreams.rpc.v2.StoreDelta
+	13, // 21: sf.substreams.rpc.v2.StoreModuleOutput.debug_encoded_store_deltas:type_name -> sf.substreams.rpc.v2.EncodedStoreDeltas
+	14, // 22: sf.substreams.rpc.v2.StoreModuleOutput.debug_info:type_name -> sf.substreams.rpc.v2.OutputDebugInfo
+	0,  // 23: sf.substreams.rpc.v2.EncodedStoreDeltas.format:type_name -> sf.substreams.rpc.v2.StoreDeltaFormat
+	20, // 24: sf.substreams.rpc.v2.ModulesProgress.running_jobs:type_name -> sf.substreams.rpc.v2.Job
+	22, // 25: sf.substreams.rpc.v2.ModulesProgress.modules_stats:type_name -> sf.substreams.rpc.v2.ModuleStats
+	21, // 26: sf.substreams.rpc.v2.ModulesProgress.stages:type_name -> sf.substreams.rpc.v2.Stage
+	16, // 27: sf.substreams.rpc.v2.ModulesProgress.processed_bytes:type_name -> sf.substreams.rpc.v2.ProcessedBytes
+	19, // 28: sf.substreams.rpc.v2.FailureSummary.modules:type_name -> sf.substreams.rpc.v2.ModuleFailures
+	25, // 29: sf.substreams.rpc.v2.Stage.completed_ranges:type_name -> sf.substreams.rpc.v2.BlockRange
+	23, // 30: sf.substreams.rpc.v2.ModuleStats.external_call_metrics:type_name -> sf.substreams.rpc.v2.ExternalCallMetric
+	1,  // 31: sf.substreams.rpc.v2.StoreDelta.operation:type_name -> sf.substreams.rpc.v2.StoreDelta.Operation
+	2,  // 32: sf.substreams.rpc.v2.Stream.Blocks:input_type -> sf.substreams.rpc.v2.Request
+	3,  // 33: sf.substreams.rpc.v2.Stream.Blocks:output_type -> sf.substreams.rpc.v2.Response
+	33, // [33:34] is the sub-list for method output_type
+	32, // [32:33] is the sub-list for method input_type
+	32, // [32:32] is the sub-list for extension type_name
+	32, // [32:32] is the sub-list for extension extendee
+	0,  // [0:32] is the sub-list for field type_name
 }
 
 func init() { file_sf_substreams_rpc_v2_service_proto_init() }
@@ -1966,7 +2478,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BlockUndoSignal); i {
+			switch v := v.(*StreamStart); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1978,7 +2490,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BlockScopedData); i {
+			switch v := v.(*BlockUndoSignal); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1990,7 +2502,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SessionInit); i {
+			switch v := v.(*BlockScopedData); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2002,7 +2514,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InitialSnapshotComplete); i {
+			switch v := v.(*ModuleLogs); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2014,7 +2526,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InitialSnapshotData); i {
+			switch v := v.(*SessionInit); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2026,7 +2538,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MapModuleOutput); i {
+			switch v := v.(*InitialSnapshotComplete); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2038,7 +2550,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StoreModuleOutput); i {
+			switch v := v.(*InitialSnapshotData); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2050,7 +2562,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*OutputDebugInfo); i {
+			switch v := v.(*MapModuleOutput); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2062,7 +2574,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ModulesProgress); i {
+			switch v := v.(*StoreModuleOutput); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2074,7 +2586,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ProcessedBytes); i {
+			switch v := v.(*EncodedStoreDeltas); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2086,7 +2598,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Error); i {
+			switch v := v.(*OutputDebugInfo); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2098,7 +2610,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Job); i {
+			switch v := v.(*ModulesProgress); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2110,7 +2622,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Stage); i {
+			switch v := v.(*ProcessedBytes); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2122,7 +2634,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ModuleStats); i {
+			switch v := v.(*Error); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2134,7 +2646,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ExternalCallMetric); i {
+			switch v := v.(*FailureSummary); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2146,7 +2658,7 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StoreDelta); i {
+			switch v := v.(*ModuleFailures); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2158,6 +2670,66 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 			}
 		}
 		file_sf_substreams_rpc_v2_service_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Job); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_substreams_rpc_v2_service_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Stage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_substreams_rpc_v2_service_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ModuleStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_substreams_rpc_v2_service_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExternalCallMetric); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_substreams_rpc_v2_service_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StoreDelta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_substreams_rpc_v2_service_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*BlockRange); i {
 			case 0:
 				return &v.state
@@ -2176,6 +2748,8 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 		(*Response_BlockScopedData)(nil),
 		(*Response_BlockUndoSignal)(nil),
 		(*Response_FatalError)(nil),
+		(*Response_FailureSummary)(nil),
+		(*Response_StreamStart)(nil),
 		(*Response_DebugSnapshotData)(nil),
 		(*Response_DebugSnapshotComplete)(nil),
 	}
@@ -2184,8 +2758,8 @@ func file_sf_substreams_rpc_v2_service_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sf_substreams_rpc_v2_service_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   19,
+			NumEnums:      2,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -37,7 +37,7 @@ func (a *AnyModuleOutput) IsEmpty() bool {
 	if a.MapOutput != nil {
 		return len(a.MapOutput.MapOutput.Value) == 0
 	}
-	return len(a.StoreOutput.DebugStoreDeltas) == 0
+	return len(a.StoreOutput.DebugStoreDeltas) == 0 && a.StoreOutput.DebugEncodedStoreDeltas == nil
 }
 
 func (m *MapModuleOutput) ToAny() *AnyModuleOutput {
@@ -52,8 +52,12 @@ func (s *StoreModuleOutput) ToAny() *AnyModuleOutput {
 	}
 }
 
+// AllModuleOutputs returns the outputs of the block, the output module's first unless it
+// failed, see `ModuleErrors`.
 func (bd *BlockScopedData) AllModuleOutputs() (out []*AnyModuleOutput) {
-	out = append(out, bd.Output.ToAny())
+	if bd.Output != nil {
+		out = append(out, bd.Output.ToAny())
+	}
 	for _, mapOut := range bd.DebugMapOutputs {
 		out = append(out, mapOut.ToAny())
 	}
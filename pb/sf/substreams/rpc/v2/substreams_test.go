@@ -34,3 +34,25 @@ func Test_ValidateRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockScopedData_AllModuleOutputs(t *testing.T) {
+	data := &BlockScopedData{
+		Output:            &MapModuleOutput{Name: "output_mod_1"},
+		DebugMapOutputs:   []*MapModuleOutput{{Name: "debug_mod"}},
+		DebugStoreOutputs: []*StoreModuleOutput{{Name: "debug_store"}},
+	}
+	var names []string
+	for _, out := range data.AllModuleOutputs() {
+		names = append(names, out.Name())
+	}
+	assert.Equal(t, []string{"output_mod_1", "debug_mod", "debug_store"}, names)
+
+	// the output module failed, its error returned as data
+	data.Output = nil
+	data.ModuleErrors = []*Error{{Module: "output_mod_1", Reason: "boom"}}
+	names = nil
+	for _, out := range data.AllModuleOutputs() {
+		names = append(names, out.Name())
+	}
+	assert.Equal(t, []string{"debug_mod", "debug_store"}, names)
+}
@@ -0,0 +1,48 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errDrainTimeout = errors.New("block did not complete within the drain timeout")
+
+// blockExecutionContext returns the context the modules of a block are executed with.
+// `ctx` is canceled when the client disconnects, or the request is otherwise stopped:
+// with a `drainTimeout`, the returned context outlives it by that long, letting the
+// block complete, and is then canceled with `errDrainTimeout` wrapping the cause of
+// `ctx`. It keeps the values of `ctx` but, as with `context.WithoutCancel`, not its
+// deadline, bounded by the drain timeout instead. Without a drain timeout, `ctx` is
+// returned as is and modules stop as soon as it is canceled. The returned function
+// must be called once the block is executed.
+func blockExecutionContext(ctx context.Context, drainTimeout time.Duration) (context.Context, context.CancelFunc) {
+	if drainTimeout == 0 {
+		return ctx, func() {}
+	}
+
+	execCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(drainTimeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel(fmt.Errorf("%w: %w", errDrainTimeout, context.Cause(ctx)))
+		case <-execCtx.Done():
+		}
+	})
+	return execCtx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// checkDrained returns `errDrainTimeout` once `ctx`, from `blockExecutionContext`, was
+// canceled for exceeding the drain timeout, so that no more modules are executed.
+func checkDrained(ctx context.Context) error {
+	if err := context.Cause(ctx); errors.Is(err, errDrainTimeout) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	pbsubstreamstest "github.com/streamingfast/substreams/pb/sf/substreams/v1/test"
+	"github.com/streamingfast/substreams/pipeline/exec"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+func TestPipeline_disconnectDrainTimeout(t *testing.T) {
+	drainTimeout := 50 * time.Millisecond
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{ProductionMode: true})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+	requestCtx, disconnect := context.WithCancel(ctx)
+
+	testMap := &pbsubstreams.Module{Name: "test_map", Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}}
+	pipe := &Pipeline{
+		forkHandler:     NewForkHandler(),
+		outputGraph:     outputmodules.TestNew(),
+		executionStages: outputmodules.ExecutionStages{{{testMap}}},
+		moduleExecutors: [][]exec.ModuleExecutor{{mapTestExecutor(t, ctx, "test_map")}},
+	}
+	block := &pbsubstreamstest.Block{Id: "block", Number: 10}
+	execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), &pbsubstreams.Clock{Id: block.Id, Number: block.Number})
+
+	execCtx, executed := blockExecutionContext(requestCtx, drainTimeout)
+	defer executed()
+
+	// a slow module of the block is running when the client disconnects
+	disconnect()
+	start := time.Now()
+	select {
+	case <-execCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("block execution not aborted after the drain timeout")
+	}
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, drainTimeout, "the block is given the drain timeout to complete")
+	assert.Less(t, elapsed, drainTimeout+time.Second)
+
+	// the remaining modules of the block are not executed
+	err := pipe.executeModules(execCtx, execOutput)
+	assert.ErrorIs(t, err, errDrainTimeout)
+	assert.ErrorIs(t, err, context.Canceled, "the cause of the request's cancellation is kept")
+	assert.NotContains(t, execOutput.Values, "test_map")
+}
+
+func TestPipeline_blockOutlivesDisconnect(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{ProductionMode: true})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+	requestCtx, disconnect := context.WithCancel(ctx)
+
+	testMap := &pbsubstreams.Module{Name: "test_map", Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}}
+	pipe := &Pipeline{
+		forkHandler:     NewForkHandler(),
+		outputGraph:     outputmodules.TestNew(),
+		executionStages: outputmodules.ExecutionStages{{{testMap}}},
+		moduleExecutors: [][]exec.ModuleExecutor{{mapTestExecutor(t, ctx, "test_map")}},
+	}
+	block := &pbsubstreamstest.Block{Id: "block", Number: 10}
+	execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), &pbsubstreams.Clock{Id: block.Id, Number: block.Number})
+
+	execCtx, executed := blockExecutionContext(requestCtx, time.Minute)
+	defer executed()
+
+	// the client disconnects before the modules of the block run, they still complete
+	disconnect()
+	require.Error(t, requestCtx.Err())
+	require.NoError(t, pipe.executeModules(execCtx, execOutput))
+	assert.NoError(t, execCtx.Err())
+	assert.Contains(t, execOutput.Values, "test_map")
+}
+
+func Test_blockExecutionContext(t *testing.T) {
+	requestCtx, disconnect := context.WithCancel(context.Background())
+	defer disconnect()
+
+	// without a drain timeout, modules run with the request's context
+	execCtx, executed := blockExecutionContext(requestCtx, 0)
+	assert.Equal(t, requestCtx, execCtx)
+	executed()
+
+	// a block completing is not reported as drained, even if the client disconnects afterwards
+	execCtx, executed = blockExecutionContext(requestCtx, time.Millisecond)
+	require.NoError(t, execCtx.Err())
+	executed()
+	disconnect()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, checkDrained(execCtx))
+}
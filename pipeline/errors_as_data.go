@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+)
+
+// errorsAsData decides, when a request asks for errors to be returned as data, which
+// module errors end the stream and which are only reported in the block's outputs.
+// An error is recoverable when it comes from a mapper whose output is not needed by
+// any store: skipping it for a block leaves the stores' state intact. Modules reading
+// the output of a failed mapper are skipped for the block.
+type errorsAsData struct {
+	mapInputs   map[string][]string // mappers read by each module
+	feedsStores map[string]bool     // stores and the mappers they depend on
+
+	failed   map[string]bool // modules failed or skipped on the current block
+	blockNum uint64          // the current block
+
+	failures *failureSummary // errors returned over the whole stream
+}
+
+func newErrorsAsData(stages outputmodules.ExecutionStages) *errorsAsData {
+	e := &errorsAsData{
+		mapInputs:   make(map[string][]string),
+		feedsStores: make(map[string]bool),
+		failures:    newFailureSummary(),
+	}
+
+	var modules []*pbsubstreams.Module
+	for _, stage := range stages {
+		for _, layer := range stage {
+			modules = append(modules, layer...)
+		}
+	}
+	for _, module := range modules {
+		for _, input := range module.Inputs {
+			if mapInput := input.GetMap(); mapInput != nil {
+				e.mapInputs[module.Name] = append(e.mapInputs[module.Name], mapInput.ModuleName)
+			}
+		}
+		if module.GetKindStore() != nil {
+			e.feedsStores[module.Name] = true
+		}
+	}
+
+	// modules come in execution order, so walking them backwards reaches every
+	// module after all of those reading its output
+	for i := len(modules) - 1; i >= 0; i-- {
+		if !e.feedsStores[modules[i].Name] {
+			continue
+		}
+		for _, parent := range e.mapInputs[modules[i].Name] {
+			e.feedsStores[parent] = true
+		}
+	}
+	return e
+}
+
+func (e *errorsAsData) startBlock(blockNum uint64) {
+	e.failed = make(map[string]bool)
+	e.blockNum = blockNum
+}
+
+// skip tells if `moduleName` cannot run on the current block because a mapper it reads
+// failed, in which case it is considered failed too.
+func (e *errorsAsData) skip(moduleName string) bool {
+	for _, parent := range e.mapInputs[moduleName] {
+		if e.failed[parent] {
+			e.failed[moduleName] = true
+			return true
+		}
+	}
+	return false
+}
+
+// recover returns the error to send to the client for `err`, the error of `moduleName`
+// on the current block, or nil when it must end the stream.
+func (e *errorsAsData) recover(moduleName string, res resultObj) *pbsubstreamsrpc.Error {
+	if e.feedsStores[moduleName] || errors.Is(res.err, context.Canceled) || errors.Is(res.err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	e.failed[moduleName] = true
+	moduleError := &pbsubstreamsrpc.Error{Module: moduleName, Reason: res.err.Error()}
+	if res.output != nil {
+		moduleError.Logs = res.output.Logs
+		moduleError.LogsTruncated = res.output.DebugLogsTruncated
+	}
+	e.failures.record(e.blockNum, moduleError)
+	return moduleError
+}
+
+// filterLayer returns the executors of `layer` that can run on the current block.
+func filterLayer[E interface{ Name() string }](e *errorsAsData, layer []E) []E {
+	var out []E
+	for _, executor := range layer {
+		if !e.skip(executor.Name()) {
+			out = append(out, executor)
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	pbsubstreamstest "github.com/streamingfast/substreams/pb/sf/substreams/v1/test"
+	"github.com/streamingfast/substreams/pipeline/exec"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+func TestPipeline_errorsAsData(t *testing.T) {
+	testMap := &pbsubstreams.Module{
+		Name:   "test_map",
+		Kind:   &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}},
+		Inputs: []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.substreams.v1.test.Block"}}}},
+	}
+	testStore := &pbsubstreams.Module{
+		Name:   "test_store",
+		Kind:   &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{}},
+		Inputs: []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: "test_map"}}}},
+	}
+
+	newPipeline := func(ctx context.Context, stages outputmodules.ExecutionStages) *Pipeline {
+		return &Pipeline{
+			forkHandler:     NewForkHandler(),
+			outputGraph:     outputmodules.TestNew(),
+			executionStages: stages,
+			moduleExecutors: [][]exec.ModuleExecutor{{mapTestExecutor(t, ctx, "test_map")}},
+		}
+	}
+	newExecOutput := func(blockNum uint64, corrupted bool) *ExecOutputTesting {
+		block := &pbsubstreamstest.Block{Id: "block", Number: blockNum}
+		execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), &pbsubstreams.Clock{Id: block.Id, Number: block.Number})
+		if corrupted {
+			execOutput.Values["sf.substreams.v1.test.Block"] = []byte{0xff, 0xff, 0xff}
+		}
+		return execOutput
+	}
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{ProductionMode: true, ErrorsAsData: true})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+
+	pipe := newPipeline(ctx, outputmodules.ExecutionStages{{{testMap}}})
+	failing := newExecOutput(10, true)
+	require.NoError(t, pipe.executeModules(ctx, failing))
+	require.Len(t, pipe.moduleErrors, 1)
+	assert.Equal(t, "test_map", pipe.moduleErrors[0].Module)
+	assert.Len(t, pipe.extraMapModuleOutputs, 0)
+	assert.NotContains(t, failing.Values, "test_map")
+
+	// the stream goes on with the next block
+	succeeding := newExecOutput(11, false)
+	require.NoError(t, pipe.executeModules(ctx, succeeding))
+	assert.Len(t, pipe.moduleErrors, 0)
+	assert.Contains(t, succeeding.Values, "test_map")
+
+	// a store depends on the failing mapper, skipping it would corrupt the store
+	pipe = newPipeline(ctx, outputmodules.ExecutionStages{{{testMap}}, {{testStore}}})
+	assert.Error(t, pipe.executeModules(ctx, newExecOutput(10, true)))
+
+	// without the flag, errors terminate the stream
+	ctx = reqctx.WithRequest(ctx, &reqctx.RequestDetails{ProductionMode: true})
+	pipe = newPipeline(ctx, outputmodules.ExecutionStages{{{testMap}}})
+	assert.Error(t, pipe.executeModules(ctx, newExecOutput(10, true)))
+}
+
+func Test_newErrorsAsData(t *testing.T) {
+	mapper := func(name string, inputs ...string) *pbsubstreams.Module {
+		m := &pbsubstreams.Module{Name: name, Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}}
+		for _, input := range inputs {
+			m.Inputs = append(m.Inputs, &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: input}}})
+		}
+		return m
+	}
+	store := mapper("store", "b")
+	store.Kind = &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{}}
+
+	// a -> b -> store, a -> c -> d
+	e := newErrorsAsData(outputmodules.ExecutionStages{
+		{{mapper("a")}},
+		{{mapper("b", "a"), mapper("c", "a")}},
+		{{store, mapper("d", "c")}},
+	})
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "store": true}, e.feedsStores)
+
+	e.startBlock(10)
+	assert.Nil(t, e.recover("a", resultObj{err: assert.AnError}))
+	moduleError := e.recover("c", resultObj{err: assert.AnError})
+	require.NotNil(t, moduleError)
+	assert.Equal(t, "c", moduleError.Module)
+	assert.True(t, e.skip("d"))
+	assert.False(t, e.skip("b"))
+	assert.Nil(t, e.recover("d", resultObj{err: context.Canceled}))
+}
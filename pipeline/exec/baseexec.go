@@ -14,6 +14,11 @@ import (
 
 var ErrWasmDeterministicExec = errors.New("wasm execution failed deterministically")
 
+// ErrWasmEnvironmentalExec marks the wasm execution failures which are not tied to the
+// module's code and inputs, running it again may succeed. Failures are deterministic
+// unless known to be environmental, see `wasm.IsEnvironmentalError`.
+var ErrWasmEnvironmentalExec = errors.New("wasm execution failed on an environmental error")
+
 type BaseExecutor struct {
 	ctx context.Context
 
@@ -91,7 +96,10 @@ func (e *BaseExecutor) wasmCall(outputGetter execout.ExecutionOutputGetter) (cal
 			return nil, fmt.Errorf("block %d: module %q: %w: %s", clock.Number, e.moduleName, ErrWasmDeterministicExec, errExecutor.Error())
 		}
 		if err != nil {
-			return nil, fmt.Errorf("block %d: module %q: general wasm execution failed: %v", clock.Number, e.moduleName, err)
+			if e.ctx.Err() != nil || wasm.IsEnvironmentalError(err) {
+				return nil, fmt.Errorf("block %d: module %q: %w: %w", clock.Number, e.moduleName, ErrWasmEnvironmentalExec, err)
+			}
+			return nil, fmt.Errorf("block %d: module %q: %w: %w", clock.Number, e.moduleName, ErrWasmDeterministicExec, err)
 		}
 		if e.instanceCacheEnabled {
 			if err := inst.Cleanup(e.ctx); err != nil {
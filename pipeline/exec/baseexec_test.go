@@ -0,0 +1,58 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/wasm"
+	_ "github.com/streamingfast/substreams/wasm/wazero"
+)
+
+// divideByZeroWasm is a module exporting `memory`, `alloc`, `dealloc` and
+// `divide(ptr, len)`, which divides 1 by the length of its input.
+var divideByZeroWasm = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic, version
+	// types: (i32) -> i32, (i32, i32) -> ()
+	0x01, 0x0b, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x00,
+	// functions: alloc, dealloc, divide
+	0x03, 0x04, 0x03, 0x00, 0x01, 0x01,
+	// memory: 1 page
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	// exports
+	0x07, 0x25, 0x04,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	0x05, 'a', 'l', 'l', 'o', 'c', 0x00, 0x00,
+	0x07, 'd', 'e', 'a', 'l', 'l', 'o', 'c', 0x00, 0x01,
+	0x06, 'd', 'i', 'v', 'i', 'd', 'e', 0x00, 0x02,
+	// code
+	0x0a, 0x12, 0x03,
+	0x04, 0x00, 0x41, 0x00, 0x0b, // alloc: i32.const 0
+	0x02, 0x00, 0x0b, // dealloc: nop
+	0x08, 0x00, 0x41, 0x01, 0x20, 0x01, 0x6e, 0x1a, 0x0b, // divide: drop(1 / len)
+}
+
+func TestBaseExecutor_DivisionByZeroTrapIsNotRetryable(t *testing.T) {
+	ctx := reqctx.WithReqStats(context.Background(), metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+
+	module, err := wasm.NewRegistry(nil, 0).NewModule(ctx, divideByZeroWasm)
+	require.NoError(t, err)
+	defer module.Close(ctx)
+
+	executor := NewBaseExecutor(ctx, "divider", module, false, []wasm.Argument{wasm.NewParamsInput("")}, "divide", nil)
+	_, err = executor.wasmCall(&MockExecOutput{
+		clockFunc: func() *pbsubstreams.Clock { return &pbsubstreams.Clock{Number: 10} },
+	})
+	require.Error(t, err)
+
+	assert.True(t, errors.Is(err, ErrWasmDeterministicExec))
+	assert.False(t, errors.Is(err, ErrWasmEnvironmentalExec))
+	assert.ErrorContains(t, err, "integer divide by zero")
+}
@@ -0,0 +1,40 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/storage/execout"
+)
+
+// CheckDeterminism executes `executor` a second time on the block of `execOutput` and
+// compares the result with `output`, the output of its first execution. A difference is
+// logged, naming the module and block, and counted in the metrics. Store modules are
+// not checked, executing them again would write their deltas twice.
+func CheckDeterminism(ctx context.Context, executor ModuleExecutor, execOutput execout.ExecutionOutputGetter, output []byte) (deterministic bool, err error) {
+	if _, ok := executor.(*StoreModuleExecutor); ok {
+		return true, nil
+	}
+
+	again, _, err := executor.run(ctx, execOutput)
+	if err != nil {
+		return false, fmt.Errorf("executing module %q again: %w", executor.Name(), err)
+	}
+	if bytes.Equal(output, again) {
+		return true, nil
+	}
+
+	metrics.NondeterministicModuleOutputs.Inc()
+	reqctx.Logger(ctx).Warn("module output differs between two executions of the same block",
+		zap.String("module_name", executor.Name()),
+		zap.Uint64("block_num", execOutput.Clock().Number),
+		zap.Int("first_output_size", len(output)),
+		zap.Int("second_output_size", len(again)),
+	)
+	return false, nil
+}
@@ -0,0 +1,54 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/storage/execout"
+)
+
+func TestCheckDeterminism(t *testing.T) {
+	ctx := reqctx.WithReqStats(context.Background(), metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+	output := &MockExecOutput{
+		clockFunc: func() *pbsubstreams.Clock { return &pbsubstreams.Clock{Number: 42} },
+		cacheMap:  make(map[string][]byte),
+	}
+
+	newExecutor := func(name string, deterministic bool) *MockModuleExecutor {
+		executions := 0
+		return &MockModuleExecutor{
+			name: name,
+			RunFunc: func(ctx context.Context, reader execout.ExecutionOutputGetter) ([]byte, *pbssinternal.ModuleOutput, error) {
+				executions++
+				data := []byte("output")
+				if !deterministic {
+					// e.g. iterating over a map, or reading the time
+					data = []byte(fmt.Sprintf("output %d", executions))
+				}
+				return data, &pbssinternal.ModuleOutput{}, nil
+			},
+		}
+	}
+
+	before := testutil.ToFloat64(metrics.NondeterministicModuleOutputs.Native())
+	for _, executor := range []*MockModuleExecutor{newExecutor("determinism.stable", true), newExecutor("determinism.flaky", false)} {
+		_, outputBytes, err := RunModule(ctx, executor, output)
+		require.NoError(t, err)
+
+		deterministic, err := CheckDeterminism(ctx, executor, output, outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, executor.name == "determinism.stable", deterministic, executor.name)
+	}
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.NondeterministicModuleOutputs.Native())-before, "only the flaky module is counted")
+}
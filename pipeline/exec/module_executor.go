@@ -47,6 +47,7 @@ func RunModule(ctx context.Context, executor ModuleExecutor, execOutput execout.
 
 		fillModuleOutputMetadata(executor, moduleOutput)
 		moduleOutput.Cached = true
+		reqctx.ReqStats(ctx).RecordModuleBlock(modName)
 		return moduleOutput, outputBytes, nil
 	}
 
@@ -55,7 +56,10 @@ func RunModule(ctx context.Context, executor ModuleExecutor, execOutput execout.
 	if err != nil {
 		return nil, nil, fmt.Errorf("execute: %w", err)
 	}
-	reqctx.ReqStats(ctx).RecordModuleWasmBlock(modName, time.Since(t0))
+	reqStats := reqctx.ReqStats(ctx)
+	reqStats.RecordModuleWasmBlock(modName, time.Since(t0))
+	// also counted when the execution is skipped for lack of inputs
+	reqStats.RecordModuleBlock(modName)
 
 	fillModuleOutputMetadata(executor, moduleOutput)
 
@@ -125,6 +125,8 @@ func TestModuleExecutorRunner_Run_HappyPath(t *testing.T) {
 func TestModuleExecutorRunner_Run_CachedOutput(t *testing.T) {
 	ctx := context.Background()
 
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+	ctx = reqctx.WithReqStats(ctx, stats)
 	applied := false
 
 	executor := &MockModuleExecutor{
@@ -166,4 +168,5 @@ func TestModuleExecutorRunner_Run_CachedOutput(t *testing.T) {
 	assert.True(t, applied)
 	assert.NotEmpty(t, moduleOutput)
 	assert.True(t, moduleOutput.Cached)
+	assert.Equal(t, map[string]uint64{"test": 1}, stats.ModuleBlockCounts())
 }
@@ -44,7 +44,7 @@ func (e *StoreModuleExecutor) run(ctx context.Context, reader execout.ExecutionO
 		return nil, nil, fmt.Errorf("store wasm call: %w", err)
 	}
 
-	return e.wrapDeltas()
+	return e.wrapDeltas(reqctx.Details(ctx).ShouldOrderDeltasByKey(e.moduleName))
 }
 
 func (e *StoreModuleExecutor) HasValidOutput() bool {
@@ -52,10 +52,15 @@ func (e *StoreModuleExecutor) HasValidOutput() bool {
 	return ok
 }
 
-func (e *StoreModuleExecutor) wrapDeltas() ([]byte, *pbssinternal.ModuleOutput, error) {
+// wrapDeltas returns the deltas of the block, to cache and to send, ordered by key
+// within an ordinal when `orderByKey` is set, see `store.OrderDeltasByKey`.
+func (e *StoreModuleExecutor) wrapDeltas(orderByKey bool) ([]byte, *pbssinternal.ModuleOutput, error) {
 	deltas := &pbssinternal.StoreDeltas{
 		StoreDeltas: e.outputStore.GetDeltas(),
 	}
+	if orderByKey {
+		store.OrderDeltasByKey(deltas.StoreDeltas)
+	}
 
 	data, err := proto.Marshal(deltas)
 	if err != nil {
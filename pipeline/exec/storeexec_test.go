@@ -0,0 +1,47 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+func TestStoreModuleExecutor_wrapDeltasOrderedByKey(t *testing.T) {
+	run := func(orderByKey bool) (cached, sent []string) {
+		config, err := store.NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+		s := config.NewFullKV(zap.NewNop())
+		s.SetBytes(1, "b", []byte("v"))
+		s.SetBytes(1, "a", []byte("v"))
+
+		executor := &StoreModuleExecutor{outputStore: s}
+		data, output, err := executor.wrapDeltas(orderByKey)
+		require.NoError(t, err)
+
+		deltas := &pbssinternal.StoreDeltas{}
+		require.NoError(t, proto.Unmarshal(data, deltas))
+		for _, delta := range deltas.StoreDeltas {
+			cached = append(cached, delta.Key)
+		}
+		for _, delta := range output.GetStoreDeltas().StoreDeltas {
+			sent = append(sent, delta.Key)
+		}
+		return
+	}
+
+	cached, sent := run(true)
+	assert.Equal(t, []string{"a", "b"}, cached)
+	assert.Equal(t, []string{"a", "b"}, sent)
+
+	cached, sent = run(false)
+	assert.Equal(t, []string{"b", "a"}, cached)
+	assert.Equal(t, []string{"b", "a"}, sent)
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"slices"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+// maxFailureSampleReasons caps the distinct reasons kept for each module in the summary.
+const maxFailureSampleReasons = 3
+
+// failureSummary aggregates the module errors returned as data over a stream, sent to
+// the client once it ends.
+type failureSummary struct {
+	modules []*pbsubstreamsrpc.ModuleFailures // in the order of their first failure
+	byName  map[string]*pbsubstreamsrpc.ModuleFailures
+}
+
+func newFailureSummary() *failureSummary {
+	return &failureSummary{
+		byName: make(map[string]*pbsubstreamsrpc.ModuleFailures),
+	}
+}
+
+func (s *failureSummary) record(blockNum uint64, moduleError *pbsubstreamsrpc.Error) {
+	failures, found := s.byName[moduleError.Module]
+	if !found {
+		failures = &pbsubstreamsrpc.ModuleFailures{
+			Module:           moduleError.Module,
+			FirstFailedBlock: blockNum,
+		}
+		s.byName[moduleError.Module] = failures
+		s.modules = append(s.modules, failures)
+	}
+
+	failures.Count++
+	failures.LastFailedBlock = blockNum
+	if len(failures.SampleReasons) < maxFailureSampleReasons && !slices.Contains(failures.SampleReasons, moduleError.Reason) {
+		failures.SampleReasons = append(failures.SampleReasons, moduleError.Reason)
+	}
+}
+
+func (s *failureSummary) isEmpty() bool {
+	return len(s.modules) == 0
+}
+
+func (s *failureSummary) response() *pbsubstreamsrpc.Response {
+	return &pbsubstreamsrpc.Response{
+		Message: &pbsubstreamsrpc.Response_FailureSummary{
+			FailureSummary: &pbsubstreamsrpc.FailureSummary{Modules: s.modules},
+		},
+	}
+}
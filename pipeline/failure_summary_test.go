@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams"
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	pbsubstreamstest "github.com/streamingfast/substreams/pb/sf/substreams/v1/test"
+	"github.com/streamingfast/substreams/pipeline/exec"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+func TestPipeline_failureSummary(t *testing.T) {
+	mapper := func(name string) *pbsubstreams.Module {
+		return &pbsubstreams.Module{
+			Name:   name,
+			Kind:   &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}},
+			Inputs: []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.substreams.v1.test.Block"}}}},
+		}
+	}
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{ProductionMode: true, ErrorsAsData: true})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+
+	var responses []substreams.ResponseFromAnyTier
+	pipe := &Pipeline{
+		ctx:             ctx,
+		forkHandler:     NewForkHandler(),
+		outputGraph:     outputmodules.TestNew(),
+		executionStages: outputmodules.ExecutionStages{{{mapper("test_map"), mapper("missing_entrypoint")}}},
+		moduleExecutors: [][]exec.ModuleExecutor{{mapTestExecutor(t, ctx, "test_map"), mapTestExecutor(t, ctx, "missing_entrypoint")}},
+		respFunc: func(resp substreams.ResponseFromAnyTier) error {
+			responses = append(responses, resp)
+			return nil
+		},
+	}
+
+	for blockNum := uint64(10); blockNum < 13; blockNum++ {
+		block := &pbsubstreamstest.Block{Id: "block", Number: blockNum}
+		execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), &pbsubstreams.Clock{Id: block.Id, Number: block.Number})
+		if blockNum != 11 {
+			execOutput.Values["sf.substreams.v1.test.Block"] = []byte{0xff, 0xff, 0xff}
+		}
+		require.NoError(t, pipe.executeModules(ctx, execOutput))
+	}
+
+	// the summary is sent whatever ends the stream
+	assert.Equal(t, assert.AnError, pipe.OnStreamTerminated(ctx, assert.AnError))
+	require.Len(t, responses, 1)
+	summary := responses[0].(*pbsubstreamsrpc.Response).GetFailureSummary()
+	require.NotNil(t, summary)
+	require.Len(t, summary.Modules, 2)
+
+	byModule := map[string]*pbsubstreamsrpc.ModuleFailures{}
+	for _, failures := range summary.Modules {
+		byModule[failures.Module] = failures
+	}
+
+	testMap := byModule["test_map"]
+	require.NotNil(t, testMap)
+	assert.Equal(t, uint64(2), testMap.Count)
+	assert.Equal(t, uint64(10), testMap.FirstFailedBlock)
+	assert.Equal(t, uint64(12), testMap.LastFailedBlock)
+	require.Len(t, testMap.SampleReasons, 2)
+	assert.Contains(t, testMap.SampleReasons[0], "block 10")
+
+	missing := byModule["missing_entrypoint"]
+	require.NotNil(t, missing)
+	assert.Equal(t, uint64(3), missing.Count)
+	assert.Equal(t, uint64(10), missing.FirstFailedBlock)
+	assert.Equal(t, uint64(12), missing.LastFailedBlock)
+	assert.NotEmpty(t, missing.SampleReasons)
+}
+
+func Test_failureSummarySampleReasons(t *testing.T) {
+	s := newFailureSummary()
+	assert.True(t, s.isEmpty())
+
+	for i, reason := range []string{"a", "b", "a", "c", "d"} {
+		s.record(uint64(i), &pbsubstreamsrpc.Error{Module: "mod", Reason: reason})
+	}
+	require.False(t, s.isEmpty())
+	assert.Equal(t, []string{"a", "b", "c"}, s.modules[0].SampleReasons)
+	assert.Equal(t, uint64(5), s.modules[0].Count)
+}
@@ -1,13 +1,20 @@
 package pipeline
 
 import (
-	"github.com/streamingfast/bstream"
+	"errors"
+	"fmt"
 	"sync"
 
+	"github.com/streamingfast/bstream"
+
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 )
 
+// ErrDeepReorg fails the stream on a reorg undoing a block further behind the head than
+// the reorg grace window, the stores are not reverted that far.
+var ErrDeepReorg = errors.New("reorg deeper than the grace window")
+
 type UndoHandler func(clock *pbsubstreams.Clock, moduleOutputs []*pbssinternal.ModuleOutput)
 
 // TODO(abourget): The scope of this object and the Engine
@@ -22,6 +29,11 @@ type ForkHandler struct {
 	reversibleOutputs map[string][]*pbssinternal.ModuleOutput
 	undoHandlers      []UndoHandler
 
+	// reorgGraceWindow is the number of blocks, counting the head, which can be undone
+	// by reverting their store deltas. 0 means no limit.
+	reorgGraceWindow uint64
+	headBlockNum     uint64
+
 	mu sync.RWMutex
 }
 
@@ -43,6 +55,10 @@ func (f *ForkHandler) handleUndo(
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
+	if f.reorgGraceWindow != 0 && clock.Number+f.reorgGraceWindow <= f.headBlockNum {
+		return fmt.Errorf("%w: undoing block %d, %d blocks behind head block %d, the grace window is %d blocks", ErrDeepReorg, clock.Number, f.headBlockNum-clock.Number, f.headBlockNum, f.reorgGraceWindow)
+	}
+
 	if moduleOutputs, found := f.reversibleOutputs[clock.Id]; found {
 		for _, h := range f.undoHandlers {
 			h(clock, moduleOutputs)
@@ -51,6 +67,17 @@ func (f *ForkHandler) handleUndo(
 	return nil
 }
 
+// trackHead records `blockNum`, a new block processed, as the head the depth of reorgs
+// is measured from.
+func (f *ForkHandler) trackHead(blockNum uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if blockNum > f.headBlockNum {
+		f.headBlockNum = blockNum
+	}
+}
+
 func (f *ForkHandler) removeReversibleOutput(blockID string) {
 	f.mu.Lock()
 	delete(f.reversibleOutputs, blockID)
@@ -1,11 +1,17 @@
 package pipeline
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
-	"github.com/stretchr/testify/require"
+	store2 "github.com/streamingfast/substreams/storage/store"
 )
 
 var reversibleOutputs = map[string][]*pbssinternal.ModuleOutput{
@@ -111,3 +117,42 @@ func Test_HandleIrreversibility(t *testing.T) {
 		})
 	}
 }
+
+func Test_HandleUndoReorgGraceWindow(t *testing.T) {
+	config, err := store2.NewConfig("mod1", 0, "mod1", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	fullKV := config.NewFullKV(zap.NewNop())
+	storeMap := store2.NewMap()
+	storeMap.Set(fullKV)
+	stores := &Stores{StoreMap: storeMap}
+
+	forkHandler := NewForkHandler()
+	forkHandler.reorgGraceWindow = 3
+	forkHandler.registerUndoHandler(func(clock *pbsubstreams.Clock, moduleOutputs []*pbssinternal.ModuleOutput) {
+		for _, moduleOutput := range moduleOutputs {
+			stores.storesHandleUndo(moduleOutput)
+		}
+	})
+
+	for blockNum := uint64(96); blockNum <= 100; blockNum++ {
+		forkHandler.trackHead(blockNum)
+		fullKV.Set(0, "last", fmt.Sprintf("%d", blockNum))
+		forkHandler.addReversibleOutput(&pbssinternal.ModuleOutput{
+			ModuleName: "mod1",
+			Data:       &pbssinternal.ModuleOutput_StoreDeltas{StoreDeltas: &pbssinternal.StoreDeltas{StoreDeltas: fullKV.GetDeltas()}},
+		}, fmt.Sprintf("%da", blockNum))
+		fullKV.Reset()
+	}
+
+	for blockNum := uint64(100); blockNum >= 98; blockNum-- {
+		require.NoError(t, forkHandler.handleUndo(&pbsubstreams.Clock{Id: fmt.Sprintf("%da", blockNum), Number: blockNum}, nil))
+	}
+	val, found := fullKV.GetLast("last")
+	require.True(t, found)
+	assert.Equal(t, []byte("97"), val)
+
+	err = forkHandler.handleUndo(&pbsubstreams.Clock{Id: "97a", Number: 97}, nil)
+	assert.ErrorIs(t, err, ErrDeepReorg)
+	val, _ = fullKV.GetLast("last")
+	assert.Equal(t, []byte("97"), val, "the store is left as is")
+}
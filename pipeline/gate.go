@@ -9,17 +9,20 @@ import (
 )
 
 type gate struct {
-	requestStartBlockNum uint64
-	disabled             bool
-	passed               bool
-	snapshotSent         bool
+	requestStartBlockNum  uint64
+	linearHandoffBlockNum uint64
+	disabled              bool
+	passed                bool
+	snapshotSent          bool
+	liveTransitionSent    bool
 }
 
 func newGate(ctx context.Context) *gate {
 	reqDetails := reqctx.Details(ctx)
 	return &gate{
-		disabled:             reqDetails.IsTier2Request,
-		requestStartBlockNum: reqDetails.ResolvedStartBlockNum,
+		disabled:              reqDetails.IsTier2Request,
+		requestStartBlockNum:  reqDetails.ResolvedStartBlockNum,
+		linearHandoffBlockNum: reqDetails.LinearHandoffBlockNum,
 	}
 }
 
@@ -49,6 +52,16 @@ func (g *gate) shouldSendOutputs() bool {
 	return g.passed
 }
 
+// shouldMarkLiveTransition tells if the outputs of `blockNum`, about to be sent, are the
+// first ones at or past the live handoff block. It only returns true once.
+func (g *gate) shouldMarkLiveTransition(blockNum uint64) bool {
+	if g.disabled || g.liveTransitionSent || blockNum < g.linearHandoffBlockNum {
+		return false
+	}
+	g.liveTransitionSent = true
+	return true
+}
+
 func blockTriggersGate(blockNum, requestStartBlockNum uint64, step bstream.StepType) bool {
 	if step.Matches(bstream.StepNew) {
 		return blockNum >= requestStartBlockNum
@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/bstream"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+func TestGate_liveTransition(t *testing.T) {
+	tests := []struct {
+		name           string
+		startBlock     uint64
+		handoffBlock   uint64
+		firstBlock     uint64
+		expectMarkAt   []uint64
+		isTier2Request bool
+	}{
+		{
+			name:         "production, cached outputs sent up to the handoff",
+			startBlock:   5,
+			handoffBlock: 10,
+			firstBlock:   10,
+			expectMarkAt: []uint64{10},
+		},
+		{
+			name:         "development, handoff before the start block",
+			startBlock:   8,
+			handoffBlock: 6,
+			firstBlock:   6,
+			expectMarkAt: []uint64{8},
+		},
+		{
+			name:           "tier2",
+			startBlock:     5,
+			handoffBlock:   5,
+			firstBlock:     5,
+			isTier2Request: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := newGate(reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{
+				ResolvedStartBlockNum: test.startBlock,
+				LinearHandoffBlockNum: test.handoffBlock,
+				IsTier2Request:        test.isTier2Request,
+			}))
+
+			var markedAt []uint64
+			for blockNum := test.firstBlock; blockNum < test.firstBlock+10; blockNum++ {
+				g.processBlock(blockNum, bstream.StepNew)
+				if g.shouldSendOutputs() && g.shouldMarkLiveTransition(blockNum) {
+					markedAt = append(markedAt, blockNum)
+				}
+			}
+			assert.Equal(t, test.expectMarkAt, markedAt)
+		})
+	}
+}
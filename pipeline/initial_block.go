@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+)
+
+// moduleInitialBlocks maps the modules of the execution stages to the block they start
+// executing at: their initial block, or the one of a map input starting later. Published
+// packages may declare a module starting before a map input, which would otherwise run
+// on blocks where that input has no output.
+func moduleInitialBlocks(stages outputmodules.ExecutionStages) map[string]uint64 {
+	out := make(map[string]uint64)
+	for _, stage := range stages {
+		for _, layer := range stage {
+			for _, module := range layer {
+				initialBlock := module.InitialBlock
+				for _, input := range module.Inputs {
+					mapInput := input.GetMap()
+					if mapInput == nil {
+						continue
+					}
+					if inputInitialBlock, found := out[mapInput.ModuleName]; found && inputInitialBlock > initialBlock {
+						initialBlock = inputInitialBlock
+					}
+				}
+				out[module.Name] = initialBlock
+			}
+		}
+	}
+	return out
+}
+
+// filterStarted keeps the executors of a layer whose module has started at `blockNum`,
+// a module is never executed before its initial block, where it has no meaningful
+// output. The layer is returned as is, without allocating, once all its modules started.
+func filterStarted[E interface{ Name() string }](initialBlocks map[string]uint64, layer []E, blockNum uint64) []E {
+	for i, executor := range layer {
+		if initialBlock, found := initialBlocks[executor.Name()]; found && blockNum < initialBlock {
+			out := make([]E, i, len(layer))
+			copy(out, layer[:i])
+			for _, executor := range layer[i+1:] {
+				if initialBlock, found := initialBlocks[executor.Name()]; found && blockNum < initialBlock {
+					continue
+				}
+				out = append(out, executor)
+			}
+			return out
+		}
+	}
+	return layer
+}
+
+// awaitInitialValues lists the stores with initial values whose module has not started
+// at `firstBlock`, the first block the pipeline processes. They are seeded when reaching
+// their initial block, see `seedInitialValues`. The stores of modules started before hold
+// the values already, through the snapshots built on top of the stores seeded then.
+func (p *Pipeline) awaitInitialValues(firstBlock uint64) {
+	p.pendingInitialValues = nil
+	for name, initialBlock := range p.moduleInitialBlocks {
+		config, found := p.stores.configs[name]
+		if !found || !config.HasInitialValues() || initialBlock < firstBlock {
+			continue
+		}
+		p.pendingInitialValues = append(p.pendingInitialValues, name)
+	}
+}
+
+// seedInitialValues loads their initial values in the stores whose module starts at
+// `blockNum`, before any module executes on it.
+func (p *Pipeline) seedInitialValues(blockNum uint64) {
+	if len(p.pendingInitialValues) == 0 {
+		return
+	}
+	remaining := p.pendingInitialValues[:0]
+	for _, name := range p.pendingInitialValues {
+		if blockNum < p.moduleInitialBlocks[name] {
+			remaining = append(remaining, name)
+			continue
+		}
+		if s, found := p.stores.StoreMap.Get(name); found {
+			if loader, ok := s.(interface{ LoadInitialValues() }); ok {
+				loader.LoadInitialValues()
+			}
+		}
+	}
+	p.pendingInitialValues = remaining
+}
@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	pbsubstreamstest "github.com/streamingfast/substreams/pb/sf/substreams/v1/test"
+	"github.com/streamingfast/substreams/pipeline/exec"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+func TestPipeline_notExecutedBeforeInitialBlock(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+
+	testMap := &pbsubstreams.Module{Name: "test_map", InitialBlock: 15}
+	stages := outputmodules.ExecutionStages{{{testMap}}}
+	pipe := &Pipeline{
+		forkHandler:         NewForkHandler(),
+		outputGraph:         outputmodules.TestNew(),
+		executionStages:     stages,
+		moduleInitialBlocks: moduleInitialBlocks(stages),
+		moduleExecutors:     [][]exec.ModuleExecutor{{mapTestExecutor(t, ctx, "test_map")}},
+	}
+
+	before := &pbsubstreamstest.Block{Id: "block-10", Number: 10}
+	execOutput := NewExecOutputTesting(t, bstreamBlk(t, before), &pbsubstreams.Clock{Id: before.Id, Number: before.Number})
+	require.NoError(t, pipe.executeModules(ctx, execOutput))
+	_, found := execOutput.Values["test_map"]
+	assert.False(t, found, "the module has no output before its initial block")
+
+	after := &pbsubstreamstest.Block{Id: "block-20", Number: 20}
+	execOutput = NewExecOutputTesting(t, bstreamBlk(t, after), &pbsubstreams.Clock{Id: after.Id, Number: after.Number})
+	require.NoError(t, pipe.executeModules(ctx, execOutput))
+	_, found = execOutput.Values["test_map"]
+	assert.True(t, found)
+}
+
+func TestPipeline_seedsInitialValuesAtInitialBlock(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{})
+
+	newConfig := func(name string, initialBlock uint64) *store.Config {
+		config, err := store.NewConfig(name, initialBlock, name+".hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+		require.NoError(t, config.SetInitialValues(map[string][]byte{"seeded": []byte("value")}))
+		return config
+	}
+	configs := store.ConfigMap{"later": newConfig("later", 15), "started": newConfig("started", 5)}
+	stores := NewStores(ctx, configs, 10, 10, 0, false)
+	storeMap := store.NewMap()
+	for _, config := range configs {
+		storeMap.Set(config.NewFullKV(zap.NewNop()))
+	}
+	stores.SetStoreMap(storeMap)
+
+	pipe := &Pipeline{
+		stores: stores,
+		moduleInitialBlocks: map[string]uint64{
+			"later":   15,
+			"started": 5,
+		},
+	}
+	pipe.awaitInitialValues(10)
+
+	seeded := func(name string) bool {
+		s, found := storeMap.Get(name)
+		require.True(t, found)
+		_, found = s.GetLast("seeded")
+		return found
+	}
+
+	pipe.seedInitialValues(10)
+	assert.False(t, seeded("later"), "not seeded before the module's initial block")
+
+	// the initial block itself may be skipped by the chain
+	pipe.seedInitialValues(16)
+	assert.True(t, seeded("later"))
+	assert.False(t, seeded("started"), "stores started before the first block hold the values through their snapshot")
+	assert.Empty(t, pipe.pendingInitialValues)
+}
+
+func TestModuleInitialBlocks_startsWithMapInputs(t *testing.T) {
+	mapInput := func(name string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: name}}}
+	}
+	storeInput := func(name string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Store_{Store: &pbsubstreams.Module_Input_Store{ModuleName: name}}}
+	}
+
+	stages := outputmodules.ExecutionStages{{
+		{{Name: "late_map", InitialBlock: 20}, {Name: "early_store", InitialBlock: 30}},
+		{{Name: "consumer", InitialBlock: 10, Inputs: []*pbsubstreams.Module_Input{mapInput("late_map"), storeInput("early_store")}}},
+		{{Name: "leaf", InitialBlock: 5, Inputs: []*pbsubstreams.Module_Input{mapInput("consumer")}}},
+	}}
+
+	assert.Equal(t, map[string]uint64{
+		"late_map":    20,
+		"early_store": 30,
+		"consumer":    20,
+		"leaf":        20,
+	}, moduleInitialBlocks(stages))
+}
+
+type namedExecutor string
+
+func (n namedExecutor) Name() string { return string(n) }
+
+func TestFilterStarted(t *testing.T) {
+	initialBlocks := map[string]uint64{"a": 10, "b": 20, "c": 10}
+	layer := []namedExecutor{"a", "b", "c"}
+
+	assert.Equal(t, []namedExecutor{"a", "c"}, filterStarted(initialBlocks, layer, 15))
+	assert.Empty(t, filterStarted(initialBlocks, layer, 5))
+	assert.Equal(t, layer, filterStarted(initialBlocks, layer, 20))
+	assert.Zero(t, testing.AllocsPerRun(10, func() { filterStarted(initialBlocks, layer, 20) }))
+}
@@ -32,6 +32,17 @@ func WithPostBlockHook(f substreams.BlockHook) Option {
 	}
 }
 
+// WithStoreDeltasHandler calls `handler` with the deltas of store `storeName` for each
+// block the pipeline processes, and with the deltas it reverts on forks, for in-process
+// consumers embedding substreams, see `service.WithPipelineOptions`. The handler runs in
+// a goroutine of its own: up to `bufferSize` blocks wait for it, past that the processing
+// of blocks waits for it to catch up. It stops being called when the request ends.
+func WithStoreDeltasHandler(storeName string, bufferSize int, handler StoreDeltasHandler) Option {
+	return func(p *Pipeline) {
+		p.stores.subscribe(p.ctx, storeName, bufferSize, handler)
+	}
+}
+
 func WithPostJobHook(f substreams.PostJobHook) Option {
 	return func(p *Pipeline) {
 		p.postJobHooks = append(p.postJobHooks, f)
@@ -44,6 +55,15 @@ func WithFinalBlocksOnly() Option {
 	}
 }
 
+// WithStartAcknowledgement makes the pipeline send, before backprocessing and before any
+// output, a `StreamStart` message at the effective start block, with `cursor`, the
+// cursor resuming the stream there.
+func WithStartAcknowledgement(cursor string) Option {
+	return func(p *Pipeline) {
+		p.startAck = &startAck{cursor: cursor}
+	}
+}
+
 func WithHighestStage(stage uint32) Option {
 	return func(p *Pipeline) {
 		s := int(stage)
@@ -30,23 +30,33 @@ func (g *Graph) ModuleHashes() *manifest.ModuleHashes { return g.moduleHashes }
 func (g *Graph) LowestInitBlock() uint64              { return g.lowestInitBlock }
 
 func NewOutputModuleGraph(outputModule string, productionMode bool, modules *pbsubstreams.Modules) (out *Graph, err error) {
+	return NewOutputModuleGraphWithMaxDepth(outputModule, productionMode, modules, 0)
+}
+
+// NewOutputModuleGraphWithMaxDepth is NewOutputModuleGraph, also rejecting output modules
+// at the end of an input chain longer than `maxDepth` modules, 0 meaning no limit.
+func NewOutputModuleGraphWithMaxDepth(outputModule string, productionMode bool, modules *pbsubstreams.Modules, maxDepth uint64) (out *Graph, err error) {
 	out = &Graph{
 		requestModules: modules,
 	}
-	if err := out.computeGraph(outputModule, productionMode, modules); err != nil {
+	if err := out.computeGraph(outputModule, productionMode, modules, maxDepth); err != nil {
 		return nil, fmt.Errorf("module graph: %w", err)
 	}
 
 	return out, nil
 }
 
-func (g *Graph) computeGraph(outputModule string, productionMode bool, modules *pbsubstreams.Modules) error {
+func (g *Graph) computeGraph(outputModule string, productionMode bool, modules *pbsubstreams.Modules, maxDepth uint64) error {
 	graph, err := manifest.NewModuleGraph(modules.Modules)
 	if err != nil {
 		return fmt.Errorf("compute graph: %w", err)
 	}
 	outputModuleName := outputModule
 
+	if err := graph.ValidateDepth(outputModuleName, maxDepth); err != nil {
+		return err
+	}
+
 	processModules, err := graph.ModulesDownTo(outputModuleName)
 	if err != nil {
 		return fmt.Errorf("building execution moduleGraph: %w", err)
@@ -247,9 +257,61 @@ func (g *Graph) hashModules(graph *manifest.ModuleGraph) error {
 	return nil
 }
 
-func (g *Graph) ValidateRequestStartBlock(requestStartBlockNum uint64) error {
-	if requestStartBlockNum < g.outputModule.InitialBlock {
-		return fmt.Errorf("start block %d smaller than request outputs for module %q with start block %d", requestStartBlockNum, g.outputModule.Name, g.outputModule.InitialBlock)
+// EffectiveStartBlock returns the block a request starting at `requestStartBlockNum`
+// effectively starts at for a module starting at `moduleInitialBlock`: a module produces
+// nothing before its initial block, so earlier starts are snapped to it.
+func EffectiveStartBlock(requestStartBlockNum, moduleInitialBlock uint64) uint64 {
+	return max(requestStartBlockNum, moduleInitialBlock)
+}
+
+// EffectiveStartBlock returns the start block of the request snapped to the initial
+// block of the output module, see `EffectiveStartBlock`.
+func (g *Graph) EffectiveStartBlock(requestStartBlockNum uint64) uint64 {
+	return EffectiveStartBlock(requestStartBlockNum, g.outputModule.InitialBlock)
+}
+
+// ValidateEffectiveStartBlock ensures `startBlockNum` is not below the initial block of
+// the output module, as is the case once snapped with `EffectiveStartBlock`.
+func (g *Graph) ValidateEffectiveStartBlock(startBlockNum uint64) error {
+	if effective := g.EffectiveStartBlock(startBlockNum); effective != startBlockNum {
+		return fmt.Errorf("start block %d smaller than request outputs for module %q with start block %d", startBlockNum, g.outputModule.Name, g.outputModule.InitialBlock)
+	}
+	return nil
+}
+
+// ValidateStoreOutputModules ensures every module for which a store output mode
+// was requested is a store that is part of the graph and whose output is returned
+// along the output module's: none in production mode, and not the output module itself.
+func (g *Graph) ValidateStoreOutputModules(modules []string, productionMode bool) error {
+	if len(modules) != 0 && productionMode {
+		return fmt.Errorf("store output modes cannot be requested in production mode, where only the output module's output is returned")
+	}
+	for _, name := range modules {
+		if g.IsOutputModule(name) {
+			return fmt.Errorf("store output mode requested for %q, which is the output module", name)
+		}
+		found := false
+		for _, store := range g.stores {
+			if store.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("store output mode requested for %q, which is not a store module of this request", name)
+		}
+	}
+	return nil
+}
+
+// ValidateRequestStopBlock ensures the output module starts before the (exclusive) stop
+// block of a bounded request, otherwise the request would not produce anything.
+func (g *Graph) ValidateRequestStopBlock(requestStopBlockNum uint64, unbounded bool) error {
+	if unbounded {
+		return nil
+	}
+	if g.outputModule.InitialBlock >= requestStopBlockNum {
+		return fmt.Errorf("module %q starts at block %d, which is not before the request stop block %d", g.outputModule.Name, g.outputModule.InitialBlock, requestStopBlockNum)
 	}
 	return nil
 }
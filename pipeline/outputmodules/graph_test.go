@@ -157,3 +157,38 @@ func TestGraph_computeSchedulableModules(t *testing.T) {
 		})
 	}
 }
+
+func TestGraph_EffectiveStartBlock(t *testing.T) {
+	g := &Graph{outputModule: &pbsubstreams.Module{Name: "map_out", InitialBlock: 100}}
+
+	assert.Equal(t, uint64(100), g.EffectiveStartBlock(10))
+	assert.Equal(t, uint64(100), g.EffectiveStartBlock(100))
+	assert.Equal(t, uint64(150), g.EffectiveStartBlock(150))
+
+	assert.NoError(t, g.ValidateEffectiveStartBlock(g.EffectiveStartBlock(10)))
+	assert.EqualError(t, g.ValidateEffectiveStartBlock(10), `start block 10 smaller than request outputs for module "map_out" with start block 100`)
+}
+
+func TestGraph_ValidateRequestStopBlock(t *testing.T) {
+	g := &Graph{outputModule: &pbsubstreams.Module{Name: "map_out", InitialBlock: 100}}
+
+	assert.NoError(t, g.ValidateRequestStopBlock(0, true))
+	assert.NoError(t, g.ValidateRequestStopBlock(101, false))
+
+	err := g.ValidateRequestStopBlock(100, false)
+	assert.EqualError(t, err, `module "map_out" starts at block 100, which is not before the request stop block 100`)
+	assert.Error(t, g.ValidateRequestStopBlock(50, false))
+}
+
+func TestGraph_ValidateStoreOutputModules(t *testing.T) {
+	g := &Graph{
+		stores:       []*pbsubstreams.Module{{Name: "store_a"}, {Name: "store_b"}, {Name: "store_out"}},
+		outputModule: &pbsubstreams.Module{Name: "store_out"},
+	}
+
+	assert.NoError(t, g.ValidateStoreOutputModules([]string{"store_a", "store_b"}, false))
+	assert.NoError(t, g.ValidateStoreOutputModules(nil, true))
+	assert.EqualError(t, g.ValidateStoreOutputModules([]string{"store_a", "map_out"}, false), `store output mode requested for "map_out", which is not a store module of this request`)
+	assert.EqualError(t, g.ValidateStoreOutputModules([]string{"store_out"}, false), `store output mode requested for "store_out", which is the output module`)
+	assert.Error(t, g.ValidateStoreOutputModules([]string{"store_a"}, true))
+}
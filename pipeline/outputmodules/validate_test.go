@@ -27,6 +27,9 @@ func Test_ValidateRequest(t *testing.T) {
 		{"single legacy map output module is accepted for none sub-request", req(1, testOutputMap), testBlockType, nil},
 		{"single map output module is accepted for none sub-request", req(1, testOutputMap), testBlockType, nil},
 		{"single store output module is not accepted for none sub-request", req(1, testOutputStore), testBlockType, fmt.Errorf("validate tier1 request: output module must be of kind 'map'")},
+		{"store module missing its value type", req(1, withStoreModule("store_mod", ""), testOutputMap), testBlockType, fmt.Errorf(`modules validation failed: module "store_mod": store value type is missing`)},
+		{"store module with unknown value type", req(1, withStoreModule("store_mod", "uint256"), testOutputMap), testBlockType, fmt.Errorf(`modules validation failed: module "store_mod": unknown store value type "uint256"`)},
+		{"store module with known value type", req(1, withStoreModule("store_mod", "bigint"), testOutputMap), testBlockType, nil},
 		{"debug initial snapshots not accepted in production mode", req(1, testOutputMap, withDebugInitialSnapshotForModules([]string{"foo"}), withProductionMode()), "", fmt.Errorf(`validate tier1 request: cannot set 'debug-modules-initial-snapshot' in 'production-mode'`)},
 	}
 
@@ -51,6 +54,19 @@ func withOutputModule(outputModule, kind string) reqOption {
 	}
 }
 
+func withStoreModule(name, valueType string) reqOption {
+	return func(req *pbsubstreamsrpc.Request) *pbsubstreamsrpc.Request {
+		req.Modules.Modules = append(req.Modules.Modules, &pbsubstreams.Module{
+			Name: name,
+			Kind: &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{
+				UpdatePolicy: pbsubstreams.Module_KindStore_UPDATE_POLICY_SET,
+				ValueType:    valueType,
+			}},
+		})
+		return req
+	}
+}
+
 func withProductionMode() reqOption {
 	return func(req *pbsubstreamsrpc.Request) *pbsubstreamsrpc.Request {
 		req.ProductionMode = true
@@ -47,10 +47,14 @@ type Pipeline struct {
 	loadedModules   map[uint32]wasm.Module
 	moduleExecutors [][]exec.ModuleExecutor // Staged module executors
 	executionStages outputmodules.ExecutionStages
+	errorsAsData    *errorsAsData // set when the request asks for module errors as data
 
 	mapModuleOutput         *pbsubstreamsrpc.MapModuleOutput
 	extraMapModuleOutputs   []*pbsubstreamsrpc.MapModuleOutput
 	extraStoreModuleOutputs []*pbsubstreamsrpc.StoreModuleOutput
+	extraModuleOutputsBytes int
+	moduleLogs              []*pbsubstreamsrpc.ModuleLogs // when the request streams module logs
+	moduleErrors            []*pbsubstreamsrpc.Error      // when the request returns errors as data
 
 	respFunc         substreams.ResponseFunc
 	lastProgressSent time.Time
@@ -62,8 +66,12 @@ type Pipeline struct {
 
 	processingModule *processingModule
 
+	moduleInitialBlocks  map[string]uint64 // modules are not executed before their initial block
+	pendingInitialValues []string          // stores seeded with their initial values on their initial block
+
 	gate            *gate
 	finalBlocksOnly bool
+	startAck        *startAck // set when the request asks for the stream start to be acknowledged
 	highestStage    *int
 
 	forkHandler     *ForkHandler
@@ -104,6 +112,7 @@ func New(
 		traceID:         traceID,
 		startTime:       time.Now(),
 	}
+	pipe.forkHandler.reorgGraceWindow = runtimeConfig.ReorgGraceWindow
 	for _, opt := range opts {
 		opt(pipe)
 	}
@@ -116,6 +125,9 @@ func (p *Pipeline) init(ctx context.Context) (err error) {
 	p.forkHandler.registerUndoHandler(func(clock *pbsubstreams.Clock, moduleOutputs []*pbssinternal.ModuleOutput) {
 		for _, modOut := range moduleOutputs {
 			p.stores.storesHandleUndo(modOut)
+			if err := p.stores.publishUndo(ctx, clock, modOut); err != nil {
+				reqctx.Logger(ctx).Warn("publishing undone store deltas", zap.String("store", modOut.ModuleName), zap.Error(err))
+			}
 		}
 	})
 
@@ -131,6 +143,7 @@ func (p *Pipeline) init(ctx context.Context) (err error) {
 		stagedModules = stagedModules[0 : *highest+1]
 	}
 	p.executionStages = stagedModules
+	p.moduleInitialBlocks = moduleInitialBlocks(stagedModules)
 
 	return nil
 }
@@ -146,6 +159,7 @@ func (p *Pipeline) InitTier2Stores(ctx context.Context) (err error) {
 	}
 
 	p.stores.SetStoreMap(storeMap)
+	p.awaitInitialValues(reqctx.Details(ctx).ResolvedStartBlockNum)
 
 	logger := reqctx.Logger(ctx)
 	logger.Info("stores loaded", zap.Object("stores", p.stores.StoreMap), zap.Int("stage", reqctx.Details(ctx).Tier2Stage))
@@ -158,16 +172,30 @@ func (p *Pipeline) InitTier1StoresAndBackprocess(ctx context.Context, reqPlan *p
 		return err
 	}
 
+	if p.startAck != nil {
+		if err := p.sendStartAcknowledgement(ctx); err != nil {
+			return fmt.Errorf("sending stream start acknowledgement: %w", err)
+		}
+	}
+
 	if reqPlan.RequiresParallelProcessing() {
 		storeMap, err := p.runParallelProcess(ctx, reqPlan)
 		if err != nil {
 			return fmt.Errorf("run_parallel_process failed: %w", err)
 		}
 		p.stores.SetStoreMap(storeMap) // this is valid even if we don't have stores in the parallelProcessing but only a mapper
+		if reqPlan.LinearPipeline != nil {
+			p.awaitInitialValues(reqPlan.LinearPipeline.StartBlock)
+		}
 		return nil
 	}
 
-	p.stores.SetStoreMap(p.setupEmptyStores(ctx))
+	storeMap, err := p.setupEmptyStores(ctx)
+	if err != nil {
+		return fmt.Errorf("stores setup failed: %w", err)
+	}
+	p.stores.SetStoreMap(storeMap)
+	p.awaitInitialValues(reqctx.Details(ctx).ResolvedStartBlockNum)
 	return nil
 }
 
@@ -230,14 +258,26 @@ func (p *Pipeline) setupSubrequestStores(ctx context.Context) (storeMap store.Ma
 	return storeMap, nil
 }
 
-func (p *Pipeline) setupEmptyStores(ctx context.Context) store.Map {
+// setupEmptyStores creates the stores of the execution stages only: stores of the
+// manifest that the output module does not depend on are neither created nor executed.
+func (p *Pipeline) setupEmptyStores(ctx context.Context) (store.Map, error) {
 	logger := reqctx.Logger(ctx)
 	storeMap := store.NewMap()
-	for _, storeConfig := range p.stores.configs {
-		fullStore := storeConfig.NewFullKV(logger)
-		storeMap.Set(fullStore)
+	for _, stage := range p.executionStages {
+		for _, layer := range stage {
+			if !layer.IsStoreLayer() {
+				continue
+			}
+			for _, mod := range layer {
+				storeConfig, found := p.stores.configs[mod.Name]
+				if !found {
+					return nil, fmt.Errorf("store %q: missing config", mod.Name)
+				}
+				storeMap.Set(storeConfig.NewFullKV(logger))
+			}
+		}
 	}
-	return storeMap
+	return storeMap, nil
 }
 
 // runParallelProcess
@@ -268,27 +308,9 @@ func (p *Pipeline) runParallelProcess(ctx context.Context, reqPlan *plan.Request
 		return nil, fmt.Errorf("building parallel processor: %w", err)
 	}
 
-	stats := reqctx.ReqStats(ctx)
 	progressCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	go func() {
-		stream := response.New(p.respFunc)
-
-		meter := dmetering.GetBytesMeter(ctx)
-		for {
-			select {
-			case <-time.After(time.Millisecond * 500):
-				stagesProgress := stats.Stages()
-				jobs := stats.JobsStats()
-				modStats := stats.AggregatedModulesStats()
-				remoteBytesRead, remoteBytesWritten := stats.RemoteBytesConsumption()
-
-				stream.SendModulesStats(modStats, stagesProgress, jobs, meter.BytesRead()+remoteBytesRead, meter.BytesWritten()+remoteBytesWritten)
-			case <-progressCtx.Done():
-				return
-			}
-		}
-	}()
+	go p.sendProgressHeartbeats(progressCtx, reqDetails.ProgressHeartbeatInterval)
 
 	logger.Info("starting parallel processing")
 
@@ -301,6 +323,37 @@ func (p *Pipeline) runParallelProcess(ctx context.Context, reqPlan *plan.Request
 	return storeMap, nil
 }
 
+const (
+	DefaultProgressHeartbeatInterval = 500 * time.Millisecond
+	MinProgressHeartbeatInterval     = 100 * time.Millisecond
+)
+
+// sendProgressHeartbeats periodically sends the progress of the backprocessing until `ctx` is done,
+// even when no data is produced. The stages' completed ranges carry the latest processed block
+// of each stage, and the modules stats their processed block counts.
+func (p *Pipeline) sendProgressHeartbeats(ctx context.Context, interval time.Duration) {
+	if interval == 0 {
+		interval = DefaultProgressHeartbeatInterval
+	}
+
+	stats := reqctx.ReqStats(ctx)
+	stream := response.New(p.respFunc)
+	meter := dmetering.GetBytesMeter(ctx)
+	for {
+		select {
+		case <-time.After(interval):
+			stagesProgress := stats.Stages()
+			jobs := stats.JobsStats()
+			modStats := stats.AggregatedModulesStats()
+			remoteBytesRead, remoteBytesWritten := stats.RemoteBytesConsumption()
+
+			stream.SendModulesStats(modStats, stagesProgress, jobs, meter.BytesRead()+remoteBytesRead, meter.BytesWritten()+remoteBytesWritten)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (p *Pipeline) isOutputModule(name string) bool {
 	return p.outputGraph.IsOutputModule(name)
 }
@@ -544,22 +597,28 @@ func (p *Pipeline) cleanUpModuleExecutors(ctx context.Context) error {
 	return nil
 }
 
+// blockScopedData holds the outputs of the block being processed.
+func (p *Pipeline) blockScopedData() *pbsubstreamsrpc.BlockScopedData {
+	return &pbsubstreamsrpc.BlockScopedData{
+		Output:            p.mapModuleOutput,
+		DebugMapOutputs:   p.extraMapModuleOutputs,
+		DebugStoreOutputs: p.extraStoreModuleOutputs,
+		DebugModuleLogs:   p.moduleLogs,
+		ModuleErrors:      p.moduleErrors,
+	}
+}
+
+// returnModuleDataOutputs sends `out`, holding the outputs of the block, once its clock
+// and cursor are set.
 func returnModuleDataOutputs(
 	clock *pbsubstreams.Clock,
 	cursor *bstream.Cursor,
-	mapModuleOutput *pbsubstreamsrpc.MapModuleOutput,
-	extraMapModuleOutputs []*pbsubstreamsrpc.MapModuleOutput,
-	extraStoreModuleOutputs []*pbsubstreamsrpc.StoreModuleOutput,
+	out *pbsubstreamsrpc.BlockScopedData,
 	respFunc substreams.ResponseFunc,
 ) error {
-	out := &pbsubstreamsrpc.BlockScopedData{
-		Clock:             clock,
-		Output:            mapModuleOutput,
-		DebugMapOutputs:   extraMapModuleOutputs,
-		DebugStoreOutputs: extraStoreModuleOutputs,
-		Cursor:            cursor.ToOpaque(),
-		FinalBlockHeight:  cursor.LIB.Num(),
-	}
+	out.Clock = clock
+	out.Cursor = cursor.ToOpaque()
+	out.FinalBlockHeight = cursor.LIB.Num()
 
 	if err := respFunc(substreams.NewBlockScopedDataResponse(out)); err != nil {
 		return fmt.Errorf("calling return func: %w", err)
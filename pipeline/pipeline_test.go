@@ -2,9 +2,13 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dstore"
 	"github.com/stretchr/testify/assert"
@@ -12,16 +16,22 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 
+	"github.com/streamingfast/substreams"
 	"github.com/streamingfast/substreams/manifest"
 	"github.com/streamingfast/substreams/metrics"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	pbsubstreamstest "github.com/streamingfast/substreams/pb/sf/substreams/v1/test"
+	"github.com/streamingfast/substreams/pipeline/cache"
 	"github.com/streamingfast/substreams/pipeline/exec"
 	"github.com/streamingfast/substreams/pipeline/outputmodules"
 	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/service/config"
 	store2 "github.com/streamingfast/substreams/storage/store"
+	"github.com/streamingfast/substreams/storage/store/deltaformat"
 	"github.com/streamingfast/substreams/wasm"
 
 	//_ "github.com/streamingfast/substreams/wasm/wasmtime"
@@ -72,6 +82,192 @@ func TestPipeline_runExecutor(t *testing.T) {
 	}
 }
 
+func TestPipeline_debugIntermediateOutputs(t *testing.T) {
+	for _, blockNum := range []uint64{10, 11} {
+		ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{
+			ProductionMode:                  true,
+			DebugIntermediateOutputs:        true,
+			DebugIntermediateOutputsAtBlock: 10,
+		})
+		ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+		pipe := &Pipeline{
+			forkHandler: NewForkHandler(),
+			outputGraph: outputmodules.TestNew(),
+		}
+		block := &pbsubstreamstest.Block{Id: fmt.Sprintf("block-%d", blockNum), Number: blockNum}
+		clock := &pbsubstreams.Clock{Id: block.Id, Number: block.Number}
+		execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), clock)
+
+		// test_map is not the output module, so its output is only kept on the debugged block
+		executor := mapTestExecutor(t, ctx, "test_map")
+		res := pipe.execute(ctx, executor, execOutput)
+		require.NoError(t, pipe.applyExecutionResult(ctx, executor, res, execOutput))
+
+		if blockNum == 10 {
+			require.Len(t, pipe.extraMapModuleOutputs, 1)
+			assert.Equal(t, "test_map", pipe.extraMapModuleOutputs[0].Name)
+		} else {
+			assert.Len(t, pipe.extraMapModuleOutputs, 0)
+		}
+	}
+}
+
+func TestPipeline_streamModuleLogs(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{
+				ProductionMode:   true,
+				StreamModuleLogs: enabled,
+			})
+			ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+			pipe := &Pipeline{
+				forkHandler: NewForkHandler(),
+				outputGraph: outputmodules.TestNew(),
+			}
+			block := &pbsubstreamstest.Block{Id: "block-10", Number: 10}
+			clock := &pbsubstreams.Clock{Id: block.Id, Number: block.Number}
+			execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), clock)
+
+			executor := mapTestExecutor(t, ctx, "test_map")
+			res := resultObj{
+				output: &pbssinternal.ModuleOutput{
+					ModuleName:         "test_map",
+					Data:               &pbssinternal.ModuleOutput_MapOutput{MapOutput: &anypb.Any{}},
+					Logs:               []string{"first", "second"},
+					DebugLogsTruncated: true,
+				},
+				bytes: []byte("output"),
+			}
+			require.NoError(t, pipe.applyExecutionResult(ctx, executor, res, execOutput))
+
+			var sent *pbsubstreamsrpc.BlockScopedData
+			respFunc := func(resp substreams.ResponseFromAnyTier) error {
+				sent = resp.(*pbsubstreamsrpc.Response).GetBlockScopedData()
+				return nil
+			}
+			cursor := &bstream.Cursor{Step: bstream.StepNew, Block: bstream.NewBlockRef(block.Id, block.Number), LIB: bstream.NewBlockRef(block.Id, block.Number), HeadBlock: bstream.NewBlockRef(block.Id, block.Number)}
+			require.NoError(t, returnModuleDataOutputs(clock, cursor, pipe.blockScopedData(), respFunc))
+			require.NotNil(t, sent)
+
+			logs := sent.DebugModuleLogs
+			if !enabled {
+				assert.Len(t, logs, 0)
+				return
+			}
+			require.Len(t, logs, 1)
+			assert.Equal(t, "test_map", logs[0].ModuleName)
+			assert.Equal(t, []string{"first", "second"}, logs[0].Logs)
+			assert.True(t, logs[0].LogsTruncated)
+			assert.Empty(t, sent.AllModuleOutputs(), "module logs are not module outputs")
+		})
+	}
+}
+
+func Test_executeLayer(t *testing.T) {
+	var lock sync.Mutex
+	done := map[string]bool{}
+	started := make(chan string, 2)
+
+	// "a" and "b" are independent, each one waits for the other to start
+	independent := func(name string) resultObj {
+		started <- name
+		select {
+		case <-time.After(time.Second):
+			return resultObj{err: fmt.Errorf("%s: modules did not run concurrently", name)}
+		case <-waitForCount(started, 2):
+		}
+		lock.Lock()
+		done[name] = true
+		lock.Unlock()
+		return resultObj{bytes: []byte(name)}
+	}
+	results := executeLayer([]string{"a", "b"}, 2, independent)
+	require.NoError(t, results[0].err)
+	require.NoError(t, results[1].err)
+	assert.Equal(t, "a", string(results[0].bytes))
+	assert.Equal(t, "b", string(results[1].bytes))
+
+	// "c" depends on "a" and "b", it is part of the next layer
+	results = executeLayer([]string{"c"}, 2, func(name string) resultObj {
+		lock.Lock()
+		defer lock.Unlock()
+		assert.True(t, done["a"] && done["b"], "dependent module executed before its dependencies")
+		return resultObj{}
+	})
+	require.Len(t, results, 1)
+
+	var running, maxRunning int
+	executeLayer([]string{"a", "b", "c"}, 1, func(name string) resultObj {
+		lock.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		lock.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return resultObj{}
+	})
+	assert.Equal(t, 1, maxRunning)
+}
+
+// waitForCount returns a channel closed once `ch` holds `count` values, without consuming them.
+func waitForCount(ch chan string, count int) <-chan struct{} {
+	out := make(chan struct{})
+	go func() {
+		for len(ch) < count {
+			time.Sleep(time.Millisecond)
+		}
+		close(out)
+	}()
+	return out
+}
+
+func TestPipeline_sendProgressHeartbeats(t *testing.T) {
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+	ctx, cancel := context.WithCancel(reqctx.WithReqStats(context.Background(), stats))
+	defer cancel()
+
+	var lock sync.Mutex
+	var processedUpTo []uint64
+	pipe := &Pipeline{
+		respFunc: func(resp substreams.ResponseFromAnyTier) error {
+			progress := resp.(*pbsubstreamsrpc.Response).GetProgress()
+			require.NotNil(t, progress)
+			ranges := progress.Stages[0].CompletedRanges
+
+			lock.Lock()
+			defer lock.Unlock()
+			processedUpTo = append(processedUpTo, ranges[len(ranges)-1].EndBlock)
+			return nil
+		},
+	}
+
+	// backprocessing advances without producing any data
+	completedUpTo := func(end uint64) {
+		stats.RecordStages([]*pbsubstreamsrpc.Stage{{
+			Modules:         []string{"store"},
+			CompletedRanges: []*pbsubstreamsrpc.BlockRange{{StartBlock: 0, EndBlock: end}},
+		}})
+	}
+	completedUpTo(100)
+	go pipe.sendProgressHeartbeats(ctx, 10*time.Millisecond)
+
+	time.Sleep(35 * time.Millisecond)
+	completedUpTo(200)
+	time.Sleep(35 * time.Millisecond)
+	cancel()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.GreaterOrEqual(t, len(processedUpTo), 4)
+	assert.Equal(t, uint64(100), processedUpTo[0])
+	assert.Equal(t, uint64(200), processedUpTo[len(processedUpTo)-1])
+	for i := 1; i < len(processedUpTo); i++ {
+		assert.GreaterOrEqual(t, processedUpTo[i], processedUpTo[i-1])
+	}
+}
+
 func mapTestExecutor(t *testing.T, ctx context.Context, name string) *exec.MapperModuleExecutor {
 	pkg := manifest.TestReadManifest(t, "../test/testdata/substreams-test-v0.1.0.spkg")
 
@@ -178,6 +374,55 @@ func TestSetupSubrequestStores(t *testing.T) {
 	//})
 }
 
+func TestPipeline_prunesUnrelatedModules(t *testing.T) {
+	mapKind := &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}
+	storeKind := &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{}}
+	mapInput := func(name string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: name}}}
+	}
+	storeInput := func(name string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Store_{Store: &pbsubstreams.Module_Input_Store{ModuleName: name}}}
+	}
+	modules := &pbsubstreams.Modules{
+		Binaries: []*pbsubstreams.Binary{{}},
+		Modules: []*pbsubstreams.Module{
+			{Name: "map_a", Kind: mapKind},
+			{Name: "store_b", Kind: storeKind, Inputs: []*pbsubstreams.Module_Input{mapInput("map_a")}},
+			{Name: "map_c", Kind: mapKind, Inputs: []*pbsubstreams.Module_Input{storeInput("store_b"), mapInput("map_a")}},
+			// unrelated to the output module
+			{Name: "map_x", Kind: mapKind},
+			{Name: "store_y", Kind: storeKind, Inputs: []*pbsubstreams.Module_Input{mapInput("map_x")}},
+		},
+	}
+
+	graph, err := outputmodules.NewOutputModuleGraph("map_c", true, modules)
+	require.NoError(t, err)
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{Modules: modules, OutputModule: "map_c"})
+	p := Pipeline{
+		outputGraph: graph,
+		forkHandler: NewForkHandler(),
+		// configs of all the manifest's stores
+		stores: &Stores{configs: testConfigMap(t, []testStoreConfig{{name: "store_b"}, {name: "store_y"}})},
+	}
+	require.NoError(t, p.init(ctx))
+
+	var executed []string
+	for _, stage := range p.executionStages {
+		for _, layer := range stage {
+			for _, mod := range layer {
+				executed = append(executed, mod.Name)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"map_a", "store_b", "map_c"}, executed)
+
+	storeMap, err := p.setupEmptyStores(ctx)
+	require.NoError(t, err)
+	assert.Len(t, storeMap, 1)
+	assert.Contains(t, storeMap, "store_b")
+}
+
 func testConfigMap(t *testing.T, configs []testStoreConfig) store2.ConfigMap {
 	t.Helper()
 	confMap := make(store2.ConfigMap)
@@ -213,6 +458,7 @@ func withTestRequest(t *testing.T, outputModule string, startBlock uint64) conte
 			OutputModule:  outputModule,
 			StartBlockNum: int64(startBlock),
 		},
+		true,
 		func() (uint64, error) { return 0, nil },
 		newTestCursorResolver().resolveCursor,
 		func() (uint64, error) { return 0, nil },
@@ -260,3 +506,161 @@ func (cr *testCursorResolver) resolveCursor(_ context.Context, cursor *bstream.C
 	}
 	return resp.lastValid, resp.currentHead, resp.err
 }
+
+func TestPipeline_storeOutputModes(t *testing.T) {
+	modes, err := ParseStoreOutputModes("store_a=deltas, store_b=snapshot")
+	require.NoError(t, err)
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{
+		StoreOutputModes: modes,
+	})
+
+	confMap := testConfigMap(t, []testStoreConfig{{name: "store_a"}, {name: "store_b"}})
+	storeMap := store2.NewMap()
+	for _, name := range []string{"store_a", "store_b"} {
+		kv := confMap[name].NewFullKV(zap.NewNop())
+		kv.Set(0, "existing", "old")
+		kv.Set(1, "new", "value")
+		storeMap.Set(kv)
+	}
+
+	pipe := &Pipeline{
+		outputGraph: outputmodules.TestNew(),
+		stores:      &Stores{StoreMap: storeMap},
+	}
+
+	save := func(name string) error {
+		return pipe.saveModuleOutput(ctx, &pbssinternal.ModuleOutput{
+			ModuleName: name,
+			Data: &pbssinternal.ModuleOutput_StoreDeltas{StoreDeltas: &pbssinternal.StoreDeltas{
+				StoreDeltas: []*pbssinternal.StoreDelta{
+					{Operation: pbssinternal.StoreDelta_CREATE, Ordinal: 1, Key: "new", NewValue: []byte("value")},
+				},
+			}},
+		}, name, false, false)
+	}
+	require.NoError(t, save("store_a"))
+	require.NoError(t, save("store_b"))
+
+	require.Len(t, pipe.extraStoreModuleOutputs, 2)
+
+	deltas := pipe.extraStoreModuleOutputs[0]
+	assert.Equal(t, "store_a", deltas.Name)
+	require.Len(t, deltas.DebugStoreDeltas, 1)
+	assert.Equal(t, "new", deltas.DebugStoreDeltas[0].Key)
+
+	snapshot := pipe.extraStoreModuleOutputs[1]
+	assert.Equal(t, "store_b", snapshot.Name)
+	require.Len(t, snapshot.DebugStoreDeltas, 2)
+	assert.Equal(t, "existing", snapshot.DebugStoreDeltas[0].Key)
+	assert.Equal(t, []byte("old"), snapshot.DebugStoreDeltas[0].NewValue)
+	assert.Equal(t, "new", snapshot.DebugStoreDeltas[1].Key)
+
+	// stores over their scan limit are not copied whole on every block
+	confMap["store_b"].SetScanLimit(1)
+	assert.ErrorContains(t, save("store_b"), `store "store_b" holds more than the 1 keys a snapshot output can return`)
+}
+
+func TestPipeline_storeDeltaFormat(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{
+		StoreDeltaFormat: deltaformat.JSON,
+	})
+	pipe := &Pipeline{
+		outputGraph: outputmodules.TestNew(),
+		stores:      &Stores{configs: testConfigMap(t, []testStoreConfig{{name: "store_a"}})},
+	}
+
+	save := func(name string) error {
+		return pipe.saveModuleOutput(ctx, &pbssinternal.ModuleOutput{
+			ModuleName: name,
+			Data: &pbssinternal.ModuleOutput_StoreDeltas{StoreDeltas: &pbssinternal.StoreDeltas{
+				StoreDeltas: []*pbssinternal.StoreDelta{
+					{Operation: pbssinternal.StoreDelta_CREATE, Ordinal: 1, Key: "new", NewValue: []byte("value")},
+				},
+			}},
+		}, name, false, false)
+	}
+	require.NoError(t, save("store_a"))
+
+	require.Len(t, pipe.extraStoreModuleOutputs, 1)
+	output := pipe.extraStoreModuleOutputs[0]
+	assert.Nil(t, output.DebugStoreDeltas)
+	require.NotNil(t, output.DebugEncodedStoreDeltas)
+	assert.Equal(t, deltaformat.JSON, output.DebugEncodedStoreDeltas.Format)
+	deltas, err := deltaformat.Decode(deltaformat.JSON, "string", output.DebugEncodedStoreDeltas.Data)
+	require.NoError(t, err)
+	require.Len(t, deltas, 1)
+	assert.Equal(t, "new", deltas[0].Key)
+
+	// the deltas cannot be formatted without the store's value type
+	assert.ErrorContains(t, save("unknown"), `store "unknown" not found`)
+}
+
+func TestParseStoreOutputModes(t *testing.T) {
+	_, err := ParseStoreOutputModes("store_a=full")
+	assert.Error(t, err)
+	_, err = ParseStoreOutputModes("store_a")
+	assert.Error(t, err)
+	_, err = ParseStoreOutputModes("store_a=deltas,store_a=snapshot")
+	assert.Error(t, err)
+}
+
+func TestPipeline_freezesStoresAfterLastBlock(t *testing.T) {
+	confMap := testConfigMap(t, []testStoreConfig{
+		{name: "mod1", initBlock: 0, writtenUpTo: 0},
+	})
+	fullKV := confMap["mod1"].NewFullKV(zap.NewNop())
+	storeMap := store2.NewMap()
+	storeMap.Set(fullKV)
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{StopBlockNum: 20})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+	engine, err := cache.NewEngine(ctx, config.RuntimeConfig{}, nil, "sf.substreams.v1.test.Block")
+	require.NoError(t, err)
+	p := &Pipeline{
+		runtimeConfig:   config.RuntimeConfig{StateBundleSize: 10},
+		stores:          &Stores{configs: confMap, StoreMap: storeMap},
+		forkHandler:     NewForkHandler(),
+		gate:            newGate(ctx),
+		outputGraph:     outputmodules.TestNew(),
+		execOutputCache: engine,
+		moduleExecutors: [][]exec.ModuleExecutor{},
+	}
+
+	fullKV.Set(0, "before", "value")
+
+	block := &pbsubstreamstest.Block{Id: "19a", Number: 19}
+	require.NoError(t, p.handleStepNew(ctx, bstreamBlk(t, block), &pbsubstreams.Clock{Id: "19a", Number: 19}, nil))
+
+	assert.PanicsWithValue(t, `store "mod1" is frozen, write rejected: block 19, the last one before the stop block 20, was processed`, func() {
+		fullKV.Set(1, "after", "value")
+	})
+	assert.ErrorContains(t, fullKV.Append(1, "before", []byte("more")), "is frozen")
+	_, found := fullKV.GetLast("after")
+	assert.False(t, found)
+}
+
+func TestPipeline_recordsModuleOutputSize(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+	pipe := &Pipeline{
+		forkHandler: NewForkHandler(),
+		outputGraph: outputmodules.TestNew(),
+	}
+	histogram := metrics.ModuleOutputMebibytes.Native().WithLabelValues("test_map").(prometheus.Histogram)
+	before := &dto.Metric{}
+	require.NoError(t, histogram.Write(before))
+
+	block := &pbsubstreamstest.Block{Id: "block-10", Number: 10}
+	execOutput := NewExecOutputTesting(t, bstreamBlk(t, block), &pbsubstreams.Clock{Id: block.Id, Number: block.Number})
+	executor := mapTestExecutor(t, ctx, "test_map")
+	require.NoError(t, pipe.applyExecutionResult(ctx, executor, pipe.execute(ctx, executor, execOutput), execOutput))
+
+	output := execOutput.Values["test_map"]
+	require.NotEmpty(t, output)
+
+	metric := &dto.Metric{}
+	require.NoError(t, histogram.Write(metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount()-before.GetHistogram().GetSampleCount())
+	assert.InDelta(t, float64(len(output))/(1<<20), metric.GetHistogram().GetSampleSum()-before.GetHistogram().GetSampleSum(), 1e-12)
+}
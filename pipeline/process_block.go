@@ -10,6 +10,7 @@ import (
 
 	"github.com/streamingfast/bstream"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/streamingfast/dmetering"
@@ -20,6 +21,7 @@ import (
 	"github.com/streamingfast/substreams/pipeline/exec"
 	"github.com/streamingfast/substreams/reqctx"
 	"github.com/streamingfast/substreams/storage/execout"
+	"github.com/streamingfast/substreams/storage/store/deltaformat"
 )
 
 func (p *Pipeline) ProcessBlock(block *bstream.Block, obj interface{}) (err error) {
@@ -187,6 +189,7 @@ func (p *Pipeline) handleStepFinal(clock *pbsubstreams.Clock) error {
 
 func (p *Pipeline) handleStepNew(ctx context.Context, block *bstream.Block, clock *pbsubstreams.Clock, cursor *bstream.Cursor) (err error) {
 	p.insideReorgUpTo = nil
+	p.forkHandler.trackHead(clock.Number)
 	reqDetails := reqctx.Details(ctx)
 
 	if p.respFunc != nil {
@@ -204,7 +207,7 @@ func (p *Pipeline) handleStepNew(ctx context.Context, block *bstream.Block, cloc
 		}()
 	}
 
-	if isBlockOverStopBlock(clock.Number, reqDetails.StopBlockNum) {
+	if reqDetails.IsBlockOverStopBlock(clock.Number) {
 		return io.EOF
 	}
 
@@ -219,6 +222,12 @@ func (p *Pipeline) handleStepNew(ctx context.Context, block *bstream.Block, cloc
 		if err := p.stores.flushStores(ctx, p.executionStages, clock.Number); err != nil {
 			return fmt.Errorf("step new irr: stores end of stream: %w", err)
 		}
+	} else if reqDetails.StoreSnapshotSaveInterval != 0 && !reqDetails.DisableStoreSnapshots {
+		// the stores hold the blocks before this one, they are final up to the LIB included
+		reversible := cursor == nil || clock.Number > cursor.LIB.Num()+1
+		if err := p.stores.saveStoresAtBoundaries(ctx, clock.Number, reversible); err != nil {
+			return fmt.Errorf("step new: saving stores: %w", err)
+		}
 	}
 
 	// note: if we start on a forked cursor, the undo signal will appear BEFORE we send the snapshot
@@ -238,10 +247,21 @@ func (p *Pipeline) handleStepNew(ctx context.Context, block *bstream.Block, cloc
 		return fmt.Errorf("pre block hook: %w", err)
 	}
 
-	if err := p.executeModules(ctx, execOutput); err != nil {
+	execCtx, executed := blockExecutionContext(ctx, p.runtimeConfig.DisconnectDrainTimeout)
+	err = p.executeModules(execCtx, execOutput)
+	executed()
+	if err != nil {
 		return fmt.Errorf("execute modules: %w", err)
 	}
 
+	if err := p.stores.checkMemoryBudget(); err != nil {
+		return err
+	}
+
+	if err := p.stores.publishDeltas(ctx, clock); err != nil {
+		return fmt.Errorf("publishing store deltas: %w", err)
+	}
+
 	if p.gate.shouldSendOutputs() {
 		logger.Debug("will return module outputs")
 		if p.pendingUndoMessage != nil {
@@ -250,12 +270,21 @@ func (p *Pipeline) handleStepNew(ctx context.Context, block *bstream.Block, cloc
 			}
 		}
 		p.pendingUndoMessage = nil
-		if err = returnModuleDataOutputs(clock, cursor, p.mapModuleOutput, p.extraMapModuleOutputs, p.extraStoreModuleOutputs, p.respFunc); err != nil {
+		out := p.blockScopedData()
+		out.LiveTransition = p.gate.shouldMarkLiveTransition(clock.Number)
+		if err = returnModuleDataOutputs(clock, cursor, out, p.respFunc); err != nil {
 			return fmt.Errorf("failed to return module data output: %w", err)
 		}
 	}
 
+	if err := p.stores.commitStores(); err != nil {
+		return fmt.Errorf("committing stores: %w", err)
+	}
 	p.stores.resetStores()
+	if reqDetails.IsBlockOverStopBlock(clock.Number + 1) {
+		// the last block of the range is processed, a write to a store from now on is a bug
+		p.stores.freezeStores(fmt.Sprintf("block %d, the last one before the stop block %d, was processed", clock.Number, reqDetails.StopBlockNum))
+	}
 	logger.Debug("block processed", zap.Uint64("block_num", block.Number))
 	return nil
 }
@@ -267,39 +296,54 @@ func (p *Pipeline) executeModules(ctx context.Context, execOutput execout.Execut
 	p.mapModuleOutput = nil
 	p.extraMapModuleOutputs = nil
 	p.extraStoreModuleOutputs = nil
+	p.extraModuleOutputsBytes = 0
+	p.moduleLogs = nil
+	p.moduleErrors = nil
 	moduleExecutors, err := p.buildModuleExecutors(ctx)
 	if err != nil {
 		return fmt.Errorf("building wasm module tree: %w", err)
 	}
+
+	reqDetails := reqctx.Details(ctx)
+	var asData *errorsAsData
+	if reqDetails.ErrorsAsData && !reqDetails.IsTier2Request {
+		if p.errorsAsData == nil {
+			p.errorsAsData = newErrorsAsData(p.executionStages)
+		}
+		asData = p.errorsAsData
+		asData.startBlock(execOutput.Clock().Number)
+	}
+
+	p.seedInitialValues(execOutput.Clock().Number)
+
 	for _, stage := range moduleExecutors {
 		//t0 := time.Now()
+		stage = filterStarted(p.moduleInitialBlocks, stage, execOutput.Clock().Number)
+		if asData != nil {
+			stage = filterLayer(asData, stage)
+		}
 
 		if len(stage) < 2 {
 			//fmt.Println("Linear stage", len(stage))
 			for _, executor := range stage {
 				res := p.execute(ctx, executor, execOutput)
+				if p.reportModuleError(asData, executor, res) {
+					continue
+				}
 				if err := p.applyExecutionResult(ctx, executor, res, execOutput); err != nil {
 					return fmt.Errorf("applying executor results %q: %w", executor.Name(), res.err)
 				}
 			}
 		} else {
-			results := make([]resultObj, len(stage))
-			wg := sync.WaitGroup{}
-			//fmt.Println("Parallelized in stage", stageIdx, len(stage))
-			for i, executor := range stage {
-				wg.Add(1)
-				i := i
-				executor := executor
-				go func() {
-					defer wg.Done()
-					res := p.execute(ctx, executor, execOutput)
-					results[i] = res
-				}()
-			}
-			wg.Wait()
+			results := executeLayer(stage, p.runtimeConfig.ModuleExecutionConcurrency, func(executor exec.ModuleExecutor) resultObj {
+				return p.execute(ctx, executor, execOutput)
+			})
 
 			for i, result := range results {
 				executor := stage[i]
+				if p.reportModuleError(asData, executor, result) {
+					continue
+				}
 				if result.err != nil {
 					//p.returnFailureProgress(ctx, err, executor)
 					return fmt.Errorf("running executor %q: %w", executor.Name(), result.err)
@@ -315,6 +359,52 @@ func (p *Pipeline) executeModules(ctx context.Context, execOutput execout.Execut
 	return nil
 }
 
+// reportModuleError adds the error of `res` to the block's outputs and returns true
+// when errors are returned as data and the error is recoverable.
+func (p *Pipeline) reportModuleError(asData *errorsAsData, executor exec.ModuleExecutor, res resultObj) bool {
+	if asData == nil || res.err == nil {
+		return false
+	}
+	moduleError := asData.recover(executor.Name(), res)
+	if moduleError == nil {
+		return false
+	}
+	p.moduleErrors = append(p.moduleErrors, moduleError)
+	return true
+}
+
+// executeLayer runs `run` on every executor of a layer, which have no dependencies on
+// each other, with at most `concurrency` of them running at once (0 meaning no limit).
+// Results are returned in the layer's order, so they can be applied deterministically.
+func executeLayer[E any](layer []E, concurrency uint64, run func(E) resultObj) []resultObj {
+	results := make([]resultObj, len(layer))
+
+	var sem chan struct{}
+	if concurrency > 0 {
+		sem = make(chan struct{}, concurrency)
+	}
+
+	wg := sync.WaitGroup{}
+	for i, executor := range layer {
+		wg.Add(1)
+		i := i
+		executor := executor
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			results[i] = run(executor)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
 type resultObj struct {
 	output *pbssinternal.ModuleOutput
 	bytes  []byte
@@ -325,28 +415,59 @@ func (p *Pipeline) execute(ctx context.Context, executor exec.ModuleExecutor, ex
 	logger := reqctx.Logger(ctx)
 
 	executorName := executor.Name()
+	if err := checkDrained(ctx); err != nil {
+		return resultObj{err: err}
+	}
 	logger.Debug("executing", zap.Uint64("block", execOutput.Clock().Number), zap.String("module_name", executorName))
 
 	moduleOutput, outputBytes, runError := exec.RunModule(ctx, executor, execOutput)
+	if runError == nil && !moduleOutput.Cached && p.shouldCheckDeterminism(ctx, execOutput.Clock().Number) {
+		if _, err := exec.CheckDeterminism(ctx, executor, execOutput, outputBytes); err != nil {
+			logger.Warn("cannot check module output determinism", zap.String("module_name", executorName), zap.Error(err))
+		}
+	}
 	return resultObj{moduleOutput, outputBytes, runError}
 }
 
+// shouldCheckDeterminism tells if the modules executed on `blockNum` are part of the
+// sample executed twice to check their determinism, only done in development mode.
+func (p *Pipeline) shouldCheckDeterminism(ctx context.Context, blockNum uint64) bool {
+	interval := p.runtimeConfig.DeterminismCheckInterval
+	return interval != 0 && !reqctx.Details(ctx).ProductionMode && blockNum%interval == 0
+}
+
 func (p *Pipeline) applyExecutionResult(ctx context.Context, executor exec.ModuleExecutor, res resultObj, execOutput execout.ExecutionOutput) (err error) {
 	executorName := executor.Name()
 	hasValidOutput := executor.HasValidOutput()
 
+	reqDetails := reqctx.Details(ctx)
+	captureIntermediate := reqDetails.ShouldCaptureIntermediateOutputs(execOutput.Clock().Number)
+	isProduction := reqDetails.ProductionMode && !captureIntermediate
+
 	moduleOutput, outputBytes, runError := res.output, res.bytes, res.err
 	if runError != nil {
 		if hasValidOutput {
-			p.saveModuleOutput(moduleOutput, executor.Name(), reqctx.Details(ctx).ProductionMode)
+			// the execution error is the one reported
+			_ = p.saveModuleOutput(ctx, moduleOutput, executor.Name(), isProduction, captureIntermediate)
 		}
 		return fmt.Errorf("execute module: %w", runError)
 	}
 
+	if reqDetails.StreamModuleLogs && moduleOutput != nil && len(moduleOutput.Logs) != 0 {
+		p.moduleLogs = append(p.moduleLogs, &pbsubstreamsrpc.ModuleLogs{
+			ModuleName:    executorName,
+			Logs:          moduleOutput.Logs,
+			LogsTruncated: moduleOutput.DebugLogsTruncated,
+		})
+	}
+
 	if !hasValidOutput {
 		return nil
 	}
-	p.saveModuleOutput(moduleOutput, executor.Name(), reqctx.Details(ctx).ProductionMode)
+	if err := p.saveModuleOutput(ctx, moduleOutput, executor.Name(), isProduction, captureIntermediate); err != nil {
+		return err
+	}
+	metrics.ObserveModuleOutputSize(reqctx.Logger(ctx), executorName, execOutput.Clock().Number, len(outputBytes), p.runtimeConfig.ModuleOutputSizeWarningBytes)
 	if err := execOutput.Set(executorName, outputBytes); err != nil {
 		return fmt.Errorf("set output cache: %w", err)
 	}
@@ -356,20 +477,43 @@ func (p *Pipeline) applyExecutionResult(ctx context.Context, executor exec.Modul
 	return nil
 }
 
-func (p *Pipeline) saveModuleOutput(output *pbssinternal.ModuleOutput, moduleName string, isProduction bool) {
+// maxDebugIntermediateOutputsBytes caps the size of the intermediate outputs returned
+// for a single block when they are explicitly requested for debugging.
+const maxDebugIntermediateOutputsBytes = 10 * 1024 * 1024
+
+func (p *Pipeline) saveModuleOutput(ctx context.Context, output *pbssinternal.ModuleOutput, moduleName string, isProduction bool, capped bool) error {
 	if p.isOutputModule(moduleName) {
 		p.mapModuleOutput = toRPCMapModuleOutputs(output)
-		return
+		return nil
 	}
 	if isProduction {
-		return
+		return nil
+	}
+
+	if capped {
+		p.extraModuleOutputsBytes += proto.Size(output)
+		if p.extraModuleOutputsBytes > maxDebugIntermediateOutputsBytes {
+			reqctx.Logger(ctx).Info("skipping intermediate module output, size cap reached", zap.String("module_name", moduleName), zap.Int("cap_bytes", maxDebugIntermediateOutputsBytes))
+			return nil
+		}
 	}
 
 	if storeOutputs := toRPCStoreModuleOutputs(output); storeOutputs != nil {
+		if reqctx.Details(ctx).StoreOutputMode(moduleName) == reqctx.StoreOutputSnapshot {
+			if err := p.replaceWithStoreSnapshot(storeOutputs); err != nil {
+				return fmt.Errorf("snapshot output: %w", err)
+			}
+		}
+		if format := reqctx.Details(ctx).StoreDeltaFormat; format != deltaformat.Protobuf {
+			if err := p.formatStoreOutput(storeOutputs, format); err != nil {
+				return fmt.Errorf("formatting store output: %w", err)
+			}
+		}
 		p.extraStoreModuleOutputs = append(p.extraStoreModuleOutputs, storeOutputs)
 	}
 
 	if mapOutput := toRPCMapModuleOutputs(output); mapOutput != nil {
 		p.extraMapModuleOutputs = append(p.extraMapModuleOutputs, mapOutput)
 	}
+	return nil
 }
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/bstream/hub"
+	"github.com/streamingfast/bstream/stream"
 	"github.com/streamingfast/dstore"
 	"go.uber.org/zap"
 	grpccodes "google.golang.org/grpc/codes"
@@ -15,14 +18,96 @@ import (
 
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
 	"github.com/streamingfast/substreams/reqctx"
 )
 
 type getBlockFunc func() (uint64, error)
 
+// HeadBlockGetter resolves a block of the live source, the head or a recent final block,
+// waiting for the live source to be ready until `ctx` is done.
+type HeadBlockGetter func(ctx context.Context) (uint64, error)
+
+// WithHeadResolutionTimeout binds `getBlock` to `ctx`, giving the live source at most
+// `timeout` to be ready, as it is not while the live hub is starting up. Past it, an
+// `Unavailable` error is returned so the client can retry later. With a `timeout` of 0,
+// the error is returned right away when the live source is not ready.
+func WithHeadResolutionTimeout(ctx context.Context, getBlock HeadBlockGetter, timeout time.Duration) func() (uint64, error) {
+	return func() (uint64, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		blockNum, err := getBlock(ctx)
+		if err != nil && errors.Is(err, context.DeadlineExceeded) {
+			return 0, status.Errorf(grpccodes.Unavailable, "live block source not available after %s, please retry later", timeout)
+		}
+		return blockNum, err
+	}
+}
+
+// IsUnboundedRequest tells if `request` has no stop block. A StopBlockNum of 0 historically
+// means "unbounded" and is still interpreted that way, unless `explicitStopBlock` is set,
+// which takes precedence and makes the request bounded, even when StopBlockNum is 0.
+func IsUnboundedRequest(request *pbsubstreamsrpc.Request, explicitStopBlock bool) bool {
+	return !explicitStopBlock && request.StopBlockNum == 0
+}
+
+// ParseStoreOutputModes parses a comma-separated list of `<store>=<mode>` pairs, where
+// mode is either `deltas` or `snapshot`, as sent in `X-Sf-Substreams-Store-Output-Modes`.
+func ParseStoreOutputModes(in string) (map[string]reqctx.StoreOutputMode, error) {
+	out := make(map[string]reqctx.StoreOutputMode)
+	for _, entry := range strings.Split(in, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, mode, found := strings.Cut(entry, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid store output mode %q, expected <store>=<mode>", entry)
+		}
+		if _, exists := out[name]; exists {
+			return nil, fmt.Errorf("store output mode for %q specified more than once", name)
+		}
+		switch mode {
+		case "deltas":
+			out[name] = reqctx.StoreOutputDeltas
+		case "snapshot":
+			out[name] = reqctx.StoreOutputSnapshot
+		default:
+			return nil, fmt.Errorf("invalid store output mode %q for %q, expected 'deltas' or 'snapshot'", mode, name)
+		}
+	}
+	return out, nil
+}
+
+// ResolveUncachedModules returns the modules of `graph` whose outputs must not be cached:
+// the ones listed in `requested`, a comma-separated list of module names as sent in
+// `X-Sf-Substreams-Uncached-Modules`, unless `overrides` forces caching by module hash.
+func ResolveUncachedModules(requested string, graph *outputmodules.Graph, overrides map[string]bool) map[string]bool {
+	out := make(map[string]bool)
+	for _, name := range strings.Split(requested, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out[name] = true
+		}
+	}
+
+	for _, mod := range graph.UsedModules() {
+		if cached, found := overrides[graph.ModuleHashes().Get(mod.Name)]; found {
+			if cached {
+				delete(out, mod.Name)
+			} else {
+				out[mod.Name] = true
+			}
+		}
+	}
+	return out
+}
+
 func BuildRequestDetails(
 	ctx context.Context,
 	request *pbsubstreamsrpc.Request,
+	unbounded bool,
 	getRecentFinalBlock getBlockFunc,
 	resolveCursor CursorResolver,
 	getHeadBlock getBlockFunc) (req *reqctx.RequestDetails, undoSignal *pbsubstreamsrpc.BlockUndoSignal, err error) {
@@ -32,16 +117,28 @@ func BuildRequestDetails(
 		DebugInitialStoreSnapshotForModules: request.DebugInitialStoreSnapshotForModules,
 		ProductionMode:                      request.ProductionMode,
 		StopBlockNum:                        request.StopBlockNum,
+		Unbounded:                           unbounded,
 		UniqueID:                            nextUniqueID(),
 	}
 
-	req.ResolvedStartBlockNum, req.ResolvedCursor, undoSignal, err = resolveStartBlockNum(ctx, request, resolveCursor, getHeadBlock)
+	// cursors past the stop block are rejected when resolving them, one on the last block
+	// of the range is valid and resumes a completed stream
+	fromCursor := request.StartCursor != ""
+
+	req.ResolvedStartBlockNum, req.ResolvedCursor, undoSignal, err = resolveStartBlockNum(ctx, request, unbounded, resolveCursor, getHeadBlock)
 
 	if err != nil {
 		return nil, nil, err
 	}
+	if outputModule := findModule(request.Modules, request.OutputModule); outputModule != nil {
+		req.ResolvedStartBlockNum = outputmodules.EffectiveStartBlock(req.ResolvedStartBlockNum, outputModule.InitialBlock)
+	}
+	// an explicit stop block of 0 is an empty range, not an invalid one
+	if !fromCursor && !unbounded && request.StopBlockNum != 0 && request.StopBlockNum < req.ResolvedStartBlockNum {
+		return nil, nil, stream.NewErrInvalidArg("stop block %d is below the effective start block %d", request.StopBlockNum, req.ResolvedStartBlockNum)
+	}
 
-	linearHandoff, err := computeLiveHandoffBlockNum(request.ProductionMode, req.ResolvedStartBlockNum, request.StopBlockNum, getRecentFinalBlock)
+	linearHandoff, err := computeLiveHandoffBlockNum(request.ProductionMode, req.ResolvedStartBlockNum, request.StopBlockNum, unbounded, getRecentFinalBlock)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -66,22 +163,31 @@ func BuildRequestDetailsFromSubrequest(request *pbssinternal.ProcessRangeRequest
 	return req
 }
 
+func findModule(modules *pbsubstreams.Modules, name string) *pbsubstreams.Module {
+	for _, module := range modules.GetModules() {
+		if module.Name == name {
+			return module
+		}
+	}
+	return nil
+}
+
 var uniqueRequestIDCounter = &atomic.Uint64{}
 
 func nextUniqueID() uint64 {
 	return uniqueRequestIDCounter.Add(1)
 }
 
-func computeLiveHandoffBlockNum(productionMode bool, startBlock, stopBlock uint64, getRecentFinalBlockFunc func() (uint64, error)) (uint64, error) {
+func computeLiveHandoffBlockNum(productionMode bool, startBlock, stopBlock uint64, unbounded bool, getRecentFinalBlockFunc func() (uint64, error)) (uint64, error) {
 	if productionMode {
 		maxHandoff, err := getRecentFinalBlockFunc()
 		if err != nil {
-			if stopBlock == 0 {
+			if unbounded {
 				return 0, fmt.Errorf("cannot determine a recent finalized block: %w", err)
 			}
 			return stopBlock, nil
 		}
-		if stopBlock == 0 {
+		if unbounded {
 			return maxHandoff, nil
 		}
 		return min(stopBlock, maxHandoff), nil
@@ -94,7 +200,13 @@ func computeLiveHandoffBlockNum(productionMode bool, startBlock, stopBlock uint6
 }
 
 // resolveStartBlockNum will occasionally modify or remove the cursor inside the request
-func resolveStartBlockNum(ctx context.Context, req *pbsubstreamsrpc.Request, resolveCursor CursorResolver, getHeadBlock getBlockFunc) (uint64, string, *pbsubstreamsrpc.BlockUndoSignal, error) {
+//
+// Cursors on StepNew and StepNewIrreversible carry the data of their block, so the
+// stream resumes on the next block. A StepIrreversible cursor only signals that its
+// block became final: the data of that block and of the following ones, up to the
+// cursor's head, was already sent on StepNew. Those resume after the head instead,
+// unless the request is for final blocks only: nothing past the cursor's block was sent.
+func resolveStartBlockNum(ctx context.Context, req *pbsubstreamsrpc.Request, unbounded bool, resolveCursor CursorResolver, getHeadBlock getBlockFunc) (uint64, string, *pbsubstreamsrpc.BlockUndoSignal, error) {
 	// TODO(abourget): a caller will need to verify that, if there's a cursor.Step that is New or Undo,
 	// then we need to validate that we are returning not only a number, but an ID,
 	// We then need to sync from a known finalized Snapshot's block, down to the potentially
@@ -122,7 +234,17 @@ func resolveStartBlockNum(ctx context.Context, req *pbsubstreamsrpc.Request, res
 		return 0, "", nil, status.Errorf(grpccodes.InvalidArgument, "invalid StartCursor %q: %s", cursor, err.Error())
 	}
 
-	if req.StopBlockNum > 0 && req.StopBlockNum < cursor.Block.Num() {
+	if !req.FinalBlocksOnly && cursor.Step == bstream.StepIrreversible && cursor.HeadBlock != nil && cursor.HeadBlock.Num() > cursor.Block.Num() {
+		// handled as the StepNew cursor of the head, which may have been forked out since
+		cursor = &bstream.Cursor{
+			Step:      bstream.StepNew,
+			Block:     cursor.HeadBlock,
+			LIB:       cursor.Block,
+			HeadBlock: cursor.HeadBlock,
+		}
+	}
+
+	if !unbounded && req.StopBlockNum < cursor.Block.Num() {
 		return 0, "", nil, status.Errorf(grpccodes.InvalidArgument, "StartCursor %q is after StopBlockNum %d", cursor, req.StopBlockNum)
 	}
 
@@ -214,3 +336,51 @@ func NewCursorResolver(hub *hub.ForkableHub, mergedBlocksStore, forkedBlocksStor
 		return jctBlkGetter.reorgJunctionBlock, jctBlkGetter.currentHead, nil
 	}
 }
+
+// StartCursorGetter returns the cursor resuming a stream at `startBlockNum`, the cursor of
+// the block preceding it. There is none when the stream starts at the first streamable
+// block of the chain, the cursor is then empty.
+type StartCursorGetter func(ctx context.Context, startBlockNum uint64) (string, error)
+
+func NewStartCursorGetter(hub *hub.ForkableHub, mergedBlocksStore dstore.Store) StartCursorGetter {
+	return func(ctx context.Context, startBlockNum uint64) (string, error) {
+		if startBlockNum <= bstream.GetProtocolFirstStreamableBlock {
+			return "", nil
+		}
+		num := startBlockNum - 1
+
+		if hub != nil && hub.IsReady() {
+			if headNum, headID, _, libNum, err := hub.HeadInfo(); err == nil && num > libNum {
+				blk := hub.GetBlock(num, "")
+				lib := hub.GetBlock(libNum, "")
+				if blk == nil || lib == nil {
+					return "", fmt.Errorf("block %d not found in the live segment", num)
+				}
+				return (&bstream.Cursor{
+					Step:      bstream.StepNew,
+					Block:     blk.AsRef(),
+					LIB:       lib.AsRef(),
+					HeadBlock: bstream.NewBlockRef(headID, headNum),
+				}).ToOpaque(), nil
+			}
+			if blk := hub.GetBlock(num, ""); blk != nil {
+				return finalBlockCursor(blk.AsRef()), nil
+			}
+		}
+
+		blk, err := bstream.FetchBlockFromMergedBlocksStore(ctx, num, mergedBlocksStore)
+		if err != nil {
+			return "", fmt.Errorf("fetching block %d from merged blocks: %w", num, err)
+		}
+		return finalBlockCursor(blk.AsRef()), nil
+	}
+}
+
+func finalBlockCursor(ref bstream.BlockRef) string {
+	return (&bstream.Cursor{
+		Step:      bstream.StepIrreversible,
+		Block:     ref,
+		LIB:       ref,
+		HeadBlock: ref,
+	}).ToOpaque()
+}
@@ -3,14 +3,25 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 
 	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/bstream/stream"
+	"github.com/streamingfast/dgrpc"
+	"github.com/streamingfast/dstore"
 
+	"github.com/streamingfast/substreams/orchestrator/plan"
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
 )
 
 func Test_resolveStartBlockNum(t *testing.T) {
@@ -106,6 +117,69 @@ func Test_resolveStartBlockNum(t *testing.T) {
 			expectedBlockNum: 11,
 			wantErr:          false,
 		},
+		{
+			name: "step irreversible behind head", // blocks up to the head were already sent, they are not sent again
+			req: &pbsubstreamsrpc.Request{
+				StartBlockNum: 10,
+				StartCursor: (&bstream.Cursor{
+					Step:      bstream.StepIrreversible,
+					Block:     bstream.NewBlockRef("10a", 10),
+					LIB:       bstream.NewBlockRef("10a", 10),
+					HeadBlock: bstream.NewBlockRef("13a", 13),
+				}).ToOpaque(),
+			},
+			expectedBlockNum: 14,
+			wantErr:          false,
+			wantCursor:       "c1:1:13:13a:10:10a",
+		},
+		{
+			name: "step irreversible behind head, final blocks only", // only final blocks were sent, up to the cursor's block
+			req: &pbsubstreamsrpc.Request{
+				StartBlockNum:   10,
+				FinalBlocksOnly: true,
+				StartCursor: (&bstream.Cursor{
+					Step:      bstream.StepIrreversible,
+					Block:     bstream.NewBlockRef("10a", 10),
+					LIB:       bstream.NewBlockRef("10a", 10),
+					HeadBlock: bstream.NewBlockRef("13a", 13),
+				}).ToOpaque(),
+			},
+			expectedBlockNum: 11,
+			wantErr:          false,
+		},
+		{
+			name: "step irreversible behind forked head",
+			req: &pbsubstreamsrpc.Request{
+				StartBlockNum: 10,
+				StartCursor: (&bstream.Cursor{
+					Step:      bstream.StepIrreversible,
+					Block:     bstream.NewBlockRef("10a", 10),
+					LIB:       bstream.NewBlockRef("10a", 10),
+					HeadBlock: bstream.NewBlockRef("13a", 13),
+				}).ToOpaque(),
+			},
+			expectedBlockNum: 12,
+			wantErr:          false,
+			cursorResolverArgs: []interface{}{
+				"c1:1:13:13a:10:10a", bstream.NewBlockRef("11a", 11), bstream.NewBlockRef("14b", 14), nil,
+			},
+			wantUndoLastBlock: bstream.NewBlockRef("11a", 11),
+			wantCursor:        "c3:1:11:11a:14:14b:10:10a",
+		},
+		{
+			name: "step new irreversible behind head", // the data of the block itself was sent with the cursor
+			req: &pbsubstreamsrpc.Request{
+				StartBlockNum: 10,
+				StartCursor: (&bstream.Cursor{
+					Step:      bstream.StepNewIrreversible,
+					Block:     bstream.NewBlockRef("10a", 10),
+					LIB:       bstream.NewBlockRef("10a", 10),
+					HeadBlock: bstream.NewBlockRef("13a", 13),
+				}).ToOpaque(),
+			},
+			expectedBlockNum: 11,
+			wantErr:          false,
+		},
 		{
 			name: "step new irreversible",
 			req: &pbsubstreamsrpc.Request{
@@ -144,6 +218,7 @@ func Test_resolveStartBlockNum(t *testing.T) {
 			got, outCursor, undoSignal, err := resolveStartBlockNum(
 				context.Background(),
 				tt.req,
+				tt.req.StopBlockNum == 0,
 				newTestCursorResolver(tt.cursorResolverArgs...).resolveCursor,
 				func() (uint64, error) { return tt.headBlock, tt.headBlockErr },
 			)
@@ -204,6 +279,7 @@ func Test_computeLiveHandoffBlockNum(t *testing.T) {
 				test.prodMode,
 				test.startBlockNum,
 				test.stopBlockNum,
+				test.stopBlockNum == 0,
 				func() (uint64, error) {
 					if !test.liveHubAvailable {
 						return 0, fmt.Errorf("live not available")
@@ -220,6 +296,27 @@ func Test_computeLiveHandoffBlockNum(t *testing.T) {
 	}
 }
 
+func TestIsUnboundedRequest(t *testing.T) {
+	assert.True(t, IsUnboundedRequest(&pbsubstreamsrpc.Request{StopBlockNum: 0}, false), "legacy clients sending 0 mean unbounded")
+	assert.False(t, IsUnboundedRequest(&pbsubstreamsrpc.Request{StopBlockNum: 0}, true), "explicit stop block 0 is bounded")
+	assert.False(t, IsUnboundedRequest(&pbsubstreamsrpc.Request{StopBlockNum: 10}, false))
+	assert.False(t, IsUnboundedRequest(&pbsubstreamsrpc.Request{StopBlockNum: 10}, true))
+}
+
+func Test_computeLiveHandoffBlockNum_explicitStopBlockZero(t *testing.T) {
+	got, err := computeLiveHandoffBlockNum(true, 10, 0, false, func() (uint64, error) { return 100, nil })
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), got)
+
+	got, err = computeLiveHandoffBlockNum(true, 10, 0, false, func() (uint64, error) { return 0, fmt.Errorf("live not available") })
+	require.NoError(t, err, "bounded requests do not need a recent final block")
+	assert.Equal(t, uint64(0), got)
+
+	got, err = computeLiveHandoffBlockNum(true, 10, 0, true, func() (uint64, error) { return 100, nil })
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), got)
+}
+
 func TestBuildRequestDetails(t *testing.T) {
 	req, _, err := BuildRequestDetails(
 		context.Background(),
@@ -228,6 +325,7 @@ func TestBuildRequestDetails(t *testing.T) {
 			ProductionMode: false,
 			OutputModule:   "nomatch",
 		},
+		true,
 		func() (uint64, error) {
 			assert.True(t, true, "should pass here")
 			return 999, nil
@@ -249,6 +347,7 @@ func TestBuildRequestDetails(t *testing.T) {
 			ProductionMode: true,
 			OutputModule:   "",
 		},
+		true,
 		func() (uint64, error) {
 			return 999, nil
 		},
@@ -262,3 +361,161 @@ func TestBuildRequestDetails(t *testing.T) {
 	assert.Equal(t, 10, int(req.ResolvedStartBlockNum))
 	assert.Equal(t, 999, int(req.LinearHandoffBlockNum))
 }
+
+func TestBuildRequestDetails_StopBlockBelowStart(t *testing.T) {
+	build := func(startBlock int64, stopBlock uint64, unbounded bool) error {
+		_, _, err := BuildRequestDetails(
+			context.Background(),
+			&pbsubstreamsrpc.Request{StartBlockNum: startBlock, StopBlockNum: stopBlock},
+			unbounded,
+			func() (uint64, error) { return 999, nil },
+			newTestCursorResolver().resolveCursor,
+			func() (uint64, error) { return 1000, nil },
+		)
+		return err
+	}
+
+	var invalidArg *stream.ErrInvalidArg
+	err := build(20, 10, false)
+	require.ErrorAs(t, err, &invalidArg)
+	assert.EqualError(t, err, "stop block 10 is below the effective start block 20")
+
+	// relative start blocks are resolved against the head first
+	require.ErrorAs(t, build(-10, 100, false), &invalidArg)
+
+	assert.NoError(t, build(20, 0, true), "stop block 0 is unbounded")
+	assert.NoError(t, build(20, 0, false))
+	assert.NoError(t, build(20, 20, false))
+	assert.NoError(t, build(20, 30, false))
+}
+
+func TestBuildRequestDetails_SnapsStartBlockToModuleStart(t *testing.T) {
+	modules := &pbsubstreams.Modules{
+		Binaries: []*pbsubstreams.Binary{{Type: "wasm/rust-v1"}},
+		Modules: []*pbsubstreams.Module{{
+			Name:         "map_out",
+			InitialBlock: 100,
+			Kind:         &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:test"}},
+			Inputs:       []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.test.Block"}}}},
+			Output:       &pbsubstreams.Module_Output{Type: "proto:test"},
+		}},
+	}
+	graph, err := outputmodules.NewOutputModuleGraph("map_out", false, modules)
+	require.NoError(t, err)
+
+	build := func(startBlock int64) *reqctx.RequestDetails {
+		req, _, err := BuildRequestDetails(
+			context.Background(),
+			&pbsubstreamsrpc.Request{StartBlockNum: startBlock, OutputModule: "map_out", Modules: modules},
+			true,
+			func() (uint64, error) { return 999, nil },
+			newTestCursorResolver().resolveCursor,
+			func() (uint64, error) { return 999, nil },
+		)
+		require.NoError(t, err)
+		return req
+	}
+
+	req := build(10)
+	assert.Equal(t, uint64(100), req.ResolvedStartBlockNum)
+	assert.Equal(t, uint64(100), req.LinearHandoffBlockNum)
+	assert.Equal(t, req.ResolvedStartBlockNum, graph.EffectiveStartBlock(10))
+	assert.NoError(t, graph.ValidateEffectiveStartBlock(req.ResolvedStartBlockNum))
+	assert.Error(t, graph.ValidateEffectiveStartBlock(10), "unsnapped start blocks are still rejected")
+
+	reqPlan, err := plan.BuildTier1RequestPlan(false, 10, graph.LowestInitBlock(), req.ResolvedStartBlockNum, req.LinearHandoffBlockNum, 0, true)
+	require.NoError(t, err)
+	assert.Nil(t, reqPlan.BuildStores)
+	assert.Equal(t, uint64(100), reqPlan.LinearPipeline.StartBlock)
+
+	req = build(150)
+	assert.Equal(t, uint64(150), req.ResolvedStartBlockNum)
+	assert.Equal(t, uint64(150), graph.EffectiveStartBlock(150))
+	assert.NoError(t, graph.ValidateEffectiveStartBlock(req.ResolvedStartBlockNum))
+}
+
+func TestBuildRequestDetails_HeadResolutionTimeout(t *testing.T) {
+	ctx := context.Background()
+	var returned atomic.Int32
+	hangingResolver := func(ctx context.Context) (uint64, error) {
+		defer returned.Add(1)
+		<-ctx.Done()
+		return 0, fmt.Errorf("live hub not ready: %w", ctx.Err())
+	}
+
+	_, _, err := BuildRequestDetails(
+		ctx,
+		&pbsubstreamsrpc.Request{StartBlockNum: -10, ProductionMode: true},
+		true,
+		WithHeadResolutionTimeout(ctx, hangingResolver, 10*time.Millisecond),
+		nil,
+		WithHeadResolutionTimeout(ctx, hangingResolver, 10*time.Millisecond),
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, dgrpc.AsGRPCError(err).Code())
+	assert.Equal(t, int32(1), returned.Load(), "the resolver is not left behind")
+
+	// without a timeout, the request fails right away when the live source is not ready
+	_, err = WithHeadResolutionTimeout(ctx, hangingResolver, 0)()
+	assert.Equal(t, codes.Unavailable, dgrpc.AsGRPCError(err).Code())
+
+	fastResolver := WithHeadResolutionTimeout(ctx, func(context.Context) (uint64, error) { return 42, nil }, 0)
+	blockNum, err := fastResolver()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), blockNum)
+}
+
+func TestResolveUncachedModules(t *testing.T) {
+	mapModule := func(name string, input *pbsubstreams.Module_Input) *pbsubstreams.Module {
+		return &pbsubstreams.Module{
+			Name:   name,
+			Kind:   &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:test"}},
+			Inputs: []*pbsubstreams.Module_Input{input},
+			Output: &pbsubstreams.Module_Output{Type: "proto:test"},
+		}
+	}
+	modules := &pbsubstreams.Modules{
+		Binaries: []*pbsubstreams.Binary{{Type: "wasm/rust-v1"}},
+		Modules: []*pbsubstreams.Module{
+			mapModule("map_a", &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.test.Block"}}}),
+			mapModule("map_b", &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: "map_a"}}}),
+		},
+	}
+	graph, err := outputmodules.NewOutputModuleGraph("map_b", true, modules)
+	require.NoError(t, err)
+	hashA := graph.ModuleHashes().Get("map_a")
+	hashB := graph.ModuleHashes().Get("map_b")
+
+	assert.Equal(t, map[string]bool{}, ResolveUncachedModules("", graph, nil))
+	assert.Equal(t, map[string]bool{"map_a": true}, ResolveUncachedModules(" map_a, ", graph, nil))
+	assert.Equal(t, map[string]bool{"map_b": true}, ResolveUncachedModules("map_a", graph, map[string]bool{hashA: true, hashB: false}))
+}
+
+func TestNewStartCursorGetter(t *testing.T) {
+	readerFactory := bstream.GetBlockReaderFactory
+	bstream.GetBlockReaderFactory = bstream.TestBlockReaderFactory
+	defer func() { bstream.GetBlockReaderFactory = readerFactory }()
+
+	mergedBlocksStore := dstore.NewMockStore(nil)
+	mergedBlocksStore.SetFile("0000000000", []byte(strings.Join([]string{
+		bstream.TestJSONBlockWithLIBNum("00000008a", "00000007a", 7),
+		bstream.TestJSONBlockWithLIBNum("00000009a", "00000008a", 8),
+		bstream.TestJSONBlockWithLIBNum("0000000aa", "00000009a", 9),
+	}, "\n")))
+	getStartCursor := NewStartCursorGetter(nil, mergedBlocksStore)
+
+	opaqueCursor, err := getStartCursor(context.Background(), 10)
+	require.NoError(t, err)
+	cursor, err := bstream.CursorFromOpaque(opaqueCursor)
+	require.NoError(t, err)
+	assert.True(t, cursor.IsOnFinalBlock())
+	assert.Equal(t, "00000009a", cursor.Block.ID())
+
+	startBlockNum, _, _, err := resolveStartBlockNum(context.Background(), &pbsubstreamsrpc.Request{StartCursor: opaqueCursor}, true, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), startBlockNum, "the cursor resumes the stream at its start block")
+
+	opaqueCursor, err = getStartCursor(context.Background(), bstream.GetProtocolFirstStreamableBlock)
+	require.NoError(t, err)
+	assert.Empty(t, opaqueCursor, "no block precedes the first streamable block")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/streamingfast/substreams/storage/store"
+	"github.com/streamingfast/substreams/storage/store/deltaformat"
 
 	"github.com/streamingfast/substreams"
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
@@ -58,3 +59,49 @@ func (p *Pipeline) sendSnapshots(storeMap store.Map, snapshotModules []string) e
 
 	return nil
 }
+
+// replaceWithStoreSnapshot replaces the deltas of a store module output with the full
+// content of the store, as CREATE operations sorted by key, for clients that asked for
+// the store's snapshot instead of its deltas. Stores holding more keys than their scan
+// limit, see `store.Config.SetScanLimit`, cannot be returned whole and fail the request.
+func (p *Pipeline) replaceWithStoreSnapshot(output *pbsubstreamsrpc.StoreModuleOutput) error {
+	if p.stores == nil {
+		return fmt.Errorf("no stores available")
+	}
+	store, found := p.stores.StoreMap.Get(output.Name)
+	if !found {
+		return fmt.Errorf("store %q not found", output.Name)
+	}
+
+	result := store.Scan("", "", 0)
+	if result.Truncated {
+		return fmt.Errorf("store %q holds more than the %d keys a snapshot output can return", output.Name, len(result.Entries))
+	}
+
+	snapshot := make([]*pbsubstreamsrpc.StoreDelta, len(result.Entries))
+	for i, entry := range result.Entries {
+		snapshot[i] = &pbsubstreamsrpc.StoreDelta{
+			Operation: pbsubstreamsrpc.StoreDelta_CREATE,
+			Key:       entry.Key,
+			NewValue:  entry.Value,
+		}
+	}
+	output.DebugStoreDeltas = snapshot
+	return nil
+}
+
+// formatStoreOutput replaces the deltas of `output` by their serialization in `format`.
+func (p *Pipeline) formatStoreOutput(output *pbsubstreamsrpc.StoreModuleOutput, format deltaformat.Format) error {
+	config, found := p.stores.configs[output.Name]
+	if !found {
+		return fmt.Errorf("store %q not found", output.Name)
+	}
+
+	data, err := deltaformat.Encode(format, config.ValueType(), output.DebugStoreDeltas)
+	if err != nil {
+		return fmt.Errorf("encoding deltas of store %q: %w", output.Name, err)
+	}
+	output.DebugStoreDeltas = nil
+	output.DebugEncodedStoreDeltas = &pbsubstreamsrpc.EncodedStoreDeltas{Format: format, Data: data}
+	return nil
+}
@@ -0,0 +1,31 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/streamingfast/substreams"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+type startAck struct {
+	cursor string // resumes the stream at its start block
+}
+
+// sendStartAcknowledgement sends, once the pipeline is set up, the `StreamStart` message:
+// clients waiting for a first message know the stream is established, where it starts,
+// and resuming from its cursor restarts the stream there. A pending undo signal is sent
+// before it, the ack's cursor being the one resolved past the reorg.
+func (p *Pipeline) sendStartAcknowledgement(ctx context.Context) error {
+	if p.pendingUndoMessage != nil {
+		if err := p.respFunc(p.pendingUndoMessage); err != nil {
+			return err
+		}
+		p.pendingUndoMessage = nil
+	}
+
+	return p.respFunc(substreams.NewStreamStartResponse(&pbsubstreamsrpc.StreamStart{
+		StartBlockNum: reqctx.Details(ctx).ResolvedStartBlockNum,
+		Cursor:        p.startAck.cursor,
+	}))
+}
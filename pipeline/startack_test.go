@@ -0,0 +1,95 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/bstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams"
+	"github.com/streamingfast/substreams/metrics"
+	"github.com/streamingfast/substreams/orchestrator/plan"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	pbsubstreamstest "github.com/streamingfast/substreams/pb/sf/substreams/v1/test"
+	"github.com/streamingfast/substreams/pipeline/cache"
+	"github.com/streamingfast/substreams/pipeline/exec"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/service/config"
+	store2 "github.com/streamingfast/substreams/storage/store"
+)
+
+func TestPipeline_startAcknowledgement(t *testing.T) {
+	startCursor := (&bstream.Cursor{
+		Step:      bstream.StepNew,
+		Block:     bstream.NewBlockRef("9a", 9),
+		LIB:       bstream.NewBlockRef("8a", 8),
+		HeadBlock: bstream.NewBlockRef("9a", 9),
+	}).ToOpaque()
+
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{
+		Modules:               &pbsubstreams.Modules{},
+		OutputModule:          "test_map",
+		ResolvedStartBlockNum: 10,
+		LinearHandoffBlockNum: 10,
+		ResolvedCursor:        startCursor,
+		StopBlockNum:          20,
+	})
+	ctx = reqctx.WithReqStats(ctx, metrics.NewReqStats(&metrics.Config{}, zap.NewNop()))
+
+	var responses []*pbsubstreamsrpc.Response
+	respFunc := func(resp substreams.ResponseFromAnyTier) error {
+		responses = append(responses, resp.(*pbsubstreamsrpc.Response))
+		return nil
+	}
+
+	engine, err := cache.NewEngine(ctx, config.RuntimeConfig{}, nil, "sf.substreams.v1.test.Block")
+	require.NoError(t, err)
+	testMap := &pbsubstreams.Module{Name: "test_map", Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}}
+	pipe := &Pipeline{
+		ctx:             ctx,
+		runtimeConfig:   config.RuntimeConfig{StateBundleSize: 10},
+		forkHandler:     NewForkHandler(),
+		outputGraph:     outputmodules.TestNew(),
+		stores:          NewStores(ctx, store2.ConfigMap{}, 10, 10, 0, false),
+		gate:            newGate(ctx),
+		execOutputCache: engine,
+		moduleExecutors: [][]exec.ModuleExecutor{{mapTestExecutor(t, ctx, "test_map")}},
+		respFunc:        respFunc,
+	}
+	WithStartAcknowledgement(startCursor)(pipe)
+
+	require.NoError(t, pipe.InitTier1StoresAndBackprocess(ctx, &plan.RequestPlan{}))
+	pipe.executionStages = outputmodules.ExecutionStages{{{testMap}}}
+
+	block := &pbsubstreamstest.Block{Id: "10a", Number: 10}
+	blockCursor := &bstream.Cursor{
+		Step:      bstream.StepNew,
+		Block:     bstream.NewBlockRef("10a", 10),
+		LIB:       bstream.NewBlockRef("8a", 8),
+		HeadBlock: bstream.NewBlockRef("10a", 10),
+	}
+	pipe.gate.processBlock(10, bstream.StepNew)
+	require.NoError(t, pipe.handleStepNew(ctx, bstreamBlk(t, block), &pbsubstreams.Clock{Id: "10a", Number: 10}, blockCursor))
+
+	require.NotEmpty(t, responses)
+	ack := responses[0].GetStreamStart()
+	require.NotNil(t, ack, "the acknowledgement precedes any data")
+	assert.Equal(t, uint64(10), ack.StartBlockNum)
+	assert.Equal(t, startCursor, ack.Cursor)
+
+	var blocks []*pbsubstreamsrpc.BlockScopedData
+	for _, resp := range responses {
+		if data := resp.GetBlockScopedData(); data != nil {
+			blocks = append(blocks, data)
+		}
+	}
+	require.Len(t, blocks, 1)
+	data := blocks[0]
+	assert.Equal(t, uint64(10), data.Clock.Number)
+	assert.Equal(t, blockCursor.ToOpaque(), data.Cursor)
+}
@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/streamingfast/bstream"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	"github.com/streamingfast/substreams/storage/execout"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+// ReadStoreAsOfCursor returns the state of the store `storeConfig` consistent with
+// `cursor`, resolved like the StartCursor of a request: the state once the last block
+// sent before the cursor was processed, rather than the latest one. It is rebuilt from
+// the latest full snapshot up to that block, with the store deltas of the following
+// blocks, read from the module's output cache `execoutConfig`, applied on top. Without
+// a snapshot, the deltas apply on top of the store's initial values.
+//
+// `resolveCursor` is only called for cursors on reversible blocks. Cursors past the
+// snapshots and cached outputs available are rejected.
+func ReadStoreAsOfCursor(
+	ctx context.Context,
+	cursor string,
+	resolveCursor CursorResolver,
+	storeConfig *store.Config,
+	execoutConfig *execout.Config,
+	logger *zap.Logger,
+) (store.ReadOnlyStore, error) {
+	// the first block whose data was not sent yet, the state is read right before it
+	endBlock, _, _, err := resolveStartBlockNum(ctx, &pbsubstreamsrpc.Request{StartCursor: cursor}, true, resolveCursor, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	initialBlock := storeConfig.ModuleInitialBlock()
+	out := storeConfig.NewFullKV(logger)
+	if endBlock <= initialBlock {
+		return out, nil
+	}
+
+	snapshot, err := latestFullSnapshot(ctx, storeConfig, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	snapshotBlock := initialBlock
+	if snapshot != nil {
+		if err := out.Load(ctx, snapshot); err != nil {
+			return nil, fmt.Errorf("loading store %q snapshot %s: %w", storeConfig.Name(), snapshot.Filename, err)
+		}
+		snapshotBlock = snapshot.Range.ExclusiveEndBlock
+	} else if storeConfig.HasInitialValues() {
+		// the store was seeded at its initial block, the snapshots carry the values after
+		out.LoadInitialValues()
+	}
+
+	if err := applyCachedDeltas(ctx, out, execoutConfig, snapshotBlock, endBlock); err != nil {
+		return nil, fmt.Errorf("store %q as of block %d: %w", storeConfig.Name(), endBlock-1, err)
+	}
+	return out, nil
+}
+
+// latestFullSnapshot returns the full snapshot of the store ending the latest at or
+// before `endBlock`, nil if there is none.
+func latestFullSnapshot(ctx context.Context, storeConfig *store.Config, endBlock uint64) (*store.FileInfo, error) {
+	files, err := storeConfig.ListSnapshotFiles(ctx, endBlock)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshot files: %w", err)
+	}
+
+	var latest *store.FileInfo
+	for _, file := range files {
+		if file.Partial || file.Range.StartBlock != storeConfig.ModuleInitialBlock() || file.Range.ExclusiveEndBlock > endBlock {
+			continue
+		}
+		if latest == nil || file.Range.ExclusiveEndBlock > latest.Range.ExclusiveEndBlock {
+			latest = file
+		}
+	}
+	return latest, nil
+}
+
+// applyCachedDeltas applies to `into`, in block order, the store deltas cached for the
+// blocks in [startBlock, endBlock), failing if the cache doesn't cover them all.
+func applyCachedDeltas(ctx context.Context, into *store.FullKV, execoutConfig *execout.Config, startBlock, endBlock uint64) error {
+	if startBlock == endBlock {
+		return nil
+	}
+
+	files, err := execoutConfig.ListSnapshotFiles(ctx, bstream.NewOpenRange(execoutConfig.ModuleInitialBlock()))
+	if err != nil {
+		return fmt.Errorf("listing cached outputs: %w", err)
+	}
+
+	next := startBlock
+	for _, fileInfo := range files {
+		if next >= endBlock {
+			break
+		}
+		if fileInfo.BlockRange.StartBlock > next || fileInfo.BlockRange.ExclusiveEndBlock <= next {
+			continue
+		}
+
+		file := execoutConfig.NewFile(fileInfo.BlockRange)
+		if err := file.Load(ctx); err != nil {
+			return fmt.Errorf("loading cached outputs %s: %w", fileInfo.Filename, err)
+		}
+		for _, item := range file.SortedItems() {
+			if item.BlockNum < next || item.BlockNum >= endBlock {
+				continue
+			}
+			deltas := &pbssinternal.StoreDeltas{}
+			if err := proto.Unmarshal(item.Payload, deltas); err != nil {
+				return fmt.Errorf("unmarshalling deltas of block %d: %w", item.BlockNum, err)
+			}
+			for _, delta := range deltas.StoreDeltas {
+				into.ApplyDelta(delta)
+			}
+		}
+		next = fileInfo.BlockRange.ExclusiveEndBlock
+	}
+
+	if next < endBlock {
+		return fmt.Errorf("no cached outputs from block %d, the cursor is beyond the available data", next)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/streamingfast/substreams/block"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/execout"
+	store2 "github.com/streamingfast/substreams/storage/store"
+)
+
+func TestReadStoreAsOfCursor(t *testing.T) {
+	ctx := context.Background()
+	objStore := dstore.NewMockStore(nil)
+	storeConfig, err := store2.NewConfig("mod1", 0, "mod1", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", objStore, "")
+	require.NoError(t, err)
+	execoutConfig, err := execout.NewConfig("mod1", 0, pbsubstreams.ModuleKindStore, "mod1", objStore, zap.NewNop())
+	require.NoError(t, err)
+
+	snapshot := storeConfig.NewFullKV(zap.NewNop())
+	snapshot.Set(0, "a", "1")
+	snapshot.Set(0, "b", "2")
+	_, writer, err := snapshot.Save(20)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	blockDeltas := map[uint64][]*pbssinternal.StoreDelta{
+		21: {{Operation: pbssinternal.StoreDelta_UPDATE, Key: "a", OldValue: []byte("1"), NewValue: []byte("3")}},
+		23: {{Operation: pbssinternal.StoreDelta_CREATE, Key: "c", NewValue: []byte("4")}},
+		25: {{Operation: pbssinternal.StoreDelta_DELETE, Key: "b", OldValue: []byte("2")}},
+	}
+	outputs := execoutConfig.NewFile(block.NewRange(20, 30))
+	for blockNum, deltas := range blockDeltas {
+		data, err := proto.Marshal(&pbssinternal.StoreDeltas{StoreDeltas: deltas})
+		require.NoError(t, err)
+		outputs.SetItem(&pbsubstreams.Clock{Id: bstream.NewBlockRef("", blockNum).String(), Number: blockNum}, data)
+	}
+	require.NoError(t, outputs.Save(ctx))
+
+	finalCursor := func(blockNum uint64) string {
+		ref := bstream.NewBlockRef("", blockNum)
+		return (&bstream.Cursor{Step: bstream.StepNewIrreversible, Block: ref, LIB: ref, HeadBlock: ref}).ToOpaque()
+	}
+
+	asOf, err := ReadStoreAsOfCursor(ctx, finalCursor(23), nil, storeConfig, execoutConfig, zap.NewNop())
+	require.NoError(t, err)
+
+	direct, err := storeConfig.StateAt(ctx, 20, zap.NewNop())
+	require.NoError(t, err)
+	direct.ApplyDelta(blockDeltas[21][0])
+	direct.ApplyDelta(blockDeltas[23][0])
+
+	assert.Equal(t, direct.Length(), asOf.Length())
+	for _, key := range []string{"a", "b", "c"} {
+		expected, _ := direct.GetLast(key)
+		actual, _ := asOf.GetLast(key)
+		assert.Equal(t, expected, actual, key)
+	}
+	val, _ := asOf.GetLast("a")
+	assert.Equal(t, []byte("3"), val)
+	assert.True(t, asOf.HasLast("b"), "deleted after the cursor")
+
+	asOf, err = ReadStoreAsOfCursor(ctx, finalCursor(29), nil, storeConfig, execoutConfig, zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, asOf.HasLast("b"))
+
+	_, err = ReadStoreAsOfCursor(ctx, finalCursor(35), nil, storeConfig, execoutConfig, zap.NewNop())
+	assert.ErrorContains(t, err, "no cached outputs from block 30, the cursor is beyond the available data")
+}
+
+func TestReadStoreAsOfCursor_InitialValues(t *testing.T) {
+	ctx := context.Background()
+	objStore := dstore.NewMockStore(nil)
+	storeConfig, err := store2.NewConfig("mod1", 10, "mod1", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", objStore, "")
+	require.NoError(t, err)
+	require.NoError(t, storeConfig.SetInitialValues(map[string][]byte{"a": []byte("1"), "b": []byte("2")}))
+	execoutConfig, err := execout.NewConfig("mod1", 10, pbsubstreams.ModuleKindStore, "mod1", objStore, zap.NewNop())
+	require.NoError(t, err)
+
+	outputs := execoutConfig.NewFile(block.NewRange(10, 20))
+	data, err := proto.Marshal(&pbssinternal.StoreDeltas{StoreDeltas: []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_UPDATE, Key: "a", OldValue: []byte("1"), NewValue: []byte("3")},
+	}})
+	require.NoError(t, err)
+	outputs.SetItem(&pbsubstreams.Clock{Id: bstream.NewBlockRef("", 12).String(), Number: 12}, data)
+	require.NoError(t, outputs.Save(ctx))
+
+	ref := bstream.NewBlockRef("", 15)
+	cursor := (&bstream.Cursor{Step: bstream.StepNewIrreversible, Block: ref, LIB: ref, HeadBlock: ref}).ToOpaque()
+	asOf, err := ReadStoreAsOfCursor(ctx, cursor, nil, storeConfig, execoutConfig, zap.NewNop())
+	require.NoError(t, err)
+
+	val, _ := asOf.GetLast("a")
+	assert.Equal(t, []byte("3"), val)
+	val, _ = asOf.GetLast("b")
+	assert.Equal(t, []byte("2"), val, "initial value untouched by the module")
+}
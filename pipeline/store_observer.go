@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+// StoreDeltas are the deltas a store went through while processing a block or, when
+// Undo is set, the deltas reverted from it because the block was forked out.
+type StoreDeltas struct {
+	StoreName string
+	Clock     *pbsubstreams.Clock
+	Undo      bool
+	Deltas    []*pbssinternal.StoreDelta
+}
+
+// StoreDeltasHandler is called, in order, with the deltas of each block of a subscribed
+// store, see `WithStoreDeltasHandler`.
+type StoreDeltasHandler func(deltas *StoreDeltas)
+
+type storeSubscription struct {
+	storeName string
+	events    chan *StoreDeltas
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *storeSubscription) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// subscribe registers `handler` to be called with the deltas of store `storeName`, for
+// each block processed from now on. The handler runs in a goroutine of its own: up to
+// `bufferSize` blocks wait for it, past that the processing of blocks waits for the
+// handler to catch up.
+//
+// The subscription ends when `ctx` is done or `unsubscribe` is called, deltas not yet
+// handled are then dropped.
+func (s *Stores) subscribe(ctx context.Context, storeName string, bufferSize int, handler StoreDeltasHandler) (unsubscribe func()) {
+	sub := &storeSubscription{
+		storeName: storeName,
+		events:    make(chan *StoreDeltas, bufferSize),
+		done:      make(chan struct{}),
+	}
+
+	s.subscriptionsLock.Lock()
+	s.subscriptions = append(s.subscriptions, sub)
+	s.subscriptionsLock.Unlock()
+
+	unsubscribe = func() {
+		sub.close()
+
+		s.subscriptionsLock.Lock()
+		defer s.subscriptionsLock.Unlock()
+		for i, other := range s.subscriptions {
+			if other == sub {
+				s.subscriptions = append(s.subscriptions[:i:i], s.subscriptions[i+1:]...)
+				break
+			}
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				unsubscribe()
+			case <-sub.done:
+			case deltas := <-sub.events:
+				handler(deltas)
+				continue
+			}
+			return
+		}
+	}()
+
+	return unsubscribe
+}
+
+// publishDeltas sends the deltas of the block `clock` to the subscribers of each store,
+// it blocks while a subscriber's buffer is full.
+func (s *Stores) publishDeltas(ctx context.Context, clock *pbsubstreams.Clock) error {
+	for _, sub := range s.activeSubscriptions() {
+		subscribed, found := s.StoreMap.Get(sub.storeName)
+		if !found {
+			continue
+		}
+		deltaStore, ok := subscribed.(store.DeltaAccessor)
+		if !ok {
+			continue
+		}
+		// the store reuses its deltas slice from one block to the other
+		deltas := append([]*pbssinternal.StoreDelta(nil), deltaStore.GetDeltas()...)
+		if err := sub.publish(ctx, &StoreDeltas{StoreName: sub.storeName, Clock: clock, Deltas: deltas}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishUndo sends the deltas reverted from a store by a fork to its subscribers.
+func (s *Stores) publishUndo(ctx context.Context, clock *pbsubstreams.Clock, moduleOutput *pbssinternal.ModuleOutput) error {
+	for _, sub := range s.activeSubscriptions() {
+		if sub.storeName != moduleOutput.ModuleName {
+			continue
+		}
+		if err := sub.publish(ctx, &StoreDeltas{StoreName: sub.storeName, Clock: clock, Undo: true, Deltas: moduleOutput.GetStoreDeltas().GetStoreDeltas()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Stores) activeSubscriptions() []*storeSubscription {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	if len(s.subscriptions) == 0 {
+		return nil
+	}
+	return append([]*storeSubscription(nil), s.subscriptions...)
+}
+
+func (s *storeSubscription) publish(ctx context.Context, deltas *StoreDeltas) error {
+	select {
+	case s.events <- deltas:
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
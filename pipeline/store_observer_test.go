@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/service/config"
+	store2 "github.com/streamingfast/substreams/storage/store"
+)
+
+func TestStores_Subscribe(t *testing.T) {
+	confMap := testConfigMap(t, []testStoreConfig{
+		{name: "mod1", initBlock: 0, writtenUpTo: 0},
+		{name: "mod2", initBlock: 0, writtenUpTo: 0},
+	})
+	mod1 := confMap["mod1"].NewFullKV(zap.NewNop())
+	mod2 := confMap["mod2"].NewFullKV(zap.NewNop())
+	storeMap := store2.NewMap()
+	storeMap.Set(mod1)
+	storeMap.Set(mod2)
+	stores := &Stores{configs: confMap, StoreMap: storeMap}
+
+	ctx := context.Background()
+	received := make(chan *StoreDeltas, 10)
+	unsubscribe := stores.subscribe(ctx, "mod1", 1, func(deltas *StoreDeltas) {
+		received <- deltas
+	})
+
+	for blockNum := uint64(10); blockNum < 12; blockNum++ {
+		mod1.Set(0, "key", "value")
+		mod2.Set(0, "other", "value")
+		require.NoError(t, stores.publishDeltas(ctx, &pbsubstreams.Clock{Number: blockNum}))
+		stores.resetStores()
+	}
+
+	for blockNum := uint64(10); blockNum < 12; blockNum++ {
+		deltas := receive(t, received)
+		assert.Equal(t, "mod1", deltas.StoreName)
+		assert.Equal(t, blockNum, deltas.Clock.Number)
+		assert.False(t, deltas.Undo)
+		require.Len(t, deltas.Deltas, 1)
+		assert.Equal(t, "key", deltas.Deltas[0].Key)
+	}
+
+	undone := &pbssinternal.ModuleOutput{
+		ModuleName: "mod1",
+		Data: &pbssinternal.ModuleOutput_StoreDeltas{StoreDeltas: &pbssinternal.StoreDeltas{
+			StoreDeltas: []*pbssinternal.StoreDelta{{Operation: pbssinternal.StoreDelta_CREATE, Key: "key"}},
+		}},
+	}
+	require.NoError(t, stores.publishUndo(ctx, &pbsubstreams.Clock{Number: 11}, undone))
+	deltas := receive(t, received)
+	assert.True(t, deltas.Undo)
+	assert.Equal(t, uint64(11), deltas.Clock.Number)
+
+	unsubscribe()
+	mod1.Set(0, "key", "value")
+	require.NoError(t, stores.publishDeltas(ctx, &pbsubstreams.Clock{Number: 12}))
+	select {
+	case deltas := <-received:
+		t.Fatalf("unexpected deltas after unsubscribing at block %d", deltas.Clock.Number)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStores_SubscribeBackpressure(t *testing.T) {
+	confMap := testConfigMap(t, []testStoreConfig{
+		{name: "mod1", initBlock: 0, writtenUpTo: 0},
+	})
+	storeMap := store2.NewMap()
+	storeMap.Set(confMap["mod1"].NewFullKV(zap.NewNop()))
+	stores := &Stores{configs: confMap, StoreMap: storeMap}
+
+	release := make(chan struct{})
+	unsubscribe := stores.subscribe(context.Background(), "mod1", 0, func(*StoreDeltas) {
+		<-release
+	})
+
+	// the handler holds the first block, the second one waits for it
+	require.NoError(t, stores.publishDeltas(context.Background(), &pbsubstreams.Clock{Number: 10}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, stores.publishDeltas(ctx, &pbsubstreams.Clock{Number: 11}), context.DeadlineExceeded)
+
+	// unsubscribing releases the blocks processing
+	unsubscribe()
+	require.NoError(t, stores.publishDeltas(context.Background(), &pbsubstreams.Clock{Number: 12}))
+	close(release)
+}
+
+func receive(t *testing.T, received chan *StoreDeltas) *StoreDeltas {
+	t.Helper()
+	select {
+	case deltas := <-received:
+		return deltas
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for store deltas")
+		return nil
+	}
+}
+
+func TestWithStoreDeltasHandler(t *testing.T) {
+	confMap := testConfigMap(t, []testStoreConfig{
+		{name: "mod1", initBlock: 0, writtenUpTo: 0},
+	})
+	mod1 := confMap["mod1"].NewFullKV(zap.NewNop())
+	storeMap := store2.NewMap()
+	storeMap.Set(mod1)
+	stores := &Stores{configs: confMap, StoreMap: storeMap}
+
+	ctx, cancel := context.WithCancel(reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{}))
+	received := make(chan *StoreDeltas, 10)
+	New(ctx, nil, stores, nil, nil, nil, config.RuntimeConfig{}, nil, "", WithStoreDeltasHandler("mod1", 1, func(deltas *StoreDeltas) {
+		received <- deltas
+	}))
+
+	mod1.Set(0, "key", "value")
+	require.NoError(t, stores.publishDeltas(ctx, &pbsubstreams.Clock{Number: 10}))
+	assert.Equal(t, uint64(10), receive(t, received).Clock.Number)
+
+	// the subscription ends with the request
+	cancel()
+	require.Eventually(t, func() bool { return len(stores.activeSubscriptions()) == 0 }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+var storesReadyPollInterval = 5 * time.Second
+
+// WaitForStoresReady blocks until every store in `configs` has a complete snapshot
+// ending at `upToBlock`, polling the storage until `timeout` expires. It returns
+// whether all the stores were found ready.
+func WaitForStoresReady(ctx context.Context, configs store.ConfigMap, upToBlock uint64, timeout time.Duration) (bool, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		notReady, err := storesNotReady(ctx, configs, upToBlock)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return false, nil
+			}
+			return false, err
+		}
+		if len(notReady) == 0 {
+			return true, nil
+		}
+
+		logger.Debug("waiting for stores to be ready", zap.Uint64("up_to_block", upToBlock), zap.Strings("stores", notReady))
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return false, nil
+			}
+			return false, ctx.Err()
+		case <-time.After(storesReadyPollInterval):
+		}
+	}
+}
+
+func storesNotReady(ctx context.Context, configs store.ConfigMap, upToBlock uint64) (out []string, err error) {
+	for name, config := range configs {
+		if config.ModuleInitialBlock() >= upToBlock {
+			continue
+		}
+
+		files, err := config.ListSnapshotFiles(ctx, upToBlock)
+		if err != nil {
+			return nil, fmt.Errorf("listing snapshots of store %q: %w", name, err)
+		}
+
+		ready := false
+		for _, file := range files {
+			if !file.Partial && file.Range.ExclusiveEndBlock == upToBlock {
+				ready = true
+				break
+			}
+		}
+		if !ready {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
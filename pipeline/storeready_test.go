@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	store2 "github.com/streamingfast/substreams/storage/store"
+)
+
+func TestWaitForStoresReady(t *testing.T) {
+	defer func(prev time.Duration) { storesReadyPollInterval = prev }(storesReadyPollInterval)
+	storesReadyPollInterval = 10 * time.Millisecond
+
+	var lock sync.Mutex
+	files := map[string]bool{}
+	objStore := dstore.NewMockStore(nil)
+	objStore.WalkFunc = func(ctx context.Context, prefix string, f func(filename string) error) error {
+		lock.Lock()
+		defer lock.Unlock()
+		for filename := range files {
+			if err := f(filename); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	conf, err := store2.NewConfig("mod1", 0, "mod1", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", objStore, "")
+	require.NoError(t, err)
+	confMap := store2.ConfigMap{"mod1": conf}
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{})
+
+	ready, err := WaitForStoresReady(ctx, confMap, 100, 30*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Lock()
+		files["0000000100-0000000000.kv"] = true
+		lock.Unlock()
+	}()
+
+	ready, err = WaitForStoresReady(ctx, confMap, 100, 5*time.Second)
+	require.NoError(t, err)
+	assert.True(t, ready)
+}
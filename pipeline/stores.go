@@ -3,6 +3,7 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
@@ -24,6 +25,11 @@ type Stores struct {
 	// tier1 to tier2.
 	partialsWritten block.Ranges // when backprocessing, to report back to orchestrator
 	tier            string
+
+	lastSaveCheckBlock *uint64 // see `saveStoresAtBoundaries`
+
+	subscriptionsLock sync.Mutex
+	subscriptions     []*storeSubscription // see `WithStoreDeltasHandler`
 }
 
 func NewStores(ctx context.Context, storeConfigs store.ConfigMap, storeSnapshotSaveInterval, requestStartBlockNum, stopBlockNum uint64, isTier2Request bool) *Stores {
@@ -48,6 +54,29 @@ func (s *Stores) SetStoreMap(storeMap store.Map) {
 	s.StoreMap = storeMap
 }
 
+// checkMemoryBudget fails when the stores of the request, together, hold more
+// data than the memory budget allows.
+func (s *Stores) checkMemoryBudget() error {
+	budget := s.configs.MemoryBudget()
+	if budget == nil {
+		return nil
+	}
+	return budget.Check()
+}
+
+// commitStores makes the writes of the block durable, for the stores kept on local
+// disk, see `store.ConfigMap.SetSpillDirectory`.
+func (s *Stores) commitStores() error {
+	for _, st := range s.StoreMap.All() {
+		if committable, ok := st.(store.Committable); ok {
+			if err := committable.Commit(); err != nil {
+				return fmt.Errorf("store %q: %w", st.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *Stores) resetStores() {
 	for _, s := range s.StoreMap.All() {
 		if resetableStore, ok := s.(store.Resettable); ok {
@@ -56,6 +85,15 @@ func (s *Stores) resetStores() {
 	}
 }
 
+// freezeStores makes any further write to the stores fail, see `store.Freezable`.
+func (s *Stores) freezeStores(reason string) {
+	for _, s := range s.StoreMap.All() {
+		if freezableStore, ok := s.(store.Freezable); ok {
+			freezableStore.Freeze(reason)
+		}
+	}
+}
+
 // flushStores is called only for Tier2 request, as to not save reversible stores.
 func (s *Stores) flushStores(ctx context.Context, executionStages outputmodules.ExecutionStages, blockNum uint64) (err error) {
 	if s.StoreMap == nil {
@@ -75,6 +113,45 @@ func (s *Stores) flushStores(ctx context.Context, executionStages outputmodules.
 	return nil
 }
 
+// saveStoresAtBoundaries saves all the stores at the boundaries passed by `blockNum`,
+// on tier1 requests overriding the snapshot interval, before `blockNum` is processed.
+// Boundaries passed while the stores hold reversible blocks stay pending until the
+// first final block past them. A snapshot is only saved at an exact boundary: when the
+// stores were moved past it in the meantime, it is skipped rather than labelled with a
+// state that isn't the one at the boundary.
+func (s *Stores) saveStoresAtBoundaries(ctx context.Context, blockNum uint64, reversible bool) error {
+	if s.StoreMap == nil {
+		return nil
+	}
+
+	lastBlock := s.lastSaveCheckBlock
+	s.lastSaveCheckBlock = &blockNum
+	if reversible {
+		return nil
+	}
+
+	reqDetails := reqctx.Details(ctx)
+	for _, boundaryBlock := range s.bounder.GetStoreFlushRanges(false, 0, blockNum) {
+		if reqDetails.IsBlockOverStopBlock(boundaryBlock) {
+			continue
+		}
+		if lastBlock != nil && *lastBlock >= boundaryBlock {
+			s.logger.Info("skipping store save at boundary, the stores moved past it on reversible blocks", zap.Uint64("boundary", boundaryBlock), zap.Uint64("block_num", blockNum))
+			continue
+		}
+		for _, saveStore := range s.StoreMap.All() {
+			if saveStore.InitialBlock() >= boundaryBlock {
+				continue
+			}
+			s.logger.Info("saving store at boundary", zap.Uint64("boundary", boundaryBlock), zap.String("store", saveStore.Name()))
+			if err := s.saveStoreSnapshot(ctx, saveStore, boundaryBlock); err != nil {
+				return fmt.Errorf("save store snapshot %q: %w", saveStore.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *Stores) saveStoresSnapshots(ctx context.Context, lastLayer outputmodules.LayerModules, stage int, boundaryBlock uint64) (err error) {
 	for _, mod := range lastLayer {
 		store := s.StoreMap[mod.Name]
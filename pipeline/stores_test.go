@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	store2 "github.com/streamingfast/substreams/storage/store"
+)
+
+func TestStores_saveStoresAtOverriddenInterval(t *testing.T) {
+	ctx := reqctx.WithRequest(context.Background(), &reqctx.RequestDetails{StoreSnapshotSaveInterval: 10, StopBlockNum: 100})
+	config, err := store2.NewConfig("mod1", 0, "mod1", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	confMap := store2.ConfigMap{"mod1": config}
+
+	stores := NewStores(ctx, confMap, reqctx.Details(ctx).StoreSnapshotSaveInterval, 0, 100, false)
+	fullKV := config.NewFullKV(zap.NewNop())
+	storeMap := store2.NewMap()
+	storeMap.Set(fullKV)
+	stores.SetStoreMap(storeMap)
+
+	for blockNum := uint64(0); blockNum < 25; blockNum++ {
+		// blocks from 20 on are reversible
+		require.NoError(t, stores.saveStoresAtBoundaries(ctx, blockNum, blockNum >= 20))
+		fullKV.Set(0, "last", fmt.Sprintf("%d", blockNum))
+	}
+
+	files, err := config.ListSnapshotFiles(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, uint64(10), files[0].Range.ExclusiveEndBlock)
+
+	saved := config.NewFullKV(zap.NewNop())
+	require.NoError(t, saved.Load(ctx, files[0]))
+	val, found := saved.GetLast("last")
+	require.True(t, found)
+	assert.Equal(t, []byte("9"), val, "the state after the last block before the boundary")
+
+	// final again, the boundary 20 passed on reversible blocks can't be saved exactly anymore,
+	// the boundary 30 falls in skipped blocks
+	for _, blockNum := range []uint64{26, 28, 33} {
+		require.NoError(t, stores.saveStoresAtBoundaries(ctx, blockNum, false))
+		fullKV.Set(0, "last", fmt.Sprintf("%d", blockNum))
+	}
+
+	files, err = config.ListSnapshotFiles(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, uint64(30), files[1].Range.ExclusiveEndBlock)
+
+	saved = config.NewFullKV(zap.NewNop())
+	require.NoError(t, saved.Load(ctx, files[1]))
+	val, _ = saved.GetLast("last")
+	assert.Equal(t, []byte("28"), val)
+}
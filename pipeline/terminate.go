@@ -29,6 +29,12 @@ func (p *Pipeline) OnStreamTerminated(ctx context.Context, err error) error {
 
 	p.runPostJobHooks(ctx, p.lastFinalClock)
 
+	if p.errorsAsData != nil && !p.errorsAsData.failures.isEmpty() {
+		if sendErr := p.respFunc(p.errorsAsData.failures.response()); sendErr != nil {
+			logger.Warn("failed to send the summary of module failures", zap.Error(sendErr))
+		}
+	}
+
 	if !errors.Is(err, stream.ErrStopBlockReached) && !errors.Is(err, io.EOF) {
 		return err
 	}
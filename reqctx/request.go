@@ -2,14 +2,29 @@ package reqctx
 
 import (
 	"strconv"
+	"time"
 
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 )
 
 type IsOutputModuleFunc func(name string) bool
 
+// StoreOutputMode selects what is returned for a store module's output on each block.
+type StoreOutputMode int
+
+const (
+	// StoreOutputDeltas returns the deltas produced by the store on the block, the default.
+	StoreOutputDeltas StoreOutputMode = iota
+	// StoreOutputSnapshot returns the full content of the store once the block is applied.
+	StoreOutputSnapshot
+)
+
 type RequestDetails struct {
 	Modules *pbsubstreams.Modules
+	// SubrequestModulesHash, when set, makes sub-requests reference the modules by
+	// this hash instead of embedding them, see `ProcessRangeRequest.modules_hash`.
+	SubrequestModulesHash string
 
 	DebugInitialStoreSnapshotForModules []string
 	OutputModule                        string
@@ -19,13 +34,77 @@ type RequestDetails struct {
 
 	LinearHandoffBlockNum uint64
 	StopBlockNum          uint64
-	MaxParallelJobs       uint64
-	CacheTag              string
-	UniqueID              uint64
+	// Unbounded takes precedence over StopBlockNum: when true, the request never stops.
+	// For backward compatibility, a StopBlockNum of 0 means unbounded, unless the client
+	// explicitly declared its stop block (see `X-Sf-Substreams-Explicit-Stop-Block`),
+	// in which case 0 is a genuine (exclusive) stop block.
+	Unbounded       bool
+	MaxParallelJobs uint64
+	CacheTag        string
+	UniqueID        uint64
 
 	ProductionMode bool
 	IsTier2Request bool
 	Tier2Stage     int
+
+	// When DebugIntermediateOutputs is set, the outputs of all executed modules
+	// are returned for block DebugIntermediateOutputsAtBlock, even in production mode.
+	DebugIntermediateOutputs        bool
+	DebugIntermediateOutputsAtBlock uint64
+
+	// When StreamModuleLogs is set, the logs of every module executed on a block are
+	// returned along with the block's outputs, even when the block succeeded.
+	StreamModuleLogs bool
+
+	// When ErrorsAsData is set, a mapper failing on a block, when no store depends on it,
+	// is reported in the block's outputs and processing continues with the next block.
+	ErrorsAsData bool
+
+	// ProgressHeartbeatInterval is the interval at which progress messages are sent
+	// while backprocessing, the pipeline's default is used when 0.
+	ProgressHeartbeatInterval time.Duration
+
+	// WaitForStoresTimeout, when non-zero, makes the request wait up to that duration
+	// for its stores to be available from storage before backprocessing them.
+	WaitForStoresTimeout time.Duration
+
+	// StoreOutputModes holds, per store module, the output mode requested by the client.
+	// Stores not listed here are returned as deltas.
+	StoreOutputModes map[string]StoreOutputMode
+
+	// StoreDeltaFormat is the serialization of the store deltas returned to the client,
+	// other formats than protobuf are set in `StoreModuleOutput.DebugEncodedStoreDeltas`.
+	StoreDeltaFormat pbsubstreamsrpc.StoreDeltaFormat
+
+	// UncachedModules are the modules whose outputs are recomputed instead of being
+	// read from or written to the cache.
+	UncachedModules map[string]bool
+
+	// DeterministicDeltaOrder orders the store deltas of every module sharing an ordinal by
+	// key, whatever the order the module wrote them in. It is always the case for the
+	// modules whose outputs are cached, so the cache, the deltas returned and the ones fed
+	// to the modules consuming them have the same order.
+	DeterministicDeltaOrder bool
+
+	// StoreSnapshotSaveInterval, when not 0, replaces the service's interval for the store
+	// snapshots tier1 saves while processing final blocks of this request.
+	StoreSnapshotSaveInterval uint64
+
+	// SkipEmptyCachedOutputs leaves out of the stream the blocks for which the output
+	// module's cached output is empty, in production mode. The blocks processed live, or
+	// whose output is not cached, are always returned.
+	SkipEmptyCachedOutputs bool
+
+	// DisableStoreSnapshots runs the request without writing anything to storage: nothing
+	// is backprocessed, the stores are built in memory by processing linearly from their
+	// modules' initial block, and their snapshots are never saved.
+	DisableStoreSnapshots bool
+}
+
+// ShouldOrderDeltasByKey tells if the deltas of store `modName` sharing an ordinal must be
+// ordered by key, see `DeterministicDeltaOrder`.
+func (d *RequestDetails) ShouldOrderDeltasByKey(modName string) bool {
+	return d.DeterministicDeltaOrder || !d.UncachedModules[modName]
 }
 
 func (d *RequestDetails) UniqueIDString() string {
@@ -40,6 +119,19 @@ func (d *RequestDetails) ShouldReturnWrittenPartials(modName string) bool {
 	return d.IsTier2Request && d.IsOutputModule(modName)
 }
 
+// IsBlockOverStopBlock tells if `blockNum` is at or past the request's (exclusive) stop block.
+func (d *RequestDetails) IsBlockOverStopBlock(blockNum uint64) bool {
+	return !d.Unbounded && blockNum >= d.StopBlockNum
+}
+
+func (d *RequestDetails) ShouldCaptureIntermediateOutputs(blockNum uint64) bool {
+	return d.DebugIntermediateOutputs && !d.IsTier2Request && blockNum == d.DebugIntermediateOutputsAtBlock
+}
+
+func (d *RequestDetails) StoreOutputMode(modName string) StoreOutputMode {
+	return d.StoreOutputModes[modName]
+}
+
 func (d *RequestDetails) ShouldStreamCachedOutputs() bool {
 	return d.ProductionMode &&
 		d.ResolvedStartBlockNum < d.LinearHandoffBlockNum
@@ -1 +1,20 @@
 package reqctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestDetails_IsBlockOverStopBlock(t *testing.T) {
+	unbounded := &RequestDetails{StopBlockNum: 0, Unbounded: true}
+	assert.False(t, unbounded.IsBlockOverStopBlock(0))
+	assert.False(t, unbounded.IsBlockOverStopBlock(1000))
+
+	stopAtZero := &RequestDetails{StopBlockNum: 0}
+	assert.True(t, stopAtZero.IsBlockOverStopBlock(0))
+
+	bounded := &RequestDetails{StopBlockNum: 10}
+	assert.False(t, bounded.IsBlockOverStopBlock(9))
+	assert.True(t, bounded.IsBlockOverStopBlock(10))
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"time"
+
 	"github.com/streamingfast/dstore"
 
+	"github.com/streamingfast/substreams/block"
 	"github.com/streamingfast/substreams/orchestrator/work"
+	"github.com/streamingfast/substreams/storage/store"
 )
 
 // RuntimeConfig is a global configuration for the service.
@@ -12,8 +16,13 @@ type RuntimeConfig struct {
 	StateBundleSize uint64
 
 	MaxWasmFuel                uint64 // if not 0, enable fuel consumption monitoring to stop runaway wasm module processing forever
+	ModuleExecutionConcurrency uint64 // maximum number of independent modules executed concurrently for a given block, 0 means no limit
 	MaxJobsAhead               uint64 // limit execution of depencency jobs so they don't go too far ahead of the modules that depend on them (ex: module X is 2 million blocks ahead of module Y that depends on it, we don't want to schedule more module X jobs until Y caught up a little bit)
 	DefaultParallelSubrequests uint64 // how many sub-jobs to launch for a given user
+	MaxBackprocessingBlocks    uint64 // if not 0, tier1 requests needing to backprocess more blocks than this are rejected
+	// if not 0, sub-requests whose modules serialize to more bytes than this reference
+	// them by hash, the workers fetching them from BaseObjectStore, instead of embedding them
+	SubrequestModulesReferenceThreshold uint64
 	// derives substores `states/`, for `store` modules snapshots (full and partial)
 	// and `outputs/` for execution output of both `map` and `store` module kinds
 	BaseObjectStore dstore.Store
@@ -21,6 +30,135 @@ type RuntimeConfig struct {
 	WorkerFactory   work.WorkerFactory
 
 	ModuleExecutionTracing bool
+	StoreSpillDirectory    string // if set, store states are kept on local disk under this directory instead of in memory
+	ValidateStoreValues    bool   // if set, values written to stores must parse as the store's value type, at a performance cost
+	ValidateStoreKeys      bool   // if set, keys written to stores must be valid UTF-8, and at most MaxStoreKeyLength bytes if not 0
+	MaxStoreKeyLength      uint64
+	AuditStoreSnapshots    bool   // if set, full store snapshots produced from partials are verified in the background against a replay of the partials, kept until then
+	MaxStoresMemoryBytes   uint64 // if not 0, requests whose stores hold more data than this, all together, fail with `ResourceExhausted`
+
+	StoreSnapshotKeyFormatter store.SnapshotKeyFormatter // if set, names the store snapshot objects, must be the same on both tiers
+	StoreSnapshotKeyProvider  store.SnapshotKeyProvider  // if set, store snapshots are written encrypted with the key it returns, must be the same on both tiers
+	StoreMergeConcurrency     uint64                     // if not 0, number of partial stores loaded ahead of their merge while backprocessing
+	ExecOutDecodeConcurrency  int                        // if greater than 1, number of goroutines decoding the cached outputs of a loaded file
+
+	// AllowDebugIntermediateOutputs lets clients request the outputs of all intermediate modules for a given block,
+	// this should only be enabled on development or trusted endpoints.
+	AllowDebugIntermediateOutputs bool
+
+	// AllowModuleLogsStreaming lets clients request the logs of all the modules on every
+	// block, this should only be enabled on development or trusted endpoints.
+	AllowModuleLogsStreaming bool
+
+	// AllowStoreSnapshotIntervalOverride lets clients choose the interval at which the
+	// stores of their request are saved, this should only be enabled on development or
+	// trusted endpoints.
+	AllowStoreSnapshotIntervalOverride bool
+
+	// ModuleOutputSizeWarningBytes, when not 0, logs a warning naming the module for each
+	// output of a block larger than this.
+	ModuleOutputSizeWarningBytes uint64
+
+	// ResponseBufferSize is the number of responses that can be queued for a client before
+	// the pipeline waits on it, 0 sends every response synchronously. When the buffer is full,
+	// the request fails with `ResourceExhausted` instead if FailOnResponseBufferOverflow is set.
+	ResponseBufferSize           int
+	FailOnResponseBufferOverflow bool
+
+	// ModuleCacheOverrides forces, by module hash, whether the outputs of a module are cached,
+	// regardless of what the request asks for.
+	ModuleCacheOverrides map[string]bool
+
+	// CompletedRanges are, by module hash, block ranges tier1 considers already processed,
+	// without looking for their snapshots, for example migrated from another deployment.
+	CompletedRanges map[string]block.Ranges
+
+	// StreamIdleTimeout terminates a stream whose client stopped reading: when a response
+	// cannot be sent for that long, 0 means no limit.
+	StreamIdleTimeout time.Duration
+
+	// HeadResolutionTimeout is how long a request being set up waits for the live source
+	// to be ready to resolve the head or a recent final block, 0 failing it right away.
+	HeadResolutionTimeout time.Duration
+
+	// DisconnectDrainTimeout is how long the block being processed when the client
+	// disconnects may still take to complete, its remaining modules are then not
+	// executed. With 0, they are stopped as soon as the client disconnects.
+	DisconnectDrainTimeout time.Duration
+
+	// MaxModuleDepth rejects requests whose output module depends on an input chain longer
+	// than this many modules, 0 means no limit.
+	MaxModuleDepth uint64
+
+	// PreloadStoreSnapshots are the module hashes of stores whose latest full snapshot,
+	// under DefaultCacheTag, is read into memory at startup so the first requests using
+	// them don't wait on storage. The cache holds at most StoreSnapshotCacheBytes.
+	PreloadStoreSnapshots   []string
+	StoreSnapshotCacheBytes uint64
+
+	// DeterminismCheckInterval, when not 0, executes the map modules of development mode
+	// requests a second time on blocks that are a multiple of it, warning when the two
+	// outputs differ.
+	DeterminismCheckInterval uint64
+
+	// ReorgGraceWindow is how many blocks behind the head, counting it, a reorg can undo
+	// by reverting the store deltas of the undone blocks. Deeper reorgs fail the stream, 0
+	// means no limit.
+	ReorgGraceWindow uint64
+
+	// ShareIdenticalExecutions makes concurrent identical bounded requests share a single
+	// execution, whose responses are sent to all of them.
+	ShareIdenticalExecutions bool
+
+	// MaxStoreDeletedPrefixes caps the number of distinct key prefixes a store module can
+	// delete within a segment, failing the module beyond it. 0 means no limit.
+	MaxStoreDeletedPrefixes uint64
+
+	// MaxConcurrentWasmInstantiations caps the number of wasm modules being instantiated
+	// at once across all the requests of the service, the others waiting for their turn.
+	// 0 means no limit.
+	MaxConcurrentWasmInstantiations uint64
+
+	// ReservedStoreKeyPrefix is the prefix of the keys store modules cannot write, an
+	// empty one letting them write any key but the ones the system writes itself.
+	ReservedStoreKeyPrefix string
+
+	// DisableStoreSnapshots runs all the requests of the service like the ones asking for
+	// it with the `X-Sf-Substreams-Disable-Store-Snapshots` header, without writing
+	// anything to BaseObjectStore.
+	DisableStoreSnapshots bool
+
+	// BigFloatStorePrecision is the precision, in bits, with which the partials of
+	// `bigfloat` stores are merged. 0 merges them as decimals.
+	BigFloatStorePrecision uint
+
+	// CoalesceStoreAppends records the appends to a same key within a block as a single
+	// delta, see `store.Config.SetAppendCoalescing`.
+	CoalesceStoreAppends bool
+
+	// SubrequestMaxRetries is the number of times a tier2 sub-request failing with a
+	// transient error is retried before failing the request, waiting SubrequestRetryBackoff
+	// before the first retry, then longer on each, following a Fibonacci sequence.
+	SubrequestMaxRetries   uint64
+	SubrequestRetryBackoff time.Duration
+
+	// MaxUnsentBlocks, when not 0, caps the number of blocks of a request whose responses
+	// are not sent yet, counting the one being processed and the ones whose responses wait
+	// in the response buffer, see ResponseBufferSize. Processing pauses until the client
+	// catches up. Without ResponseBufferSize, the responses go through a buffer of
+	// MaxUnsentBlocks responses, never failing on overflow. It does not bound the blocks
+	// the block source reads ahead.
+	MaxUnsentBlocks uint64
+
+	// StoreLoadMaxRetries is the number of times loading a store snapshot failing with a
+	// transient storage error is retried, waiting StoreLoadRetryBackoff before the first
+	// retry, then twice longer on each.
+	StoreLoadMaxRetries   uint64
+	StoreLoadRetryBackoff time.Duration
+
+	// StoreScanLimit caps the number of keys returned by a single enumeration of a store,
+	// the rest being returned page by page. 0 means no limit.
+	StoreScanLimit uint64
 }
 
 func NewRuntimeConfig(
@@ -41,6 +179,13 @@ func NewRuntimeConfig(
 		DefaultCacheTag:            defaultCacheTag,
 		WorkerFactory:              workerFactory,
 		// overridden by Tier Options
-		ModuleExecutionTracing: false,
+		ModuleExecutionTracing:        false,
+		AllowDebugIntermediateOutputs: false,
+		ReservedStoreKeyPrefix:        store.DefaultReservedKeyPrefix,
+		SubrequestMaxRetries:          work.DefaultMaxRetries,
+		SubrequestRetryBackoff:        work.DefaultRetryBackoff,
+		StoreLoadMaxRetries:           store.DefaultLoadRetries,
+		StoreLoadRetryBackoff:         store.DefaultLoadBackoff,
+		StoreScanLimit:                store.DefaultScanLimit,
 	}
 }
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errClientIdle = errors.New("client idle")
+
+// idleSender sends the responses of a stream from a single goroutine, with a single
+// timer armed while a response is being sent and reset after each successful one. When
+// it fires, the client is considered gone: the stream context is canceled, with a cause
+// wrapping `errClientIdle`, and that error is returned by all the sends that follow.
+//
+// A send that timed out keeps running in the background until the stream is torn down.
+type idleSender[T any] struct {
+	send    func(T) error
+	timeout time.Duration
+	timer   *time.Timer
+
+	pending chan T
+	results chan error
+	idle    chan struct{}
+	idleErr error
+}
+
+// newIdleSender returns a sender calling `send`, and the context of the stream, canceled
+// when the client is idle for `timeout`. A 0 timeout waits indefinitely, calling `send`
+// directly. The sender is not safe for concurrent use.
+func newIdleSender[T any](ctx context.Context, send func(T) error, timeout time.Duration) (context.Context, *idleSender[T]) {
+	s := &idleSender[T]{send: send, timeout: timeout}
+	if timeout == 0 {
+		return ctx, s
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	s.pending = make(chan T)
+	s.results = make(chan error, 1)
+	s.idle = make(chan struct{})
+	s.idleErr = fmt.Errorf("client did not read any response for %s: %w", timeout, errClientIdle)
+	s.timer = time.AfterFunc(timeout, func() {
+		close(s.idle)
+		cancel(s.idleErr)
+	})
+	s.timer.Stop()
+
+	go func() {
+		for {
+			select {
+			case resp := <-s.pending:
+				s.results <- s.send(resp)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, s
+}
+
+func (s *idleSender[T]) Send(resp T) error {
+	if s.timer == nil {
+		return s.send(resp)
+	}
+
+	select {
+	case <-s.idle:
+		return s.idleErr
+	default:
+	}
+
+	s.timer.Reset(s.timeout)
+	select {
+	case s.pending <- resp:
+	case <-s.idle:
+		return s.idleErr
+	}
+
+	select {
+	case err := <-s.results:
+		if err == nil {
+			s.timer.Stop()
+		}
+		return err
+	case <-s.idle:
+		return s.idleErr
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleSender(t *testing.T) {
+	var received []int
+	reading := func(resp int) error {
+		received = append(received, resp)
+		return nil
+	}
+	_, unlimited := newIdleSender(context.Background(), reading, 0)
+	require.NoError(t, unlimited.Send(1))
+
+	ctx, sender := newIdleSender(context.Background(), reading, 50*time.Millisecond)
+	require.NoError(t, sender.Send(2))
+	// the timer is not running between sends
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, sender.Send(3))
+	assert.Equal(t, []int{1, 2, 3}, received)
+	assert.NoError(t, ctx.Err())
+
+	stalled := make(chan struct{})
+	defer close(stalled)
+	stalledClient := func(int) error {
+		<-stalled
+		return nil
+	}
+
+	ctx, sender = newIdleSender(context.Background(), stalledClient, 50*time.Millisecond)
+	start := time.Now()
+	err := sender.Send(4)
+	assert.ErrorIs(t, err, errClientIdle)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// the stream is canceled, and no other send is attempted
+	<-ctx.Done()
+	assert.ErrorIs(t, context.Cause(ctx), errClientIdle)
+	assert.ErrorIs(t, sender.Send(5), errClientIdle)
+}
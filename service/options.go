@@ -1,7 +1,12 @@
 package service
 
 import (
+	"time"
+
+	"github.com/streamingfast/substreams/block"
 	"github.com/streamingfast/substreams/pipeline"
+	"github.com/streamingfast/substreams/service/config"
+	"github.com/streamingfast/substreams/storage/store"
 	"github.com/streamingfast/substreams/wasm"
 )
 
@@ -45,6 +50,488 @@ func WithMaxWasmFuelPerBlockModule(maxFuel uint64) Option {
 	}
 }
 
+// WithModuleExecutionConcurrency limits how many independent modules are executed
+// concurrently for a given block, 1 executing them serially.
+func WithModuleExecutionConcurrency(concurrency uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ModuleExecutionConcurrency = concurrency
+		case *Tier2Service:
+			s.runtimeConfig.ModuleExecutionConcurrency = concurrency
+		}
+	}
+}
+
+// WithDebugIntermediateOutputs allows clients to set the `X-Sf-Substreams-Debug-Intermediate-Outputs-Block`
+// header, returning the outputs of all the executed modules for that block.
+func WithDebugIntermediateOutputs() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.AllowDebugIntermediateOutputs = true
+		case *Tier2Service:
+			s.runtimeConfig.AllowDebugIntermediateOutputs = true
+		}
+	}
+}
+
+// WithModuleLogsStreaming allows clients to set the `X-Sf-Substreams-Stream-Module-Logs`
+// header, returning the logs of all the executed modules on every block.
+func WithModuleLogsStreaming() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.AllowModuleLogsStreaming = true
+		}
+	}
+}
+
+// WithStoreSnapshotIntervalOverride allows clients to set the
+// `X-Sf-Substreams-Store-Snapshot-Interval` header, saving the stores of their request
+// at that interval instead of the service's. The snapshots saved off the state bundle
+// boundaries are written to a namespace of the request, not to the shared cache.
+func WithStoreSnapshotIntervalOverride() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.AllowStoreSnapshotIntervalOverride = true
+		}
+	}
+}
+
+// WithDeterminismCheck executes, for development mode requests, the map modules a second
+// time on one block out of `sampleInterval`, flagging the modules whose two outputs
+// differ in the logs and the `substreams_nondeterministic_module_outputs` metric.
+func WithDeterminismCheck(sampleInterval uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.DeterminismCheckInterval = sampleInterval
+		}
+	}
+}
+
+// WithReorgGraceWindow fails the streams going through a reorg which undoes a block
+// outside of the last `blocks` blocks, counting the head, instead of reverting their
+// stores that far: with 1, only the head block can be undone.
+func WithReorgGraceWindow(blocks uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ReorgGraceWindow = blocks
+		}
+	}
+}
+
+// WithSharedExecutions runs once the bounded requests, from the same user, received while
+// an identical one is being executed and has not sent any block yet, they receive the
+// responses of that execution.
+func WithSharedExecutions() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ShareIdenticalExecutions = true
+		}
+	}
+}
+
+// WithMaxStoreDeletedPrefixes fails the store modules deleting more than `max` distinct
+// key prefixes within a segment, which would make merging their partials slow.
+func WithMaxStoreDeletedPrefixes(max uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.MaxStoreDeletedPrefixes = max
+		case *Tier2Service:
+			s.runtimeConfig.MaxStoreDeletedPrefixes = max
+		}
+	}
+}
+
+// WithMaxConcurrentWasmInstantiations throttles the instantiation of wasm modules so that
+// at most `max` of them are instantiated at once, a burst of requests otherwise spiking
+// the memory used.
+func WithMaxConcurrentWasmInstantiations(max uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.MaxConcurrentWasmInstantiations = max
+		case *Tier2Service:
+			s.runtimeConfig.MaxConcurrentWasmInstantiations = max
+		}
+	}
+}
+
+// WithReservedStoreKeyPrefix changes the prefix of the keys store modules cannot write,
+// `__!__` by default, for modules whose keys legitimately start with it. An empty
+// `prefix` lifts the restriction.
+func WithReservedStoreKeyPrefix(prefix string) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ReservedStoreKeyPrefix = prefix
+		case *Tier2Service:
+			s.runtimeConfig.ReservedStoreKeyPrefix = prefix
+		}
+	}
+}
+
+// WithoutStoreSnapshots never writes store snapshots, nor any other object, for the
+// requests served, for endpoints running ephemeral queries. Their stores are instead
+// built in memory from their modules' initial block.
+func WithoutStoreSnapshots() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.DisableStoreSnapshots = true
+		}
+	}
+}
+
+// WithBigFloatStorePrecision merges the partials of `bigfloat` stores with `bits` of
+// precision, for values too large to be summed as decimals without rounding. Stores
+// merged with a precision cannot be merged again with another one.
+func WithBigFloatStorePrecision(bits uint) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.BigFloatStorePrecision = bits
+		case *Tier2Service:
+			s.runtimeConfig.BigFloatStorePrecision = bits
+		}
+	}
+}
+
+// WithStoreAppendCoalescing records the appends of store modules to a same key within a
+// block as a single delta, for modules appending many times to hot keys. Readers of such
+// a key at ordinals between its first and last appends in the block see its value from
+// before the first one.
+func WithStoreAppendCoalescing() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.CoalesceStoreAppends = true
+		case *Tier2Service:
+			s.runtimeConfig.CoalesceStoreAppends = true
+		}
+	}
+}
+
+// WithSubrequestRetryPolicy changes how many times the tier2 sub-requests failing with a
+// transient error, such as an unavailable tier2, are retried, 3 by default, and the
+// initial backoff between their attempts, one second by default.
+func WithSubrequestRetryPolicy(maxRetries uint64, backoff time.Duration) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.SubrequestMaxRetries = maxRetries
+			s.runtimeConfig.SubrequestRetryBackoff = backoff
+		}
+	}
+}
+
+// WithStoreLoadRetryPolicy changes how many times loading a store snapshot failing with a
+// transient storage error is retried, 5 by default, and the initial backoff between the
+// attempts, one second by default.
+func WithStoreLoadRetryPolicy(maxRetries uint64, backoff time.Duration) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StoreLoadMaxRetries = maxRetries
+			s.runtimeConfig.StoreLoadRetryBackoff = backoff
+		case *Tier2Service:
+			s.runtimeConfig.StoreLoadMaxRetries = maxRetries
+			s.runtimeConfig.StoreLoadRetryBackoff = backoff
+		}
+	}
+}
+
+// WithStoreScanLimit caps the number of keys returned by a single enumeration of a
+// store, 10 000 by default, 0 meaning no limit.
+func WithStoreScanLimit(limit uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StoreScanLimit = limit
+		case *Tier2Service:
+			s.runtimeConfig.StoreScanLimit = limit
+		}
+	}
+}
+
+// WithMaxUnsentBlocks caps the number of blocks of a request being processed or waiting
+// for the client in the response buffer, pausing the processing until the client catches
+// up. Without WithResponseBuffer, the responses go through a buffer of `limit` responses
+// never failing on overflow. It does not bound the blocks the block source reads ahead.
+func WithMaxUnsentBlocks(limit uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.MaxUnsentBlocks = limit
+		}
+	}
+}
+
+// WithStoreSpillDirectory keeps the stores' state on local disk, under a
+// request-specific directory created in `dir`, instead of in memory.
+func WithStoreSpillDirectory(dir string) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StoreSpillDirectory = dir
+		case *Tier2Service:
+			s.runtimeConfig.StoreSpillDirectory = dir
+		}
+	}
+}
+
+// WithHeadResolutionTimeout sets how long a request waits for the live source to be
+// ready to resolve the chain's head before failing with an `Unavailable` error, instead
+// of failing right away.
+func WithHeadResolutionTimeout(timeout time.Duration) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.HeadResolutionTimeout = timeout
+		}
+	}
+}
+
+// WithStoreValueValidation makes modules fail as soon as they write a value that
+// does not parse as the store's declared value type, at some performance cost.
+func WithStoreValueValidation() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ValidateStoreValues = true
+		case *Tier2Service:
+			s.runtimeConfig.ValidateStoreValues = true
+		}
+	}
+}
+
+// WithStoreSnapshotAudit reads back every full store snapshot produced by merging
+// partials, compares it with a replay of these partials, deleted only afterwards, and
+// logs an error listing the divergent keys when they do not match.
+func WithStoreSnapshotAudit() Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.AuditStoreSnapshots = true
+		}
+	}
+}
+
+// WithMaxBackprocessingBlocks rejects requests whose backprocessing, from the earliest
+// block needed to the live handoff, spans more than `maxBlocks` blocks.
+func WithMaxBackprocessingBlocks(maxBlocks uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.MaxBackprocessingBlocks = maxBlocks
+		}
+	}
+}
+
+// WithMaxModuleDepth rejects requests whose output module is at the end of an input
+// chain longer than `maxDepth` modules, instead of running overly deep module graphs.
+func WithMaxModuleDepth(maxDepth uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.MaxModuleDepth = maxDepth
+		}
+	}
+}
+
+// WithStoreSnapshotPreload reads, in the background at startup, the latest full snapshot
+// of the stores with the given module hashes into a cache of at most `maxBytes`, from
+// which requests then load them.
+func WithStoreSnapshotPreload(moduleHashes []string, maxBytes uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.PreloadStoreSnapshots = moduleHashes
+			s.runtimeConfig.StoreSnapshotCacheBytes = maxBytes
+		}
+	}
+}
+
+// WithSubrequestModulesReference makes sub-requests reference the request's modules by
+// hash, instead of embedding them, when they serialize to more than `thresholdBytes`.
+// The workers fetch them from the state store, which both tiers must share, and all the
+// workers must support it.
+func WithSubrequestModulesReference(thresholdBytes uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.SubrequestModulesReferenceThreshold = thresholdBytes
+		}
+	}
+}
+
+// WithModuleCacheOverride forces the outputs of the module with hash `moduleHash` to be
+// cached, or to never be, whatever the module declares in its manifest.
+func WithModuleCacheOverride(moduleHash string, cached bool) Option {
+	return func(a anyTierService) {
+		set := func(runtimeConfig *config.RuntimeConfig) {
+			if runtimeConfig.ModuleCacheOverrides == nil {
+				runtimeConfig.ModuleCacheOverrides = make(map[string]bool)
+			}
+			runtimeConfig.ModuleCacheOverrides[moduleHash] = cached
+		}
+		switch s := a.(type) {
+		case *Tier1Service:
+			set(&s.runtimeConfig)
+		case *Tier2Service:
+			set(&s.runtimeConfig)
+		}
+	}
+}
+
+// WithStreamIdleTimeout terminates, with `DeadlineExceeded`, the streams of clients that
+// let a response wait for `timeout` without reading it, freeing the resources they hold.
+func WithStreamIdleTimeout(timeout time.Duration) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StreamIdleTimeout = timeout
+		}
+	}
+}
+
+// WithDisconnectDrainTimeout lets the block being processed when a client disconnects
+// complete for up to `timeout`, its remaining modules are then not executed. By
+// default, they are stopped right away.
+func WithDisconnectDrainTimeout(timeout time.Duration) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.DisconnectDrainTimeout = timeout
+		case *Tier2Service:
+			s.runtimeConfig.DisconnectDrainTimeout = timeout
+		}
+	}
+}
+
+// WithStoreKeyValidation makes modules fail when writing store keys that are not valid
+// UTF-8 or, if `maxLength` is not 0, longer than `maxLength` bytes.
+func WithStoreKeyValidation(maxLength uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ValidateStoreKeys = true
+			s.runtimeConfig.MaxStoreKeyLength = maxLength
+		case *Tier2Service:
+			s.runtimeConfig.ValidateStoreKeys = true
+			s.runtimeConfig.MaxStoreKeyLength = maxLength
+		}
+	}
+}
+
+// WithStoreSnapshotKeyFormatter overrides the naming of the store snapshot objects. Both
+// tiers must be configured with the same formatter, or they won't find each other's snapshots.
+func WithStoreSnapshotKeyFormatter(formatter store.SnapshotKeyFormatter) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StoreSnapshotKeyFormatter = formatter
+		case *Tier2Service:
+			s.runtimeConfig.StoreSnapshotKeyFormatter = formatter
+		}
+	}
+}
+
+// WithStoreSnapshotEncryption makes both tiers write store snapshots encrypted with
+// AES-GCM, under the key returned by `provider` for each request. Plain snapshots written
+// before it was enabled still load, snapshots encrypted with another key fail to load.
+func WithStoreSnapshotEncryption(provider store.SnapshotKeyProvider) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StoreSnapshotKeyProvider = provider
+		case *Tier2Service:
+			s.runtimeConfig.StoreSnapshotKeyProvider = provider
+		}
+	}
+}
+
+// WithStoreMergeConcurrency makes tier1 load up to `concurrency` partial stores in the
+// background while merging the previous ones, overlapping their download with the merges.
+func WithStoreMergeConcurrency(concurrency uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.StoreMergeConcurrency = concurrency
+		}
+	}
+}
+
+// WithExecOutDecodeConcurrency decodes the cached module outputs of each loaded file with
+// up to `concurrency` goroutines instead of serially, preserving their block order.
+func WithExecOutDecodeConcurrency(concurrency int) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ExecOutDecodeConcurrency = concurrency
+		case *Tier2Service:
+			s.runtimeConfig.ExecOutDecodeConcurrency = concurrency
+		}
+	}
+}
+
+// WithMaxStoresMemory caps the approximate memory used by all the stores of a request,
+// the request fails with `ResourceExhausted`, naming the largest stores, when it is exceeded.
+func WithMaxStoresMemory(maxBytes uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.MaxStoresMemoryBytes = maxBytes
+		case *Tier2Service:
+			s.runtimeConfig.MaxStoresMemoryBytes = maxBytes
+		}
+	}
+}
+
+// WithModuleOutputSizeWarning logs a warning, with the module name and the size, for each
+// module output of a block larger than `thresholdBytes`.
+func WithModuleOutputSizeWarning(thresholdBytes uint64) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ModuleOutputSizeWarningBytes = thresholdBytes
+		case *Tier2Service:
+			s.runtimeConfig.ModuleOutputSizeWarningBytes = thresholdBytes
+		}
+	}
+}
+
+// WithResponseBuffer queues up to `highWaterMark` responses per request so that a slow
+// client does not stall the pipeline. Once the buffer is full, the pipeline waits for
+// the client, or the request fails with `ResourceExhausted` when `failOnOverflow` is set.
+func WithResponseBuffer(highWaterMark int, failOnOverflow bool) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ResponseBufferSize = highWaterMark
+			s.runtimeConfig.FailOnResponseBufferOverflow = failOnOverflow
+		}
+	}
+}
+
+// WithCompletedRanges makes tier1 consider the block ranges of `completedRanges`, keyed
+// by module hash, as already processed when planning the sub-requests, trusting that the
+// matching snapshots and outputs exist without looking for them.
+func WithCompletedRanges(completedRanges map[string]block.Ranges) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.CompletedRanges = completedRanges
+		}
+	}
+}
+
 func WithModuleExecutionTracing() Option {
 	return func(a anyTierService) {
 		switch s := a.(type) {
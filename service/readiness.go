@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/streamingfast/bstream/hub"
+	dgrpcserver "github.com/streamingfast/dgrpc/server"
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var readinessCheckInterval = time.Second
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readinessGate turns away requests with `Unavailable` until all of its checks have
+// passed once. It then stays ready, later failures of a dependency are left to the
+// requests using it.
+type readinessGate struct {
+	checks []readinessCheck
+	ready  atomic.Bool
+
+	lock      sync.Mutex
+	notReason error
+}
+
+func newReadinessGate(checks ...readinessCheck) *readinessGate {
+	g := &readinessGate{checks: checks, notReason: errors.New("readiness not checked yet")}
+	if len(checks) == 0 {
+		g.ready.Store(true)
+	}
+	return g
+}
+
+// stateStoreReadinessCheck passes once the state store can be reached.
+func stateStoreReadinessCheck(stateStore dstore.Store) readinessCheck {
+	return readinessCheck{
+		name: "state store",
+		check: func(ctx context.Context) error {
+			_, err := stateStore.FileExists(ctx, "readiness-probe")
+			return err
+		},
+	}
+}
+
+// hubReadinessCheck passes once the forkable hub has bootstrapped from live blocks.
+func hubReadinessCheck(forkableHub *hub.ForkableHub) readinessCheck {
+	return readinessCheck{
+		name: "forkable hub",
+		check: func(ctx context.Context) error {
+			if !forkableHub.IsReady() {
+				return errors.New("not bootstrapped yet")
+			}
+			return nil
+		},
+	}
+}
+
+func (g *readinessGate) isReady() bool {
+	return g == nil || g.ready.Load()
+}
+
+// evaluate runs the checks, in order, and flips the gate to ready if they all pass.
+func (g *readinessGate) evaluate(ctx context.Context) bool {
+	if g.isReady() {
+		return true
+	}
+
+	for _, c := range g.checks {
+		if err := c.check(ctx); err != nil {
+			g.lock.Lock()
+			g.notReason = fmt.Errorf("%s: %w", c.name, err)
+			g.lock.Unlock()
+			return false
+		}
+	}
+	g.ready.Store(true)
+	return true
+}
+
+// run evaluates the gate every `readinessCheckInterval` until it is ready or `done` is closed.
+func (g *readinessGate) run(done <-chan struct{}, logger *zap.Logger) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(readinessCheckInterval)
+	defer ticker.Stop()
+	for !g.evaluate(ctx) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	logger.Info("service is ready to accept requests")
+}
+
+// err returns an `Unavailable` error with the reason the gate is not ready yet, nil once it is.
+func (g *readinessGate) err() error {
+	if g.isReady() {
+		return nil
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return status.Errorf(codes.Unavailable, "service is not ready yet, please retry later: %s", g.notReason)
+}
+
+// healthCheck reports the service as not ready while the gate is closed, deferring
+// to `next`, when not nil, afterwards.
+func (g *readinessGate) healthCheck(next dgrpcserver.HealthCheck) dgrpcserver.HealthCheck {
+	return func(ctx context.Context) (bool, interface{}, error) {
+		if err := g.err(); err != nil {
+			return false, nil, nil
+		}
+		if next == nil {
+			return true, nil, nil
+		}
+		return next(ctx)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	"github.com/streamingfast/substreams/service/config"
+)
+
+func TestTier1Service_ReadinessGate(t *testing.T) {
+	var hubReady atomic.Bool
+	s := TestNewService(config.RuntimeConfig{}, 0, nil)
+	s.readiness = newReadinessGate(
+		stateStoreReadinessCheck(dstore.NewMockStore(nil)),
+		readinessCheck{name: "forkable hub", check: func(ctx context.Context) error {
+			if !hubReady.Load() {
+				return errors.New("not bootstrapped yet")
+			}
+			return nil
+		}},
+	)
+
+	healthCheck := s.readiness.healthCheck(nil)
+	blocks := func() error {
+		return s.Blocks(context.Background(), connect.NewRequest(&pbsubstreamsrpc.Request{}), nil)
+	}
+
+	assert.False(t, s.readiness.evaluate(context.Background()))
+	err := blocks()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, err.Error(), "forkable hub: not bootstrapped yet")
+	healthy, _, _ := healthCheck(context.Background())
+	assert.False(t, healthy)
+
+	defer func(previous time.Duration) { readinessCheckInterval = previous }(readinessCheckInterval)
+	readinessCheckInterval = 5 * time.Millisecond
+	done := make(chan struct{})
+	defer close(done)
+	go s.readiness.run(done, zlog)
+
+	hubReady.Store(true)
+	require.Eventually(t, s.IsReady, time.Second, 5*time.Millisecond)
+
+	// past the gate, the request is now validated
+	assert.Equal(t, codes.InvalidArgument, status.Code(blocks()))
+	healthy, _, _ = healthCheck(context.Background())
+	assert.True(t, healthy)
+}
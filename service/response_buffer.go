@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/streamingfast/substreams"
+)
+
+// responseBuffer sits between the pipeline and the client stream: responses are
+// queued, up to a high-water mark, and sent from a separate goroutine so that a slow
+// client does not stall the pipeline until the queue is full.
+type responseBuffer struct {
+	ctx            context.Context
+	send           substreams.ResponseFunc
+	queue          chan substreams.ResponseFromAnyTier
+	failOnOverflow bool
+
+	closeLock sync.RWMutex
+	closed    bool
+
+	queued   atomic.Uint64 // number of responses accepted by Send
+	sentLock sync.Mutex
+	sent     uint64        // number of responses sent to the client
+	sentTick chan struct{} // closed, then replaced, each time a response is sent
+
+	done chan struct{}
+	err  error // error that stopped the drain loop, readable once `done` is closed
+}
+
+// newResponseBuffer starts draining queued responses to `send`. When more than
+// `highWaterMark` responses are pending, Send blocks until the client catches up,
+// or fails with `ResourceExhausted` if `failOnOverflow` is set.
+func newResponseBuffer(ctx context.Context, send substreams.ResponseFunc, highWaterMark int, failOnOverflow bool) *responseBuffer {
+	b := &responseBuffer{
+		ctx:            ctx,
+		send:           send,
+		queue:          make(chan substreams.ResponseFromAnyTier, highWaterMark),
+		failOnOverflow: failOnOverflow,
+		sentTick:       make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	go b.drain()
+	return b
+}
+
+func (b *responseBuffer) drain() {
+	defer close(b.done)
+	for resp := range b.queue {
+		if err := b.send(resp); err != nil {
+			b.err = err
+			return
+		}
+
+		b.sentLock.Lock()
+		b.sent++
+		close(b.sentTick)
+		b.sentTick = make(chan struct{})
+		b.sentLock.Unlock()
+	}
+}
+
+// waitSent blocks until `count` responses were sent to the client, failing if sending
+// them stops or `ctx` is done first.
+func (b *responseBuffer) waitSent(ctx context.Context, count uint64) error {
+	for {
+		b.sentLock.Lock()
+		sent, tick := b.sent, b.sentTick
+		b.sentLock.Unlock()
+		if sent >= count {
+			return nil
+		}
+
+		select {
+		case <-tick:
+		case <-b.done:
+			if b.err != nil {
+				return b.err
+			}
+			return status.Error(codes.Canceled, "response stream already closed")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Send queues `resp`, it is a `substreams.ResponseFunc`.
+func (b *responseBuffer) Send(resp substreams.ResponseFromAnyTier) error {
+	b.closeLock.RLock()
+	defer b.closeLock.RUnlock()
+
+	if b.closed {
+		return status.Error(codes.Canceled, "response stream already closed")
+	}
+
+	select {
+	case <-b.done:
+		return b.err
+	default:
+	}
+
+	if b.failOnOverflow {
+		select {
+		case b.queue <- resp:
+			b.queued.Add(1)
+			return nil
+		default:
+			return status.Errorf(codes.ResourceExhausted, "client is not consuming responses fast enough, more than %d responses are pending", cap(b.queue))
+		}
+	}
+
+	select {
+	case b.queue <- resp:
+		b.queued.Add(1)
+		return nil
+	case <-b.done:
+		return b.err
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
+
+// Close stops accepting responses and waits until the pending ones are sent, returning
+// the error that interrupted sending them, if any.
+func (b *responseBuffer) Close() error {
+	b.closeLock.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.closeLock.Unlock()
+
+	<-b.done
+	return b.err
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/streamingfast/substreams"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+// slowClient consumes one response each time `release` is signaled.
+type slowClient struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	received []substreams.ResponseFromAnyTier
+}
+
+func (c *slowClient) send(resp substreams.ResponseFromAnyTier) error {
+	<-c.release
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.received = append(c.received, resp)
+	return nil
+}
+
+func (c *slowClient) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.received)
+}
+
+func TestResponseBuffer_SlowConsumer(t *testing.T) {
+	client := &slowClient{release: make(chan struct{})}
+	buffer := newResponseBuffer(context.Background(), client.send, 3, false)
+
+	responses := make([]*pbsubstreamsrpc.Response, 5)
+	for i := range responses {
+		responses[i] = &pbsubstreamsrpc.Response{}
+	}
+
+	// the first response is picked by the drain loop and the next 3 fill the buffer,
+	// none of them wait on the client
+	for _, resp := range responses[:4] {
+		require.NoError(t, buffer.Send(resp))
+	}
+
+	sent := make(chan error)
+	go func() { sent <- buffer.Send(responses[4]) }()
+	select {
+	case <-sent:
+		t.Fatal("send should block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.release <- struct{}{}
+	require.NoError(t, <-sent)
+	assert.Equal(t, 1, client.count())
+
+	go func() {
+		for i := 0; i < 4; i++ {
+			client.release <- struct{}{}
+		}
+	}()
+	require.NoError(t, buffer.Close())
+	require.Len(t, client.received, 5)
+	for i, resp := range responses {
+		assert.Same(t, resp, client.received[i])
+	}
+
+	assert.Equal(t, codes.Canceled, status.Code(buffer.Send(&pbsubstreamsrpc.Response{})))
+}
+
+func TestResponseBuffer_FailOnOverflow(t *testing.T) {
+	client := &slowClient{release: make(chan struct{})}
+	buffer := newResponseBuffer(context.Background(), client.send, 2, true)
+
+	require.NoError(t, buffer.Send(&pbsubstreamsrpc.Response{}))
+	// wait for the drain loop to be stuck on the client with the first response
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, buffer.Send(&pbsubstreamsrpc.Response{}))
+	require.NoError(t, buffer.Send(&pbsubstreamsrpc.Response{}))
+
+	err := buffer.Send(&pbsubstreamsrpc.Response{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	close(client.release)
+	require.NoError(t, buffer.Close())
+	assert.Equal(t, 3, client.count())
+}
@@ -47,7 +47,7 @@ func ListenTier1(
 ) error {
 
 	// note: some of these common options don't work with connectWeb
-	options := GetCommonServerOptions(addr, logger, healthcheck)
+	options := GetCommonServerOptions(addr, logger, svc.readiness.healthCheck(healthcheck))
 
 	options = append(options, dgrpcserver.WithConnectInterceptor(dauthconnect.NewAuthInterceptor(auth, logger)))
 	options = append(options, dgrpcserver.WithConnectStrictContentType(false))
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/streamingfast/dauth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/streamingfast/substreams"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+// sharedExecutionQueueSize is how many responses a request sharing an execution can lag
+// behind it before being dropped, the execution is not throttled on any of them.
+const sharedExecutionQueueSize = 1000
+
+var errNoSubscriberLeft = errors.New("no request left receiving the responses of the shared execution")
+
+var errSubscriberDropped = status.Error(codes.ResourceExhausted, "too slow receiving the responses of the execution shared with identical requests, please reconnect")
+
+// sharedExecutions lets concurrent identical requests share a single execution: the first
+// request starts it and its responses are sent to every request sharing it. Requests can
+// only join an execution until it sends its first block, they first receive the
+// responses sent before they joined.
+type sharedExecutions struct {
+	lock    sync.Mutex
+	running map[string]*sharedExecution
+}
+
+func newSharedExecutions() *sharedExecutions {
+	return &sharedExecutions{
+		running: make(map[string]*sharedExecution),
+	}
+}
+
+type sharedExecution struct {
+	cancel context.CancelCauseFunc
+
+	lock        sync.Mutex
+	joinable    bool                             // until the first block is sent
+	replay      []substreams.ResponseFromAnyTier // sent while joinable, progress aside
+	progress    substreams.ResponseFromAnyTier   // the latest progress sent while joinable
+	subscribers []*subscriber
+
+	finished chan struct{}
+	err      error // readable once `finished` is closed
+}
+
+// subscriber is a request sharing an execution, it receives the responses through its
+// queue, sending them to its client from its own goroutine.
+type subscriber struct {
+	queue    chan substreams.ResponseFromAnyTier
+	dropped  chan struct{} // closed when the queue overflowed
+	dropOnce sync.Once
+}
+
+// drop signals the subscriber it lags too far behind, once whatever the number of
+// concurrent sends finding its queue full.
+func (s *subscriber) drop() {
+	s.dropOnce.Do(func() { close(s.dropped) })
+}
+
+// run executes the request identified by `key`, sending its responses to `send`. When an
+// execution of the same request can still be joined, it subscribes to it instead of
+// starting a new one with `execute`. Executions run on a context detached from the
+// request starting them, they are canceled once no request is left receiving their
+// responses.
+func (s *sharedExecutions) run(ctx context.Context, key string, send substreams.ResponseFunc, execute func(ctx context.Context, send substreams.ResponseFunc) error) error {
+	execution, sub := s.join(ctx, key, execute)
+	defer execution.unsubscribe(sub)
+
+	for {
+		select {
+		case resp := <-sub.queue:
+			if err := send(resp); err != nil {
+				return err
+			}
+		case <-sub.dropped:
+			return errSubscriberDropped
+		case <-execution.finished:
+			select {
+			case <-sub.dropped:
+				// the responses queued are incomplete
+				return errSubscriberDropped
+			default:
+			}
+			// nothing is queued anymore once the execution finished
+			for {
+				select {
+				case resp := <-sub.queue:
+					if err := send(resp); err != nil {
+						return err
+					}
+				default:
+					return execution.err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// join subscribes to the execution of `key` if it can still be joined, or starts a new
+// one running `execute`.
+func (s *sharedExecutions) join(ctx context.Context, key string, execute func(ctx context.Context, send substreams.ResponseFunc) error) (*sharedExecution, *subscriber) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if execution, found := s.running[key]; found {
+		if sub := execution.subscribe(); sub != nil {
+			return execution, sub
+		}
+	}
+
+	// values of the request, like its logger or auth, are kept
+	executionCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	execution := &sharedExecution{
+		cancel:   cancel,
+		joinable: true,
+		finished: make(chan struct{}),
+	}
+	sub := execution.subscribe()
+	s.running[key] = execution
+
+	go func() {
+		err := execute(executionCtx, execution.send)
+		cancel(nil)
+		s.finish(key, execution, err)
+	}()
+	return execution, sub
+}
+
+func (s *sharedExecutions) finish(key string, execution *sharedExecution, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.running[key] == execution {
+		delete(s.running, key)
+	}
+	execution.err = err
+	close(execution.finished)
+}
+
+// subscribe adds a subscriber, its queue holding the responses to replay, or returns nil
+// if the execution can't be joined anymore.
+func (e *sharedExecution) subscribe() *subscriber {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.joinable {
+		return nil
+	}
+
+	sub := &subscriber{
+		queue:   make(chan substreams.ResponseFromAnyTier, len(e.replay)+1+sharedExecutionQueueSize),
+		dropped: make(chan struct{}),
+	}
+	for _, resp := range e.replay {
+		sub.queue <- resp
+	}
+	if e.progress != nil {
+		sub.queue <- e.progress
+	}
+	e.subscribers = append(e.subscribers, sub)
+	return sub
+}
+
+// send is the `substreams.ResponseFunc` of the execution, queuing `resp` for all its
+// subscribers. Those lagging too far behind are dropped, the execution fails when none
+// is left.
+func (e *sharedExecution) send(resp substreams.ResponseFromAnyTier) error {
+	e.lock.Lock()
+	if e.joinable {
+		e.record(resp)
+	}
+	subscribers := make([]*subscriber, len(e.subscribers))
+	copy(subscribers, e.subscribers)
+	e.lock.Unlock()
+
+	if len(subscribers) == 0 {
+		return errNoSubscriberLeft
+	}
+	for _, sub := range subscribers {
+		select {
+		case sub.queue <- resp:
+		default:
+			sub.drop()
+			e.unsubscribe(sub)
+		}
+	}
+	return nil
+}
+
+// record keeps `resp` to replay it to the requests joining later, until the first block
+// is sent. Progress messages hold the full progress, only the latest is kept.
+func (e *sharedExecution) record(resp substreams.ResponseFromAnyTier) {
+	rpcResp, _ := resp.(*pbsubstreamsrpc.Response)
+	switch {
+	case rpcResp.GetBlockScopedData() != nil:
+		e.joinable = false
+		e.replay = nil
+		e.progress = nil
+	case rpcResp.GetProgress() != nil:
+		e.progress = resp
+	default:
+		e.replay = append(e.replay, resp)
+	}
+}
+
+// unsubscribe removes `sub`, canceling the execution when it was the last subscriber.
+func (e *sharedExecution) unsubscribe(sub *subscriber) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for i, s := range e.subscribers {
+		if s == sub {
+			e.subscribers = append(e.subscribers[:i], e.subscribers[i+1:]...)
+			break
+		}
+	}
+	if len(e.subscribers) == 0 {
+		e.joinable = false
+		e.cancel(errNoSubscriberLeft)
+	}
+}
+
+// sharedExecutionHeaderPrefixes are the prefixes of the request headers that identify the
+// user or change the responses of a request, identical requests only share their
+// execution if they have the same values for all of them.
+var sharedExecutionHeaderPrefixes = []string{
+	"x-sf-substreams-",
+	dauth.SFHeaderUserID,
+	dauth.SFHeaderApiKeyID,
+}
+
+// sharedExecutionKey identifies the requests which can share their execution, the same
+// `requestID` with the same headers matching `sharedExecutionHeaderPrefixes`.
+func sharedExecutionKey(ctx context.Context, requestID string) string {
+	key := []string{requestID}
+	if auth := dauth.FromContext(ctx); auth != nil {
+		var headers []string
+		for header, value := range auth {
+			header = strings.ToLower(header)
+			for _, prefix := range sharedExecutionHeaderPrefixes {
+				if strings.HasPrefix(header, prefix) {
+					headers = append(headers, fmt.Sprintf("%s=%s", header, value))
+					break
+				}
+			}
+		}
+		sort.Strings(headers)
+		key = append(key, headers...)
+	}
+	return strings.Join(key, "|")
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/dauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/streamingfast/substreams"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+type recordingClient struct {
+	mu       sync.Mutex
+	received []substreams.ResponseFromAnyTier
+}
+
+func (c *recordingClient) send(resp substreams.ResponseFromAnyTier) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.received = append(c.received, resp)
+	return nil
+}
+
+func TestSharedExecutions_IdenticalRequestsExecuteOnce(t *testing.T) {
+	shared := newSharedExecutions()
+	responses := []*pbsubstreamsrpc.Response{
+		{Message: &pbsubstreamsrpc.Response_Session{Session: &pbsubstreamsrpc.SessionInit{}}},
+		{Message: &pbsubstreamsrpc.Response_Progress{Progress: &pbsubstreamsrpc.ModulesProgress{}}},
+		{Message: &pbsubstreamsrpc.Response_Progress{Progress: &pbsubstreamsrpc.ModulesProgress{}}},
+		{Message: &pbsubstreamsrpc.Response_BlockScopedData{BlockScopedData: &pbsubstreamsrpc.BlockScopedData{}}},
+	}
+
+	var executions atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	execute := func(_ context.Context, send substreams.ResponseFunc) error {
+		executions.Add(1)
+		for _, resp := range responses[:3] {
+			if err := send(resp); err != nil {
+				return err
+			}
+		}
+		close(started)
+		<-release
+		return send(responses[3])
+	}
+
+	clients := []*recordingClient{{}, {}}
+	errs := make(chan error, len(clients))
+	go func() {
+		errs <- shared.run(context.Background(), "request", clients[0].send, execute)
+	}()
+	<-started
+	go func() {
+		errs <- shared.run(context.Background(), "request", clients[1].send, execute)
+	}()
+
+	// the second request is replayed the session and the latest progress
+	require.Eventually(t, func() bool {
+		clients[1].mu.Lock()
+		defer clients[1].mu.Unlock()
+		return len(clients[1].received) == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+
+	for range clients {
+		require.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), executions.Load())
+	assert.Len(t, clients[0].received, len(responses))
+	assert.Equal(t, []substreams.ResponseFromAnyTier{responses[0], responses[2], responses[3]}, clients[1].received)
+
+	// once completed, the same request is executed again
+	require.NoError(t, shared.run(context.Background(), "request", (&recordingClient{}).send, func(context.Context, substreams.ResponseFunc) error {
+		executions.Add(1)
+		return nil
+	}))
+	assert.Equal(t, int32(2), executions.Load())
+}
+
+func TestSharedExecutions_NotJoinableOnceBlocksAreSent(t *testing.T) {
+	shared := newSharedExecutions()
+	sent := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		_ = shared.run(context.Background(), "request", (&recordingClient{}).send, func(_ context.Context, send substreams.ResponseFunc) error {
+			if err := send(&pbsubstreamsrpc.Response{Message: &pbsubstreamsrpc.Response_BlockScopedData{BlockScopedData: &pbsubstreamsrpc.BlockScopedData{}}}); err != nil {
+				return err
+			}
+			close(sent)
+			<-release
+			return nil
+		})
+	}()
+	<-sent
+	defer close(release)
+
+	var executed bool
+	require.NoError(t, shared.run(context.Background(), "request", (&recordingClient{}).send, func(context.Context, substreams.ResponseFunc) error {
+		executed = true
+		return nil
+	}))
+	assert.True(t, executed, "the request runs its own execution")
+}
+
+func TestSharedExecutions_LeaderLeaving(t *testing.T) {
+	shared := newSharedExecutions()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	execute := func(ctx context.Context, send substreams.ResponseFunc) error {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return send(&pbsubstreamsrpc.Response{})
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		leaderErr <- shared.run(leaderCtx, "request", (&recordingClient{}).send, execute)
+	}()
+	<-started
+
+	follower := &recordingClient{}
+	followerErr := make(chan error)
+	go func() {
+		followerErr <- shared.run(context.Background(), "request", follower.send, execute)
+	}()
+	require.Eventually(t, func() bool {
+		shared.lock.Lock()
+		execution := shared.running["request"]
+		shared.lock.Unlock()
+		execution.lock.Lock()
+		defer execution.lock.Unlock()
+		return len(execution.subscribers) == 2
+	}, time.Second, time.Millisecond)
+
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	close(release)
+
+	require.NoError(t, <-followerErr, "the execution is not tied to the request which started it")
+	assert.Len(t, follower.received, 1)
+}
+
+func TestSharedExecutions_SlowSubscriberDropped(t *testing.T) {
+	shared := newSharedExecutions()
+	blocked := make(chan struct{})
+	blockingSend := func(substreams.ResponseFromAnyTier) error {
+		<-blocked
+		return nil
+	}
+
+	err := shared.run(context.Background(), "request", blockingSend, func(_ context.Context, send substreams.ResponseFunc) error {
+		defer close(blocked)
+		for i := 0; i < sharedExecutionQueueSize+3; i++ {
+			if err := send(&pbsubstreamsrpc.Response{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestSharedExecution_ConcurrentSendsDropOnce(t *testing.T) {
+	// rounds give the sends more chances to overlap
+	for round := 0; round < 200; round++ {
+		_, cancel := context.WithCancelCause(context.Background())
+		execution := &sharedExecution{cancel: cancel, joinable: true, finished: make(chan struct{})}
+		// the slow subscriber comes last, so the sends copying the subscribers before it is
+		// dropped are the more likely
+		var healthy []*subscriber
+		for i := 0; i < 100; i++ {
+			healthy = append(healthy, execution.subscribe())
+		}
+		slow := execution.subscribe()
+		for len(slow.queue) < cap(slow.queue) {
+			slow.queue <- &pbsubstreamsrpc.Response{}
+		}
+
+		// the goroutines start together, for several of them to find the slow queue full
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 64; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				assert.NoError(t, execution.send(&pbsubstreamsrpc.Response{}))
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		select {
+		case <-slow.dropped:
+		default:
+			t.Fatal("the slow subscriber should be dropped")
+		}
+		assert.Equal(t, healthy, execution.subscribers)
+		for _, sub := range healthy {
+			assert.Len(t, sub.queue, 64)
+		}
+	}
+}
+
+func TestSharedExecutionKey(t *testing.T) {
+	key := func(headers dauth.TrustedHeaders) string {
+		return sharedExecutionKey(dauth.WithTrustedHeaders(context.Background(), headers), "request")
+	}
+
+	base := key(dauth.TrustedHeaders{"x-sf-user-id": "user", "x-real-ip": "1.1.1.1"})
+	assert.Equal(t, base, key(dauth.TrustedHeaders{"x-sf-user-id": "user", "x-real-ip": "2.2.2.2"}))
+	assert.NotEqual(t, base, key(dauth.TrustedHeaders{"x-sf-user-id": "other", "x-real-ip": "1.1.1.1"}))
+	assert.NotEqual(t, base, key(dauth.TrustedHeaders{"x-sf-user-id": "user", "x-sf-substreams-new-header": "true"}))
+}
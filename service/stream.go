@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/bstream/hub"
@@ -83,7 +84,23 @@ func (sf *StreamFactory) New(
 		options...), nil
 }
 
-func (s *StreamFactory) GetRecentFinalBlock() (uint64, error) {
+// waitHubReady waits for the live hub to be ready, until `ctx` is done.
+func (s *StreamFactory) waitHubReady(ctx context.Context) error {
+	if s.hub == nil || s.hub.IsReady() {
+		return nil
+	}
+	select {
+	case <-s.hub.Ready:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("live hub not ready: %w", ctx.Err())
+	}
+}
+
+func (s *StreamFactory) GetRecentFinalBlock(ctx context.Context) (uint64, error) {
+	if err := s.waitHubReady(ctx); err != nil {
+		return 0, err
+	}
 	_, _, _, finalBlockNum, err := s.hub.HeadInfo()
 	if finalBlockNum > bstream.GetProtocolFirstStreamableBlock+200 {
 		finalBlockNum -= finalBlockNum % 100
@@ -95,7 +112,10 @@ func (s *StreamFactory) GetRecentFinalBlock() (uint64, error) {
 	return finalBlockNum, err
 }
 
-func (s *StreamFactory) GetHeadBlock() (uint64, error) {
+func (s *StreamFactory) GetHeadBlock(ctx context.Context) (uint64, error) {
+	if err := s.waitHubReady(ctx); err != nil {
+		return 0, err
+	}
 	headNum, _, _, _, err := s.hub.HeadInfo()
 	if err != nil {
 		return 0, err
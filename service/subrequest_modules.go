@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/streamingfast/dstore"
+	"google.golang.org/protobuf/proto"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+// subrequestModulesPath is where, in the state store shared by both tiers, the modules
+// referenced by sub-requests are stored, named after their hash.
+const subrequestModulesPath = "subrequest-modules"
+
+// referenceSubrequestModules stores `modules` for the workers and returns their hash when
+// they serialize to more than `threshold` bytes. It returns an empty hash for smaller
+// modules, embedded in the sub-requests as is.
+func referenceSubrequestModules(ctx context.Context, stateStore dstore.Store, modules *pbsubstreams.Modules, threshold uint64) (string, error) {
+	if uint64(proto.Size(modules)) <= threshold {
+		return "", nil
+	}
+
+	cnt, err := proto.MarshalOptions{Deterministic: true}.Marshal(modules)
+	if err != nil {
+		return "", fmt.Errorf("marshalling modules: %w", err)
+	}
+	sum := sha256.Sum256(cnt)
+	hash := hex.EncodeToString(sum[:])
+
+	modulesStore, err := stateStore.SubStore(subrequestModulesPath)
+	if err != nil {
+		return "", fmt.Errorf("getting substore: %w", err)
+	}
+	exists, err := modulesStore.FileExists(ctx, hash)
+	if err != nil {
+		return "", fmt.Errorf("checking modules %s exist: %w", hash, err)
+	}
+	if !exists {
+		if err := modulesStore.WriteObject(ctx, hash, bytes.NewReader(cnt)); err != nil {
+			return "", fmt.Errorf("writing modules %s: %w", hash, err)
+		}
+	}
+	return hash, nil
+}
+
+// subrequestModulesCache fetches the modules referenced by sub-requests, keeping the
+// most recent ones in memory as the same modules are referenced by all the sub-requests
+// of a request.
+type subrequestModulesCache struct {
+	stateStore dstore.Store
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*pbsubstreams.Modules
+	order   []string // oldest first
+}
+
+func newSubrequestModulesCache(stateStore dstore.Store, maxEntries int) *subrequestModulesCache {
+	return &subrequestModulesCache{
+		stateStore: stateStore,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*pbsubstreams.Modules),
+	}
+}
+
+// resolve sets the modules of `request` when it only references them by hash.
+func (c *subrequestModulesCache) resolve(ctx context.Context, request *pbssinternal.ProcessRangeRequest) error {
+	if request.Modules != nil || request.ModulesHash == "" {
+		return nil
+	}
+	modules, err := c.get(ctx, request.ModulesHash)
+	if err != nil {
+		return err
+	}
+	request.Modules = modules
+	return nil
+}
+
+func (c *subrequestModulesCache) get(ctx context.Context, hash string) (*pbsubstreams.Modules, error) {
+	c.mu.Lock()
+	modules, found := c.entries[hash]
+	c.mu.Unlock()
+	if found {
+		return modules, nil
+	}
+
+	modules, err := c.fetch(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found := c.entries[hash]; !found {
+		if len(c.order) >= c.maxEntries {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.entries[hash] = modules
+		c.order = append(c.order, hash)
+	}
+	return modules, nil
+}
+
+func (c *subrequestModulesCache) fetch(ctx context.Context, hash string) (*pbsubstreams.Modules, error) {
+	modulesStore, err := c.stateStore.SubStore(subrequestModulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("getting substore: %w", err)
+	}
+	reader, err := modulesStore.OpenObject(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("opening modules %s: %w", hash, err)
+	}
+	defer reader.Close()
+
+	cnt, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading modules %s: %w", hash, err)
+	}
+	sum := sha256.Sum256(cnt)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("modules %s: content does not match its hash", hash)
+	}
+
+	modules := &pbsubstreams.Modules{}
+	if err := proto.Unmarshal(cnt, modules); err != nil {
+		return nil, fmt.Errorf("unmarshalling modules %s: %w", hash, err)
+	}
+	return modules, nil
+}
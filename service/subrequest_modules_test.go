@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/orchestrator/work"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+)
+
+func TestSubrequestModulesReference(t *testing.T) {
+	ctx := context.Background()
+	stateStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+	threshold := uint64(16 * 1024)
+
+	modules := func(binarySize int) *pbsubstreams.Modules {
+		return &pbsubstreams.Modules{
+			Binaries: []*pbsubstreams.Binary{{Type: "wasm/rust-v1", Content: bytes.Repeat([]byte{1}, binarySize)}},
+			Modules: []*pbsubstreams.Module{{
+				Name: "map_a",
+				Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:sf.substreams.v1.test.Block"}},
+			}},
+		}
+	}
+	workRange := block.NewRange(10, 20)
+
+	large := modules(1024 * 1024)
+	hash, err := referenceSubrequestModules(ctx, stateStore, large, threshold)
+	require.NoError(t, err)
+	require.NotEmpty(t, hash)
+
+	request := work.NewRequest(&reqctx.RequestDetails{Modules: large, OutputModule: "map_a", SubrequestModulesHash: hash}, 0, workRange)
+	assert.Nil(t, request.Modules)
+	assert.Equal(t, hash, request.ModulesHash)
+	assert.Less(t, uint64(proto.Size(request)), threshold)
+
+	cache := newSubrequestModulesCache(stateStore, 32)
+	require.NoError(t, cache.resolve(ctx, request))
+	assert.True(t, proto.Equal(large, request.Modules), "the worker fetches the referenced modules")
+
+	again, err := referenceSubrequestModules(ctx, stateStore, large, threshold)
+	require.NoError(t, err)
+	assert.Equal(t, hash, again, "the same modules have the same reference")
+
+	small := modules(128)
+	hash, err = referenceSubrequestModules(ctx, stateStore, small, threshold)
+	require.NoError(t, err)
+	assert.Empty(t, hash)
+
+	request = work.NewRequest(&reqctx.RequestDetails{Modules: small, OutputModule: "map_a"}, 0, workRange)
+	assert.Equal(t, small, request.Modules, "small modules are embedded")
+	assert.Empty(t, request.ModulesHash)
+}
+
+func TestSubrequestModulesCache_Tampered(t *testing.T) {
+	ctx := context.Background()
+	stateStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+	modulesStore, err := stateStore.SubStore(subrequestModulesPath)
+	require.NoError(t, err)
+
+	hash := "0000000000000000000000000000000000000000000000000000000000000000"
+	require.NoError(t, modulesStore.WriteObject(ctx, hash, bytes.NewReader([]byte("content"))))
+
+	_, err = newSubrequestModulesCache(stateStore, 32).get(ctx, hash)
+	assert.ErrorContains(t, err, "does not match its hash")
+}
@@ -28,7 +28,7 @@ func TestNewService(runtimeConfig config.RuntimeConfig, linearHandoffBlockNum ui
 		blockType:         "sf.substreams.v1.test.Block",
 		streamFactoryFunc: streamFactoryFunc,
 		runtimeConfig:     runtimeConfig,
-		getRecentFinalBlock: func() (uint64, error) {
+		getRecentFinalBlock: func(context.Context) (uint64, error) {
 			if linearHandoffBlockNum != 0 {
 				return linearHandoffBlockNum, nil
 			}
@@ -45,7 +45,7 @@ func (s *Tier1Service) TestBlocks(ctx context.Context, isSubRequest bool, reques
 		return stream.NewErrInvalidArg(err.Error())
 	}
 
-	return s.blocks(ctx, request, outputGraph, respFunc)
+	return s.blocks(ctx, request, outputGraph, respFunc, nil)
 }
 
 func TestNewServiceTier2(runtimeConfig config.RuntimeConfig, streamFactoryFunc StreamFactoryFunc) *Tier2Service {
@@ -55,6 +55,7 @@ func TestNewServiceTier2(runtimeConfig config.RuntimeConfig, streamFactoryFunc S
 		runtimeConfig:     runtimeConfig,
 		tracer:            nil,
 		logger:            zlog,
+		subrequestModules: newSubrequestModulesCache(runtimeConfig.BaseObjectStore, 32),
 	}
 }
 
@@ -62,6 +63,9 @@ func (s *Tier2Service) TestProcessRange(ctx context.Context, request *pbssintern
 	if traceID == nil {
 		traceID = &TestTraceID
 	}
+	if err := s.subrequestModules.resolve(ctx, request); err != nil {
+		return err
+	}
 
 	return s.processRange(ctx, request, respFunc, *traceID)
 }
@@ -6,11 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/streamingfast/bstream"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/streamingfast/bstream/hub"
 	"github.com/streamingfast/bstream/stream"
@@ -22,6 +24,7 @@ import (
 	"github.com/streamingfast/logging"
 	tracing "github.com/streamingfast/sf-tracing"
 	"github.com/streamingfast/shutter"
+	"golang.org/x/exp/maps"
 
 	"github.com/bufbuild/connect-go"
 	"github.com/streamingfast/substreams"
@@ -40,6 +43,7 @@ import (
 	"github.com/streamingfast/substreams/service/config"
 	"github.com/streamingfast/substreams/storage/execout"
 	"github.com/streamingfast/substreams/storage/store"
+	"github.com/streamingfast/substreams/storage/store/deltaformat"
 	"github.com/streamingfast/substreams/wasm"
 	"go.opentelemetry.io/otel/attribute"
 	ttrace "go.opentelemetry.io/otel/trace"
@@ -63,9 +67,18 @@ type Tier1Service struct {
 	tracer             ttrace.Tracer
 	logger             *zap.Logger
 
-	getRecentFinalBlock func() (uint64, error)
+	getRecentFinalBlock pipeline.HeadBlockGetter
 	resolveCursor       pipeline.CursorResolver
-	getHeadBlock        func() (uint64, error)
+	getHeadBlock        pipeline.HeadBlockGetter
+	getStartCursor      pipeline.StartCursorGetter
+
+	readiness *readinessGate // nil is always ready
+
+	snapshotCache *store.SnapshotCache // nil unless store snapshots are preloaded
+
+	sharedExecutions *sharedExecutions // nil unless identical requests share their execution
+
+	wasmInstantiations *wasm.InstantiationLimiter // nil unless instantiations are limited
 }
 
 func NewTier1(
@@ -89,6 +102,7 @@ func NewTier1(
 	logger.Info("creating grpc client factory", zap.Reflect("config", substreamsClientConfig))
 	clientFactory := client.NewInternalClientFactory(substreamsClientConfig)
 
+	var s *Tier1Service
 	runtimeConfig := config.NewRuntimeConfig(
 		stateBundleSize,
 		parallelSubRequests,
@@ -97,16 +111,20 @@ func NewTier1(
 		stateStore,
 		defaultCacheTag,
 		func(logger *zap.Logger) work.Worker {
-			return work.NewRemoteWorker(clientFactory, logger)
+			worker := work.NewRemoteWorker(clientFactory, logger)
+			// workers are created per request, once the options are applied
+			worker.SetRetryPolicy(s.runtimeConfig.SubrequestMaxRetries, s.runtimeConfig.SubrequestRetryBackoff)
+			return worker
 		},
 	)
-	s := &Tier1Service{
+	s = &Tier1Service{
 		Shutter:        shutter.New(),
 		runtimeConfig:  runtimeConfig,
 		blockType:      blockType,
 		tracer:         tracing.GetTracer(),
 		failedRequests: make(map[string]*recordedFailure),
 		resolveCursor:  pipeline.NewCursorResolver(hub, mergedBlocksStore, forkedBlocksStore),
+		getStartCursor: pipeline.NewStartCursorGetter(hub, mergedBlocksStore),
 		logger:         logger,
 	}
 
@@ -119,15 +137,58 @@ func NewTier1(
 	s.getRecentFinalBlock = sf.GetRecentFinalBlock
 	s.getHeadBlock = sf.GetHeadBlock
 
+	checks := []readinessCheck{stateStoreReadinessCheck(stateStore)}
+	if hub != nil {
+		checks = append(checks, hubReadinessCheck(hub))
+	}
+	s.readiness = newReadinessGate(checks...)
+
 	metrics.RegisterMetricSet(logger)
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	go s.readiness.run(s.Terminating(), logger)
+
+	s.wasmInstantiations = wasm.NewInstantiationLimiter(s.runtimeConfig.MaxConcurrentWasmInstantiations)
+
+	if s.runtimeConfig.ShareIdenticalExecutions {
+		s.sharedExecutions = newSharedExecutions()
+	}
+
+	if len(s.runtimeConfig.PreloadStoreSnapshots) != 0 {
+		s.snapshotCache = store.NewSnapshotCache(s.runtimeConfig.StoreSnapshotCacheBytes)
+		preloadCtx, cancelPreload := context.WithCancel(context.Background())
+		s.OnTerminating(func(error) { cancelPreload() })
+		go s.preloadStoreSnapshots(preloadCtx)
+	}
+
 	return s
 }
 
+// preloadStoreSnapshots fills the snapshot cache, it is stopped by the service shutdown.
+func (s *Tier1Service) preloadStoreSnapshots(ctx context.Context) {
+	cacheStore, err := s.runtimeConfig.BaseObjectStore.SubStore(s.runtimeConfig.DefaultCacheTag)
+	if err != nil {
+		s.logger.Warn("cannot preload store snapshots", zap.Error(err))
+		return
+	}
+
+	err = s.snapshotCache.Preload(ctx, cacheStore, s.runtimeConfig.PreloadStoreSnapshots, s.runtimeConfig.StoreSnapshotKeyFormatter, s.logger)
+	if err != nil {
+		s.logger.Warn("preloading store snapshots failed", zap.Error(err))
+		return
+	}
+	s.logger.Info("store snapshots preloaded", zap.Uint64("cache_size", s.snapshotCache.Size()))
+}
+
+// IsReady reports if the service accepts requests, which it does once the state store
+// and the forkable hub are ready.
+func (s *Tier1Service) IsReady() bool {
+	return s.readiness.isReady()
+}
+
 func (s *Tier1Service) BlockType() string {
 	return s.blockType
 }
@@ -138,6 +199,10 @@ func (s *Tier1Service) Blocks(
 	stream *connect.ServerStream[pbsubstreamsrpc.Response],
 ) error {
 
+	if err := s.readiness.err(); err != nil {
+		return err
+	}
+
 	// We keep `err` here as the unaltered error from `blocks` call, this is used in the EndSpan to record the full error
 	// and not only the `grpcError` one which is a subset view of the full `err`.
 	var err error
@@ -154,6 +219,8 @@ func (s *Tier1Service) Blocks(
 	// We need to ensure that the response function is NEVER used after this Blocks handler has returned.
 	// We use a context that will be canceled on defer, and a lock to prevent races. The respFunc is used in various threads
 	mut := sync.Mutex{}
+	// the stream context is canceled when the client stops reading the responses
+	ctx, sender := newIdleSender(ctx, stream.Send, s.runtimeConfig.StreamIdleTimeout)
 	respContext, cancel := context.WithCancel(ctx)
 	defer func() {
 		mut.Lock()
@@ -161,7 +228,12 @@ func (s *Tier1Service) Blocks(
 		mut.Unlock()
 	}()
 
-	respFunc := tier1ResponseHandler(respContext, &mut, logger, stream)
+	respFunc := tier1ResponseHandler(respContext, &mut, logger, sender.Send)
+	var respBuffer *responseBuffer
+	if size, failOnOverflow := responseBufferSettings(s.runtimeConfig); size > 0 {
+		respBuffer = newResponseBuffer(respContext, respFunc, size, failOnOverflow)
+		respFunc = respBuffer.Send
+	}
 
 	span.SetAttributes(attribute.Int64("substreams.tier", 1))
 
@@ -200,7 +272,7 @@ func (s *Tier1Service) Blocks(
 		return status.Error(codes.InvalidArgument, fmt.Errorf("validate request: %w", err).Error())
 	}
 
-	outputGraph, err := outputmodules.NewOutputModuleGraph(request.OutputModule, request.ProductionMode, request.Modules)
+	outputGraph, err := outputmodules.NewOutputModuleGraphWithMaxDepth(request.OutputModule, request.ProductionMode, request.Modules, s.runtimeConfig.MaxModuleDepth)
 	if err != nil {
 		return bsstream.NewErrInvalidArg(err.Error())
 	}
@@ -221,19 +293,22 @@ func (s *Tier1Service) Blocks(
 		return err
 	}
 
-	// On app shutdown, we cancel the running '.blocks()' command,
-	// we catch this situation via IsTerminating() to return a special error.
-	runningContext, cancelRunning := context.WithCancelCause(ctx)
-	go func() {
-		select {
-		case <-ctx.Done():
-			return
-		case <-s.Terminating():
-			cancelRunning(fmt.Errorf("endpoint is shutting down, please reconnect"))
-		}
-	}()
+	runningContext := s.cancelOnTermination(ctx)
 
-	err = s.blocks(runningContext, request, outputGraph, respFunc)
+	if s.sharedExecutions != nil && !pipeline.IsUnboundedRequest(request, isExplicitStopBlock(ctx)) {
+		err = s.sharedExecutions.run(runningContext, sharedExecutionKey(ctx, requestID), respFunc, func(executionCtx context.Context, respFunc substreams.ResponseFunc) error {
+			// responses fan out to several clients, the stream is not throttled on any of them
+			return s.blocks(s.cancelOnTermination(executionCtx), request, outputGraph, respFunc, nil)
+		})
+	} else {
+		err = s.blocks(runningContext, request, outputGraph, respFunc, respBuffer)
+	}
+	if respBuffer != nil {
+		// responses still in the buffer were produced before `blocks` returned, they must reach the client first
+		if flushErr := respBuffer.Close(); err == nil {
+			err = flushErr
+		}
+	}
 
 	if grpcError := toGRPCError(runningContext, err); grpcError != nil {
 		switch status.Code(grpcError) {
@@ -254,6 +329,21 @@ func (s *Tier1Service) Blocks(
 	return nil
 }
 
+// cancelOnTermination returns a context canceled with `ctx`, or on app shutdown, in which
+// case its cause is an error asking the client to reconnect.
+func (s *Tier1Service) cancelOnTermination(ctx context.Context) context.Context {
+	out, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-out.Done():
+			return
+		case <-s.Terminating():
+			cancel(fmt.Errorf("endpoint is shutting down, please reconnect"))
+		}
+	}()
+	return out
+}
+
 func (s *Tier1Service) writePackage(ctx context.Context, request *pbsubstreamsrpc.Request, outputGraph *outputmodules.Graph) error {
 	asPackage := &pbsubstreams.Package{
 		Modules:    request.Modules,
@@ -282,13 +372,26 @@ func (s *Tier1Service) writePackage(ctx context.Context, request *pbsubstreamsrp
 	return nil
 }
 
+// isExplicitStopBlock tells if the client asked for its stop block to be taken as is,
+// even when 0, see `pipeline.IsUnboundedRequest`.
+func isExplicitStopBlock(ctx context.Context) bool {
+	if auth := dauth.FromContext(ctx); auth != nil {
+		return auth.Get("X-Sf-Substreams-Explicit-Stop-Block") == "true"
+	}
+	return false
+}
+
 var IsValidCacheTag = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString
 
-func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Request, outputGraph *outputmodules.Graph, respFunc substreams.ResponseFunc) error {
+// blocks runs the request, sending its responses to `respFunc`. When they go through
+// `respBuffer`, the blocks waiting for the client are limited per MaxUnsentBlocks.
+func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Request, outputGraph *outputmodules.Graph, respFunc substreams.ResponseFunc, respBuffer *responseBuffer) error {
+	unbounded := pipeline.IsUnboundedRequest(request, isExplicitStopBlock(ctx))
+
 	chainFirstStreamableBlock := bstream.GetProtocolFirstStreamableBlock
 	if request.StartBlockNum >= 0 && request.StartBlockNum < int64(chainFirstStreamableBlock) {
 		return stream.NewErrInvalidArg("invalid start block %d, must be >= %d (the first streamable block of the chain)", request.StartBlockNum, chainFirstStreamableBlock)
-	} else if request.StartBlockNum < 0 && request.StopBlockNum > 0 {
+	} else if request.StartBlockNum < 0 && !unbounded {
 		if int64(request.StopBlockNum)+int64(request.StartBlockNum) < int64(chainFirstStreamableBlock) {
 			request.StartBlockNum = int64(chainFirstStreamableBlock)
 		}
@@ -298,14 +401,21 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 
 	logger := reqctx.Logger(ctx)
 
-	requestDetails, undoSignal, err := pipeline.BuildRequestDetails(ctx, request, s.getRecentFinalBlock, s.resolveCursor, s.getHeadBlock)
+	getRecentFinalBlock := pipeline.WithHeadResolutionTimeout(ctx, s.getRecentFinalBlock, s.runtimeConfig.HeadResolutionTimeout)
+	getHeadBlock := pipeline.WithHeadResolutionTimeout(ctx, s.getHeadBlock, s.runtimeConfig.HeadResolutionTimeout)
+	requestDetails, undoSignal, err := pipeline.BuildRequestDetails(ctx, request, unbounded, getRecentFinalBlock, s.resolveCursor, getHeadBlock)
 	if err != nil {
 		return fmt.Errorf("build request details: %w", err)
 	}
 
 	requestDetails.MaxParallelJobs = s.runtimeConfig.DefaultParallelSubrequests
 	requestDetails.CacheTag = s.runtimeConfig.DefaultCacheTag
+	requestDetails.DisableStoreSnapshots = s.runtimeConfig.DisableStoreSnapshots
+	var requestedUncachedModules string
+	var startAck bool
 	if auth := dauth.FromContext(ctx); auth != nil {
+		requestedUncachedModules = auth.Get("X-Sf-Substreams-Uncached-Modules")
+
 		if parallelJobs := auth.Get("X-Sf-Substreams-Parallel-Jobs"); parallelJobs != "" {
 			if ll, err := strconv.ParseUint(parallelJobs, 10, 64); err == nil {
 				requestDetails.MaxParallelJobs = ll
@@ -319,10 +429,151 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 			}
 		}
 
+		if debugBlock := auth.Get("X-Sf-Substreams-Debug-Intermediate-Outputs-Block"); debugBlock != "" {
+			if !s.runtimeConfig.AllowDebugIntermediateOutputs {
+				return stream.NewErrInvalidArg("debugging intermediate outputs is not allowed on this endpoint")
+			}
+			blockNum, err := strconv.ParseUint(debugBlock, 10, 64)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Debug-Intermediate-Outputs-Block %q: %s", debugBlock, err)
+			}
+			requestDetails.DebugIntermediateOutputs = true
+			requestDetails.DebugIntermediateOutputsAtBlock = blockNum
+		}
+
+		if snapshotInterval := auth.Get("X-Sf-Substreams-Store-Snapshot-Interval"); snapshotInterval != "" {
+			if !s.runtimeConfig.AllowStoreSnapshotIntervalOverride {
+				return stream.NewErrInvalidArg("overriding the store snapshot interval is not allowed on this endpoint")
+			}
+			interval, err := strconv.ParseUint(snapshotInterval, 10, 64)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Store-Snapshot-Interval %q: %s", snapshotInterval, err)
+			}
+			if interval == 0 {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Store-Snapshot-Interval %q: must be a positive number of blocks", snapshotInterval)
+			}
+			requestDetails.StoreSnapshotSaveInterval = interval
+		}
+
+		if streamLogs := auth.Get("X-Sf-Substreams-Stream-Module-Logs"); streamLogs != "" {
+			enabled, err := strconv.ParseBool(streamLogs)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Stream-Module-Logs %q: %s", streamLogs, err)
+			}
+			if enabled && !s.runtimeConfig.AllowModuleLogsStreaming {
+				return stream.NewErrInvalidArg("streaming module logs is not allowed on this endpoint")
+			}
+			requestDetails.StreamModuleLogs = enabled
+		}
+
+		if errorsAsData := auth.Get("X-Sf-Substreams-Errors-As-Data"); errorsAsData != "" {
+			enabled, err := strconv.ParseBool(errorsAsData)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Errors-As-Data %q: %s", errorsAsData, err)
+			}
+			requestDetails.ErrorsAsData = enabled
+		}
+
+		if skipEmpty := auth.Get("X-Sf-Substreams-Skip-Empty-Outputs"); skipEmpty != "" {
+			enabled, err := strconv.ParseBool(skipEmpty)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Skip-Empty-Outputs %q: %s", skipEmpty, err)
+			}
+			requestDetails.SkipEmptyCachedOutputs = enabled
+		}
+
+		if disableSnapshots := auth.Get("X-Sf-Substreams-Disable-Store-Snapshots"); disableSnapshots != "" {
+			disabled, err := strconv.ParseBool(disableSnapshots)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Disable-Store-Snapshots %q: %s", disableSnapshots, err)
+			}
+			requestDetails.DisableStoreSnapshots = disabled || s.runtimeConfig.DisableStoreSnapshots
+		}
+
+		if ack := auth.Get("X-Sf-Substreams-Start-Ack"); ack != "" {
+			enabled, err := strconv.ParseBool(ack)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Start-Ack %q: %s", ack, err)
+			}
+			startAck = enabled
+		}
+
+		if deltaOrder := auth.Get("X-Sf-Substreams-Deterministic-Delta-Order"); deltaOrder != "" {
+			enabled, err := strconv.ParseBool(deltaOrder)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Deterministic-Delta-Order %q: %s", deltaOrder, err)
+			}
+			requestDetails.DeterministicDeltaOrder = enabled
+		}
+
+		if heartbeat := auth.Get("X-Sf-Substreams-Progress-Heartbeat-Interval"); heartbeat != "" {
+			interval, err := time.ParseDuration(heartbeat)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Progress-Heartbeat-Interval %q: %s", heartbeat, err)
+			}
+			if interval < pipeline.MinProgressHeartbeatInterval {
+				return stream.NewErrInvalidArg("X-Sf-Substreams-Progress-Heartbeat-Interval must be at least %s", pipeline.MinProgressHeartbeatInterval)
+			}
+			requestDetails.ProgressHeartbeatInterval = interval
+		}
+
+		if waitForStores := auth.Get("X-Sf-Substreams-Wait-For-Stores"); waitForStores != "" {
+			timeout, err := time.ParseDuration(waitForStores)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Wait-For-Stores %q: %s", waitForStores, err)
+			}
+			requestDetails.WaitForStoresTimeout = timeout
+		}
+
+		if storeOutputModes := auth.Get("X-Sf-Substreams-Store-Output-Modes"); storeOutputModes != "" {
+			modes, err := pipeline.ParseStoreOutputModes(storeOutputModes)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Store-Output-Modes: %s", err)
+			}
+			requestDetails.StoreOutputModes = modes
+		}
+
+		if storeDeltaFormat := auth.Get("X-Sf-Substreams-Store-Delta-Format"); storeDeltaFormat != "" {
+			format, err := deltaformat.ParseFormat(storeDeltaFormat)
+			if err != nil {
+				return stream.NewErrInvalidArg("invalid value for X-Sf-Substreams-Store-Delta-Format: %s", err)
+			}
+			requestDetails.StoreDeltaFormat = format
+		}
+	}
+
+	requestDetails.UncachedModules = pipeline.ResolveUncachedModules(requestedUncachedModules, outputGraph, s.runtimeConfig.ModuleCacheOverrides)
+	if requestDetails.ProductionMode && requestDetails.UncachedModules[request.OutputModule] {
+		// There are no cached outputs to stream, so the output module runs live from the start
+		// block and only its stores are backprocessed, like in development mode.
+		requestDetails.LinearHandoffBlockNum = min(requestDetails.LinearHandoffBlockNum, requestDetails.ResolvedStartBlockNum)
+		outputGraph, err = outputmodules.NewOutputModuleGraph(request.OutputModule, false, request.Modules)
+		if err != nil {
+			return stream.NewErrInvalidArg(err.Error())
+		}
+	}
+	if requestDetails.DisableStoreSnapshots {
+		// Nothing is read from nor written to the cache, all the modules run live from the
+		// linear handoff block, brought back to the stores' initial block so they are built
+		// in memory.
+		if requestDetails.ProductionMode {
+			outputGraph, err = outputmodules.NewOutputModuleGraph(request.OutputModule, false, request.Modules)
+			if err != nil {
+				return stream.NewErrInvalidArg(err.Error())
+			}
+		}
+		requestDetails.LinearHandoffBlockNum = requestDetails.ResolvedStartBlockNum
+		if len(outputGraph.Stores()) != 0 {
+			requestDetails.LinearHandoffBlockNum = min(requestDetails.LinearHandoffBlockNum, outputGraph.LowestInitBlock())
+		}
+		if limit := s.runtimeConfig.MaxBackprocessingBlocks; limit != 0 && requestDetails.ResolvedStartBlockNum-requestDetails.LinearHandoffBlockNum > limit {
+			return status.Errorf(codes.ResourceExhausted, "request requires processing %d blocks to build its stores, over the maximum of %d allowed: use a later start block or enable store snapshots", requestDetails.ResolvedStartBlockNum-requestDetails.LinearHandoffBlockNum, limit)
+		}
 	}
 
 	var requestStats *metrics.Stats
 	ctx, requestStats = setupRequestStats(ctx, requestDetails, outputGraph, false)
+	requestStats.Start(10 * time.Second)
 	defer requestStats.LogAndClose()
 
 	traceId := tracing.GetTraceID(ctx).String()
@@ -342,32 +593,110 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 		ctx = reqctx.WithModuleExecutionTracing(ctx)
 	}
 
-	if err := s.writePackage(ctx, request, outputGraph); err != nil {
-		logger.Warn("cannot write package", zap.Error(err))
+	if !requestDetails.DisableStoreSnapshots {
+		if err := s.writePackage(ctx, request, outputGraph); err != nil {
+			logger.Warn("cannot write package", zap.Error(err))
+		}
 	}
 
-	if err := outputGraph.ValidateRequestStartBlock(requestDetails.ResolvedStartBlockNum); err != nil {
+	if err := outputGraph.ValidateEffectiveStartBlock(requestDetails.ResolvedStartBlockNum); err != nil {
+		return stream.NewErrInvalidArg(err.Error())
+	}
+	if err := outputGraph.ValidateRequestStopBlock(requestDetails.StopBlockNum, requestDetails.Unbounded); err != nil {
+		return stream.NewErrInvalidArg(err.Error())
+	}
+	if err := outputGraph.ValidateStoreOutputModules(maps.Keys(requestDetails.StoreOutputModes), requestDetails.ProductionMode); err != nil {
 		return stream.NewErrInvalidArg(err.Error())
 	}
 
 	wasmRuntime := wasm.NewRegistry(s.wasmExtensions, s.runtimeConfig.MaxWasmFuel)
+	wasmRuntime.SetInstantiationLimiter(s.wasmInstantiations)
 
 	cacheStore, err := s.runtimeConfig.BaseObjectStore.SubStore(requestDetails.CacheTag)
 	if err != nil {
 		return fmt.Errorf("internal error setting store: %w", err)
 	}
 
+	if threshold := s.runtimeConfig.SubrequestModulesReferenceThreshold; threshold > 0 {
+		requestDetails.SubrequestModulesHash, err = referenceSubrequestModules(ctx, s.runtimeConfig.BaseObjectStore, requestDetails.Modules, threshold)
+		if err != nil {
+			return fmt.Errorf("storing modules for sub-requests: %w", err)
+		}
+	}
+
 	execOutputConfigs, err := execout.NewConfigs(cacheStore, outputGraph.UsedModules(), outputGraph.ModuleHashes(), s.runtimeConfig.StateBundleSize, logger)
 	if err != nil {
 		return fmt.Errorf("new config map: %w", err)
 	}
+	execOutputConfigs.DisableCaching(maps.Keys(requestDetails.UncachedModules)...)
+	execOutputConfigs.SetDecodeConcurrency(s.runtimeConfig.ExecOutDecodeConcurrency)
 
 	storeConfigs, err := store.NewConfigMap(cacheStore, outputGraph.Stores(), outputGraph.ModuleHashes(), tracing.GetTraceID(ctx).String())
 	if err != nil {
 		return fmt.Errorf("configuring stores: %w", err)
 	}
+	if s.runtimeConfig.StoreSpillDirectory != "" {
+		spillDir, err := os.MkdirTemp(s.runtimeConfig.StoreSpillDirectory, "stores-")
+		if err != nil {
+			return fmt.Errorf("creating store spill directory: %w", err)
+		}
+		defer os.RemoveAll(spillDir)
+		defer func() {
+			if err := storeConfigs.Close(); err != nil {
+				logger.Warn("unable to close spilled stores", zap.Error(err))
+			}
+		}()
+		storeConfigs.SetSpillDirectory(spillDir)
+	}
+	storeConfigs.SetValueTypeValidation(s.runtimeConfig.ValidateStoreValues)
+	storeConfigs.SetKeyValidation(s.runtimeConfig.ValidateStoreKeys, s.runtimeConfig.MaxStoreKeyLength)
+	storeConfigs.SetMaxDeletedPrefixes(s.runtimeConfig.MaxStoreDeletedPrefixes)
+	storeConfigs.SetReservedKeyPrefix(s.runtimeConfig.ReservedStoreKeyPrefix)
+	storeConfigs.SetBigFloatPrecision(s.runtimeConfig.BigFloatStorePrecision)
+	storeConfigs.SetAppendCoalescing(s.runtimeConfig.CoalesceStoreAppends)
+	storeConfigs.SetScanLimit(s.runtimeConfig.StoreScanLimit)
+	storeConfigs.SetLoadRetryPolicy(s.runtimeConfig.StoreLoadMaxRetries, s.runtimeConfig.StoreLoadRetryBackoff)
+	storeConfigs.SetSnapshotKeyFormatter(s.runtimeConfig.StoreSnapshotKeyFormatter)
+	if interval := requestDetails.StoreSnapshotSaveInterval; interval != 0 && interval%s.runtimeConfig.StateBundleSize != 0 {
+		// snapshots off the bundle boundaries of tier2 are not shared through the cache
+		if err := storeConfigs.SetOffGridSnapshots(cacheStore, s.runtimeConfig.StateBundleSize); err != nil {
+			return fmt.Errorf("configuring store snapshots: %w", err)
+		}
+	}
+	if provider := s.runtimeConfig.StoreSnapshotKeyProvider; provider != nil {
+		key, err := provider(ctx)
+		if err != nil {
+			return fmt.Errorf("getting store snapshot encryption key: %w", err)
+		}
+		snapshotCipher, err := store.NewSnapshotCipher(key)
+		if err != nil {
+			return fmt.Errorf("configuring store snapshot encryption: %w", err)
+		}
+		storeConfigs.SetSnapshotCipher(snapshotCipher)
+	}
+	storeConfigs.SetMergeConcurrency(s.runtimeConfig.StoreMergeConcurrency)
+	storeConfigs.SetSnapshotCache(s.snapshotCache)
+	storeConfigs.SetSnapshotAudit(s.runtimeConfig.AuditStoreSnapshots)
+	if limit := s.runtimeConfig.MaxStoresMemoryBytes; limit > 0 {
+		storeConfigs.SetMemoryBudget(store.NewMemoryBudget(limit))
+	}
+
+	if timeout := requestDetails.WaitForStoresTimeout; timeout > 0 && !requestDetails.DisableStoreSnapshots {
+		upToBlock := requestDetails.LinearHandoffBlockNum - requestDetails.LinearHandoffBlockNum%s.runtimeConfig.StateBundleSize
+		ready, err := pipeline.WaitForStoresReady(ctx, storeConfigs, upToBlock, timeout)
+		if err != nil {
+			return fmt.Errorf("waiting for stores: %w", err)
+		}
+		if !ready {
+			logger.Info("stores not ready before timeout, backprocessing them", zap.Uint64("up_to_block", upToBlock), zap.Duration("timeout", timeout))
+		}
+	}
 
-	stores := pipeline.NewStores(ctx, storeConfigs, s.runtimeConfig.StateBundleSize, requestDetails.LinearHandoffBlockNum, request.StopBlockNum, false)
+	storeSnapshotSaveInterval := s.runtimeConfig.StateBundleSize
+	if requestDetails.StoreSnapshotSaveInterval != 0 {
+		storeSnapshotSaveInterval = requestDetails.StoreSnapshotSaveInterval
+	}
+	stores := pipeline.NewStores(ctx, storeConfigs, storeSnapshotSaveInterval, requestDetails.LinearHandoffBlockNum, request.StopBlockNum, false)
 
 	execOutputCacheEngine, err := cache.NewEngine(ctx, s.runtimeConfig, nil, s.blockType)
 	if err != nil {
@@ -386,6 +715,20 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 	if request.FinalBlocksOnly {
 		opts = append(opts, pipeline.WithFinalBlocksOnly())
 	}
+	if startAck {
+		startCursor := requestDetails.ResolvedCursor
+		if startCursor == "" {
+			// cursors on final blocks resolve to a block number, they remain valid to resume from
+			startCursor = request.StartCursor
+		}
+		if startCursor == "" {
+			startCursor, err = s.getStartCursor(ctx, requestDetails.ResolvedStartBlockNum)
+			if err != nil {
+				return fmt.Errorf("building start cursor: %w", err)
+			}
+		}
+		opts = append(opts, pipeline.WithStartAcknowledgement(startCursor))
+	}
 
 	pipe := pipeline.New(
 		ctx,
@@ -407,11 +750,16 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 
 	scheduleStores := outputGraph.StagedUsedModules()[0].LastLayer().IsStoreLayer()
 
+	planStartBlock := requestDetails.ResolvedStartBlockNum
+	if requestDetails.DisableStoreSnapshots {
+		// the blocks before the start block are processed live instead of backprocessed
+		planStartBlock = requestDetails.LinearHandoffBlockNum
+	}
 	reqPlan, err := plan.BuildTier1RequestPlan(
-		requestDetails.ProductionMode,
+		requestDetails.ProductionMode && !requestDetails.UncachedModules[request.OutputModule] && !requestDetails.DisableStoreSnapshots,
 		s.runtimeConfig.StateBundleSize,
 		outputGraph.LowestInitBlock(),
-		requestDetails.ResolvedStartBlockNum,
+		planStartBlock,
 		requestDetails.LinearHandoffBlockNum,
 		requestDetails.StopBlockNum,
 		scheduleStores,
@@ -419,6 +767,9 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 	if err != nil {
 		return fmt.Errorf("error building request plan: %w", err)
 	}
+	if err := reqPlan.ValidateBackprocessSpan(s.runtimeConfig.MaxBackprocessingBlocks); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
 
 	logger.Info("initializing tier1 pipeline",
 		zap.Stringer("plan", reqPlan),
@@ -453,9 +804,14 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 		zap.String("cursor", cursor),
 	)
 
+	var handler bstream.Handler = pipe
+	if limit := s.runtimeConfig.MaxUnsentBlocks; limit != 0 && respBuffer != nil {
+		handler = newUnsentBlocks(ctx, pipe, respBuffer, int(limit))
+	}
+
 	blockStream, err := s.streamFactoryFunc(
 		ctx,
-		pipe,
+		handler,
 		int64(requestDetails.LinearHandoffBlockNum),
 		request.StopBlockNum,
 		cursor,
@@ -482,7 +838,21 @@ func (s *Tier1Service) buildPipelineOptions(ctx context.Context) (opts []pipelin
 	return
 }
 
-func tier1ResponseHandler(ctx context.Context, mut *sync.Mutex, logger *zap.Logger, streamSrv *connect.ServerStream[pbsubstreamsrpc.Response]) substreams.ResponseFunc {
+// responseBufferSettings returns the size of the response buffer of a request, 0 for
+// none, and whether it fails on overflow. MaxUnsentBlocks counting the blocks whose
+// responses are still in the buffer, it brings one of MaxUnsentBlocks responses when
+// none is configured, waiting for the client when full as sending directly would.
+func responseBufferSettings(cfg config.RuntimeConfig) (size int, failOnOverflow bool) {
+	if cfg.ResponseBufferSize > 0 {
+		return cfg.ResponseBufferSize, cfg.FailOnResponseBufferOverflow
+	}
+	if cfg.MaxUnsentBlocks != 0 {
+		return int(cfg.MaxUnsentBlocks), false
+	}
+	return 0, false
+}
+
+func tier1ResponseHandler(ctx context.Context, mut *sync.Mutex, logger *zap.Logger, send func(*pbsubstreamsrpc.Response) error) substreams.ResponseFunc {
 	auth := dauth.FromContext(ctx)
 	userID := auth.UserID()
 	apiKeyID := auth.APIKeyID()
@@ -498,7 +868,11 @@ func tier1ResponseHandler(ctx context.Context, mut *sync.Mutex, logger *zap.Logg
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		if err := streamSrv.Send(resp); err != nil {
+		if err := send(resp); err != nil {
+			if errors.Is(err, errClientIdle) {
+				logger.Info("client stopped reading responses, terminating stream", zap.Error(err))
+				return status.Error(codes.DeadlineExceeded, err.Error())
+			}
 			logger.Info("unable to send block probably due to client disconnecting", zap.Error(err))
 			return status.Error(codes.Unavailable, err.Error())
 		}
@@ -547,6 +921,9 @@ func toGRPCError(ctx context.Context, err error) error {
 		if context.Cause(ctx) != nil {
 			err = context.Cause(ctx)
 		}
+		if errors.Is(err, errClientIdle) {
+			return status.Error(codes.DeadlineExceeded, err.Error())
+		}
 		return status.Error(codes.Canceled, err.Error())
 	}
 
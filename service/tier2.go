@@ -26,6 +26,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	ttrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -39,6 +40,9 @@ type Tier2Service struct {
 	runtimeConfig     config.RuntimeConfig
 	tracer            ttrace.Tracer
 	logger            *zap.Logger
+
+	subrequestModules  *subrequestModulesCache
+	wasmInstantiations *wasm.InstantiationLimiter // nil unless instantiations are limited
 }
 
 func NewTier2(
@@ -64,10 +68,11 @@ func NewTier2(
 		nil,
 	)
 	s = &Tier2Service{
-		runtimeConfig: runtimeConfig,
-		blockType:     blockType,
-		tracer:        tracing.GetTracer(),
-		logger:        logger,
+		runtimeConfig:     runtimeConfig,
+		blockType:         blockType,
+		tracer:            tracing.GetTracer(),
+		logger:            logger,
+		subrequestModules: newSubrequestModulesCache(stateStore, 32),
 	}
 
 	sf := &StreamFactory{
@@ -82,6 +87,8 @@ func NewTier2(
 		opt(s)
 	}
 
+	s.wasmInstantiations = wasm.NewInstantiationLimiter(s.runtimeConfig.MaxConcurrentWasmInstantiations)
+
 	return s
 }
 
@@ -117,6 +124,10 @@ func (s *Tier2Service) ProcessRange(request *pbssinternal.ProcessRangeRequest, s
 	hostname := updateStreamHeadersHostname(streamSrv.SetHeader, logger)
 	span.SetAttributes(attribute.String("hostname", hostname))
 
+	if err := s.subrequestModules.resolve(ctx, request); err != nil {
+		logger.Warn("cannot fetch the modules referenced by the request", zap.String("modules_hash", request.ModulesHash), zap.Error(err))
+		return status.Error(codes.Unavailable, fmt.Sprintf("fetching modules %s: %s", request.ModulesHash, err))
+	}
 	if request.Modules == nil {
 		return status.Error(codes.InvalidArgument, "missing modules in request")
 	}
@@ -180,7 +191,9 @@ func (s *Tier2Service) processRange(ctx context.Context, request *pbssinternal.P
 	}
 
 	requestDetails.CacheTag = s.runtimeConfig.DefaultCacheTag
+	var requestedUncachedModules string
 	if auth := dauth.FromContext(ctx); auth != nil {
+		requestedUncachedModules = auth.Get("X-Sf-Substreams-Uncached-Modules")
 		if cacheTag := auth.Get("X-Sf-Substreams-Cache-Tag"); cacheTag != "" {
 			if IsValidCacheTag(cacheTag) {
 				requestDetails.CacheTag = cacheTag
@@ -189,16 +202,18 @@ func (s *Tier2Service) processRange(ctx context.Context, request *pbssinternal.P
 			}
 		}
 	}
+	requestDetails.UncachedModules = pipeline.ResolveUncachedModules(requestedUncachedModules, outputGraph, s.runtimeConfig.ModuleCacheOverrides)
 
 	var requestStats *metrics.Stats
 	ctx, requestStats = setupRequestStats(ctx, requestDetails, outputGraph, true)
 	defer requestStats.LogAndClose()
 
-	if err := outputGraph.ValidateRequestStartBlock(requestDetails.ResolvedStartBlockNum); err != nil {
+	if err := outputGraph.ValidateEffectiveStartBlock(requestDetails.ResolvedStartBlockNum); err != nil {
 		return stream.NewErrInvalidArg(err.Error())
 	}
 
 	wasmRuntime := wasm.NewRegistry(s.wasmExtensions, s.runtimeConfig.MaxWasmFuel)
+	wasmRuntime.SetInstantiationLimiter(s.wasmInstantiations)
 
 	cacheStore, err := s.runtimeConfig.BaseObjectStore.SubStore(requestDetails.CacheTag)
 	if err != nil {
@@ -209,11 +224,49 @@ func (s *Tier2Service) processRange(ctx context.Context, request *pbssinternal.P
 	if err != nil {
 		return fmt.Errorf("new config map: %w", err)
 	}
+	execOutputConfigs.DisableCaching(maps.Keys(requestDetails.UncachedModules)...)
+	execOutputConfigs.SetDecodeConcurrency(s.runtimeConfig.ExecOutDecodeConcurrency)
 
 	storeConfigs, err := store.NewConfigMap(cacheStore, outputGraph.Stores(), outputGraph.ModuleHashes(), traceID)
 	if err != nil {
 		return fmt.Errorf("configuring stores: %w", err)
 	}
+	if s.runtimeConfig.StoreSpillDirectory != "" {
+		spillDir, err := os.MkdirTemp(s.runtimeConfig.StoreSpillDirectory, "stores-")
+		if err != nil {
+			return fmt.Errorf("creating store spill directory: %w", err)
+		}
+		defer os.RemoveAll(spillDir)
+		defer func() {
+			if err := storeConfigs.Close(); err != nil {
+				logger.Warn("unable to close spilled stores", zap.Error(err))
+			}
+		}()
+		storeConfigs.SetSpillDirectory(spillDir)
+	}
+	storeConfigs.SetValueTypeValidation(s.runtimeConfig.ValidateStoreValues)
+	storeConfigs.SetKeyValidation(s.runtimeConfig.ValidateStoreKeys, s.runtimeConfig.MaxStoreKeyLength)
+	storeConfigs.SetMaxDeletedPrefixes(s.runtimeConfig.MaxStoreDeletedPrefixes)
+	storeConfigs.SetReservedKeyPrefix(s.runtimeConfig.ReservedStoreKeyPrefix)
+	storeConfigs.SetBigFloatPrecision(s.runtimeConfig.BigFloatStorePrecision)
+	storeConfigs.SetAppendCoalescing(s.runtimeConfig.CoalesceStoreAppends)
+	storeConfigs.SetScanLimit(s.runtimeConfig.StoreScanLimit)
+	storeConfigs.SetLoadRetryPolicy(s.runtimeConfig.StoreLoadMaxRetries, s.runtimeConfig.StoreLoadRetryBackoff)
+	storeConfigs.SetSnapshotKeyFormatter(s.runtimeConfig.StoreSnapshotKeyFormatter)
+	if provider := s.runtimeConfig.StoreSnapshotKeyProvider; provider != nil {
+		key, err := provider(ctx)
+		if err != nil {
+			return fmt.Errorf("getting store snapshot encryption key: %w", err)
+		}
+		snapshotCipher, err := store.NewSnapshotCipher(key)
+		if err != nil {
+			return fmt.Errorf("configuring store snapshot encryption: %w", err)
+		}
+		storeConfigs.SetSnapshotCipher(snapshotCipher)
+	}
+	if limit := s.runtimeConfig.MaxStoresMemoryBytes; limit > 0 {
+		storeConfigs.SetMemoryBudget(store.NewMemoryBudget(limit))
+	}
 	stores := pipeline.NewStores(ctx, storeConfigs, s.runtimeConfig.StateBundleSize, requestDetails.ResolvedStartBlockNum, request.StopBlockNum, true)
 
 	outputModule := outputGraph.OutputModule()
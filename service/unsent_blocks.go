@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/streamingfast/bstream"
+)
+
+// unsentBlocks throttles the processing of a request's blocks so that at most `limit`
+// of them are unsent at once: the one being processed, plus the ones whose responses
+// still wait in the response buffer. Processing resumes once the client catches up,
+// bounding the responses buffered whatever the speed of the client. The blocks the
+// block source reads ahead are not counted.
+type unsentBlocks struct {
+	bstream.Handler
+
+	ctx    context.Context
+	buffer *responseBuffer
+	limit  int
+	unsent []uint64 // per unsent block, the number of responses that must be sent to release it
+}
+
+// newUnsentBlocks wraps `handler`, the blocks it processes sending their responses
+// through `buffer`.
+func newUnsentBlocks(ctx context.Context, handler bstream.Handler, buffer *responseBuffer, limit int) *unsentBlocks {
+	return &unsentBlocks{
+		Handler: handler,
+		ctx:     ctx,
+		buffer:  buffer,
+		limit:   limit,
+	}
+}
+
+func (b *unsentBlocks) ProcessBlock(blk *bstream.Block, obj interface{}) error {
+	// the block about to be processed counts as unsent
+	for len(b.unsent) >= b.limit {
+		if err := b.buffer.waitSent(b.ctx, b.unsent[0]); err != nil {
+			return err
+		}
+		b.unsent = b.unsent[1:]
+	}
+
+	if err := b.Handler.ProcessBlock(blk, obj); err != nil {
+		return err
+	}
+	b.unsent = append(b.unsent, b.buffer.queued.Load())
+	return nil
+}
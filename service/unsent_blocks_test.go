@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams"
+	"github.com/streamingfast/substreams/service/config"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+type handlerFunc func(blk *bstream.Block, obj interface{}) error
+
+func (f handlerFunc) ProcessBlock(blk *bstream.Block, obj interface{}) error { return f(blk, obj) }
+
+func TestUnsentBlocks_Limit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &slowClient{release: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case client.release <- struct{}{}:
+				time.Sleep(time.Millisecond)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	// the buffer alone would let all the blocks pile up
+	buffer := newResponseBuffer(ctx, client.send, 100, false)
+
+	processed, maxHeld := 0, 0
+	limiter := newUnsentBlocks(ctx, handlerFunc(func(blk *bstream.Block, obj interface{}) error {
+		processed++
+		maxHeld = max(maxHeld, processed-client.count())
+		return buffer.Send(&pbsubstreamsrpc.Response{})
+	}), buffer, 3)
+
+	for i := uint64(0); i < 50; i++ {
+		require.NoError(t, limiter.ProcessBlock(&bstream.Block{Number: i}, nil))
+	}
+	require.NoError(t, buffer.Close())
+
+	assert.Equal(t, 50, client.count())
+	assert.Equal(t, 3, maxHeld)
+}
+
+func TestUnsentBlocks_ClientGone(t *testing.T) {
+	buffer := newResponseBuffer(context.Background(), func(resp substreams.ResponseFromAnyTier) error {
+		return assert.AnError
+	}, 10, false)
+
+	limiter := newUnsentBlocks(context.Background(), handlerFunc(func(blk *bstream.Block, obj interface{}) error {
+		return buffer.Send(&pbsubstreamsrpc.Response{})
+	}), buffer, 1)
+
+	require.NoError(t, limiter.ProcessBlock(&bstream.Block{Number: 1}, nil))
+	assert.Equal(t, assert.AnError, limiter.ProcessBlock(&bstream.Block{Number: 2}, nil))
+}
+
+func TestResponseBufferSettings(t *testing.T) {
+	tests := []struct {
+		name               string
+		cfg                config.RuntimeConfig
+		wantSize           int
+		wantFailOnOverflow bool
+	}{
+		{"none", config.RuntimeConfig{}, 0, false},
+		{"buffer", config.RuntimeConfig{ResponseBufferSize: 10, FailOnResponseBufferOverflow: true}, 10, true},
+		{"buffer with unsent blocks", config.RuntimeConfig{ResponseBufferSize: 10, MaxUnsentBlocks: 3}, 10, false},
+		{"unsent blocks alone", config.RuntimeConfig{MaxUnsentBlocks: 3}, 3, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size, failOnOverflow := responseBufferSettings(test.cfg)
+			assert.Equal(t, test.wantSize, size)
+			assert.Equal(t, test.wantFailOnOverflow, failOnOverflow)
+		})
+	}
+}
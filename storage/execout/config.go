@@ -21,6 +21,8 @@ type Config struct {
 
 	modKind            pbsubstreams.ModuleKind
 	moduleInitialBlock uint64
+	uncached           bool // outputs are never written to nor read from the cache
+	decodeConcurrency  int  // goroutines decoding the outputs of a loaded file
 
 	logger *zap.Logger
 }
@@ -48,14 +50,35 @@ func (c *Config) NewFile(targetRange *block.Range) *File {
 		store:      c.objStore,
 		Range:      targetRange,
 		logger:     c.logger,
+		uncached:   c.uncached,
+
+		decodeConcurrency: c.decodeConcurrency,
 	}
 }
 
+// SetDecodeConcurrency makes loaded files decode their outputs with up to `concurrency`
+// goroutines, 0 or 1 decoding them serially.
+func (c *Config) SetDecodeConcurrency(concurrency int) {
+	c.decodeConcurrency = concurrency
+}
+
+// DisableCaching makes the module outputs always recomputed: files of the module
+// are neither saved nor loaded, and none are listed.
+func (c *Config) DisableCaching() {
+	c.uncached = true
+}
+
+func (c *Config) Cached() bool { return !c.uncached }
+
 func (c *Config) Name() string                        { return c.name }
 func (c *Config) ModuleKind() pbsubstreams.ModuleKind { return c.modKind }
 func (c *Config) ModuleInitialBlock() uint64          { return c.moduleInitialBlock }
 
 func (c *Config) ListSnapshotFiles(ctx context.Context, inRange *bstream.Range) (files FileInfos, err error) {
+	if c.uncached {
+		return nil, nil
+	}
+
 	err = derr.RetryContext(ctx, 3, func(ctx context.Context) error {
 		// We must reset accumulated files between each retry
 		files = nil
@@ -50,3 +50,21 @@ func (c *Configs) NewFile(moduleName string, targetRange *block.Range) *File {
 func (c *Configs) NewFileWalker(moduleName string, segmenter *block.Segmenter) *FileWalker {
 	return c.ConfigMap[moduleName].NewFileWalker(segmenter)
 }
+
+// DisableCaching turns off caching of the outputs of `moduleNames`, names that are
+// not part of the requested modules are ignored.
+func (c *Configs) DisableCaching(moduleNames ...string) {
+	for _, name := range moduleNames {
+		if conf, found := c.ConfigMap[name]; found {
+			conf.DisableCaching()
+		}
+	}
+}
+
+// SetDecodeConcurrency sets the decoding concurrency of the files of all modules, see
+// `Config.SetDecodeConcurrency`.
+func (c *Configs) SetDecodeConcurrency(concurrency int) {
+	for _, conf := range c.ConfigMap {
+		conf.SetDecodeConcurrency(concurrency)
+	}
+}
@@ -0,0 +1,131 @@
+package execout
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pboutput "github.com/streamingfast/substreams/storage/execout/pb"
+)
+
+// decodeItems decodes the items of a cache file, as written by `pboutput.Map.MarshalFast`,
+// with up to `concurrency` goroutines, and returns them in the order they appear in the
+// file. Errors name the block of the item that failed to decode. With a concurrency of
+// 0 or 1, items are decoded serially.
+func decodeItems(data []byte, concurrency int) ([]*pboutput.Item, error) {
+	if concurrency <= 1 {
+		array := &pboutput.Array{}
+		if err := array.UnmarshalVTNoAlloc(data); err == nil {
+			return array.Items, nil
+		}
+		// fall through to find out which item failed
+		concurrency = 1
+	}
+
+	raw, err := splitItems(data)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*pboutput.Item, len(raw))
+	errs := make([]error, len(raw))
+	workers := min(concurrency, len(raw))
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		// each worker decodes a contiguous chunk, items are small enough that handing
+		// them out one by one costs more than decoding them
+		go func(from, to int) {
+			defer wg.Done()
+			for i := from; i < to; i++ {
+				item := &pboutput.Item{}
+				if err := item.UnmarshalVTNoAlloc(raw[i]); err != nil {
+					errs[i] = fmt.Errorf("decoding cached output of %s: %w", describeItem(raw[i], i), err)
+					continue
+				}
+				items[i] = item
+			}
+		}(w*len(raw)/workers, (w+1)*len(raw)/workers)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// splitItems returns the encoded items of a cache file, without decoding them.
+func splitItems(data []byte) (out [][]byte, err error) {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("reading field of item %d: %w", len(out), protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if num == 1 && typ == protowire.BytesType {
+			item, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, fmt.Errorf("reading item %d: %w", len(out), protowire.ParseError(n))
+			}
+			out = append(out, item)
+			data = data[n:]
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return nil, fmt.Errorf("skipping field %d: %w", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+	return out, nil
+}
+
+// describeItem names the block of an encoded item, as far as it can be read, for
+// error messages.
+func describeItem(item []byte, index int) string {
+	var blockNum uint64
+	var blockID string
+	var foundNum bool
+	for len(item) > 0 {
+		num, typ, n := protowire.ConsumeTag(item)
+		if n < 0 {
+			break
+		}
+		item = item[n:]
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(item)
+			if n < 0 {
+				break
+			}
+			blockNum, foundNum = v, true
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(item)
+			if n < 0 {
+				break
+			}
+			blockID = v
+		}
+		n = protowire.ConsumeFieldValue(num, typ, item)
+		if n < 0 {
+			break
+		}
+		item = item[n:]
+	}
+
+	switch {
+	case foundNum && blockID != "":
+		return fmt.Sprintf("block #%d (%s)", blockNum, blockID)
+	case foundNum:
+		return fmt.Sprintf("block #%d", blockNum)
+	case blockID != "":
+		return fmt.Sprintf("block %s", blockID)
+	}
+	return fmt.Sprintf("item %d, block unknown", index)
+}
@@ -0,0 +1,113 @@
+package execout
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/streamingfast/substreams/block"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	pboutput "github.com/streamingfast/substreams/storage/execout/pb"
+)
+
+func testCacheFile(t testing.TB, itemCount int, payloadSize int) []byte {
+	kv := make(map[string]*pboutput.Item, itemCount)
+	for i := 0; i < itemCount; i++ {
+		id := fmt.Sprintf("%da", i)
+		payload := make([]byte, payloadSize)
+		for j := range payload {
+			payload[j] = byte(i + j)
+		}
+		kv[id] = &pboutput.Item{BlockNum: uint64(i), BlockId: id, Timestamp: timestamppb.Now(), Payload: payload}
+	}
+	data, err := (&pboutput.Map{Kv: kv}).MarshalFast()
+	require.NoError(t, err)
+	return data
+}
+
+func TestFile_ParallelDecodePreservesOrder(t *testing.T) {
+	ctx := context.Background()
+	objStore := dstore.NewMockStore(nil)
+	config := &Config{name: "A", objStore: objStore, logger: zlog}
+
+	file := config.NewFile(block.NewRange(0, 1000))
+	for i := uint64(0); i < 1000; i++ {
+		file.SetItem(&pbsubstreams.Clock{Id: fmt.Sprintf("%da", i), Number: i}, []byte(fmt.Sprintf("output %d", i)))
+	}
+	require.NoError(t, file.Save(ctx))
+
+	serial := config.NewFile(block.NewRange(0, 1000))
+	require.NoError(t, serial.Load(ctx))
+
+	config.SetDecodeConcurrency(8)
+	parallel := config.NewFile(block.NewRange(0, 1000))
+	require.NoError(t, parallel.Load(ctx))
+
+	serialItems, parallelItems := serial.SortedItems(), parallel.SortedItems()
+	require.Len(t, parallelItems, 1000)
+	for i, item := range parallelItems {
+		assert.Equal(t, uint64(i), item.BlockNum)
+		assert.Equal(t, serialItems[i].BlockId, item.BlockId)
+		assert.Equal(t, serialItems[i].Payload, item.Payload)
+	}
+}
+
+func Test_decodeItems(t *testing.T) {
+	data := testCacheFile(t, 100, 16)
+
+	serial, err := decodeItems(data, 1)
+	require.NoError(t, err)
+	parallel, err := decodeItems(data, 4)
+	require.NoError(t, err)
+	require.Len(t, parallel, len(serial))
+	for i := range serial {
+		assert.Equal(t, serial[i].BlockNum, parallel[i].BlockNum, "items are returned in file order")
+		assert.Equal(t, serial[i].Payload, parallel[i].Payload)
+	}
+
+	empty, err := decodeItems(nil, 4)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func Test_decodeItemsErrorNamesBlock(t *testing.T) {
+	good, err := (&pboutput.Item{BlockNum: 11, BlockId: "11a", Payload: []byte("ok")}).MarshalVT()
+	require.NoError(t, err)
+	bad, err := (&pboutput.Item{BlockNum: 12, BlockId: "12a"}).MarshalVT()
+	require.NoError(t, err)
+	bad = protowire.AppendTag(bad, 3, protowire.BytesType)
+	bad = protowire.AppendVarint(bad, 100) // payload length past the end of the item
+
+	var data []byte
+	for _, item := range [][]byte{good, bad} {
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendBytes(data, item)
+	}
+
+	for _, concurrency := range []int{0, 4} {
+		_, err = decodeItems(data, concurrency)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "decoding cached output of block #12 (12a)")
+	}
+}
+
+func BenchmarkDecodeItems(b *testing.B) {
+	data := testCacheFile(b, 1000, 4096)
+
+	for _, concurrency := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency_%d", concurrency), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeItems(data, concurrency); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
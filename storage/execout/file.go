@@ -33,6 +33,9 @@ type File struct {
 	kv         map[string]*pboutput.Item
 	store      dstore.Store
 	logger     *zap.Logger
+	uncached   bool
+
+	decodeConcurrency int
 }
 
 func (c *File) Filename() string {
@@ -51,6 +54,9 @@ func (c *File) SortedItems() (out []*pboutput.Item) {
 	return
 }
 
+// SetItem records the module output for the given block. Empty outputs are
+// recorded too, so that a block for which the module produced nothing is
+// served as cached-empty on later requests instead of being recomputed.
 func (c *File) SetItem(clock *pbsubstreams.Clock, data []byte) {
 	c.Lock()
 	defer c.Unlock()
@@ -80,7 +86,7 @@ func (c *File) Get(clock *pbsubstreams.Clock) ([]byte, bool) {
 		return nil, false
 	}
 
-	return cacheItem.Payload, found
+	return itemPayload(cacheItem), found
 }
 
 func (c *File) GetAtBlock(blockNumber uint64) ([]byte, bool) {
@@ -89,14 +95,30 @@ func (c *File) GetAtBlock(blockNumber uint64) ([]byte, bool) {
 
 	for _, value := range c.kv {
 		if value.BlockNum == blockNumber {
-			return value.Payload, true
+			return itemPayload(value), true
 		}
 	}
 
 	return nil, false
 }
 
+// itemPayload returns the payload of a cached item, never nil: an item
+// with no payload is a cached-empty output, which callers must be able to tell
+// apart from a missing one.
+func itemPayload(item *pboutput.Item) []byte {
+	if item.Payload == nil {
+		return []byte{}
+	}
+	return item.Payload
+}
+
+// Load reads the file from the cache, returning `dstore.ErrNotFound` when the
+// module is not cached.
 func (c *File) Load(ctx context.Context) error {
+	if c.uncached {
+		return dstore.ErrNotFound
+	}
+
 	filename := computeDBinFilename(c.Range.StartBlock, c.Range.ExclusiveEndBlock)
 	c.logger.Debug("loading execout file", zap.String("file_name", filename), zap.Object("block_range", c.Range))
 
@@ -116,12 +138,15 @@ func (c *File) Load(ctx context.Context) error {
 			return fmt.Errorf("reading store file %s: %w", filename, err)
 		}
 
-		outputData := &pboutput.Map{}
-		if err = outputData.UnmarshalFast(bytes); err != nil {
+		items, err := decodeItems(bytes, c.decodeConcurrency)
+		if err != nil {
 			return fmt.Errorf("unmarshalling file %s: %w", filename, err)
 		}
 
-		c.kv = outputData.Kv
+		c.kv = make(map[string]*pboutput.Item, len(items))
+		for _, item := range items {
+			c.kv[item.BlockId] = item
+		}
 
 		c.logger.Debug("outputs data loaded", zap.Int("output_count", len(c.kv)), zap.Stringer("block_range", c.Range))
 		return nil
@@ -129,6 +154,9 @@ func (c *File) Load(ctx context.Context) error {
 }
 
 func (c *File) Save(ctx context.Context) error {
+	if c.uncached {
+		return nil
+	}
 
 	filename := c.Filename()
 	outputData := &pboutput.Map{Kv: c.kv}
@@ -0,0 +1,85 @@
+package execout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/block"
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestFile_EmptyOutputServedFromCache(t *testing.T) {
+	ctx := context.Background()
+	objStore := dstore.NewMockStore(nil)
+	config := &Config{name: "A", objStore: objStore, logger: zlog}
+
+	emptyClock := &pbsubstreams.Clock{Id: "10a", Number: 10}
+	fullClock := &pbsubstreams.Clock{Id: "11a", Number: 11}
+
+	first := config.NewFile(block.NewRange(10, 20))
+	first.SetItem(emptyClock, nil)
+	first.SetItem(fullClock, []byte("data"))
+	require.NoError(t, first.Save(ctx))
+
+	second := config.NewFile(block.NewRange(10, 20))
+	require.NoError(t, second.Load(ctx))
+
+	payload, found := second.Get(emptyClock)
+	require.True(t, found, "empty output should be cached")
+	assert.NotNil(t, payload)
+	assert.Len(t, payload, 0)
+
+	payload, found = second.GetAtBlock(10)
+	require.True(t, found)
+	assert.Len(t, payload, 0)
+
+	payload, found = second.Get(fullClock)
+	require.True(t, found)
+	assert.Equal(t, []byte("data"), payload)
+
+	_, found = second.Get(&pbsubstreams.Clock{Id: "12a", Number: 12})
+	assert.False(t, found)
+}
+
+func TestConfigs_DisableCaching(t *testing.T) {
+	ctx := context.Background()
+	objStore := dstore.NewMockStore(nil)
+	modules := []*pbsubstreams.Module{
+		{Name: "cheap", Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}},
+		{Name: "expensive", Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{}}},
+	}
+	configs, err := NewConfigs(objStore, modules, manifest.NewModuleHashes(), 10, zlog)
+	require.NoError(t, err)
+	configs.DisableCaching("cheap", "unknown")
+
+	assert.False(t, configs.ConfigMap["cheap"].Cached())
+	assert.True(t, configs.ConfigMap["expensive"].Cached())
+
+	clock := &pbsubstreams.Clock{Id: "10a", Number: 10}
+	for _, name := range []string{"cheap", "expensive"} {
+		file := configs.NewFile(name, block.NewRange(10, 20))
+		file.SetItem(clock, []byte(name))
+		require.NoError(t, file.Save(ctx))
+	}
+
+	expensive := configs.NewFile("expensive", block.NewRange(10, 20))
+	require.NoError(t, expensive.Load(ctx))
+	payload, found := expensive.Get(clock)
+	require.True(t, found)
+	assert.Equal(t, []byte("expensive"), payload)
+
+	cheap := configs.NewFile("cheap", block.NewRange(10, 20))
+	assert.Equal(t, dstore.ErrNotFound, cheap.Load(ctx))
+	_, found = cheap.Get(clock)
+	assert.False(t, found)
+
+	files, err := configs.ConfigMap["cheap"].ListSnapshotFiles(ctx, bstream.NewInclusiveRange(0, 20))
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+)
+
+// appendBuffer accumulates the appends to a key within a block when appends are
+// coalesced, see `Config.SetAppendCoalescing`. The state holds `value` all along, the
+// single delta for the key being only recorded when the buffer is flushed.
+type appendBuffer struct {
+	oldValue []byte // value of the key before the first append, nil when absent
+	oldFound bool
+	value    []byte // grown in place, with spare capacity, by each append
+	ordinal  uint64 // ordinal of the last append
+}
+
+// appendCoalesced appends `value` to the buffer of `key`, starting one from the current
+// value of the key on its first append of the block. No delta is recorded until the
+// buffer is flushed, so neither the appends nor the reads in between go through them.
+func (b *baseStore) appendCoalesced(ord uint64, key string, value []byte) error {
+	buf, found := b.appendBuffers[key]
+	if !found {
+		if err := b.checkKey(key); err != nil {
+			return err
+		}
+		oldValue, oldFound := b.GetAt(ord, key)
+		buf = &appendBuffer{
+			oldValue: oldValue,
+			oldFound: oldFound,
+			value:    append(make([]byte, 0, 2*(len(oldValue)+len(value))), oldValue...),
+		}
+	}
+
+	newLen := uint64(len(buf.value) + len(value))
+	if (found || buf.oldFound) && b.appendLimit > 0 && newLen >= b.appendLimit {
+		return fmt.Errorf("append would exceed limit of %d bytes", b.appendLimit)
+	}
+	if newLen > b.itemSizeLimit {
+		panic(fmt.Sprintf("key %q attempted to write %d bytes (capped at %d)", key, newLen, b.itemSizeLimit))
+	}
+
+	newValue := append(buf.value, value...)
+	b.checkValueType(key, newValue)
+
+	b.bumpOrdinal(ord)
+
+	if b.appendBuffers == nil {
+		b.appendBuffers = make(map[string]*appendBuffer)
+	}
+	b.appendBuffers[key] = buf
+	buf.value = newValue
+	buf.ordinal = ord
+
+	defer b.trackSize()
+	b.setKV(key, buf.value)
+	if b.totalSizeBytes > b.totalSizeLimit {
+		panic(fmt.Sprintf("store %q became too big at %d, maximum size: %d", b.Name(), b.totalSizeBytes, b.totalSizeLimit))
+	}
+	return nil
+}
+
+// flushAppend records the delta of the appends buffered for `key`, if any, so the
+// operations that follow see it like any other write.
+func (b *baseStore) flushAppend(key string) {
+	buf, found := b.appendBuffers[key]
+	if !found {
+		return
+	}
+	delete(b.appendBuffers, key)
+
+	delta := &pbssinternal.StoreDelta{
+		Operation: pbssinternal.StoreDelta_CREATE,
+		Ordinal:   buf.ordinal,
+		Key:       key,
+		NewValue:  buf.value[:len(buf.value):len(buf.value)],
+	}
+	if buf.oldFound {
+		delta.Operation = pbssinternal.StoreDelta_UPDATE
+		delta.OldValue = buf.oldValue
+	}
+	metrics.CountStoreDelta(b.name, delta.Operation)
+
+	// writes to other keys may have happened since, the delta goes after the ones of
+	// its ordinal to keep them ordered
+	at := sort.Search(len(b.deltas), func(i int) bool { return b.deltas[i].Ordinal > delta.Ordinal })
+	b.deltas = append(b.deltas, nil)
+	copy(b.deltas[at+1:], b.deltas[at:])
+	b.deltas[at] = delta
+}
+
+// flushAppends flushes the buffers of all the keys starting with `prefix`, in key order.
+func (b *baseStore) flushAppends(prefix string) {
+	if len(b.appendBuffers) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(b.appendBuffers))
+	for key := range b.appendBuffers {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.flushAppend(key)
+	}
+}
+
+// appendedFirst returns the value `key` had before the appends buffered for it, if any.
+func (b *baseStore) appendedFirst(key string) (val []byte, found bool, buffered bool) {
+	buf, buffered := b.appendBuffers[key]
+	if !buffered {
+		return nil, false, false
+	}
+	return buf.oldValue, buf.oldFound, true
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"fmt"
+	"maps"
+	"sync/atomic"
 
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
@@ -13,11 +15,16 @@ import (
 type baseStore struct {
 	*Config
 
-	kv             map[string][]byte          // kv is the state, and assumes all deltas were already applied to it.
+	kv             kvBackend                  // kv is the state, and assumes all deltas were already applied to it.
 	deltas         []*pbssinternal.StoreDelta // deltas are always deltas for the given block.
 	lastOrdinal    uint64
 	marshaller     marshaller.Marshaller
 	totalSizeBytes uint64
+	budgetCounter  *atomic.Int64 // this store's share of the memory budget, once registered
+
+	appendBuffers           map[string]*appendBuffer // appends of the block not recorded as deltas yet, when coalesced
+	loadedBigFloatPrecision *uint                    // bigfloat precision recorded in the snapshot last loaded, nil when it had no metadata
+	frozenReason            string                   // when set, the store is frozen and writes are rejected
 
 	logger *zap.Logger
 }
@@ -34,7 +41,7 @@ func (b *baseStore) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("name", b.name)
 	enc.AddString("hash", b.moduleHash)
 	enc.AddUint64("module_initial_block", b.moduleInitialBlock)
-	enc.AddInt("key_count", len(b.kv))
+	enc.AddInt("key_count", b.kv.Len())
 	enc.AddUint64("total_size_bytes", b.totalSizeBytes)
 
 	return nil
@@ -42,13 +49,97 @@ func (b *baseStore) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 
 func (b *baseStore) Reset() {
 	if tracer.Enabled() {
-		b.logger.Debug("flushing store", zap.Int("delta_count", len(b.deltas)), zap.Int("entry_count", len(b.kv)), zap.Uint64("total_size_bytes", b.totalSizeBytes))
+		b.logger.Debug("flushing store", zap.Int("delta_count", len(b.deltas)), zap.Int("entry_count", b.kv.Len()), zap.Uint64("total_size_bytes", b.totalSizeBytes))
 	}
 	b.deltas = nil
+	b.appendBuffers = nil
 	b.lastOrdinal = 0
 }
 
+// Commit makes the writes of the block durable in the store's backend, see
+// `Config.SetSpillDirectory`. It is to be called at the end of each block.
+func (b *baseStore) Commit() error {
+	return b.kv.Commit()
+}
+
+// kvEntry is a single entry of a snapshot.
+type kvEntry struct {
+	key   string
+	value []byte
+}
+
+// marshalState serializes the state of the store, preceded by its header: its metadata
+// then `header`, and followed by `deletePrefixes`. Marshallers able to stream go over
+// the backend entry by entry.
+func (b *baseStore) marshalState(deletePrefixes []string, header ...kvEntry) ([]byte, error) {
+	header = append([]kvEntry{{metadataKey, encodeMetadata(b.metadata())}}, header...)
+	if m, ok := b.marshaller.(marshaller.StreamMarshaller); ok {
+		return m.MarshalEntries(func(f func(key string, value []byte) error) error {
+			for _, entry := range header {
+				if err := f(entry.key, entry.value); err != nil {
+					return err
+				}
+			}
+			return b.kv.Iterate(f)
+		}, deletePrefixes)
+	}
+
+	kv := maps.Clone(b.kv.Map())
+	for _, entry := range header {
+		kv[entry.key] = entry.value
+	}
+	return b.marshaller.Marshal(&marshaller.StoreData{Kv: kv, DeletePrefixes: deletePrefixes})
+}
+
+// unmarshalState replaces the state of the store with the one serialized in `data`,
+// once its metadata is verified, and returns the delete prefixes and the size of the data.
+// The other header entries are handed to `onHeader`, when not nil, which returns false
+// for the entries of the state.
+func (b *baseStore) unmarshalState(data []byte, onHeader func(key string, value []byte) bool) (deletePrefixes []string, size uint64, err error) {
+	kv := b.Config.newKV()
+	loadedMetadata := make(map[string]string)
+	setEntry := func(key string, value []byte) error {
+		if loadMetadataEntry(loadedMetadata, key, value) || (onHeader != nil && onHeader(key, value)) {
+			return nil
+		}
+		kv.Set(key, value)
+		return nil
+	}
+
+	if m, ok := b.marshaller.(marshaller.StreamMarshaller); ok {
+		deletePrefixes, size, err = m.UnmarshalEntries(data, setEntry)
+	} else {
+		var storeData *marshaller.StoreData
+		if storeData, size, err = b.marshaller.Unmarshal(data); err == nil {
+			for k, v := range storeData.Kv {
+				setEntry(k, v)
+			}
+			deletePrefixes = storeData.DeletePrefixes
+		}
+	}
+	if err == nil {
+		err = kv.Commit()
+	}
+	if err == nil {
+		err = b.checkIntegrity(loadedMetadata)
+	}
+	if err != nil {
+		kv.Close()
+		return nil, 0, err
+	}
+
+	b.kv.Close()
+	b.kv = kv
+	b.loadedBigFloatPrecision = nil
+	if len(loadedMetadata) != 0 {
+		precision := parseBigFloatPrecision(loadedMetadata)
+		b.loadedBigFloatPrecision = &precision
+	}
+	return deletePrefixes, size, nil
+}
+
 func (b *baseStore) bumpOrdinal(ord uint64) {
+	b.checkNotFrozen()
 	if b.lastOrdinal > ord {
 		panic("cannot Set or Del a value on a state.Builder with an ordinal lower than the previous")
 	}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/streamingfast/substreams/manifest"
+)
+
+// Checksum returns a hex-encoded SHA-256 of the logical content of the store, so replicas
+// can compare their state with a single hash. Keys are hashed in lexicographical order,
+// skipping the reserved ones, and numeric values are hashed in their canonical form, so
+// `1.50` and `1.5` in a `bigdecimal` store hash the same. The checksum does not depend on
+// the insertion order nor on the snapshot serialization.
+func (b *baseStore) Checksum() string {
+	h := sha256.New()
+	var length [binary.MaxVarintLen64]byte
+	write := func(data []byte) {
+		n := binary.PutUvarint(length[:], uint64(len(data)))
+		h.Write(length[:n])
+		h.Write(data)
+	}
+
+	_ = b.Iterate(func(key string, value []byte) error {
+		write([]byte(key))
+		write(canonicalValue(b.valueType, value))
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SnapshotChecksum loads the snapshot described by `file` and returns its `Checksum`.
+func SnapshotChecksum(ctx context.Context, config *Config, file *FileInfo) (string, error) {
+	var b *baseStore
+	if file.Partial {
+		s := config.NewPartialKV(file.Range.StartBlock, zlog)
+		if err := s.Load(ctx, file); err != nil {
+			return "", fmt.Errorf("loading snapshot: %w", err)
+		}
+		b = s.baseStore
+	} else {
+		s := config.NewFullKV(zlog)
+		if err := s.Load(ctx, file); err != nil {
+			return "", fmt.Errorf("loading snapshot: %w", err)
+		}
+		b = s.baseStore
+	}
+	defer b.kv.Close()
+
+	return b.Checksum(), nil
+}
+
+// canonicalValue re-encodes numeric values in a single form, other values, and those
+// that do not parse as their value type, are returned as is.
+func canonicalValue(valueType string, value []byte) []byte {
+	switch strings.ToLower(valueType) {
+	case manifest.OutputValueTypeInt64:
+		if v, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			return []byte(strconv.FormatInt(v, 10))
+		}
+	case manifest.OutputValueTypeFloat64:
+		if v, err := strconv.ParseFloat(string(value), 64); err == nil {
+			return floatToBytes(v)
+		}
+	case manifest.OutputValueTypeBigInt:
+		if v, ok := new(big.Int).SetString(string(value), 10); ok {
+			return []byte(v.String())
+		}
+	case manifest.OutputValueTypeBigFloat:
+		if v, _, err := big.ParseFloat(string(value), 10, 100, big.ToNearestEven); err == nil {
+			return bigFloatToBytes(v)
+		}
+	case manifest.OutputValueTypeBigDecimal:
+		if v, err := decimal.NewFromString(string(value)); err == nil {
+			return []byte(v.String())
+		}
+	}
+	return value
+}
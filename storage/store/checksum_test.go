@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestBaseStore_Checksum(t *testing.T) {
+	newStore := func(valueType string, kvs ...string) *baseStore {
+		s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, valueType, nil)
+		for i := 0; i < len(kvs); i += 2 {
+			s.kv.Set(kvs[i], []byte(kvs[i+1]))
+		}
+		return s
+	}
+
+	forward := newStore(manifest.OutputValueTypeString, "a", "1", "b", "2", "c", "3")
+	backward := newStore(manifest.OutputValueTypeString, "c", "3", "b", "2", "a", "1", internalKeyPrefix+"meta", "x")
+	assert.Equal(t, forward.Checksum(), backward.Checksum())
+	assert.Len(t, forward.Checksum(), 64)
+
+	assert.NotEqual(t, forward.Checksum(), newStore(manifest.OutputValueTypeString, "a", "1", "b", "2", "c", "4").Checksum())
+	assert.NotEqual(t, forward.Checksum(), newStore(manifest.OutputValueTypeString, "a", "1", "b", "2").Checksum())
+	// keys and values are length-prefixed, moving bytes from one to the other changes the checksum
+	assert.NotEqual(t, newStore(manifest.OutputValueTypeString, "ab", "c").Checksum(), newStore(manifest.OutputValueTypeString, "a", "bc").Checksum())
+
+	assert.Equal(t,
+		newStore(manifest.OutputValueTypeBigDecimal, "a", "1.50", "b", "-0").Checksum(),
+		newStore(manifest.OutputValueTypeBigDecimal, "b", "0", "a", "1.5").Checksum(),
+	)
+	assert.Equal(t,
+		newStore(manifest.OutputValueTypeInt64, "a", "+007").Checksum(),
+		newStore(manifest.OutputValueTypeInt64, "a", "7").Checksum(),
+	)
+}
+
+func TestSnapshotChecksum(t *testing.T) {
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	full := config.NewFullKV(zap.NewNop())
+	full.kv.Set("b", []byte("2"))
+	full.kv.Set("a", []byte("1"))
+
+	file, writer, err := full.Save(100)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(context.Background()))
+
+	checksum, err := SnapshotChecksum(context.Background(), config, file)
+	require.NoError(t, err)
+	assert.Equal(t, full.Checksum(), checksum)
+}
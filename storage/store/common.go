@@ -3,13 +3,17 @@ package store
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/streamingfast/dmetering"
 
 	"github.com/streamingfast/derr"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/logging"
+	"go.uber.org/zap"
 )
 
 func saveStore(ctx context.Context, store dstore.Store, filename string, content []byte) (err error) {
@@ -21,12 +25,47 @@ func saveStore(ctx context.Context, store dstore.Store, filename string, content
 		store.SetMeter(dmetering.GetBytesMeter(ctx))
 	}
 
-	return derr.RetryContext(ctx, 5, func(ctx context.Context) error {
-		return store.WriteObject(ctx, filename, bytes.NewReader(content))
+	// The content is first written to a temporary object, then committed under its final name,
+	// so that a crash mid-write never leaves a half-written snapshot visible to loaders.
+	tmpFilename := filename + tmpFileSuffix
+	err = derr.RetryContext(ctx, 5, func(ctx context.Context) error {
+		return store.WriteObject(ctx, tmpFilename, bytes.NewReader(content))
 	})
+	if err != nil {
+		return fmt.Errorf("writing temporary file %q: %w", tmpFilename, err)
+	}
+
+	err = derr.RetryContext(ctx, 5, func(ctx context.Context) error {
+		return store.CopyObject(ctx, tmpFilename, filename)
+	})
+	if err != nil {
+		return fmt.Errorf("committing file %q: %w", filename, err)
+	}
+
+	if err := store.DeleteObject(ctx, tmpFilename); err != nil {
+		// Leftover temporary files are ignored by the loaders, the snapshot is already committed
+		logging.Logger(ctx, zlog).Warn("unable to delete temporary store file", zap.String("filename", tmpFilename), zap.Error(err))
+	}
+	return nil
+}
+
+// SnapshotLoadError is returned when a snapshot could not be loaded, either because
+// of a permanent error or because all the retries on transient errors were exhausted.
+type SnapshotLoadError struct {
+	Filename string
+	Attempts uint64
+	Err      error
 }
 
-func loadStore(ctx context.Context, store dstore.Store, filename string) (out []byte, err error) {
+func (e *SnapshotLoadError) Error() string {
+	return fmt.Sprintf("loading snapshot %q failed after %d attempt(s): %s", e.Filename, e.Attempts, e.Err)
+}
+
+func (e *SnapshotLoadError) Unwrap() error { return e.Err }
+
+const maxLoadBackoff = 30 * time.Second
+
+func loadStore(ctx context.Context, store dstore.Store, filename string, retries uint64, backoff time.Duration) (out []byte, err error) {
 	if cloned, ok := store.(dstore.Clonable); ok {
 		store, err = cloned.Clone(ctx)
 		if err != nil {
@@ -35,20 +74,46 @@ func loadStore(ctx context.Context, store dstore.Store, filename string) (out []
 		store.SetMeter(dmetering.GetBytesMeter(ctx))
 	}
 
-	err = derr.RetryContext(ctx, 5, func(ctx context.Context) error {
-		r, err := store.OpenObject(ctx, filename)
-		if err != nil {
-			return fmt.Errorf("opening file: %w", err)
+	var attempts uint64
+	for {
+		attempts++
+		out, err = readObject(ctx, store, filename)
+		if err == nil {
+			return out, nil
+		}
+		if attempts > retries || !isTransientLoadError(ctx, err) {
+			return nil, &SnapshotLoadError{Filename: filename, Attempts: attempts, Err: err}
 		}
 
-		defer r.Close()
-		data, err := io.ReadAll(r)
-		if err != nil {
-			return fmt.Errorf("reading data: %w", err)
+		logging.Logger(ctx, zlog).Debug("retrying snapshot load", zap.String("filename", filename), zap.Uint64("attempt", attempts), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return nil, &SnapshotLoadError{Filename: filename, Attempts: attempts, Err: ctx.Err()}
+		case <-time.After(backoff):
 		}
+		backoff = min(backoff*2, maxLoadBackoff)
+	}
+}
 
-		out = data
-		return nil
-	})
-	return out, err
+func readObject(ctx context.Context, store dstore.Store, filename string) ([]byte, error) {
+	r, err := store.OpenObject(ctx, filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading data: %w", err)
+	}
+	return data, nil
+}
+
+// isTransientLoadError tells if retrying the load could succeed, a missing
+// snapshot or a canceled request will not get any better.
+func isTransientLoadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, dstore.ErrNotFound) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
 }
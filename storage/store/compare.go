@@ -0,0 +1,102 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// SnapshotDiff is a key whose value differs between two snapshots of a store.
+type SnapshotDiff struct {
+	Key   string
+	Left  []byte
+	Right []byte
+
+	MissingLeft  bool
+	MissingRight bool
+}
+
+func (d *SnapshotDiff) String() string {
+	switch {
+	case d.MissingLeft:
+		return fmt.Sprintf("key %q: only in right (%q)", d.Key, d.Right)
+	case d.MissingRight:
+		return fmt.Sprintf("key %q: only in left (%q)", d.Key, d.Left)
+	default:
+		return fmt.Sprintf("key %q: left %q != right %q", d.Key, d.Left, d.Right)
+	}
+}
+
+// CompareSnapshots loads the snapshot described by `file` from both `left` and `right`
+// store configs (typically the same module, written by two different runs) and returns
+// the keys whose values differ, sorted by key. The comparison is done on the decoded
+// key/values, so it does not depend on the serialization order. Keys written by the system,
+// such as the snapshot metadata, are skipped, unless `includeInternal` is set.
+func CompareSnapshots(ctx context.Context, left, right *Config, file *FileInfo, includeInternal bool) ([]*SnapshotDiff, error) {
+	leftKV, err := loadSnapshotKV(ctx, left, file)
+	if err != nil {
+		return nil, fmt.Errorf("loading left snapshot: %w", err)
+	}
+	rightKV, err := loadSnapshotKV(ctx, right, file)
+	if err != nil {
+		return nil, fmt.Errorf("loading right snapshot: %w", err)
+	}
+
+	skip := func(key string) bool { return left.isSystemKey(key) || right.isSystemKey(key) }
+	if includeInternal {
+		skip = nil
+	}
+	return compareKVs(leftKV, rightKV, skip), nil
+}
+
+func loadSnapshotKV(ctx context.Context, config *Config, file *FileInfo) (map[string][]byte, error) {
+	var b *baseStore
+	if file.Partial {
+		s := config.NewPartialKV(file.Range.StartBlock, zap.NewNop())
+		if err := s.Load(ctx, file); err != nil {
+			return nil, err
+		}
+		b = s.baseStore
+	} else {
+		s := config.NewFullKV(zap.NewNop())
+		if err := s.Load(ctx, file); err != nil {
+			return nil, err
+		}
+		b = s.baseStore
+	}
+	defer b.kv.Close()
+
+	return b.kv.Map(), nil
+}
+
+// compareKVs returns the keys of `left` and `right` with different values, except those
+// `skip` reports, when not nil.
+func compareKVs(left, right map[string][]byte, skip func(key string) bool) (out []*SnapshotDiff) {
+	for k, l := range left {
+		if skip != nil && skip(k) {
+			continue
+		}
+		r, found := right[k]
+		if !found {
+			out = append(out, &SnapshotDiff{Key: k, Left: l, MissingRight: true})
+			continue
+		}
+		if !bytes.Equal(l, r) {
+			out = append(out, &SnapshotDiff{Key: k, Left: l, Right: r})
+		}
+	}
+	for k, r := range right {
+		if skip != nil && skip(k) {
+			continue
+		}
+		if _, found := left[k]; !found {
+			out = append(out, &SnapshotDiff{Key: k, Right: r, MissingLeft: true})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
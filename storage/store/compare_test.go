@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	writeSnapshot := func(kvs map[string]string, reservedKeyPrefix ...string) *Config {
+		config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+		if len(reservedKeyPrefix) != 0 {
+			config.SetReservedKeyPrefix(reservedKeyPrefix[0])
+		}
+
+		s := config.NewFullKV(zap.NewNop())
+		for k, v := range kvs {
+			s.kv.Set(k, []byte(v))
+		}
+		_, writer, err := s.Save(100)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(context.Background()))
+		return config
+	}
+	file := NewCompleteFileInfo("test", 0, 100)
+
+	left := writeSnapshot(map[string]string{"a": "1", "b": "2", internalKeyPrefix + "meta": "x"})
+
+	diffs, err := CompareSnapshots(context.Background(), left, writeSnapshot(map[string]string{"b": "2", "a": "1", internalKeyPrefix + "meta": "x"}), file, true)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 0)
+
+	right := writeSnapshot(map[string]string{"a": "1", "b": "3", "c": "4", internalKeyPrefix + "meta": "y"})
+	diffs, err = CompareSnapshots(context.Background(), left, right, file, false)
+	require.NoError(t, err)
+	assert.Equal(t, []*SnapshotDiff{
+		{Key: "b", Left: []byte("2"), Right: []byte("3")},
+		{Key: "c", Right: []byte("4"), MissingLeft: true},
+	}, diffs)
+
+	diffs, err = CompareSnapshots(context.Background(), left, right, file, true)
+	require.NoError(t, err)
+	require.Len(t, diffs, 3)
+	assert.Equal(t, internalKeyPrefix+"meta", diffs[0].Key)
+
+	// with another reserved prefix, modules can write under the internal one
+	left = writeSnapshot(map[string]string{internalKeyPrefix + "user": "1", metadataKeyPrefix + "x": "a"}, "custom:")
+	right = writeSnapshot(map[string]string{internalKeyPrefix + "user": "2", metadataKeyPrefix + "x": "b"}, "custom:")
+	diffs, err = CompareSnapshots(context.Background(), left, right, file, false)
+	require.NoError(t, err)
+	assert.Equal(t, []*SnapshotDiff{
+		{Key: internalKeyPrefix + "user", Left: []byte("1"), Right: []byte("2")},
+	}, diffs)
+}
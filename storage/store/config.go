@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/streamingfast/derr"
 	"github.com/streamingfast/dstore"
@@ -13,6 +15,17 @@ import (
 	"github.com/streamingfast/substreams/storage/store/marshaller"
 )
 
+const (
+	// DefaultScanLimit is the maximum number of keys returned by a single `Scan` call
+	// unless the config sets another one, see `Config.SetScanLimit`.
+	DefaultScanLimit = 10_000
+	// DefaultLoadRetries is the number of times a snapshot load failing with a transient
+	// error is retried, see `Config.SetLoadRetryPolicy`.
+	DefaultLoadRetries = 5
+	// DefaultLoadBackoff is the initial delay between the attempts of a snapshot load.
+	DefaultLoadBackoff = time.Second
+)
+
 type Config struct {
 	name       string
 	moduleHash string
@@ -25,6 +38,36 @@ type Config struct {
 	appendLimit    uint64
 	totalSizeLimit uint64
 	itemSizeLimit  uint64
+	scanLimit      uint64 // maximum number of keys returned by a single enumeration call
+	spillDirectory string // when set, the store's state is kept on local disk under this directory
+	spills         *spillSet
+
+	validateValueType bool // when set, values written are checked against `valueType`
+	validateKeys      bool // when set, keys written must be valid UTF-8, and at most `maxKeyLength` bytes if not 0
+	maxKeyLength      uint64
+	auditSnapshots    bool // when set, full snapshots written from merged partials are verified against a replay of the partials
+
+	maxDeletedPrefixes uint64 // maximum number of distinct key prefixes deleted in a partial, 0 means no limit
+
+	reservedKeyPrefix string // modules cannot write keys starting with it, unless empty
+
+	bigFloatPrecision uint // bits of precision of `bigfloat` merges, 0 merges them as decimals
+	coalesceAppends   bool // when set, the appends to a key within a block are recorded as a single delta
+
+	memoryBudget *MemoryBudget // when set, shared with the other stores of the request to cap their total size
+
+	keyFormatter   SnapshotKeyFormatter // names the snapshot objects, `DefaultSnapshotKeyFormatter` when nil
+	snapshotCipher *SnapshotCipher      // when set, snapshots are written encrypted
+	snapshotCache  *SnapshotCache       // when set, full snapshots found in it are not read from storage
+	snapshotGrid   uint64               // when set, full snapshots saved at blocks off this interval are written to `offGridStore`
+	offGridStore   dstore.Store
+
+	initialValues map[string][]byte // loaded in the stores starting at the module's initial block
+
+	mergeConcurrency uint64 // number of partials loaded ahead of the one being merged, 0 loads them one at a time
+
+	loadRetries uint64        // number of retries of a snapshot load on transient errors
+	loadBackoff time.Duration // initial delay between snapshot load attempts, doubled on each retry
 
 	// traceID uniquely identifies the connection ID so that store can be
 	// written to unique filename preventing some races when multiple Substreams
@@ -56,6 +99,10 @@ func NewConfig(
 		appendLimit:        8_388_608,     // 8MiB = 8 * 1024 * 1024,
 		totalSizeLimit:     1_073_741_824, // 1GiB
 		itemSizeLimit:      10_485_760,    // 10MiB
+		scanLimit:          DefaultScanLimit,
+		loadRetries:        DefaultLoadRetries,
+		loadBackoff:        DefaultLoadBackoff,
+		reservedKeyPrefix:  DefaultReservedKeyPrefix,
 		traceID:            traceID,
 	}, nil
 }
@@ -63,7 +110,7 @@ func NewConfig(
 func (c *Config) newBaseStore(logger *zap.Logger) *baseStore {
 	return &baseStore{
 		Config:     c,
-		kv:         make(map[string][]byte),
+		kv:         c.newKV(),
 		logger:     logger.Named("store").With(zap.String("store_name", c.name), zap.String("module_hash", c.moduleHash)),
 		marshaller: marshaller.Default(),
 	}
@@ -89,6 +136,154 @@ func (c *Config) ModuleInitialBlock() uint64 {
 	return c.moduleInitialBlock
 }
 
+// SetScanLimit overrides the maximum number of keys returned by a single `Scan` call,
+// a value of 0 disables the cap.
+func (c *Config) SetScanLimit(limit uint64) {
+	c.scanLimit = limit
+}
+
+// SetLoadRetryPolicy configures how many times loading a snapshot is retried on
+// transient storage errors, and the initial backoff between attempts.
+func (c *Config) SetLoadRetryPolicy(retries uint64, backoff time.Duration) {
+	c.loadRetries = retries
+	c.loadBackoff = backoff
+}
+
+// SetSpillDirectory makes stores created from this config keep their state on local
+// disk, under `dir`, instead of in memory. Use it for stores too large to fit in RAM.
+func (c *Config) SetSpillDirectory(dir string) {
+	c.spillDirectory = dir
+	if c.spills == nil {
+		c.spills = &spillSet{}
+	}
+}
+
+// SetValueTypeValidation makes writes fail when the value does not parse as the
+// store's declared value type, instead of only failing later on merge.
+func (c *Config) SetValueTypeValidation(enabled bool) {
+	c.validateValueType = enabled
+}
+
+// SetKeyValidation makes writes fail on keys that are not valid UTF-8 or, when
+// `maxLength` is not 0, longer than `maxLength` bytes.
+func (c *Config) SetKeyValidation(enabled bool, maxLength uint64) {
+	c.validateKeys = enabled
+	c.maxKeyLength = maxLength
+}
+
+// SetMaxDeletedPrefixes caps the number of distinct key prefixes a module can delete
+// within the segment of a partial store, the ones starting with another deleted prefix
+// not counting. A value of 0 disables the cap.
+func (c *Config) SetMaxDeletedPrefixes(max uint64) {
+	c.maxDeletedPrefixes = max
+}
+
+// SetReservedKeyPrefix changes the prefix of the keys modules cannot write, an empty
+// `prefix` lifting the restriction. The keys the system writes itself, such as snapshot
+// metadata, stay refused whatever the prefix.
+func (c *Config) SetReservedKeyPrefix(prefix string) {
+	c.reservedKeyPrefix = prefix
+}
+
+// SetBigFloatPrecision makes merges of `bigfloat` stores compute with `bits` of precision
+// instead of as decimals truncated to 34 fractional digits. The precision is recorded in
+// the snapshots, which cannot be merged with ones using another precision.
+func (c *Config) SetBigFloatPrecision(bits uint) {
+	c.bigFloatPrecision = bits
+}
+
+// SetAppendCoalescing makes the appends to a same key within a block be recorded as a
+// single delta, at the ordinal of the last one, when the block's deltas are read. It
+// saves append stores with hot keys from copying the whole value on each append, at the
+// cost of the intermediate values: readers at ordinals between the first and the last
+// append see the value the key had before the first one.
+func (c *Config) SetAppendCoalescing(enabled bool) {
+	c.coalesceAppends = enabled
+}
+
+// SetSnapshotAudit makes full snapshots produced by merging partials be read back from
+// storage and compared with a replay of these partials, whose deletion waits for it,
+// see `SnapshotAudit`.
+func (c *Config) SetSnapshotAudit(enabled bool) {
+	c.auditSnapshots = enabled
+}
+
+// SetMemoryBudget makes stores created from this config report their size to `budget`,
+// usually shared by all the stores of a request.
+func (c *Config) SetMemoryBudget(budget *MemoryBudget) {
+	c.memoryBudget = budget
+}
+
+// SetSnapshotKeyFormatter overrides how the objects holding the store's snapshots are
+// named, both when writing and when listing them. Passing nil restores the default.
+func (c *Config) SetSnapshotKeyFormatter(formatter SnapshotKeyFormatter) {
+	c.keyFormatter = formatter
+}
+
+// SetMergeConcurrency makes merges of successive partials load up to `concurrency` of
+// them in the background while the previous ones are merged, see `PartialLoader`.
+func (c *Config) SetMergeConcurrency(concurrency uint64) {
+	c.mergeConcurrency = concurrency
+}
+
+// SetSnapshotCipher makes snapshots be written encrypted with `cipher`. Encrypted and
+// plain snapshots are both loaded, so it can be enabled on stores with existing
+// snapshots. Passing nil writes plain snapshots again.
+func (c *Config) SetSnapshotCipher(cipher *SnapshotCipher) {
+	c.snapshotCipher = cipher
+}
+
+// SetSnapshotCache makes full snapshots held by `cache` load from memory instead of
+// being read from storage.
+func (c *Config) SetSnapshotCache(cache *SnapshotCache) {
+	c.snapshotCache = cache
+}
+
+// SetOffGridSnapshots keeps the shared cache to the full snapshots saved at multiples
+// of `grid`, the ones other requests list and load. The snapshots saved at other blocks
+// are written under `<module_hash>/requests/<trace_id>/states` of `base` instead, a
+// namespace of their own for the request.
+func (c *Config) SetOffGridSnapshots(base dstore.Store, grid uint64) error {
+	offGridStore, err := base.SubStore(fmt.Sprintf("%s/requests/%s/states", c.moduleHash, c.traceID))
+	if err != nil {
+		return fmt.Errorf("creating sub store: %w", err)
+	}
+	c.snapshotGrid = grid
+	c.offGridStore = offGridStore
+	return nil
+}
+
+// fullSnapshotStore returns where the full snapshot ending at `endBlock` is written.
+func (c *Config) fullSnapshotStore(endBlock uint64) dstore.Store {
+	if c.offGridStore != nil && endBlock%c.snapshotGrid != 0 {
+		return c.offGridStore
+	}
+	return c.objStore
+}
+
+func (c *Config) MergeConcurrency() uint64 {
+	return c.mergeConcurrency
+}
+
+func (c *Config) snapshotKeyFormatter() SnapshotKeyFormatter {
+	if c.keyFormatter == nil {
+		return DefaultSnapshotKeyFormatter{}
+	}
+	return c.keyFormatter
+}
+
+// snapshotKey returns the name of the object holding the snapshot described by `file`.
+func (c *Config) snapshotKey(file *FileInfo) string {
+	if file.Partial {
+		return c.snapshotKeyFormatter().PartialKey(file.Range, file.TraceID)
+	}
+	return c.snapshotKeyFormatter().FullKey(file.Range)
+}
+
+func (c *Config) SnapshotAuditEnabled() bool {
+	return c.auditSnapshots
+}
+
 func (c *Config) NewFullKV(logger *zap.Logger) *FullKV {
 	return &FullKV{c.newBaseStore(logger), "N/A"}
 }
@@ -104,7 +299,7 @@ func (c *Config) NewPartialKV(initialBlock uint64, logger *zap.Logger) *PartialK
 func (c *Config) FileSize(ctx context.Context, fileInfo *FileInfo) (int64, error) {
 	var size int64
 	err := derr.RetryContext(ctx, 3, func(ctx context.Context) error {
-		attr, err := c.objStore.ObjectAttributes(ctx, fileInfo.Filename)
+		attr, err := c.objStore.ObjectAttributes(ctx, c.snapshotKey(fileInfo))
 		if err != nil {
 			return fmt.Errorf("getting object attributes: %w", err)
 		}
@@ -124,12 +319,17 @@ func (c *Config) ListSnapshotFiles(ctx context.Context, below uint64) (files []*
 	}
 
 	logger := logging.Logger(ctx, zlog)
+	formatter := c.snapshotKeyFormatter()
 	err = derr.RetryContext(ctx, 3, func(ctx context.Context) error {
 		// We need to clear each time we start because a previous retry could have accumulated a partial state
 		files = nil
 
 		return c.objStore.Walk(ctx, "", func(filename string) (err error) {
-			fileInfo, ok := parseFileName(c.Name(), filename)
+			if strings.HasSuffix(filename, tmpFileSuffix) {
+				return nil
+			}
+
+			fileInfo, ok := parseSnapshotKey(formatter, c.Name(), filename)
 			if !ok {
 				logger.Warn("seen snapshot file that we don't know how to parse", zap.String("filename", filename))
 				return nil
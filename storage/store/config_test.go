@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 
+	"github.com/streamingfast/derr"
 	"github.com/streamingfast/dstore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/block"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 )
 
 func TestConfig_ListSnapshotFiles(t *testing.T) {
@@ -51,3 +57,152 @@ func TestConfig_ListSnapshotFiles(t *testing.T) {
 
 	assert.Equal(t, expectedFiles, actualFiles)
 }
+
+func TestSaveStore(t *testing.T) {
+	testStore := dstore.NewMockStore(nil)
+
+	require.NoError(t, saveStore(context.Background(), testStore, "0000001000-0000000000.kv", []byte("data")))
+	assert.Equal(t, map[string][]byte{"0000001000-0000000000.kv": []byte("data")}, testStore.Files, "the temporary object is deleted once committed")
+
+	c := &Config{objStore: testStore}
+	files, err := c.ListSnapshotFiles(context.Background(), 10000)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "0000001000-0000000000.kv", files[0].Filename)
+}
+
+func TestSaveStore_HalfWrittenSnapshotNeverVisible(t *testing.T) {
+	ctx := context.Background()
+	config, err := NewConfig("test", 0, "hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	testStore := config.objStore.(*dstore.MockStore)
+
+	full := config.NewFullKV(zap.NewNop())
+	full.Set(0, "key", "value")
+	file, writer, err := full.Save(1000)
+	require.NoError(t, err)
+
+	// a backend whose writes are not atomic, the process crashing halfway through one
+	testStore.WriteObjectFunc = func(ctx context.Context, base string, f io.Reader) error {
+		content, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		testStore.SetFile(base, content[:len(content)/2])
+		return derr.NewFatalError(fmt.Errorf("crashed writing %q", base))
+	}
+	require.Error(t, writer.Write(ctx))
+	require.NotEmpty(t, testStore.Files, "half of the snapshot was written")
+	assert.NotContains(t, testStore.Files, file.Filename)
+
+	files, err := config.ListSnapshotFiles(ctx, 10000)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	loaded := config.NewFullKV(zap.NewNop())
+	config.SetLoadRetryPolicy(0, 0)
+	assert.Error(t, loaded.Load(ctx, file))
+}
+
+// prefixedKeyFormatter names snapshots `snapshots/<kind>/<end>_<start>[_<trace_id>]`.
+type prefixedKeyFormatter struct{}
+
+func (prefixedKeyFormatter) FullKey(r *block.Range) string {
+	return fmt.Sprintf("snapshots/full/%012d_%012d", r.ExclusiveEndBlock, r.StartBlock)
+}
+
+func (prefixedKeyFormatter) PartialKey(r *block.Range, traceID string) string {
+	return fmt.Sprintf("snapshots/partial/%012d_%012d_%s", r.ExclusiveEndBlock, r.StartBlock, traceID)
+}
+
+func (prefixedKeyFormatter) ParseKey(key string) (*block.Range, string, bool, bool) {
+	var end, start uint64
+	if _, err := fmt.Sscanf(key, "snapshots/full/%d_%d", &end, &start); err == nil {
+		return block.NewRange(start, end), "", false, true
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, "snapshots/partial/"), "_", 3)
+	if len(parts) != 3 || !strings.HasPrefix(key, "snapshots/partial/") {
+		return nil, "", false, false
+	}
+	if _, err := fmt.Sscanf(parts[0]+"_"+parts[1], "%d_%d", &end, &start); err != nil {
+		return nil, "", false, false
+	}
+	return block.NewRange(start, end), parts[2], true, true
+}
+
+func TestConfig_SnapshotKeyFormatter(t *testing.T) {
+	ctx := context.Background()
+	config, err := NewConfig("test", 0, "hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "trace")
+	require.NoError(t, err)
+	config.SetSnapshotKeyFormatter(prefixedKeyFormatter{})
+
+	full := config.NewFullKV(zap.NewNop())
+	full.Set(0, "key", "full")
+	file, writer, err := full.Save(100)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+	assert.Equal(t, "snapshots/full/000000000100_000000000000", file.Filename)
+
+	partial := config.NewPartialKV(100, zap.NewNop())
+	partial.Set(0, "key", "partial")
+	_, writer, err = partial.Save(200)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	states := config.objStore.(*dstore.MockStore)
+	assert.Contains(t, states.Files, "snapshots/full/000000000100_000000000000")
+	assert.Contains(t, states.Files, "snapshots/partial/000000000200_000000000100_trace")
+
+	files, err := config.ListSnapshotFiles(ctx, 1000)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, &FileInfo{ModuleName: "test", Filename: "snapshots/full/000000000100_000000000000", Range: block.NewRange(0, 100)}, files[0])
+	assert.Equal(t, &FileInfo{ModuleName: "test", Filename: "snapshots/partial/000000000200_000000000100_trace", Range: block.NewRange(100, 200), TraceID: "trace", Partial: true}, files[1])
+
+	loadedFull := config.NewFullKV(zap.NewNop())
+	require.NoError(t, loadedFull.Load(ctx, NewCompleteFileInfo("test", 0, 100)))
+	value, found := loadedFull.GetLast("key")
+	require.True(t, found)
+	assert.Equal(t, "full", string(value))
+
+	loadedPartial := config.NewPartialKV(100, zap.NewNop())
+	require.NoError(t, loadedPartial.Load(ctx, files[1]))
+	value, found = loadedPartial.GetLast("key")
+	require.True(t, found)
+	assert.Equal(t, "partial", string(value))
+
+	require.NoError(t, loadedPartial.DeleteStore(ctx, NewPartialFileInfo("test", 100, 200, "trace")))
+	assert.NotContains(t, states.Files, "snapshots/partial/000000000200_000000000100_trace")
+}
+
+func TestConfig_OffGridSnapshots(t *testing.T) {
+	ctx := context.Background()
+	baseStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+	config, err := NewConfig("test", 0, "hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", baseStore, "trace")
+	require.NoError(t, err)
+	require.NoError(t, config.SetOffGridSnapshots(baseStore, 100))
+
+	full := config.NewFullKV(zap.NewNop())
+	for _, end := range []uint64{70, 100, 140} {
+		full.Set(0, "key", fmt.Sprintf("%d", end))
+		_, writer, err := full.Save(end)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+	}
+
+	// only the snapshot on the grid is in the shared cache
+	files, err := config.ListSnapshotFiles(ctx, 1000)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, uint64(100), files[0].Range.ExclusiveEndBlock)
+
+	for _, name := range []string{"0000000070-0000000000.kv", "0000000140-0000000000.kv"} {
+		found, err := baseStore.FileExists(ctx, "hash/requests/trace/states/"+name)
+		require.NoError(t, err)
+		assert.True(t, found, name)
+		found, err = baseStore.FileExists(ctx, "hash/states/"+name)
+		require.NoError(t, err)
+		assert.False(t, found, name)
+	}
+}
@@ -1,7 +1,9 @@
 package store
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/streamingfast/dstore"
 	"github.com/streamingfast/substreams/manifest"
@@ -10,6 +12,151 @@ import (
 
 type ConfigMap map[string]*Config
 
+// SetSpillDirectory makes all the stores keep their state on local disk, under `dir`.
+func (m ConfigMap) SetSpillDirectory(dir string) {
+	spills := &spillSet{}
+	for _, c := range m {
+		c.spills = spills
+		c.SetSpillDirectory(dir)
+	}
+}
+
+// Close releases the local disk state of all the stores still open, it is to be
+// called once the request is done with its stores.
+func (m ConfigMap) Close() error {
+	closed := make(map[*spillSet]bool)
+	var errs []error
+	for _, c := range m {
+		if c.spills == nil || closed[c.spills] {
+			continue
+		}
+		closed[c.spills] = true
+		if err := c.spills.closeAll(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetValueTypeValidation toggles the validation of written values for all the stores.
+func (m ConfigMap) SetValueTypeValidation(enabled bool) {
+	for _, c := range m {
+		c.SetValueTypeValidation(enabled)
+	}
+}
+
+// SetKeyValidation toggles the validation of written keys for all the stores.
+func (m ConfigMap) SetKeyValidation(enabled bool, maxLength uint64) {
+	for _, c := range m {
+		c.SetKeyValidation(enabled, maxLength)
+	}
+}
+
+// SetMaxDeletedPrefixes caps the number of key prefixes deleted in a partial for all the stores.
+func (m ConfigMap) SetMaxDeletedPrefixes(max uint64) {
+	for _, c := range m {
+		c.SetMaxDeletedPrefixes(max)
+	}
+}
+
+// SetReservedKeyPrefix changes the prefix of the keys modules cannot write for all the stores.
+func (m ConfigMap) SetReservedKeyPrefix(prefix string) {
+	for _, c := range m {
+		c.SetReservedKeyPrefix(prefix)
+	}
+}
+
+// SetBigFloatPrecision sets the precision of `bigfloat` merges for all the stores.
+func (m ConfigMap) SetBigFloatPrecision(bits uint) {
+	for _, c := range m {
+		c.SetBigFloatPrecision(bits)
+	}
+}
+
+// SetAppendCoalescing toggles the coalescing of the appends to a same key for all the stores.
+func (m ConfigMap) SetAppendCoalescing(enabled bool) {
+	for _, c := range m {
+		c.SetAppendCoalescing(enabled)
+	}
+}
+
+// SetSnapshotAudit toggles the audit of merged full snapshots for all the stores.
+func (m ConfigMap) SetSnapshotAudit(enabled bool) {
+	for _, c := range m {
+		c.SetSnapshotAudit(enabled)
+	}
+}
+
+// SetScanLimit caps the number of keys returned by a single `Scan` call for all the stores.
+func (m ConfigMap) SetScanLimit(limit uint64) {
+	for _, c := range m {
+		c.SetScanLimit(limit)
+	}
+}
+
+// SetLoadRetryPolicy sets how snapshot loads are retried on transient errors for all the stores.
+func (m ConfigMap) SetLoadRetryPolicy(retries uint64, backoff time.Duration) {
+	for _, c := range m {
+		c.SetLoadRetryPolicy(retries, backoff)
+	}
+}
+
+// SetMemoryBudget makes all the stores share `budget`, capping their total size.
+func (m ConfigMap) SetMemoryBudget(budget *MemoryBudget) {
+	for _, c := range m {
+		c.SetMemoryBudget(budget)
+	}
+}
+
+// SetSnapshotKeyFormatter makes all the stores name their snapshots with `formatter`.
+func (m ConfigMap) SetSnapshotKeyFormatter(formatter SnapshotKeyFormatter) {
+	for _, c := range m {
+		c.SetSnapshotKeyFormatter(formatter)
+	}
+}
+
+// SetOffGridSnapshots writes the full snapshots of all the stores saved off `grid` to a
+// namespace of the request, see `Config.SetOffGridSnapshots`.
+func (m ConfigMap) SetOffGridSnapshots(base dstore.Store, grid uint64) error {
+	for name, c := range m {
+		if err := c.SetOffGridSnapshots(base, grid); err != nil {
+			return fmt.Errorf("store %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SetMergeConcurrency sets the number of partials loaded ahead of their merge for all the stores.
+func (m ConfigMap) SetMergeConcurrency(concurrency uint64) {
+	for _, c := range m {
+		c.SetMergeConcurrency(concurrency)
+	}
+}
+
+// SetSnapshotCipher makes all the stores write their snapshots encrypted with `cipher`.
+func (m ConfigMap) SetSnapshotCipher(cipher *SnapshotCipher) {
+	for _, c := range m {
+		c.SetSnapshotCipher(cipher)
+	}
+}
+
+// SetSnapshotCache makes all the stores load the full snapshots held by `cache` from it.
+func (m ConfigMap) SetSnapshotCache(cache *SnapshotCache) {
+	for _, c := range m {
+		c.SetSnapshotCache(cache)
+	}
+}
+
+// MemoryBudget returns the memory budget shared by the stores, nil if there is none.
+func (m ConfigMap) MemoryBudget() *MemoryBudget {
+	for _, c := range m {
+		if c.memoryBudget != nil {
+			return c.memoryBudget
+		}
+	}
+	return nil
+}
+
 func NewConfigMap(baseObjectStore dstore.Store, storeModules []*pbsubstreams.Module, moduleHashes *manifest.ModuleHashes, traceID string) (out ConfigMap, err error) {
 	out = make(ConfigMap)
 	for _, storeModule := range storeModules {
@@ -25,6 +172,9 @@ func NewConfigMap(baseObjectStore dstore.Store, storeModules []*pbsubstreams.Mod
 		if err != nil {
 			return nil, fmt.Errorf("new store config for %q: %w", storeModule.Name, err)
 		}
+		if err := c.SetInitialValues(storeModule.GetKindStore().GetInitialValues()); err != nil {
+			return nil, fmt.Errorf("store %q: %w", storeModule.Name, err)
+		}
 		out[storeModule.Name] = c
 	}
 	return out, nil
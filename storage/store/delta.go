@@ -2,6 +2,7 @@ package store
 
 import (
 	"fmt"
+	"sort"
 
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 )
@@ -15,12 +16,22 @@ func (b *baseStore) ApplyDelta(delta *pbssinternal.StoreDelta) {
 		panic(fmt.Sprintf("key %q invalid, must be at least 1 character and not start with 0xFF", delta.Key))
 	}
 
+	if delta.Operation == pbssinternal.StoreDelta_DELETE {
+		// a key is deleted once, applying its DELETE again (or to a store which never
+		// had it) leaves the store as is
+		if _, found := b.kv.Get(delta.Key); !found {
+			return
+		}
+	}
+
+	defer b.trackSize()
+
 	newSize := uint64(len(delta.NewValue))
 	oldSize := uint64(len(delta.OldValue))
 	keySize := uint64(len(delta.Key))
 	switch delta.Operation {
 	case pbssinternal.StoreDelta_UPDATE:
-		b.kv[delta.Key] = delta.NewValue
+		b.kv.Set(delta.Key, delta.NewValue)
 		switch {
 		case newSize > oldSize:
 			b.totalSizeBytes += (newSize - oldSize)
@@ -29,12 +40,12 @@ func (b *baseStore) ApplyDelta(delta *pbssinternal.StoreDelta) {
 		}
 
 	case pbssinternal.StoreDelta_CREATE:
-		b.kv[delta.Key] = delta.NewValue
+		b.kv.Set(delta.Key, delta.NewValue)
 		b.totalSizeBytes += newSize
 		b.totalSizeBytes += keySize
 
 	case pbssinternal.StoreDelta_DELETE:
-		delete(b.kv, delta.Key)
+		b.kv.Delete(delta.Key)
 		b.totalSizeBytes -= oldSize
 		b.totalSizeBytes -= keySize
 		return
@@ -46,6 +57,8 @@ func (b *baseStore) ApplyDelta(delta *pbssinternal.StoreDelta) {
 }
 
 func (b *baseStore) ApplyDeltasReverse(deltas []*pbssinternal.StoreDelta) {
+	defer b.trackSize()
+
 	for i := len(deltas) - 1; i >= 0; i-- {
 		delta := deltas[i]
 
@@ -54,7 +67,7 @@ func (b *baseStore) ApplyDeltasReverse(deltas []*pbssinternal.StoreDelta) {
 		keySize := uint64(len(delta.Key))
 		switch delta.Operation {
 		case pbssinternal.StoreDelta_UPDATE:
-			b.kv[delta.Key] = delta.OldValue
+			b.kv.Set(delta.Key, delta.OldValue)
 			switch {
 			case newSize > oldSize:
 				b.totalSizeBytes -= (newSize - oldSize)
@@ -63,12 +76,12 @@ func (b *baseStore) ApplyDeltasReverse(deltas []*pbssinternal.StoreDelta) {
 			}
 
 		case pbssinternal.StoreDelta_CREATE:
-			delete(b.kv, delta.Key)
+			b.kv.Delete(delta.Key)
 			b.totalSizeBytes -= newSize
 			b.totalSizeBytes -= keySize
 
 		case pbssinternal.StoreDelta_DELETE:
-			b.kv[delta.Key] = delta.OldValue
+			b.kv.Set(delta.Key, delta.OldValue)
 			b.totalSizeBytes += oldSize
 			b.totalSizeBytes += keySize
 			return
@@ -76,13 +89,33 @@ func (b *baseStore) ApplyDeltasReverse(deltas []*pbssinternal.StoreDelta) {
 	}
 }
 
+// GetDeltas returns the deltas of the block, closing it for the appends buffered so far,
+// which are recorded as one delta per key.
 func (b *baseStore) GetDeltas() []*pbssinternal.StoreDelta {
+	b.flushAppends("")
 	return b.deltas
 }
 
 func (b *baseStore) SetDeltas(deltas []*pbssinternal.StoreDelta) {
+	b.appendBuffers = nil
 	b.deltas = deltas
 	for _, delta := range deltas {
 		b.ApplyDelta(delta)
 	}
 }
+
+// OrderDeltasByKey orders, in place, the deltas of a block sharing an ordinal by key, so
+// that their order does not depend on the order the module wrote them in, for example
+// iterating over a map. The deltas of a same key keep their relative order, undoing
+// them in reverse stays correct.
+func OrderDeltasByKey[D interface {
+	GetOrdinal() uint64
+	GetKey() string
+}](deltas []D) {
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].GetOrdinal() != deltas[j].GetOrdinal() {
+			return deltas[i].GetOrdinal() < deltas[j].GetOrdinal()
+		}
+		return deltas[i].GetKey() < deltas[j].GetKey()
+	})
+}
@@ -1,10 +1,18 @@
 package store
 
 import (
+	"fmt"
 	"testing"
 
-	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/streamingfast/dstore"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 )
 
 var baseStoreConfig = &Config{
@@ -84,12 +92,12 @@ func TestApplyDelta(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			s := &baseStore{
 				Config: baseStoreConfig,
-				kv:     make(map[string][]byte),
+				kv:     memoryKV{},
 			}
 			for _, delta := range test.deltas {
 				s.ApplyDelta(delta)
 			}
-			assert.Equal(t, test.expectedKV, s.kv)
+			assert.Equal(t, test.expectedKV, s.kv.Map())
 		})
 	}
 }
@@ -97,7 +105,7 @@ func TestApplyDelta(t *testing.T) {
 func Test_baseStore_SetDeltas(t *testing.T) {
 	s := baseStore{
 		Config:         baseStoreConfig,
-		kv:             map[string][]byte{"A": []byte("a")},
+		kv:             memoryKV{"A": []byte("a")},
 		totalSizeBytes: 2,
 	}
 	s.SetDeltas([]*pbssinternal.StoreDelta{
@@ -123,9 +131,74 @@ func Test_baseStore_SetDeltas(t *testing.T) {
 			NewValue:  []byte("d"),
 		},
 	})
-	assert.Len(t, s.kv, 2)
-	assert.Equal(t, "b", string(s.kv["B"]))
-	assert.Equal(t, "d", string(s.kv["C"]))
+	assert.Equal(t, 2, s.kv.Len())
+	assert.Equal(t, "b", string(s.kv.Map()["B"]))
+	assert.Equal(t, "d", string(s.kv.Map()["C"]))
 	assert.Equal(t, uint64(4), s.totalSizeBytes)
 	assert.Len(t, s.deltas, 4)
 }
+
+func TestOrderDeltasByKey(t *testing.T) {
+	insertionOrders := [][]string{
+		{"c", "a", "b"},
+		{"b", "c", "a"},
+		{"a", "b", "c"},
+	}
+
+	var emitted [][]string
+	for _, keys := range insertionOrders {
+		config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+		s := config.NewFullKV(zap.NewNop())
+		s.SetBytes(0, "first", []byte("v"))
+		for _, key := range keys {
+			s.SetBytes(1, key, []byte("v"))
+		}
+		s.SetBytes(1, "a", []byte("updated"))
+		s.SetBytes(2, "last", []byte("v"))
+
+		deltas := s.GetDeltas()
+		OrderDeltasByKey(deltas)
+
+		var order []string
+		for _, delta := range deltas {
+			order = append(order, fmt.Sprintf("%d:%s:%s", delta.Ordinal, delta.Key, delta.NewValue))
+		}
+		emitted = append(emitted, order)
+	}
+
+	expected := []string{"0:first:v", "1:a:v", "1:a:updated", "1:b:v", "1:c:v", "2:last:v"}
+	for _, order := range emitted {
+		assert.Equal(t, expected, order)
+	}
+}
+
+func TestStore_CountsDeltaOperations(t *testing.T) {
+	config, err := NewConfig("delta.counter", 0, "delta.counter.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	require.NoError(t, config.SetInitialValues(map[string][]byte{"seeded": []byte("1")}))
+	s := config.NewFullKV(zap.NewNop())
+
+	before := map[string]float64{}
+	count := func(operation string) float64 {
+		return testutil.ToFloat64(metrics.StoreDeltaOperations.Native().WithLabelValues("delta.counter", operation)) - before[operation]
+	}
+	for _, operation := range []string{"create", "update", "delete"} {
+		before[operation] = count(operation)
+	}
+
+	s.Set(0, "a", "1")
+	s.Set(0, "b", "1")
+	s.Set(1, "c", "1")
+	s.Set(1, "a", "2")
+	s.DeleteMany(2, []string{"b", "c", "missing"})
+	s.DeletePrefix(3, "a")
+
+	// replays are not module writes
+	s.LoadInitialValues()
+	s.ApplyDelta(&pbssinternal.StoreDelta{Operation: pbssinternal.StoreDelta_CREATE, Key: "replayed", NewValue: []byte("1")})
+
+	assert.Equal(t, float64(3), count("create"))
+	assert.Equal(t, float64(1), count("update"))
+	assert.Equal(t, float64(3), count("delete"))
+}
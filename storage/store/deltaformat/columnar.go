@@ -0,0 +1,150 @@
+package deltaformat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+const columnarVersion = 1
+
+// encodeColumnar lays out a batch of deltas as: a version byte, the number of deltas,
+// then one column per field. Operations take one byte each, ordinals are varint-encoded
+// differences with the previous one, and keys and values are length-prefixed, a nil
+// value being distinguished from an empty one.
+func encodeColumnar(deltas []*pbsubstreamsrpc.StoreDelta) []byte {
+	out := []byte{columnarVersion}
+	out = binary.AppendUvarint(out, uint64(len(deltas)))
+
+	for _, delta := range deltas {
+		out = append(out, byte(delta.Operation))
+	}
+	var previousOrdinal uint64
+	for _, delta := range deltas {
+		out = binary.AppendVarint(out, int64(delta.Ordinal-previousOrdinal))
+		previousOrdinal = delta.Ordinal
+	}
+	for _, delta := range deltas {
+		out = binary.AppendUvarint(out, uint64(len(delta.Key)))
+		out = append(out, delta.Key...)
+	}
+	for _, delta := range deltas {
+		out = appendColumnarValue(out, delta.OldValue)
+	}
+	for _, delta := range deltas {
+		out = appendColumnarValue(out, delta.NewValue)
+	}
+	return out
+}
+
+// appendColumnarValue writes 0 for a nil value, and the length plus one otherwise.
+func appendColumnarValue(out []byte, value []byte) []byte {
+	if value == nil {
+		return binary.AppendUvarint(out, 0)
+	}
+	out = binary.AppendUvarint(out, uint64(len(value))+1)
+	return append(out, value...)
+}
+
+var errColumnarTruncated = errors.New("truncated columnar batch")
+
+type columnarReader struct {
+	data []byte
+}
+
+func (r *columnarReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, errColumnarTruncated
+	}
+	r.data = r.data[n:]
+	return v, nil
+}
+
+func (r *columnarReader) varint() (int64, error) {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		return 0, errColumnarTruncated
+	}
+	r.data = r.data[n:]
+	return v, nil
+}
+
+func (r *columnarReader) bytes(length uint64) ([]byte, error) {
+	if uint64(len(r.data)) < length {
+		return nil, errColumnarTruncated
+	}
+	out := r.data[:length:length]
+	r.data = r.data[length:]
+	return out, nil
+}
+
+func (r *columnarReader) value() ([]byte, error) {
+	length, err := r.uvarint()
+	if err != nil || length == 0 {
+		return nil, err
+	}
+	return r.bytes(length - 1)
+}
+
+func decodeColumnar(data []byte) ([]*pbsubstreamsrpc.StoreDelta, error) {
+	if len(data) == 0 {
+		return nil, errColumnarTruncated
+	}
+	if data[0] != columnarVersion {
+		return nil, fmt.Errorf("unsupported columnar batch version %d", data[0])
+	}
+	r := &columnarReader{data: data[1:]}
+
+	count, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := r.bytes(count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*pbsubstreamsrpc.StoreDelta, count)
+	for i := range out {
+		out[i] = &pbsubstreamsrpc.StoreDelta{Operation: pbsubstreamsrpc.StoreDelta_Operation(ops[i])}
+	}
+
+	var ordinal uint64
+	for _, delta := range out {
+		diff, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		ordinal += uint64(diff)
+		delta.Ordinal = ordinal
+	}
+	for _, delta := range out {
+		length, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		key, err := r.bytes(length)
+		if err != nil {
+			return nil, err
+		}
+		delta.Key = string(key)
+	}
+	for _, delta := range out {
+		if delta.OldValue, err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	for _, delta := range out {
+		if delta.NewValue, err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(r.data) != 0 {
+		return nil, fmt.Errorf("%d unexpected trailing bytes in columnar batch", len(r.data))
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+// Package deltaformat serializes the store deltas streamed to clients in formats
+// other than the native protobuf messages, for consumers replicating store changes.
+package deltaformat
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+)
+
+// Format is the serialization of the deltas, carried with them in `EncodedStoreDeltas`.
+type Format = pbsubstreamsrpc.StoreDeltaFormat
+
+const (
+	// Protobuf deltas are sent as `StoreModuleOutput` messages, the default.
+	Protobuf = pbsubstreamsrpc.StoreDeltaFormat_STORE_DELTA_FORMAT_PROTOBUF
+	// JSON deltas have their values decoded according to the store's value type.
+	JSON = pbsubstreamsrpc.StoreDeltaFormat_STORE_DELTA_FORMAT_JSON
+	// Columnar deltas are batched in a compact binary layout, one column per field,
+	// with values left as is.
+	Columnar = pbsubstreamsrpc.StoreDeltaFormat_STORE_DELTA_FORMAT_COLUMNAR
+)
+
+// ParseFormat parses a format name, as sent in `X-Sf-Substreams-Store-Delta-Format`.
+func ParseFormat(in string) (Format, error) {
+	switch in {
+	case "", "protobuf":
+		return Protobuf, nil
+	case "json":
+		return JSON, nil
+	case "columnar":
+		return Columnar, nil
+	}
+	return 0, fmt.Errorf("invalid store delta format %q, expected 'protobuf', 'json' or 'columnar'", in)
+}
+
+// Encode serializes the deltas of a store holding values of type `valueType`.
+func Encode(f Format, valueType string, deltas []*pbsubstreamsrpc.StoreDelta) ([]byte, error) {
+	switch f {
+	case Protobuf:
+		return proto.Marshal(&pbsubstreamsrpc.StoreModuleOutput{DebugStoreDeltas: deltas})
+	case JSON:
+		return encodeJSON(valueType, deltas)
+	case Columnar:
+		return encodeColumnar(deltas), nil
+	}
+	return nil, fmt.Errorf("unknown store delta format %s", f)
+}
+
+// Decode is the reverse of `Encode`.
+func Decode(f Format, valueType string, data []byte) ([]*pbsubstreamsrpc.StoreDelta, error) {
+	switch f {
+	case Protobuf:
+		out := &pbsubstreamsrpc.StoreModuleOutput{}
+		if err := proto.Unmarshal(data, out); err != nil {
+			return nil, err
+		}
+		return out.DebugStoreDeltas, nil
+	case JSON:
+		return decodeJSON(valueType, data)
+	case Columnar:
+		return decodeColumnar(data)
+	}
+	return nil, fmt.Errorf("unknown store delta format %s", f)
+}
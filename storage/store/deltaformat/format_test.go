@@ -0,0 +1,112 @@
+package deltaformat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+func testDeltas(values ...[]byte) []*pbsubstreamsrpc.StoreDelta {
+	return []*pbsubstreamsrpc.StoreDelta{
+		{Operation: pbsubstreamsrpc.StoreDelta_CREATE, Ordinal: 1, Key: "a", NewValue: values[0]},
+		{Operation: pbsubstreamsrpc.StoreDelta_UPDATE, Ordinal: 3, Key: "a", OldValue: values[0], NewValue: values[1]},
+		{Operation: pbsubstreamsrpc.StoreDelta_DELETE, Ordinal: 3, Key: "b", OldValue: values[1]},
+	}
+}
+
+func assertDeltasEqual(t *testing.T, expected, actual []*pbsubstreamsrpc.StoreDelta) {
+	t.Helper()
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		assert.True(t, proto.Equal(expected[i], actual[i]), "delta %d: expected %v, got %v", i, expected[i], actual[i])
+	}
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	tests := []struct {
+		valueType    string
+		deltas       []*pbsubstreamsrpc.StoreDelta
+		expectedJSON string
+	}{
+		{
+			valueType:    manifest.OutputValueTypeInt64,
+			deltas:       testDeltas([]byte("10"), []byte("-4")),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":10},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":10,"new_value":-4},{"operation":"DELETE","ordinal":3,"key":"b","old_value":-4}]`,
+		},
+		{
+			valueType:    manifest.OutputValueTypeFloat64,
+			deltas:       testDeltas([]byte("1.5e+06"), []byte("NaN")),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":1.5e+06},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":1.5e+06,"new_value":"NaN"},{"operation":"DELETE","ordinal":3,"key":"b","old_value":"NaN"}]`,
+		},
+		{
+			valueType:    manifest.OutputValueTypeBigInt,
+			deltas:       testDeltas([]byte("123456789012345678901234567890"), []byte("0")),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":"123456789012345678901234567890"},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":"123456789012345678901234567890","new_value":"0"},{"operation":"DELETE","ordinal":3,"key":"b","old_value":"0"}]`,
+		},
+		{
+			valueType:    manifest.OutputValueTypeString,
+			deltas:       testDeltas([]byte(`say "hi"`), []byte("")),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":"say \"hi\""},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":"say \"hi\"","new_value":""},{"operation":"DELETE","ordinal":3,"key":"b","old_value":""}]`,
+		},
+		{
+			valueType:    manifest.OutputValueTypeScored,
+			deltas:       testDeltas(store.EncodeScoredValue(-2, []byte{0x01}), store.EncodeScoredValue(7, nil)),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":{"score":-2,"payload":"AQ=="}},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":{"score":-2,"payload":"AQ=="},"new_value":{"score":7,"payload":""}},{"operation":"DELETE","ordinal":3,"key":"b","old_value":{"score":7,"payload":""}}]`,
+		},
+		{
+			valueType:    manifest.OutputValueTypeVersioned,
+			deltas:       testDeltas(store.EncodeVersionedValue(12, []byte{0x01}), store.EncodeVersionedValue(15, nil)),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":{"block_num":12,"payload":"AQ=="}},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":{"block_num":12,"payload":"AQ=="},"new_value":{"block_num":15,"payload":""}},{"operation":"DELETE","ordinal":3,"key":"b","old_value":{"block_num":15,"payload":""}}]`,
+		},
+		{
+			valueType:    "proto:sf.test.Value",
+			deltas:       testDeltas([]byte{0x08, 0x96, 0x01}, []byte{0xff}),
+			expectedJSON: `[{"operation":"CREATE","ordinal":1,"key":"a","new_value":"CJYB"},{"operation":"UPDATE","ordinal":3,"key":"a","old_value":"CJYB","new_value":"/w=="},{"operation":"DELETE","ordinal":3,"key":"b","old_value":"/w=="}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.valueType, func(t *testing.T) {
+			data, err := Encode(JSON, test.valueType, test.deltas)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expectedJSON, string(data))
+
+			decoded, err := Decode(JSON, test.valueType, data)
+			require.NoError(t, err)
+			assertDeltasEqual(t, test.deltas, decoded)
+		})
+	}
+}
+
+func TestColumnar_RoundTrip(t *testing.T) {
+	deltas := testDeltas([]byte("value"), []byte{})
+
+	data, err := Encode(Columnar, manifest.OutputValueTypeString, deltas)
+	require.NoError(t, err)
+
+	decoded, err := Decode(Columnar, manifest.OutputValueTypeString, data)
+	require.NoError(t, err)
+	assertDeltasEqual(t, deltas, decoded)
+	assert.NotNil(t, decoded[1].NewValue, "empty values are kept distinct from missing ones")
+	assert.Nil(t, decoded[2].NewValue)
+
+	_, err = Decode(Columnar, manifest.OutputValueTypeString, data[:len(data)-1])
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"": Protobuf, "protobuf": Protobuf, "json": JSON, "columnar": Columnar} {
+		format, err := ParseFormat(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+
+	_, err := ParseFormat("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package deltaformat
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+// jsonDelta is a delta in the JSON format. Values are JSON numbers for `int64` and
+// `float64` stores, strings for the other textual types, `{"score": ..., "payload": ...}`
+// objects for `scored` stores and base64 strings otherwise. Textual values are expected
+// to be valid UTF-8.
+type jsonDelta struct {
+	Operation string          `json:"operation"`
+	Ordinal   uint64          `json:"ordinal"`
+	Key       string          `json:"key"`
+	OldValue  json.RawMessage `json:"old_value,omitempty"`
+	NewValue  json.RawMessage `json:"new_value,omitempty"`
+}
+
+type jsonScoredValue struct {
+	Score   int64  `json:"score"`
+	Payload []byte `json:"payload"`
+}
+
+type jsonVersionedValue struct {
+	BlockNum uint64 `json:"block_num"`
+	Payload  []byte `json:"payload"`
+}
+
+func encodeJSON(valueType string, deltas []*pbsubstreamsrpc.StoreDelta) ([]byte, error) {
+	out := make([]jsonDelta, len(deltas))
+	for i, delta := range deltas {
+		oldValue, err := encodeJSONValue(valueType, delta.OldValue)
+		if err != nil {
+			return nil, fmt.Errorf("delta %d on key %q: old value: %w", i, delta.Key, err)
+		}
+		newValue, err := encodeJSONValue(valueType, delta.NewValue)
+		if err != nil {
+			return nil, fmt.Errorf("delta %d on key %q: new value: %w", i, delta.Key, err)
+		}
+		out[i] = jsonDelta{
+			Operation: delta.Operation.String(),
+			Ordinal:   delta.Ordinal,
+			Key:       delta.Key,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+		}
+	}
+	return json.Marshal(out)
+}
+
+func decodeJSON(valueType string, data []byte) ([]*pbsubstreamsrpc.StoreDelta, error) {
+	var in []jsonDelta
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+
+	out := make([]*pbsubstreamsrpc.StoreDelta, len(in))
+	for i, delta := range in {
+		op, found := pbsubstreamsrpc.StoreDelta_Operation_value[delta.Operation]
+		if !found {
+			return nil, fmt.Errorf("delta %d on key %q: unknown operation %q", i, delta.Key, delta.Operation)
+		}
+		oldValue, err := decodeJSONValue(valueType, delta.OldValue)
+		if err != nil {
+			return nil, fmt.Errorf("delta %d on key %q: old value: %w", i, delta.Key, err)
+		}
+		newValue, err := decodeJSONValue(valueType, delta.NewValue)
+		if err != nil {
+			return nil, fmt.Errorf("delta %d on key %q: new value: %w", i, delta.Key, err)
+		}
+		out[i] = &pbsubstreamsrpc.StoreDelta{
+			Operation: pbsubstreamsrpc.StoreDelta_Operation(op),
+			Ordinal:   delta.Ordinal,
+			Key:       delta.Key,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+		}
+	}
+	return out, nil
+}
+
+func isTextValueType(valueType string) bool {
+	switch valueType {
+	case manifest.OutputValueTypeString, manifest.OutputValueTypeBigInt, manifest.OutputValueTypeBigDecimal, manifest.OutputValueTypeBigFloat:
+		return true
+	}
+	return false
+}
+
+func isNumberValueType(valueType string) bool {
+	return valueType == manifest.OutputValueTypeInt64 || valueType == manifest.OutputValueTypeFloat64
+}
+
+// isJSONNumber excludes the values, like `NaN` or `+Inf`, that are valid for `float64`
+// stores but not as JSON numbers.
+func isJSONNumber(value []byte) bool {
+	var number json.Number
+	return json.Unmarshal(value, &number) == nil && !strings.ContainsAny(string(value), " \t\r\n\"")
+}
+
+func encodeJSONValue(valueType string, value []byte) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch {
+	case isNumberValueType(valueType) && isJSONNumber(value):
+		return value, nil
+	case isNumberValueType(valueType), isTextValueType(valueType):
+		return json.Marshal(string(value))
+	case valueType == manifest.OutputValueTypeScored:
+		score, payload, err := store.DecodeScoredValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(jsonScoredValue{Score: score, Payload: payload})
+	case valueType == manifest.OutputValueTypeVersioned:
+		blockNum, payload, err := store.DecodeVersionedValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(jsonVersionedValue{BlockNum: blockNum, Payload: payload})
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(value))
+}
+
+func decodeJSONValue(valueType string, value json.RawMessage) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case isNumberValueType(valueType) && !bytes.HasPrefix(value, []byte(`"`)):
+		return []byte(value), nil
+	case isNumberValueType(valueType), isTextValueType(valueType):
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			return nil, err
+		}
+		return []byte(text), nil
+	case valueType == manifest.OutputValueTypeScored:
+		var scored jsonScoredValue
+		if err := json.Unmarshal(value, &scored); err != nil {
+			return nil, err
+		}
+		return store.EncodeScoredValue(scored.Score, scored.Payload), nil
+	case valueType == manifest.OutputValueTypeVersioned:
+		var versioned jsonVersionedValue
+		if err := json.Unmarshal(value, &versioned); err != nil {
+			return nil, err
+		}
+		return store.EncodeVersionedValue(versioned.BlockNum, versioned.Payload), nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(value, &encoded); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// encryptedSnapshotHeader prefixes encrypted snapshots. A protobuf message never starts
+// with a 0 byte, so plain snapshots written before encryption was enabled are told apart
+// and still load.
+var encryptedSnapshotHeader = []byte("\x00sfenc\x01")
+
+// ErrSnapshotDecryption is returned when an encrypted snapshot cannot be decrypted,
+// because the key is not the one it was encrypted with or its content was altered.
+var ErrSnapshotDecryption = errors.New("snapshot decryption failed, wrong key or corrupted content")
+
+// SnapshotKeyProvider returns the key snapshots are encrypted with, for example fetched
+// from a KMS. It is called once per request.
+type SnapshotKeyProvider func(ctx context.Context) ([]byte, error)
+
+// StaticSnapshotKey returns a SnapshotKeyProvider always returning `key`.
+func StaticSnapshotKey(key []byte) SnapshotKeyProvider {
+	return func(context.Context) ([]byte, error) { return key, nil }
+}
+
+// SnapshotCipher encrypts snapshots with AES-GCM, authenticating their content.
+type SnapshotCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSnapshotCipher returns a SnapshotCipher for `key`, 16, 24 or 32 bytes long to
+// select AES-128, AES-192 or AES-256.
+func NewSnapshotCipher(key []byte) (*SnapshotCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot cipher: %w", err)
+	}
+	return &SnapshotCipher{aead: aead}, nil
+}
+
+// Seal returns `plain` encrypted under a random nonce, prefixed by the encrypted
+// snapshot header and the nonce.
+func (c *SnapshotCipher) Seal(plain []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	out := make([]byte, len(encryptedSnapshotHeader)+nonceSize, len(encryptedSnapshotHeader)+nonceSize+len(plain)+c.aead.Overhead())
+	copy(out, encryptedSnapshotHeader)
+	nonce := out[len(encryptedSnapshotHeader):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return c.aead.Seal(out, nonce, plain, encryptedSnapshotHeader), nil
+}
+
+// Open is the reverse of Seal, it fails with ErrSnapshotDecryption if `sealed` was not
+// encrypted with this cipher's key or was altered.
+func (c *SnapshotCipher) Open(sealed []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if !isEncryptedSnapshot(sealed) || len(sealed) < len(encryptedSnapshotHeader)+nonceSize {
+		return nil, fmt.Errorf("not an encrypted snapshot")
+	}
+	nonce := sealed[len(encryptedSnapshotHeader) : len(encryptedSnapshotHeader)+nonceSize]
+	ciphertext := sealed[len(encryptedSnapshotHeader)+nonceSize:]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, encryptedSnapshotHeader)
+	if err != nil {
+		return nil, ErrSnapshotDecryption
+	}
+	return plain, nil
+}
+
+func isEncryptedSnapshot(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedSnapshotHeader)
+}
+
+// sealSnapshot encrypts the content of a snapshot about to be written, if the store
+// is configured with a cipher.
+func (c *Config) sealSnapshot(content []byte) ([]byte, error) {
+	if c.snapshotCipher == nil {
+		return content, nil
+	}
+	return c.snapshotCipher.Seal(content)
+}
+
+// openSnapshot decrypts the content of a loaded snapshot, plain snapshots are returned
+// as is, whether the store is configured with a cipher or not.
+func (c *Config) openSnapshot(data []byte) ([]byte, error) {
+	if !isEncryptedSnapshot(data) {
+		return data, nil
+	}
+	if c.snapshotCipher == nil {
+		return nil, fmt.Errorf("snapshot is encrypted but no encryption key is configured")
+	}
+	return c.snapshotCipher.Open(data)
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestSnapshotEncryption(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{1}, 32)
+	wrongKey := bytes.Repeat([]byte{2}, 32)
+
+	// a single config, its snapshots are in the same sub-store whatever the key
+	config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	withKey := func(key []byte) *Config {
+		config.SetSnapshotCipher(nil)
+		if key != nil {
+			snapshotCipher, err := NewSnapshotCipher(key)
+			require.NoError(t, err)
+			config.SetSnapshotCipher(snapshotCipher)
+		}
+		return config
+	}
+
+	saved := withKey(key).NewFullKV(zap.NewNop())
+	saved.Set(0, "secret", "value")
+	file, writer, err := saved.Save(10)
+	require.NoError(t, err)
+	assert.True(t, isEncryptedSnapshot(writer.content))
+	assert.NotContains(t, string(writer.content), "secret")
+	require.NoError(t, writer.Write(ctx))
+
+	loaded := withKey(key).NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, file))
+	val, found := loaded.GetLast("secret")
+	require.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+
+	err = withKey(wrongKey).NewFullKV(zap.NewNop()).Load(ctx, file)
+	assert.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	err = withKey(nil).NewFullKV(zap.NewNop()).Load(ctx, file)
+	assert.ErrorContains(t, err, "no encryption key is configured")
+
+	// partials are encrypted the same way
+	partial := withKey(key).NewPartialKV(10, zap.NewNop())
+	partial.Set(0, "other", "value")
+	partialFile, writer, err := partial.Save(20)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+	loadedPartial := withKey(key).NewPartialKV(10, zap.NewNop())
+	require.NoError(t, loadedPartial.Load(ctx, partialFile))
+	val, found = loadedPartial.GetLast("other")
+	require.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestSnapshotEncryption_PlainSnapshotsStillLoad(t *testing.T) {
+	ctx := context.Background()
+	objStore := dstore.NewMockStore(nil)
+	config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", objStore, "")
+	require.NoError(t, err)
+
+	plain := config.NewFullKV(zap.NewNop())
+	plain.Set(0, "key", "value")
+	file, writer, err := plain.Save(10)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	snapshotCipher, err := NewSnapshotCipher(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	config.SetSnapshotCipher(snapshotCipher)
+
+	loaded := config.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, file))
+	val, found := loaded.GetLast("key")
+	require.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestSnapshotCipher_Tampered(t *testing.T) {
+	snapshotCipher, err := NewSnapshotCipher(bytes.Repeat([]byte{1}, 16))
+	require.NoError(t, err)
+
+	sealed, err := snapshotCipher.Seal([]byte("content"))
+	require.NoError(t, err)
+	sealed[len(sealed)-1] ^= 0xff
+
+	_, err = snapshotCipher.Open(sealed)
+	assert.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	_, err = NewSnapshotCipher([]byte("short"))
+	assert.Error(t, err)
+}
@@ -9,7 +9,10 @@ import (
 	"github.com/streamingfast/substreams/block"
 )
 
-var stateFileRegex = regexp.MustCompile(`([\d]+)-([\d]+)(?:\.([^\.]+))?\.(kv|partial)`)
+var stateFileRegex = regexp.MustCompile(`([\d]+)-([\d]+)(?:\.([^\.]+))?\.(kv|partial)$`)
+
+// tmpFileSuffix marks snapshot files that are still being written and not yet committed.
+const tmpFileSuffix = ".tmp"
 
 type FileInfos []*FileInfo
 
@@ -65,18 +68,55 @@ func NewPartialFileInfo(moduleName string, start uint64, exclusiveEndBlock uint6
 	}
 }
 
-func parseFileName(moduleName, filename string) (*FileInfo, bool) {
-	res := stateFileRegex.FindAllStringSubmatch(filename, 1)
+// SnapshotKeyFormatter names the objects holding the snapshots of a store, relative to
+// the store's own directory. Keys of full snapshots must sort, lexicographically, in the
+// order of their end block, listing stops at the first snapshot starting at or after the
+// requested block.
+type SnapshotKeyFormatter interface {
+	FullKey(r *block.Range) string
+	PartialKey(r *block.Range, traceID string) string
+
+	// ParseKey is the reverse of `FullKey` and `PartialKey`, `ok` is false for keys that
+	// are not snapshots.
+	ParseKey(key string) (r *block.Range, traceID string, partial bool, ok bool)
+}
+
+// DefaultSnapshotKeyFormatter names snapshots `<end>-<start>.kv` and
+// `<end>-<start>[.<trace_id>].partial`, block numbers padded to 10 digits.
+type DefaultSnapshotKeyFormatter struct{}
+
+func (DefaultSnapshotKeyFormatter) FullKey(r *block.Range) string {
+	return FullStateFileName(r)
+}
+
+func (DefaultSnapshotKeyFormatter) PartialKey(r *block.Range, traceID string) string {
+	return PartialFileName(r, traceID)
+}
+
+func (DefaultSnapshotKeyFormatter) ParseKey(key string) (*block.Range, string, bool, bool) {
+	res := stateFileRegex.FindAllStringSubmatch(key, 1)
 	if len(res) != 1 {
+		return nil, "", false, false
+	}
+	return block.NewRange(uint64(mustAtoi(res[0][2])), uint64(mustAtoi(res[0][1]))), res[0][3], res[0][4] == "partial", true
+}
+
+func parseFileName(moduleName, filename string) (*FileInfo, bool) {
+	return parseSnapshotKey(DefaultSnapshotKeyFormatter{}, moduleName, filename)
+}
+
+func parseSnapshotKey(formatter SnapshotKeyFormatter, moduleName, key string) (*FileInfo, bool) {
+	r, traceID, partial, ok := formatter.ParseKey(key)
+	if !ok {
 		return nil, false
 	}
 
 	return &FileInfo{
 		ModuleName: moduleName,
-		Filename:   filename,
-		Range:      block.NewRange(uint64(mustAtoi(res[0][2])), uint64(mustAtoi(res[0][1]))),
-		TraceID:    res[0][3],
-		Partial:    res[0][4] == "partial",
+		Filename:   key,
+		Range:      r,
+		TraceID:    traceID,
+		Partial:    partial,
 	}, true
 }
 
@@ -0,0 +1,28 @@
+package store
+
+import "fmt"
+
+// Freezable stores reject any write once frozen.
+type Freezable interface {
+	// Freeze makes any further write panic, or fail for the calls returning an error,
+	// with `reason` in the message.
+	Freeze(reason string)
+}
+
+func (b *baseStore) Freeze(reason string) {
+	b.frozenReason = reason
+}
+
+func (b *baseStore) frozenError() error {
+	if b.frozenReason == "" {
+		return nil
+	}
+	return fmt.Errorf("store %q is frozen, write rejected: %s", b.name, b.frozenReason)
+}
+
+// checkNotFrozen panics when the store is frozen, so the module fails at the offending write.
+func (b *baseStore) checkNotFrozen() {
+	if err := b.frozenError(); err != nil {
+		panic(err.Error())
+	}
+}
@@ -24,7 +24,7 @@ func (s *FullKV) Marshaller() marshaller.Marshaller {
 func (s *FullKV) DerivePartialStore(initialBlock uint64) *PartialKV {
 	b := &baseStore{
 		Config:     s.Config,
-		kv:         make(map[string][]byte),
+		kv:         s.Config.newKV(),
 		logger:     s.logger,
 		marshaller: marshaller.Default(),
 	}
@@ -36,26 +36,27 @@ func (s *FullKV) DerivePartialStore(initialBlock uint64) *PartialKV {
 }
 
 func (s *FullKV) Load(ctx context.Context, file *FileInfo) error {
-	s.loadedFrom = file.Filename
-	s.logger.Debug("loading full store state from file", zap.String("fileName", file.Filename))
+	filename := s.snapshotKey(file)
+	s.loadedFrom = filename
+	s.logger.Debug("loading full store state from file", zap.String("fileName", filename))
 
-	data, err := loadStore(ctx, s.objStore, file.Filename)
+	data, err := s.readSnapshot(ctx, filename)
 	if err != nil {
-		return fmt.Errorf("load full store %s at %s: %w", s.name, file.Filename, err)
+		return fmt.Errorf("load full store %s at %s: %w", s.name, filename, err)
+	}
+	data, err = s.openSnapshot(data)
+	if err != nil {
+		return fmt.Errorf("decrypt full store %s at %s: %w", s.name, filename, err)
 	}
 
-	storeData, size, err := s.marshaller.Unmarshal(data)
+	_, size, err := s.unmarshalState(data, nil)
 	if err != nil {
 		return fmt.Errorf("unmarshal store: %w", err)
 	}
-
-	s.kv = storeData.Kv
 	s.totalSizeBytes = size
-	if s.kv == nil {
-		s.kv = make(map[string][]byte)
-	}
+	s.trackSize()
 
-	s.logger.Debug("full store loaded", zap.String("fileName", file.Filename), zap.Int("key_count", len(s.kv)), zap.Uint64("data_size", size))
+	s.logger.Debug("full store loaded", zap.String("fileName", filename), zap.Int("key_count", s.kv.Len()), zap.Uint64("data_size", size))
 	return nil
 }
 
@@ -65,16 +66,17 @@ func (s *FullKV) Load(ctx context.Context, file *FileInfo) error {
 func (s *FullKV) Save(endBoundaryBlock uint64) (*FileInfo, *fileWriter, error) {
 	s.logger.Debug("writing full store state", zap.Object("store", s))
 
-	stateData := &marshaller.StoreData{
-		Kv: s.kv,
-	}
-
-	content, err := s.marshaller.Marshal(stateData)
+	content, err := s.marshalState(nil)
 	if err != nil {
 		return nil, nil, fmt.Errorf("marshal kv state: %w", err)
 	}
+	content, err = s.sealSnapshot(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypt kv state: %w", err)
+	}
 
 	file := NewCompleteFileInfo(s.name, s.moduleInitialBlock, endBoundaryBlock)
+	file.Filename = s.snapshotKey(file)
 
 	s.logger.Info("saving store",
 		zap.String("file_name", file.Filename),
@@ -82,7 +84,7 @@ func (s *FullKV) Save(endBoundaryBlock uint64) (*FileInfo, *fileWriter, error) {
 	)
 
 	fw := &fileWriter{
-		store:    s.objStore,
+		store:    s.fullSnapshotStore(endBoundaryBlock),
 		filename: file.Filename,
 		content:  content,
 	}
@@ -92,12 +94,61 @@ func (s *FullKV) Save(endBoundaryBlock uint64) (*FileInfo, *fileWriter, error) {
 
 func (s *FullKV) Reset() {
 	if tracer.Enabled() {
-		s.logger.Debug("flushing store", zap.Int("delta_count", len(s.deltas)), zap.Int("entry_count", len(s.kv)))
+		s.logger.Debug("flushing store", zap.Int("delta_count", len(s.deltas)), zap.Int("entry_count", s.kv.Len()))
 	}
 	s.deltas = nil
+	s.appendBuffers = nil
 	s.lastOrdinal = 0
 }
 
 func (s *FullKV) String() string {
-	return fmt.Sprintf("fullKV name %s moduleInitialBlock %d keyCount %d loadedFrom %s deltasCount %d", s.Name(), s.moduleInitialBlock, len(s.kv), s.loadedFrom, len(s.deltas))
+	return fmt.Sprintf("fullKV name %s moduleInitialBlock %d keyCount %d loadedFrom %s deltasCount %d", s.Name(), s.moduleInitialBlock, s.kv.Len(), s.loadedFrom, len(s.deltas))
+}
+
+// ReadOnlyStore is the read side of a store, as returned by SnapshotAt.
+type ReadOnlyStore interface {
+	Reader
+	Iterable
+	Scanner
+}
+
+// SnapshotAt reconstructs, from the snapshots in storage, the state of the store at
+// `blockNum`, which must be a snapshot boundary. The returned store is independent
+// of `s`: neither sees the changes made to the other.
+func (s *FullKV) SnapshotAt(ctx context.Context, blockNum uint64) (ReadOnlyStore, error) {
+	out, err := s.Config.StateAt(ctx, blockNum, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StateAt loads, from the snapshots in storage, the state of the store at `blockNum`,
+// which must be a snapshot boundary: the full snapshot ending there if there is one,
+// otherwise the latest full snapshot before it with the following partials merged in.
+func (c *Config) StateAt(ctx context.Context, blockNum uint64, logger *zap.Logger) (*FullKV, error) {
+	if blockNum < c.moduleInitialBlock {
+		return nil, fmt.Errorf("block %d is before the initial block %d of store %q", blockNum, c.moduleInitialBlock, c.name)
+	}
+
+	files, err := c.ListSnapshotFiles(ctx, blockNum)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshot files: %w", err)
+	}
+
+	for _, file := range files {
+		if !file.Partial && file.Range.StartBlock == c.moduleInitialBlock && file.Range.ExclusiveEndBlock == blockNum {
+			out := c.NewFullKV(logger)
+			if err := out.Load(ctx, file); err != nil {
+				return nil, err
+			}
+			return out, nil
+		}
+	}
+
+	base, partials, err := replayFiles(c, files, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	return replay(ctx, c, base, partials, logger)
 }
@@ -3,11 +3,15 @@ package store
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/streamingfast/dstore"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	"github.com/streamingfast/substreams/storage/store/marshaller"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -24,7 +28,7 @@ func TestFullKV_Save_Load_Empty_MapNotNil(t *testing.T) {
 
 	kvs := &FullKV{
 		baseStore: &baseStore{
-			kv: map[string][]byte{},
+			kv: memoryKV{},
 
 			logger:     zap.NewNop(),
 			marshaller: marshaller.Default(),
@@ -44,7 +48,7 @@ func TestFullKV_Save_Load_Empty_MapNotNil(t *testing.T) {
 
 	kvl := &FullKV{
 		baseStore: &baseStore{
-			kv: map[string][]byte{},
+			kv: memoryKV{},
 
 			logger:     zap.NewNop(),
 			marshaller: marshaller.Default(),
@@ -60,3 +64,88 @@ func TestFullKV_Save_Load_Empty_MapNotNil(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNilf(t, kvl.kv, "kvl.kv is nil")
 }
+
+func TestFullKV_Load_RetriesTransientErrors(t *testing.T) {
+	store := dstore.NewMockStore(nil)
+	store.SetFile("0000000100-0000000000.kv", nil)
+
+	openCalls := 0
+	store.OpenObjectFunc = func(ctx context.Context, name string) (out io.ReadCloser, err error) {
+		openCalls++
+		if openCalls == 1 {
+			return nil, fmt.Errorf("503 service unavailable")
+		}
+		content, err := marshaller.Default().Marshal(&marshaller.StoreData{Kv: map[string][]byte{"a": []byte("1")}})
+		require.NoError(t, err)
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+
+	config := &Config{objStore: store}
+	config.SetLoadRetryPolicy(2, time.Millisecond)
+	kv := config.NewFullKV(zap.NewNop())
+
+	require.NoError(t, kv.Load(context.Background(), NewCompleteFileInfo("test", 0, 100)))
+	assert.Equal(t, 2, openCalls)
+	val, found := kv.GetLast("a")
+	assert.True(t, found)
+	assert.Equal(t, "1", string(val))
+}
+
+func TestFullKV_Load_RetriesExhausted(t *testing.T) {
+	store := dstore.NewMockStore(nil)
+	store.OpenObjectFunc = func(ctx context.Context, name string) (out io.ReadCloser, err error) {
+		return nil, fmt.Errorf("503 service unavailable")
+	}
+
+	config := &Config{objStore: store}
+	config.SetLoadRetryPolicy(2, time.Millisecond)
+
+	err := config.NewFullKV(zap.NewNop()).Load(context.Background(), NewCompleteFileInfo("test", 0, 100))
+	var loadErr *SnapshotLoadError
+	require.ErrorAs(t, err, &loadErr)
+	assert.Equal(t, uint64(3), loadErr.Attempts)
+}
+
+func TestFullKV_SnapshotAt(t *testing.T) {
+	ctx := context.Background()
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	config.itemSizeLimit = 9999
+	config.totalSizeLimit = 9999
+
+	writePartial := func(start, end uint64, kvs map[string]string) {
+		s := config.NewPartialKV(start, zap.NewNop())
+		for k, v := range kvs {
+			s.Set(0, k, v)
+		}
+		_, writer, err := s.Save(end)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+	}
+	writePartial(0, 10, map[string]string{"a": "1"})
+	writePartial(10, 20, map[string]string{"a": "2", "b": "3"})
+
+	live := config.NewFullKV(zap.NewNop())
+	live.Set(0, "a", "live")
+
+	snapshot, err := live.SnapshotAt(ctx, 20)
+	require.NoError(t, err)
+
+	val, found := snapshot.GetLast("a")
+	require.True(t, found)
+	assert.Equal(t, []byte("2"), val)
+	assert.Equal(t, uint64(2), snapshot.Length())
+
+	live.Set(1, "b", "live")
+	live.DeletePrefix(2, "a")
+	val, _ = snapshot.GetLast("b")
+	assert.Equal(t, []byte("3"), val)
+	assert.True(t, snapshot.HasLast("a"))
+
+	snapshot.(*FullKV).Set(0, "c", "snapshot")
+	assert.False(t, live.HasLast("c"))
+	assert.Equal(t, uint64(1), live.Length())
+
+	_, err = live.SnapshotAt(ctx, 30)
+	assert.EqualError(t, err, `cannot replay store "test": no partial snapshot starting at block 20`)
+}
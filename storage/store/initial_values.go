@@ -0,0 +1,55 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+)
+
+// SetInitialValues declares the keys the store holds at its module's initial block,
+// before the module first executes. Values must parse as the store's value type.
+func (c *Config) SetInitialValues(values map[string][]byte) error {
+	for key, value := range values {
+		if len(key) == 0 {
+			return fmt.Errorf("initial values: invalid key, must be at least 1 character")
+		}
+		if c.reservedKeyPrefix != "" && strings.HasPrefix(key, c.reservedKeyPrefix) {
+			return fmt.Errorf("initial values: key %s uses the prefix %s reserved for internal system use", truncatedKey(key), c.reservedKeyPrefix)
+		}
+		if c.isSystemKey(key) {
+			return fmt.Errorf("initial values: key %s collides with the keys written by the system", truncatedKey(key))
+		}
+		if err := validateValueType(c.valueType, value); err != nil {
+			return fmt.Errorf("initial values: key %s: invalid value %q for value type %q: %w", truncatedKey(key), value, c.valueType, err)
+		}
+	}
+	c.initialValues = values
+	return nil
+}
+
+func (c *Config) HasInitialValues() bool {
+	return len(c.initialValues) != 0
+}
+
+// LoadInitialValues sets the initial values declared by the module in the store. It is
+// only called on stores reaching the module's initial block: later stores get the
+// values through the snapshots, and merges, of the ones before them.
+//
+// The values are not module writes, so no deltas are recorded for them.
+func (b *baseStore) LoadInitialValues() {
+	keys := make([]string, 0, len(b.initialValues))
+	for key := range b.initialValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		b.ApplyDelta(&pbssinternal.StoreDelta{
+			Operation: pbssinternal.StoreDelta_CREATE,
+			Key:       key,
+			NewValue:  b.initialValues[key],
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_InitialValues(t *testing.T) {
+	config, err := NewConfig("test", 10, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeInt64, dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	require.NoError(t, config.SetInitialValues(map[string][]byte{"seeded": []byte("100")}))
+
+	// the partial starting at the module's initial block holds the seed from the start
+	first := config.NewPartialKV(10, zap.NewNop())
+	first.LoadInitialValues()
+	assertInt64Value(t, first, "seeded", "100")
+	assert.Empty(t, first.GetDeltas(), "initial values are not module writes")
+
+	first.SumInt64(0, "seeded", 5)
+	first.SumInt64(0, "other", 1)
+	assertInt64Value(t, first, "seeded", "105")
+
+	// later partials start empty, the seed reaches them through the merge
+	second := config.NewPartialKV(20, zap.NewNop())
+	second.SumInt64(0, "seeded", 3)
+
+	// like the squasher, merge the partials into an empty full store at the initial block
+	full := config.NewFullKV(zap.NewNop())
+	require.NoError(t, full.Merge(first))
+	assertInt64Value(t, full, "seeded", "105")
+	require.NoError(t, full.Merge(second))
+	assertInt64Value(t, full, "seeded", "108")
+	assertInt64Value(t, full, "other", "1")
+
+	// a full store executing linearly from the initial block
+	linear := config.NewFullKV(zap.NewNop())
+	linear.LoadInitialValues()
+	assertInt64Value(t, linear, "seeded", "100")
+}
+
+func TestStore_InitialValuesInvalid(t *testing.T) {
+	config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeInt64, dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, config.SetInitialValues(map[string][]byte{"key": []byte("not a number")}), `invalid value "not a number" for value type "int64"`)
+	assert.ErrorContains(t, config.SetInitialValues(map[string][]byte{"": []byte("1")}), "invalid key")
+	assert.ErrorContains(t, config.SetInitialValues(map[string][]byte{internalKeyPrefix + "key": []byte("1")}), "reserved for internal system use")
+}
+
+func assertInt64Value(t *testing.T, s interface{ GetLast(string) ([]byte, bool) }, key, expected string) {
+	t.Helper()
+	val, found := s.GetLast(key)
+	require.True(t, found, "key %q", key)
+	assert.Equal(t, expected, string(val))
+}
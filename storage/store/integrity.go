@@ -0,0 +1,133 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/streamingfast/substreams/manifest"
+)
+
+// metadataKeyPrefix prefixes the internal keys describing, in a snapshot, the module
+// definition the store was built with. They are stripped from the state on load.
+const metadataKeyPrefix = internalKeyPrefix + "meta:"
+
+const (
+	metadataValueType    = "value_type"
+	metadataUpdatePolicy = "update_policy"
+	metadataModuleHash   = "module_hash"
+
+	// metadataBigFloatPrecision is only recorded when set, see `Config.SetBigFloatPrecision`,
+	// so snapshots of stores not using it are unchanged.
+	metadataBigFloatPrecision = "bigfloat_precision"
+)
+
+// metadataKey holds all the metadata fields in a single entry, one `field=value` line
+// per field in the order of `metadataFields`. Wherever the marshaller places that entry
+// in the snapshot, two stores with the same metadata serialize it to the same bytes,
+// which separate keys, written in map order, would not guarantee.
+const metadataKey = metadataKeyPrefix + "all"
+
+var metadataFields = []string{metadataModuleHash, metadataValueType, metadataUpdatePolicy, metadataBigFloatPrecision}
+
+// IntegrityError is returned when loading a snapshot whose metadata disagrees with
+// the definition of the module being run.
+type IntegrityError struct {
+	Store    string
+	Field    string
+	Snapshot string
+	Expected string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("store %q: snapshot integrity check failed, %s is %q in the loaded snapshot but %q in the module definition", e.Store, e.Field, e.Snapshot, e.Expected)
+}
+
+func (b *baseStore) metadata() map[string]string {
+	meta := map[string]string{
+		metadataValueType:    b.valueType,
+		metadataUpdatePolicy: b.updatePolicy.String(),
+		metadataModuleHash:   b.moduleHash,
+	}
+	if b.bigFloatPrecision != 0 && b.isBigFloat() {
+		meta[metadataBigFloatPrecision] = strconv.FormatUint(uint64(b.bigFloatPrecision), 10)
+	}
+	return meta
+}
+
+// recordedBigFloatPrecision returns the bigfloat precision recorded in the snapshot last
+// loaded, if any, otherwise the configured one. A snapshot written without a precision
+// recorded 0, merging its `bigfloat` values as decimals.
+func (b *baseStore) recordedBigFloatPrecision() uint {
+	if b.loadedBigFloatPrecision == nil {
+		return b.bigFloatPrecision
+	}
+	return *b.loadedBigFloatPrecision
+}
+
+// isBigFloat reports whether the store holds `bigfloat` values, the only ones the
+// bigfloat precision applies to.
+func (c *Config) isBigFloat() bool {
+	return strings.ToLower(c.valueType) == manifest.OutputValueTypeBigFloat
+}
+
+// parseBigFloatPrecision returns the bigfloat precision recorded in `meta`, 0 if none.
+func parseBigFloatPrecision(meta map[string]string) uint {
+	bits, err := strconv.ParseUint(meta[metadataBigFloatPrecision], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(bits)
+}
+
+// encodeMetadata serializes `meta` as the value of `metadataKey`.
+func encodeMetadata(meta map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, field := range metadataFields {
+		if field == metadataBigFloatPrecision && meta[field] == "" {
+			continue
+		}
+		buf.WriteString(field)
+		buf.WriteByte('=')
+		buf.WriteString(meta[field])
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// decodeMetadata is the reverse of encodeMetadata, adding the fields to `meta`.
+func decodeMetadata(value []byte, meta map[string]string) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(value), "\n"), "\n") {
+		if field, fieldValue, found := strings.Cut(line, "="); found {
+			meta[field] = fieldValue
+		}
+	}
+}
+
+// loadMetadataEntry adds to `meta` the metadata held by an entry of a snapshot being
+// loaded. It returns false for the entries of the state.
+func loadMetadataEntry(meta map[string]string, key string, value []byte) bool {
+	if key != metadataKey {
+		return false
+	}
+	decodeMetadata(value, meta)
+	return true
+}
+
+// checkIntegrity returns an *IntegrityError if `loaded`, the metadata of a snapshot
+// being loaded, does not match the store configuration. Snapshots written before
+// metadata was recorded have none and are not verified.
+func (b *baseStore) checkIntegrity(loaded map[string]string) error {
+	expected := b.metadata()
+	for _, field := range metadataFields {
+		if field == metadataBigFloatPrecision && !b.isBigFloat() {
+			// older versions recorded it for every store
+			continue
+		}
+		if value, found := loaded[field]; found && value != expected[field] {
+			return &IntegrityError{Store: b.name, Field: field, Snapshot: value, Expected: expected[field]}
+		}
+	}
+	return nil
+}
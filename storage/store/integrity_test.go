@@ -0,0 +1,152 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/store/marshaller"
+)
+
+func TestFullKV_IntegrityCheckOnLoad(t *testing.T) {
+	ctx := context.Background()
+
+	// load saves a snapshot built with `savedValueType` and loads it in a store
+	// of the same module declared with `valueType`
+	load := func(savedValueType, valueType string) (*FullKV, error) {
+		config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, savedValueType, dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+
+		written := config.NewFullKV(zap.NewNop())
+		written.Set(0, "a", "1")
+		file, writer, err := written.Save(10)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+		assert.Equal(t, uint64(1), written.Length(), "metadata must not stay in the saved store")
+
+		loadConfig := *config
+		loadConfig.valueType = valueType
+		loaded := loadConfig.NewFullKV(zap.NewNop())
+		return loaded, loaded.Load(ctx, file)
+	}
+
+	matching, err := load("int64", "int64")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"a": []byte("1")}, matching.kv.Map())
+	matching.Set(1, "b", "2")
+
+	_, err = load("int64", "string")
+	var integrityErr *IntegrityError
+	require.ErrorAs(t, err, &integrityErr)
+	assert.Equal(t, &IntegrityError{Store: "test", Field: "value_type", Snapshot: "int64", Expected: "string"}, integrityErr)
+}
+
+func TestStore_MetadataSerializedIdentically(t *testing.T) {
+	newConfig := func() *Config {
+		config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+		return config
+	}
+
+	first := newConfig().NewFullKV(zap.NewNop())
+	for _, key := range []string{"a", "b", "c", "d"} {
+		first.Set(0, key, "value")
+	}
+	second := newConfig().NewPartialKV(0, zap.NewNop())
+	for _, key := range []string{"d", "c", "b", "a", "e"} {
+		second.Set(0, key, "value")
+	}
+
+	// the metadata is a single entry, its fields in a fixed order
+	entry, err := first.marshaller.Marshal(&marshaller.StoreData{Kv: map[string][]byte{metadataKey: encodeMetadata(first.metadata())}})
+	require.NoError(t, err)
+	assert.Equal(t, "module_hash=abc\nvalue_type=string\nupdate_policy=UPDATE_POLICY_SET\n", string(encodeMetadata(first.metadata())))
+
+	for i := 0; i < 10; i++ {
+		_, firstWriter, err := first.Save(10)
+		require.NoError(t, err)
+		_, secondWriter, err := second.Save(10)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, bytes.Count(firstWriter.content, entry))
+		assert.Equal(t, 1, bytes.Count(secondWriter.content, entry))
+	}
+}
+
+func TestFullKV_BigFloatPrecisionRecorded(t *testing.T) {
+	ctx := context.Background()
+
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigfloat", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	config.SetBigFloatPrecision(256)
+
+	written := config.NewFullKV(zap.NewNop())
+	written.kv.Set("a", []byte("1"))
+	file, writer, err := written.Save(10)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	loaded := config.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, file))
+	assert.Equal(t, uint(256), loaded.recordedBigFloatPrecision())
+
+	// resuming with another precision is refused
+	otherConfig := *config
+	otherConfig.SetBigFloatPrecision(128)
+	other := otherConfig.NewFullKV(zap.NewNop())
+	var integrityErr *IntegrityError
+	require.ErrorAs(t, other.Load(ctx, file), &integrityErr)
+	assert.Equal(t, &IntegrityError{Store: "test", Field: "bigfloat_precision", Snapshot: "256", Expected: "128"}, integrityErr)
+
+	// and so is merging partials built with another precision
+	partial := newPartialStore(map[string][]byte{"a": []byte("2")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigfloat", nil)
+	partial.SetBigFloatPrecision(128)
+	assert.EqualError(t, loaded.Merge(partial), "incompatible bigfloat precisions: cannot merge 256 bits and 128 bits")
+}
+
+func TestFullKV_BigFloatPrecisionOnlyForBigFloatStores(t *testing.T) {
+	ctx := context.Background()
+
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	config.SetBigFloatPrecision(256)
+
+	written := config.NewFullKV(zap.NewNop())
+	written.kv.Set("a", []byte("1"))
+	file, writer, err := written.Save(10)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+	assert.NotContains(t, string(writer.content), metadataBigFloatPrecision)
+
+	loaded := config.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, file))
+
+	// partials written without the precision still merge
+	partial := newPartialStore(map[string][]byte{"a": []byte("2")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64", nil)
+	require.NoError(t, loaded.Merge(partial))
+}
+
+func TestFullKV_BigFloatPrecisionKeptAfterWrites(t *testing.T) {
+	ctx := context.Background()
+
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigfloat", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	config.SetBigFloatPrecision(256)
+
+	written := config.NewFullKV(zap.NewNop())
+	written.kv.Set("a", []byte("1"))
+	file, writer, err := written.Save(10)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	loaded := config.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, file))
+	loaded.mustSet(1, "a", []byte("2"))
+	assert.Equal(t, uint(256), loaded.recordedBigFloatPrecision())
+}
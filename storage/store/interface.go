@@ -18,34 +18,50 @@ type Store interface {
 	Loadable
 	Savable
 	Iterable
+	Scanner
 	DeltaAccessor
 	Resettable
 	Mergeable
 	Named
+	Checksummer
 	// todoo: add fmt.Stringer ??
 
 	// intrinsics
 	Reader
+	TypedReader
+	TopReader
+	OrdinalSnapshotter
 
 	UpdateKeySetter
 	ConditionalKeySetter
+	ComparingKeySetter
+	NewerBlockSetter
 	Appender
+	UniqueAppender
+	Incrementer
 	Deleter
 
 	MaxBigIntSetter
 	MaxInt64Setter
 	MaxFloat64Setter
 	MaxBigDecimalSetter
+	MaxScoredSetter
 
 	MinBigIntSetter
 	MinInt64Setter
 	MinFloat64Setter
 	MinBigDecimalSetter
+	MinScoredSetter
 
 	SumBigIntSetter
 	SumInt64Setter
 	SumFloat64Setter
 	SumBigDecimalSetter
+
+	BitOrInt64Setter
+	BitOrBigIntSetter
+	BitAndInt64Setter
+	BitAndBigIntSetter
 }
 
 type PartialStore interface {
@@ -60,10 +76,20 @@ type Savable interface {
 	Save(endBoundaryBlock uint64) (*FileInfo, *fileWriter, error)
 }
 
+// Checksummer hashes the logical content of a store, see `baseStore.Checksum`.
+type Checksummer interface {
+	Checksum() string
+}
+
 type Resettable interface {
 	Reset()
 }
 
+// Committable stores write the changes of a block out once it is processed.
+type Committable interface {
+	Commit() error
+}
+
 type Named interface {
 	Name() string
 }
@@ -71,6 +97,32 @@ type Named interface {
 type Iterable interface {
 	Length() uint64
 	Iter(func(key string, value []byte) error) error
+	CountPrefix(prefix string) int
+
+	// Iterate visits all keys in lexicographical order, skipping the reserved ones, and
+	// stops at the first error returned by `f`. IterateWithReserved also visits those.
+	Iterate(f func(key string, value []byte) error) error
+	IterateWithReserved(f func(key string, value []byte) error) error
+}
+
+// Scanner enumerates keys page by page, never returning more than the
+// store's configured scan limit in a single call.
+type Scanner interface {
+	Scan(prefix string, continuationToken string, limit uint64) *ScanResult
+}
+
+type KV struct {
+	Key   string
+	Value []byte
+}
+
+type ScanResult struct {
+	Entries []*KV
+
+	// Truncated is true when more keys matched than what was returned,
+	// in which case ContinuationToken is the token to use for the next call.
+	Truncated         bool
+	ContinuationToken string
 }
 
 type DeltaAccessor interface {
@@ -94,33 +146,93 @@ type Reader interface {
 	HasAt(ord uint64, key string) bool
 }
 
+// TypedReader reads the last value of a key decoded per the store's value type, the
+// same way merges do. Reading with the accessor of another value type is an error.
+type TypedReader interface {
+	GetAsInt64(key string) (int64, bool, error)
+	GetAsBigInt(key string) (*big.Int, bool, error)
+	GetAsFloat64(key string) (float64, bool, error)
+	GetAsBigFloat(key string) (*big.Float, bool, error)
+
+	// GetBigInt is GetAsBigInt returning nil for absent keys, and panicking on errors.
+	GetBigInt(key string) (*big.Int, bool)
+}
+
+// TopReader ranks the keys of numeric stores by their value.
+type TopReader interface {
+	TopN(n int) []*KV
+}
+
+// OrdinalSnapshotter dumps the state as of an ordinal of the current block, for debugging.
+type OrdinalSnapshotter interface {
+	SnapshotAtOrdinal(ord uint64) map[string][]byte
+}
+
 type Mergeable interface {
 	ValueType() string
 	UpdatePolicy() pbsubstreams.Module_KindStore_UpdatePolicy
 }
 
 type UpdateKeySetter interface {
+	// PutBytes fails when `key` cannot be written, where Set and SetBytes panic.
+	PutBytes(ord uint64, key string, value []byte) error
 	Set(ord uint64, key string, value string)
 	SetBytes(ord uint64, key string, value []byte)
+	SetBigInt(ord uint64, key string, value *big.Int)
 }
 
 type ConditionalKeySetter interface {
+	// PutBytesIfNotExists fails when `key` cannot be written, where the Set variants panic.
+	PutBytesIfNotExists(ord uint64, key string, value []byte) error
 	SetIfNotExists(ord uint64, key string, value string)
 	SetBytesIfNotExists(ord uint64, key string, value []byte)
 }
 
+// ComparingKeySetter writes a number under a key only when it is larger, or smaller,
+// than the number already there, compared per the store's value type.
+type ComparingKeySetter interface {
+	PutIfGreater(ord uint64, key string, value []byte) (bool, error)
+	PutIfLess(ord uint64, key string, value []byte) (bool, error)
+}
+
+type NewerBlockSetter interface {
+	SetIfNewerBlock(ord uint64, key string, blockNum uint64, payload []byte)
+}
+
 type Appender interface {
 	Append(ord uint64, key string, value []byte) error
 }
 
+// Incrementer updates the integer under a key in place, for `int64` and `bigint` stores.
+type Incrementer interface {
+	Increment(ord uint64, key string, value int64)
+	Decrement(ord uint64, key string, value int64)
+}
+
+type UniqueAppender interface {
+	// AppendUnique appends `value` as a length-prefixed element, unless an identical element
+	// is already present under `key`. Keys written with it must not be written with Append.
+	AppendUnique(ord uint64, key string, value []byte) (added bool, err error)
+}
+
 type Deleter interface {
+	Delete(ord uint64, key string)
 	DeletePrefix(ord uint64, prefix string)
+	// DeleteMany deletes all of `keys` under one ordinal, skipping the absent ones.
+	DeleteMany(ord uint64, keys []string)
 	//// Deletes a range of keys, lexicographically between `lowKey` and `highKey`
 	//DeleteRange(lowKey, highKey string)
 	//// Deletes a range of keys, first considering the _value_ of such keys as a _pointerSeparator_-separated list of keys to _also_ delete.
 	//DeleteRangePointers(lowKey, highKey, pointerSeparator string)
 }
 
+type MaxScoredSetter interface {
+	SetMaxScored(ord uint64, key string, score int64, payload []byte)
+}
+type MinScoredSetter interface {
+	SetMinScored(ord uint64, key string, score int64, payload []byte)
+}
+
 type MaxBigIntSetter interface {
 	SetMaxBigInt(ord uint64, key string, value *big.Int)
 }
@@ -147,6 +259,19 @@ type MinBigDecimalSetter interface {
 	SetMinBigDecimal(ord uint64, key string, value decimal.Decimal)
 }
 
+type BitOrInt64Setter interface {
+	BitOrInt64(ord uint64, key string, value int64)
+}
+type BitOrBigIntSetter interface {
+	BitOrBigInt(ord uint64, key string, value *big.Int)
+}
+type BitAndInt64Setter interface {
+	BitAndInt64(ord uint64, key string, value int64)
+}
+type BitAndBigIntSetter interface {
+	BitAndBigInt(ord uint64, key string, value *big.Int)
+}
+
 type SumBigIntSetter interface {
 	SumBigInt(ord uint64, key string, value *big.Int)
 }
@@ -1,18 +1,101 @@
 package store
 
+import (
+	"sort"
+	"strings"
+)
+
 func (b *baseStore) Length() uint64 {
-	return uint64(len(b.kv))
+	return uint64(b.kv.Len())
 }
 
 func (b *baseStore) Iter(f func(key string, value []byte) error) error {
-	for k, v := range b.kv {
-		if err := f(k, v); err != nil {
+	return b.kv.Iterate(f)
+}
+
+func (b *baseStore) Iterate(f func(key string, value []byte) error) error {
+	return b.iterateSorted(false, f)
+}
+
+func (b *baseStore) IterateWithReserved(f func(key string, value []byte) error) error {
+	return b.iterateSorted(true, f)
+}
+
+func (b *baseStore) iterateSorted(includeReserved bool, f func(key string, value []byte) error) error {
+	var keys []string
+	_ = b.kv.Iterate(func(key string, _ []byte) error {
+		if includeReserved || !b.isSystemKey(key) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, found := b.kv.Get(key)
+		if !found {
+			continue
+		}
+		if err := f(key, value); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// CountPrefix returns the number of keys starting with `prefix`, including the changes
+// made in the current block.
+func (b *baseStore) CountPrefix(prefix string) (count int) {
+	_ = b.kv.Iterate(func(key string, _ []byte) error {
+		if strings.HasPrefix(key, prefix) {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
 func (b *baseStore) SizeBytes() uint64 {
 	return b.totalSizeBytes
 }
+
+// Scan enumerates, in lexicographical order, the keys starting with `prefix` that sort
+// strictly after `continuationToken` (an empty token starts from the beginning).
+//
+// At most `limit` entries are returned, `limit` being itself capped by the store's
+// configured scan limit (a `limit` of 0 means "up to the scan limit"). When more keys
+// remain, the result is marked as truncated and holds the ContinuationToken to pass
+// back to get the next page.
+func (b *baseStore) Scan(prefix string, continuationToken string, limit uint64) *ScanResult {
+	if limit == 0 || (b.scanLimit > 0 && limit > b.scanLimit) {
+		limit = b.scanLimit
+	}
+
+	var keys []string
+	values := make(map[string][]byte)
+	_ = b.kv.Iterate(func(k string, v []byte) error {
+		if !strings.HasPrefix(k, prefix) {
+			return nil
+		}
+		if continuationToken != "" && k <= continuationToken {
+			return nil
+		}
+		keys = append(keys, k)
+		values[k] = v
+		return nil
+	})
+	sort.Strings(keys)
+
+	out := &ScanResult{}
+	if limit > 0 && uint64(len(keys)) > limit {
+		keys = keys[:limit]
+		out.Truncated = true
+		out.ContinuationToken = keys[len(keys)-1]
+	}
+
+	out.Entries = make([]*KV, len(keys))
+	for i, k := range keys {
+		out.Entries[i] = &KV{Key: k, Value: values[k]}
+	}
+	return out
+}
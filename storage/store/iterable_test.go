@@ -0,0 +1,97 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Scan(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "", nil)
+	s.scanLimit = 3
+
+	for i := 0; i < 7; i++ {
+		s.Set(uint64(i), fmt.Sprintf("key:%d", i), "v")
+	}
+	s.Set(7, "other", "v")
+
+	res := s.Scan("key:", "", 0)
+	require.True(t, res.Truncated)
+	assert.Equal(t, "key:2", res.ContinuationToken)
+	assert.Equal(t, []string{"key:0", "key:1", "key:2"}, scanKeys(res))
+
+	res = s.Scan("key:", res.ContinuationToken, 100)
+	require.True(t, res.Truncated)
+	assert.Equal(t, []string{"key:3", "key:4", "key:5"}, scanKeys(res))
+
+	res = s.Scan("key:", res.ContinuationToken, 0)
+	assert.False(t, res.Truncated)
+	assert.Empty(t, res.ContinuationToken)
+	assert.Equal(t, []string{"key:6"}, scanKeys(res))
+
+	res = s.Scan("", "", 2)
+	assert.True(t, res.Truncated)
+	assert.Equal(t, []string{"key:0", "key:1"}, scanKeys(res))
+}
+
+func scanKeys(res *ScanResult) (out []string) {
+	for _, kv := range res.Entries {
+		out = append(out, kv.Key)
+	}
+	return
+}
+
+func TestStore_CountPrefix(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "", nil)
+
+	s.Set(0, "holder:token1:alice", "1")
+	s.Set(1, "holder:token1:bob", "1")
+	s.Set(2, "holder:token2:alice", "1")
+	assert.Equal(t, 2, s.CountPrefix("holder:token1:"))
+
+	s.Set(3, "holder:token1:bob", "2")
+	assert.Equal(t, 2, s.CountPrefix("holder:token1:"))
+
+	s.DeletePrefix(4, "holder:token1:alice")
+	assert.Equal(t, 1, s.CountPrefix("holder:token1:"))
+	assert.Equal(t, 2, s.CountPrefix("holder:"))
+	assert.Equal(t, 0, s.CountPrefix("nothing"))
+}
+
+func TestStore_Iterate(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "", nil)
+	for i, key := range []string{"c", "a", "b:2", "b:1"} {
+		s.Set(uint64(i), key, key)
+	}
+	s.kv.Set(internalKeyPrefix+"meta", []byte("internal"))
+
+	var keys []string
+	require.NoError(t, s.Iterate(func(key string, value []byte) error {
+		assert.Equal(t, key, string(value))
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "b:1", "b:2", "c"}, keys)
+
+	keys = nil
+	require.NoError(t, s.IterateWithReserved(func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{internalKeyPrefix + "meta", "a", "b:1", "b:2", "c"}, keys)
+
+	keys = nil
+	stop := fmt.Errorf("stop")
+	err := s.Iterate(func(key string, _ []byte) error {
+		keys = append(keys, key)
+		if key == "b:1" {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"a", "b:1"}, keys)
+}
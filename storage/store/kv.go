@@ -0,0 +1,58 @@
+package store
+
+// kvBackend holds the actual key/value state of a store. By default the state
+// lives in memory, but it can be spilled to local disk for stores that would
+// not fit in RAM (see `Config.SetSpillDirectory`).
+type kvBackend interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+	Len() int
+
+	// Iterate calls `f` on every entry, in no particular order. It is safe to
+	// modify the backend from within `f`.
+	Iterate(f func(key string, value []byte) error) error
+
+	// Map returns all the entries as a map, loading them in memory if needed.
+	// The returned map must not be modified.
+	Map() map[string][]byte
+
+	// Commit makes the writes made so far durable, returning any error met by the
+	// backend since the store was created.
+	Commit() error
+	Close() error
+}
+
+// kvEntries goes over key/value entries, stopping at the first error returned by `f`.
+type kvEntries func(f func(key string, value []byte) error) error
+
+// newKV returns the empty backend configured for the store.
+func (c *Config) newKV() kvBackend {
+	if c.spillDirectory == "" {
+		return make(memoryKV)
+	}
+	return newSpillKV(c.spillDirectory, c.name, c.spills)
+}
+
+type memoryKV map[string][]byte
+
+func (m memoryKV) Get(key string) ([]byte, bool) {
+	val, found := m[key]
+	return val, found
+}
+
+func (m memoryKV) Set(key string, value []byte) { m[key] = value }
+func (m memoryKV) Delete(key string)            { delete(m, key) }
+func (m memoryKV) Len() int                     { return len(m) }
+func (m memoryKV) Map() map[string][]byte       { return m }
+func (m memoryKV) Commit() error                { return nil }
+func (m memoryKV) Close() error                 { return nil }
+
+func (m memoryKV) Iterate(f func(key string, value []byte) error) error {
+	for k, v := range m {
+		if err := f(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,6 +10,19 @@ type Marshaller interface {
 	Marshal(data *StoreData) ([]byte, error)
 }
 
+// StreamMarshaller is implemented by the marshallers able to go over the entries of a
+// store one at a time, so that the state never has to be gathered in a map.
+type StreamMarshaller interface {
+	Marshaller
+
+	// MarshalEntries serializes the entries `iterate` goes over, followed by `deletePrefixes`.
+	MarshalEntries(iterate func(f func(key string, value []byte) error) error, deletePrefixes []string) ([]byte, error)
+
+	// UnmarshalEntries calls `f` on each entry of `in`, returning the delete prefixes and
+	// the size of the entries. The key and value handed to `f` point into `in`.
+	UnmarshalEntries(in []byte, f func(key string, value []byte) error) ([]string, uint64, error)
+}
+
 func Default() Marshaller {
 	return &VTproto{}
 }
@@ -1,6 +1,7 @@
 package marshaller
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 
@@ -30,12 +31,62 @@ func (p *VTproto) Marshal(data *StoreData) ([]byte, error) {
 	return stateData.MarshalVT()
 }
 
+// MarshalEntries implements StreamMarshaller, writing the same bytes as Marshal.
+func (p *VTproto) MarshalEntries(iterate func(f func(key string, value []byte) error) error, deletePrefixes []string) ([]byte, error) {
+	var out []byte
+	err := iterate(func(key string, value []byte) error {
+		out = appendKVEntry(out, key, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range deletePrefixes {
+		out = append(out, DeletePrefixEntryProtoTag)
+		out = binary.AppendUvarint(out, uint64(len(prefix)))
+		out = append(out, prefix...)
+	}
+	return out, nil
+}
+
+// UnmarshalEntries implements StreamMarshaller.
+func (p *VTproto) UnmarshalEntries(in []byte, f func(key string, value []byte) error) ([]string, uint64, error) {
+	stateData := &pbstore.StoreData{}
+	dataSize, err := unmarshalVTEntries(stateData, in, f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unmarshal store: %w", err)
+	}
+	return stateData.GetDeletePrefixes(), dataSize, nil
+}
+
+func appendKVEntry(out []byte, key string, value []byte) []byte {
+	out = append(out, KVEntryProtoTag)
+	out = binary.AppendUvarint(out, uint64(kvEntryByteSize(key, value)))
+	out = append(out, KVEntryKeyProtoTag)
+	out = binary.AppendUvarint(out, uint64(len(key)))
+	out = append(out, key...)
+	out = append(out, KVEntryValueProtoTag)
+	out = binary.AppendUvarint(out, uint64(len(value)))
+	return append(out, value...)
+}
+
+func unmarshalVT(m *pbstore.StoreData, dAtA []byte) (dataSize uint64, err error) {
+	return unmarshalVTEntries(m, dAtA, func(key string, value []byte) error {
+		if m.Kv == nil {
+			m.Kv = make(map[string][]byte)
+		}
+		m.Kv[key] = value
+		return nil
+	})
+}
+
 // The function `func (m *StoreData) UnmarshalVT(dAtA []byte) error` that is generated
 // by the vtprotobuf protobuf plugin is ok, but we can greatly improve the allocation and
 // speed with a few optimizations. This function is a 98% copy of the function in
 // ./pb/store_vtproto.pb.go
-// we've added byte counter too
-func unmarshalVT(m *pbstore.StoreData, dAtA []byte) (dataSize uint64, err error) {
+// we've added byte counter too, and the entries of `Kv` are handed to `onEntry`
+// instead of being set on `m`
+func unmarshalVTEntries(m *pbstore.StoreData, dAtA []byte, onEntry func(key string, value []byte) error) (dataSize uint64, err error) {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -93,9 +144,6 @@ func unmarshalVT(m *pbstore.StoreData, dAtA []byte) (dataSize uint64, err error)
 			if postIndex > l {
 				return 0, io.ErrUnexpectedEOF
 			}
-			if m.Kv == nil {
-				m.Kv = make(map[string][]byte)
-			}
 			var mapkey string
 			var mapvalue []byte
 			for iNdEx < postIndex {
@@ -198,7 +246,9 @@ func unmarshalVT(m *pbstore.StoreData, dAtA []byte) (dataSize uint64, err error)
 					iNdEx += skippy
 				}
 			}
-			m.Kv[mapkey] = mapvalue
+			if err := onEntry(mapkey, mapvalue); err != nil {
+				return 0, err
+			}
 			dataSize += uint64(len(mapkey) + len(mapvalue))
 			iNdEx = postIndex
 		case 2:
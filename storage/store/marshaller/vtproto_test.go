@@ -0,0 +1,54 @@
+package marshaller
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVTproto_Entries(t *testing.T) {
+	m := &VTproto{}
+	kv := map[string][]byte{
+		"a":     {0xaa},
+		"b":     {},
+		"b:key": []byte("value"),
+	}
+
+	content, err := m.MarshalEntries(func(f func(key string, value []byte) error) error {
+		keys := make([]string, 0, len(kv))
+		for k := range kv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := f(k, kv[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, []string{"c:", "d:"})
+	require.NoError(t, err)
+
+	// the streamed bytes decode like any other snapshot
+	data, size, err := m.Unmarshal(content)
+	require.NoError(t, err)
+	assert.Equal(t, kv, data.Kv)
+	assert.Equal(t, []string{"c:", "d:"}, data.DeletePrefixes)
+	assert.Equal(t, uint64(13), size)
+
+	expected, err := m.Marshal(&StoreData{Kv: kv, DeletePrefixes: []string{"c:", "d:"}})
+	require.NoError(t, err)
+
+	var entries []string
+	deletePrefixes, size, err := m.UnmarshalEntries(expected, func(key string, value []byte) error {
+		assert.Equal(t, kv[key], value)
+		entries = append(entries, key)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "b:key"}, entries)
+	assert.Equal(t, []string{"c:", "d:"}, deletePrefixes)
+	assert.Equal(t, uint64(13), size)
+}
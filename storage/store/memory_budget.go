@@ -0,0 +1,130 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dustin/go-humanize"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memoryBudgetTopConsumers is the number of stores named when the budget is exceeded.
+const memoryBudgetTopConsumers = 3
+
+// MemoryBudget caps the approximate memory used by all the stores of a request,
+// summing the sizes of keys and values each store holds. Stores report their size
+// on every write, the budget itself is only enforced by `Check`.
+//
+// Each store instance has its own counter, several instances of a module's store can
+// be alive at once, for example while partials are merged. A counter is released when
+// its store is discarded, see `Release`.
+type MemoryBudget struct {
+	limit uint64
+	total atomic.Int64
+
+	lock   sync.Mutex
+	stores map[*atomic.Int64]string // store name, by counter
+}
+
+func NewMemoryBudget(limit uint64) *MemoryBudget {
+	return &MemoryBudget{
+		limit:  limit,
+		stores: make(map[*atomic.Int64]string),
+	}
+}
+
+// counter registers a new store instance named `storeName`.
+func (m *MemoryBudget) counter(storeName string) *atomic.Int64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c := &atomic.Int64{}
+	m.stores[c] = storeName
+	return c
+}
+
+// release removes the size of a store instance no longer in use.
+func (m *MemoryBudget) release(counter *atomic.Int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.stores, counter)
+	m.total.Add(-counter.Swap(0))
+}
+
+// track records `size` as the current size of the store.
+func (m *MemoryBudget) track(counter *atomic.Int64, size uint64) {
+	previous := counter.Swap(int64(size))
+	m.total.Add(int64(size) - previous)
+}
+
+// Usage returns the sum of the sizes of all the stores.
+func (m *MemoryBudget) Usage() uint64 {
+	if total := m.total.Load(); total > 0 {
+		return uint64(total)
+	}
+	return 0
+}
+
+// Check returns a `ResourceExhausted` error naming the largest stores if their
+// total size is over the budget.
+func (m *MemoryBudget) Check() error {
+	usage := m.Usage()
+	if usage <= m.limit {
+		return nil
+	}
+
+	type consumer struct {
+		name string
+		size int64
+	}
+	m.lock.Lock()
+	consumers := make([]consumer, 0, len(m.stores))
+	for c, name := range m.stores {
+		consumers = append(consumers, consumer{name: name, size: c.Load()})
+	}
+	m.lock.Unlock()
+
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].size == consumers[j].size {
+			return consumers[i].name < consumers[j].name
+		}
+		return consumers[i].size > consumers[j].size
+	})
+	if len(consumers) > memoryBudgetTopConsumers {
+		consumers = consumers[:memoryBudgetTopConsumers]
+	}
+
+	top := make([]string, len(consumers))
+	for i, c := range consumers {
+		top[i] = fmt.Sprintf("%q (%s)", c.name, humanize.IBytes(uint64(c.size)))
+	}
+	return status.Errorf(codes.ResourceExhausted, "stores memory budget exceeded, using %s out of %s, top consumers: %s", humanize.IBytes(usage), humanize.IBytes(m.limit), strings.Join(top, ", "))
+}
+
+// trackSize reports the current size of the store to the memory budget, if any.
+func (b *baseStore) trackSize() {
+	if b.memoryBudget == nil {
+		return
+	}
+	if b.budgetCounter == nil {
+		b.budgetCounter = b.memoryBudget.counter(b.name)
+	}
+	b.memoryBudget.track(b.budgetCounter, b.totalSizeBytes)
+}
+
+// Release removes the store from the memory budget, if any. It is to be called once
+// the store is discarded, a partial once merged or a full store replaced by another,
+// so that its size no longer counts against the budget. Writing to the store again
+// registers it anew.
+func (b *baseStore) Release() {
+	if b.memoryBudget == nil || b.budgetCounter == nil {
+		return
+	}
+	b.memoryBudget.release(b.budgetCounter)
+	b.budgetCounter = nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestMemoryBudget_SharedByStores(t *testing.T) {
+	budget := NewMemoryBudget(100)
+
+	newBudgetedStore := func(name string) *baseStore {
+		s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+		s.name = name
+		s.SetMemoryBudget(budget)
+		return s
+	}
+	small := newBudgetedStore("small")
+	medium := newBudgetedStore("medium")
+	large := newBudgetedStore("large")
+	other := newBudgetedStore("other")
+
+	set := func(s *baseStore, key string, size int) {
+		s.ApplyDelta(&pbssinternal.StoreDelta{Operation: pbssinternal.StoreDelta_CREATE, Key: key, NewValue: make([]byte, size)})
+	}
+
+	set(small, "k", 9)
+	set(medium, "k", 19)
+	set(large, "k", 29)
+	set(other, "k", 4)
+	assert.Equal(t, uint64(65), budget.Usage())
+	require.NoError(t, budget.Check())
+
+	// no store is over the budget alone, but they are together
+	set(large, "k2", 38)
+	assert.Equal(t, uint64(105), budget.Usage())
+
+	err := budget.Check()
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), `top consumers: "large" (70 B), "medium" (20 B), "small" (10 B)`)
+	assert.NotContains(t, err.Error(), `"other"`)
+
+	large.ApplyDelta(&pbssinternal.StoreDelta{Operation: pbssinternal.StoreDelta_DELETE, Key: "k2", OldValue: make([]byte, 38)})
+	assert.Equal(t, uint64(65), budget.Usage())
+	require.NoError(t, budget.Check())
+}
+
+func TestMemoryBudget_CountsStoreInstances(t *testing.T) {
+	budget := NewMemoryBudget(100)
+
+	newBudgetedStore := func() *baseStore {
+		s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+		s.name = "store"
+		s.SetMemoryBudget(budget)
+		return s
+	}
+	set := func(s *baseStore, key string, size int) {
+		s.ApplyDelta(&pbssinternal.StoreDelta{Operation: pbssinternal.StoreDelta_CREATE, Key: key, NewValue: make([]byte, size)})
+	}
+
+	// a full store and a partial of the same module are both held
+	full := newBudgetedStore()
+	partial := newBudgetedStore()
+	set(full, "k", 29)
+	set(partial, "k", 9)
+	assert.Equal(t, uint64(40), budget.Usage())
+
+	set(partial, "k2", 8)
+	assert.Equal(t, uint64(50), budget.Usage())
+	set(full, "k2", 8)
+	assert.Equal(t, uint64(60), budget.Usage())
+
+	partial.Release()
+	assert.Equal(t, uint64(40), budget.Usage())
+}
+
+func TestMemoryBudget_ReleasesMergedPartials(t *testing.T) {
+	ctx := context.Background()
+	config, err := NewConfig("store", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+
+	var partials []*FileInfo
+	for i := 0; i < 10; i++ {
+		partial := config.NewPartialKV(uint64(i*10), zap.NewNop())
+		partial.Set(0, "k", strings.Repeat("v", 39))
+		file, writer, err := partial.Save(uint64(i*10 + 10))
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+		partials = append(partials, file)
+	}
+
+	// a loaded partial counts the size of its snapshot, over 100 bytes, and the full
+	// store 40 bytes: the budget only fits the full store and one partial at a time
+	budget := NewMemoryBudget(200)
+	config.SetMemoryBudget(budget)
+
+	full := config.NewFullKV(zap.NewNop())
+	for _, file := range partials {
+		partial := config.NewPartialKV(file.Range.StartBlock, zap.NewNop())
+		require.NoError(t, partial.Load(ctx, file))
+		require.NoError(t, full.Merge(partial))
+		require.NoError(t, budget.Check())
+		partial.Release()
+	}
+	assert.Equal(t, uint64(40), budget.Usage())
+
+	require.NoError(t, MergePartials(ctx, full, partials, 0))
+	assert.Equal(t, uint64(40), budget.Usage())
+
+	// a full store replaced by another one
+	full.Release()
+	assert.Equal(t, uint64(0), budget.Usage())
+
+	// a rolled partial starts over
+	partial := config.NewPartialKV(0, zap.NewNop())
+	partial.Set(0, "k", strings.Repeat("v", 39))
+	assert.Equal(t, uint64(40), budget.Usage())
+	partial.Roll(10)
+	assert.Equal(t, uint64(0), budget.Usage())
+}
@@ -11,27 +11,30 @@ import (
 
 	"github.com/shopspring/decimal"
 	"github.com/streamingfast/substreams/manifest"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 )
 
 func (b *baseStore) setKV(k string, v []byte) {
-	if prev, ok := b.kv[k]; ok {
+	if prev, ok := b.kv.Get(k); ok {
 		b.totalSizeBytes -= uint64(len(prev))
 	} else {
 		b.totalSizeBytes += uint64(len(k))
 	}
 	b.totalSizeBytes += uint64(len(v))
-	b.kv[k] = v
+	b.kv.Set(k, v)
 }
 
 func (b *baseStore) setNewKV(k string, v []byte) {
 	b.totalSizeBytes += uint64(len(k) + len(v))
-	b.kv[k] = v
+	b.kv.Set(k, v)
 }
 
 // Merge nextStore _into_ `s`, where nextStore is for the next contiguous segment's store output.
 func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
-	b.logger.Debug("merging store", zap.Int("current_key_count", len(b.kv)), zap.Uint64("mod_init_block", b.moduleInitialBlock), zap.Int("partial_key_count", len(kvPartialStore.kv)), zap.Uint64("partial_start_block", kvPartialStore.initialBlock))
+	defer b.trackSize()
+
+	b.logger.Debug("merging store", zap.Int("current_key_count", b.kv.Len()), zap.Uint64("mod_init_block", b.moduleInitialBlock), zap.Int("partial_key_count", kvPartialStore.kv.Len()), zap.Uint64("partial_start_block", kvPartialStore.initialBlock))
 
 	if kvPartialStore.updatePolicy != b.updatePolicy {
 		return fmt.Errorf("incompatible update policies: policy %q cannot merge policy %q", b.updatePolicy, kvPartialStore.updatePolicy)
@@ -41,30 +44,57 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 		return fmt.Errorf("incompatible value types: cannot merge %q and %q", b.valueType, kvPartialStore.valueType)
 	}
 
+	if b.isBigFloat() {
+		if into, from := b.recordedBigFloatPrecision(), kvPartialStore.recordedBigFloatPrecision(); into != from {
+			return fmt.Errorf("incompatible bigfloat precisions: cannot merge %d bits and %d bits", into, from)
+		}
+	}
+
+	// partials written by older versions may hold redundant prefixes
+	deletedPrefixes := coalescePrefixes(kvPartialStore.DeletedPrefixes)
+	if b.maxDeletedPrefixes != 0 && uint64(len(deletedPrefixes)) > b.maxDeletedPrefixes {
+		return fmt.Errorf("partial deletes %d distinct key prefixes, more than the maximum of %d", len(deletedPrefixes), b.maxDeletedPrefixes)
+	}
+
 	partialKvTime := time.Now()
-	for _, prefix := range kvPartialStore.DeletedPrefixes {
+	for _, prefix := range deletedPrefixes {
 		b.DeletePrefix(kvPartialStore.lastOrdinal, prefix)
 	}
-	if len(kvPartialStore.DeletedPrefixes) > 0 {
+	if len(deletedPrefixes) > 0 {
 		b.logger.Info("merging: applied delete prefixes", zap.Duration("duration", time.Since(partialKvTime)))
 	}
 
 	intoValueTypeLower := strings.ToLower(b.valueType)
 
+	b.applyDeletedKeys(kvPartialStore)
+	partialKV := kvEntries(kvPartialStore.kv.Iterate)
+
 	switch b.updatePolicy {
 	case pbsubstreams.Module_KindStore_UPDATE_POLICY_SET:
-		for k, v := range kvPartialStore.kv {
+		if intoValueTypeLower == manifest.OutputValueTypeVersioned {
+			if err := b.mergeVersioned(partialKV); err != nil {
+				return fmt.Errorf("merging versioned values: %w", err)
+			}
+			break
+		}
+		if err := partialKV(func(k string, v []byte) error {
 			b.setKV(k, v)
+			return nil
+		}); err != nil {
+			return err
 		}
 	case pbsubstreams.Module_KindStore_UPDATE_POLICY_SET_IF_NOT_EXISTS:
-		for k, v := range kvPartialStore.kv {
-			if _, found := b.kv[k]; !found {
+		if err := partialKV(func(k string, v []byte) error {
+			if _, found := b.kv.Get(k); !found {
 				b.setNewKV(k, v)
 			}
+			return nil
+		}); err != nil {
+			return err
 		}
 	case pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND:
-		for k, v := range kvPartialStore.kv {
-			if prevVal, found := b.kv[k]; found {
+		if err := partialKV(func(k string, v []byte) error {
+			if prevVal, found := b.kv.Get(k); found {
 				newLen := len(prevVal) + len(v)
 				if b.appendLimit > 0 && uint64(newLen) >= b.appendLimit {
 					return fmt.Errorf("append would exceed limit of %d bytes", b.appendLimit)
@@ -77,6 +107,9 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 			} else {
 				b.setNewKV(k, v)
 			}
+			return nil
+		}); err != nil {
+			return err
 		}
 	case pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD:
 		// check valueType to do the right thing
@@ -85,40 +118,82 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 			sum := func(a, b int64) int64 {
 				return a + b
 			}
-			for k, v := range kvPartialStore.kv {
-				v0b, fv0 := b.kv[k]
-				v0 := foundOrZeroInt64(v0b, fv0)
-				v1 := foundOrZeroInt64(v, true)
+			if err := partialKV(func(k string, v []byte) error {
+				v0b, fv0 := b.kv.Get(k)
+				v0, err := foundOrZeroInt64(k, v0b, fv0)
+				if err != nil {
+					return err
+				}
+				v1, err := foundOrZeroInt64(k, v, true)
+				if err != nil {
+					return err
+				}
 				b.setKV(k, []byte(fmt.Sprintf("%d", sum(v0, v1))))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeFloat64:
 			sum := func(a, b float64) float64 {
 				return a + b
 			}
-			for k, v := range kvPartialStore.kv {
-				v0b, fv0 := b.kv[k]
-				v0 := foundOrZeroFloat(v0b, fv0)
-				v1 := foundOrZeroFloat(v, true)
+			if err := partialKV(func(k string, v []byte) error {
+				v0b, fv0 := b.kv.Get(k)
+				v0, err := foundOrZeroFloat(k, v0b, fv0)
+				if err != nil {
+					return err
+				}
+				v1, err := foundOrZeroFloat(k, v, true)
+				if err != nil {
+					return err
+				}
 				b.setKV(k, floatToBytes(sum(v0, v1)))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeBigInt:
 			sum := func(a, b *big.Int) *big.Int {
 				return new(big.Int).Add(a, b)
 			}
-			for k, v := range kvPartialStore.kv {
-				v0b, fv0 := b.kv[k]
-				v0 := foundOrZeroBigInt(v0b, fv0)
-				v1 := foundOrZeroBigInt(v, true)
+			if err := partialKV(func(k string, v []byte) error {
+				v0b, fv0 := b.kv.Get(k)
+				v0, err := foundOrZeroBigInt(k, v0b, fv0)
+				if err != nil {
+					return err
+				}
+				v1, err := foundOrZeroBigInt(k, v, true)
+				if err != nil {
+					return err
+				}
 				b.setKV(k, []byte(fmt.Sprintf("%d", sum(v0, v1))))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeBigFloat:
+			if b.bigFloatPrecision != 0 {
+				if err := b.mergeBigFloat(partialKV, func(a, c *big.Float) *big.Float { return a.Add(a, c) }); err != nil {
+					return err
+				}
+				break
+			}
 			fallthrough
 		case manifest.OutputValueTypeBigDecimal:
-			for k, v := range kvPartialStore.kv {
-				v0b, fv0 := b.kv[k]
-				v0 := foundOrZeroBigDecimal(v0b, fv0)
-				v1 := foundOrZeroBigDecimal(v, true)
+			if err := partialKV(func(k string, v []byte) error {
+				v0b, fv0 := b.kv.Get(k)
+				v0, err := foundOrZeroBigDecimal(k, v0b, fv0)
+				if err != nil {
+					return err
+				}
+				v1, err := foundOrZeroBigDecimal(k, v, true)
+				if err != nil {
+					return err
+				}
 				b.setKV(k, []byte(v0.Add(v1).String()))
+				return nil
+			}); err != nil {
+				return err
 			}
 		default:
 			return fmt.Errorf("update policy %q not supported for value type %q", b.updatePolicy, b.valueType)
@@ -132,16 +207,25 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return b
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroInt64(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroInt64(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, []byte(fmt.Sprintf("%d", v1)))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroInt64(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroInt64(v, true)
 
 				b.setKV(k, []byte(fmt.Sprintf("%d", max(v0, v1))))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeFloat64:
 			max := func(a, b float64) float64 {
@@ -150,16 +234,25 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return a
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroFloat(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroFloat(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, floatToBytes(v1))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroFloat(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroFloat(v, true)
 
 				b.setKV(k, floatToBytes(max(v0, v1)))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeBigInt:
 			max := func(a, b *big.Int) *big.Int {
@@ -168,18 +261,38 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return a
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroBigInt(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroBigInt(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, []byte(v1.String()))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroBigInt(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroBigInt(v, true)
 
 				b.setKV(k, []byte(fmt.Sprintf("%d", max(v0, v1))))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeBigFloat:
+			if b.bigFloatPrecision != 0 {
+				if err := b.mergeBigFloat(partialKV, func(a, c *big.Float) *big.Float {
+					if a.Cmp(c) < 0 {
+						return c
+					}
+					return a
+				}); err != nil {
+					return err
+				}
+				break
+			}
 			fallthrough
 		case manifest.OutputValueTypeBigDecimal:
 			max := func(a, b decimal.Decimal) decimal.Decimal {
@@ -188,16 +301,29 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return a
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroBigDecimal(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroBigDecimal(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, []byte(v1.String()))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroBigDecimal(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroBigDecimal(v, true)
 
 				b.setNewKV(k, []byte(max(v0, v1).String()))
+				return nil
+			}); err != nil {
+				return err
+			}
+		case manifest.OutputValueTypeScored:
+			if err := b.mergeScored(partialKV, func(existing, candidate int64) bool { return candidate > existing }); err != nil {
+				return fmt.Errorf("merging max scored values: %w", err)
 			}
 		default:
 			return fmt.Errorf("update policy %q not supported for value type %q", kvPartialStore.updatePolicy, kvPartialStore.valueType)
@@ -211,16 +337,25 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return b
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroInt64(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroInt64(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, []byte(fmt.Sprintf("%d", v1)))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroInt64(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroInt64(v, true)
 
 				b.setKV(k, []byte(fmt.Sprintf("%d", min(v0, v1))))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeFloat64:
 			min := func(a, b float64) float64 {
@@ -229,16 +364,25 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return b
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroFloat(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroFloat(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, floatToBytes(v1))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroFloat(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroFloat(v, true)
 
 				b.setKV(k, floatToBytes(min(v0, v1)))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeBigInt:
 			min := func(a, b *big.Int) *big.Int {
@@ -247,18 +391,38 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return b
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroBigInt(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroBigInt(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, []byte(v1.String()))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroBigInt(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroBigInt(v, true)
 
 				b.setKV(k, []byte(fmt.Sprintf("%d", min(v0, v1))))
+				return nil
+			}); err != nil {
+				return err
 			}
 		case manifest.OutputValueTypeBigFloat:
+			if b.bigFloatPrecision != 0 {
+				if err := b.mergeBigFloat(partialKV, func(a, c *big.Float) *big.Float {
+					if c.Cmp(a) < 0 {
+						return c
+					}
+					return a
+				}); err != nil {
+					return err
+				}
+				break
+			}
 			fallthrough
 		case manifest.OutputValueTypeBigDecimal:
 			min := func(a, b decimal.Decimal) decimal.Decimal {
@@ -267,96 +431,262 @@ func (b *baseStore) Merge(kvPartialStore *PartialKV) error {
 				}
 				return b
 			}
-			for k, v := range kvPartialStore.kv {
-				v1 := foundOrZeroBigDecimal(v, true)
-				v, found := b.kv[k]
+			if err := partialKV(func(k string, v []byte) error {
+				v1, err := foundOrZeroBigDecimal(k, v, true)
+				if err != nil {
+					return err
+				}
+				v, found := b.kv.Get(k)
 				if !found {
 					b.setNewKV(k, []byte(v1.String()))
-					continue
+					return nil
+				}
+				v0, err := foundOrZeroBigDecimal(k, v, true)
+				if err != nil {
+					return err
 				}
-				v0 := foundOrZeroBigDecimal(v, true)
 				b.setNewKV(k, []byte(min(v0, v1).String()))
+				return nil
+			}); err != nil {
+				return err
+			}
+		case manifest.OutputValueTypeScored:
+			if err := b.mergeScored(partialKV, func(existing, candidate int64) bool { return candidate < existing }); err != nil {
+				return fmt.Errorf("merging min scored values: %w", err)
 			}
 		default:
 			return fmt.Errorf("update policy %q not supported for value type %q", b.updatePolicy, b.valueType)
 		}
+	case pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR:
+		if err := b.mergeBitwise(partialKV,
+			func(a, b int64) int64 { return a | b },
+			func(a, b *big.Int) *big.Int { return new(big.Int).Or(a, b) },
+		); err != nil {
+			return err
+		}
+	case pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND:
+		if err := b.mergeBitwise(partialKV,
+			func(a, b int64) int64 { return a & b },
+			func(a, b *big.Int) *big.Int { return new(big.Int).And(a, b) },
+		); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("update policy %q not supported", b.updatePolicy) // should have been validated already
 	}
 
 	b.Reset() // Merge should never keep deltas or ordinals
+	return b.Commit()
+}
+
+// mergeBitwise combines, for each key of `partialKV`, the full store's value with the
+// partial's one using the operator of the store's integer value type. A key absent from
+// the full store takes the partial's value.
+func (b *baseStore) mergeBitwise(partialKV kvEntries, int64Op func(a, b int64) int64, bigIntOp func(a, b *big.Int) *big.Int) error {
+	switch strings.ToLower(b.valueType) {
+	case manifest.OutputValueTypeInt64:
+		if err := partialKV(func(k string, v []byte) error {
+			v1, err := foundOrZeroInt64(k, v, true)
+			if err != nil {
+				return err
+			}
+			v, found := b.kv.Get(k)
+			if !found {
+				b.setNewKV(k, []byte(fmt.Sprintf("%d", v1)))
+				return nil
+			}
+			v0, err := foundOrZeroInt64(k, v, true)
+			if err != nil {
+				return err
+			}
+			b.setKV(k, []byte(fmt.Sprintf("%d", int64Op(v0, v1))))
+			return nil
+		}); err != nil {
+			return err
+		}
+	case manifest.OutputValueTypeBigInt:
+		if err := partialKV(func(k string, v []byte) error {
+			v1, err := foundOrZeroBigInt(k, v, true)
+			if err != nil {
+				return err
+			}
+			v, found := b.kv.Get(k)
+			if !found {
+				b.setNewKV(k, []byte(v1.String()))
+				return nil
+			}
+			v0, err := foundOrZeroBigInt(k, v, true)
+			if err != nil {
+				return err
+			}
+			b.setKV(k, []byte(bigIntOp(v0, v1).String()))
+			return nil
+		}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("update policy %q not supported for value type %q", b.updatePolicy, b.valueType)
+	}
 	return nil
 }
 
-func foundOrZeroInt64(in []byte, found bool) int64 {
+// mergeBigFloat combines, for each key of `partialKV`, the full store's value with the
+// partial's one using `op`, both parsed with the store's configured bigfloat precision.
+// A key absent from the full store takes the partial's value.
+func (b *baseStore) mergeBigFloat(partialKV kvEntries, op func(a, b *big.Float) *big.Float) error {
+	return partialKV(func(k string, v []byte) error {
+		v1, err := foundOrZeroBigFloat(k, v, true, b.bigFloatPrecision)
+		if err != nil {
+			return err
+		}
+		v, found := b.kv.Get(k)
+		if !found {
+			b.setNewKV(k, bigFloatToBytes(v1))
+			return nil
+		}
+		v0, err := foundOrZeroBigFloat(k, v, true, b.bigFloatPrecision)
+		if err != nil {
+			return err
+		}
+		b.setKV(k, bigFloatToBytes(op(v0, v1)))
+		return nil
+	})
+}
+
+// applyDeletedKeys deletes, from the full store, the keys recorded as deleted in the
+// partial, see `PartialKV.DeleteMany`.
+func (b *baseStore) applyDeletedKeys(partial *PartialKV) {
+	for key := range partial.DeletedKeys {
+		if val, found := b.kv.Get(key); found {
+			b.ApplyDelta(&pbssinternal.StoreDelta{
+				Operation: pbssinternal.StoreDelta_DELETE,
+				Key:       key,
+				OldValue:  val,
+			})
+		}
+	}
+}
+
+// The foundOrZero* helpers parse the value of `key` for merging: an absent key counts as
+// zero, while a present value that doesn't parse, a corrupted snapshot for example, is
+// an error rather than being silently taken for zero.
+
+func foundOrZeroInt64(key string, in []byte, found bool) (int64, error) {
 	if !found {
-		return 0
+		return 0, nil
 	}
-	val, err := strconv.ParseInt(string(in), 10, 64)
+	val, err := parseInt64(in)
 	if err != nil {
-		return 0
+		return 0, invalidMergeValue(key, in, manifest.OutputValueTypeInt64, err)
 	}
-	return int64(val)
+	return val, nil
 }
 
-func foundOrZeroBigDecimal(in []byte, found bool) decimal.Decimal {
+func foundOrZeroBigDecimal(key string, in []byte, found bool) (decimal.Decimal, error) {
 	if !found {
-		return decimal.NewFromInt(0)
+		return decimal.NewFromInt(0), nil
 	}
 	out, err := decimal.NewFromString(string(in))
 	if err != nil {
-		panic(err)
+		return decimal.Decimal{}, invalidMergeValue(key, in, manifest.OutputValueTypeBigDecimal, err)
 	}
-	return out.Truncate(34)
+	return out.Truncate(34), nil
 }
 
-func foundOrZeroBigFloat(in []byte, found bool) *big.Float {
+func foundOrZeroBigFloat(key string, in []byte, found bool, prec uint) (*big.Float, error) {
 	if !found {
-		return new(big.Float).SetPrec(100)
+		return new(big.Float).SetPrec(prec), nil
 	}
-	return bytesToBigFloat(in)
+	out, err := parseBigFloatWithPrecision(in, prec)
+	if err != nil {
+		return nil, invalidMergeValue(key, in, manifest.OutputValueTypeBigFloat, err)
+	}
+	return out, nil
 }
 
-func foundOrZeroBigInt(in []byte, found bool) *big.Int {
+func foundOrZeroBigInt(key string, in []byte, found bool) (*big.Int, error) {
 	if !found {
-		return new(big.Int)
+		return new(big.Int), nil
+	}
+	out, err := parseBigInt(in)
+	if err != nil {
+		return nil, invalidMergeValue(key, in, manifest.OutputValueTypeBigInt, err)
 	}
-	return bytesToBigInt(in)
+	return out, nil
 }
 
-func foundOrZeroFloat(in []byte, found bool) float64 {
+func foundOrZeroFloat(key string, in []byte, found bool) (float64, error) {
 	if !found {
-		return float64(0)
+		return float64(0), nil
 	}
 
 	f, err := strconv.ParseFloat(string(in), 64)
 	if err != nil {
-		return float64(0)
+		return 0, invalidMergeValue(key, in, manifest.OutputValueTypeFloat64, err)
 	}
-	return f
+	return f, nil
+}
+
+func invalidMergeValue(key string, value []byte, valueType string, err error) error {
+	return fmt.Errorf("merging key %q: value %q is not a valid %s: %w", key, value, valueType, err)
+}
+
+func parseInt64(in []byte) (int64, error) {
+	return strconv.ParseInt(string(in), 10, 64)
+}
+
+// defaultBigFloatPrecision is the precision, in bits, of `bigfloat` values parsed when the
+// store does not configure one.
+const defaultBigFloatPrecision = 100
+
+func parseBigFloat(in []byte) (*big.Float, error) {
+	return parseBigFloatWithPrecision(in, defaultBigFloatPrecision)
+}
+
+func parseBigFloatWithPrecision(in []byte, prec uint) (*big.Float, error) {
+	newFloat, _, err := big.ParseFloat(string(in), 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, err
+	}
+	return newFloat.SetPrec(prec), nil
+}
+
+func parseFloat64(in []byte) (float64, error) {
+	newFloat, err := parseBigFloat(in)
+	if err != nil {
+		return 0, err
+	}
+	f, _ := newFloat.Float64()
+	return f, nil
+}
+
+func parseBigInt(in []byte) (*big.Int, error) {
+	bi, success := new(big.Int).SetString(string(in), 10)
+	if !success {
+		return nil, fmt.Errorf("invalid integer %q", in)
+	}
+	return bi, nil
 }
 
 func strToBigFloat(in string) *big.Float {
-	newFloat, _, err := big.ParseFloat(in, 10, 100, big.ToNearestEven)
+	newFloat, err := parseBigFloat([]byte(in))
 	if err != nil {
 		panic(fmt.Sprintf("cannot load float %q: %s", in, err))
 	}
-	return newFloat.SetPrec(100)
+	return newFloat
 }
 
 func strToFloat(in string) float64 {
-	newFloat, _, err := big.ParseFloat(in, 10, 100, big.ToNearestEven)
+	f, err := parseFloat64([]byte(in))
 	if err != nil {
 		panic(fmt.Sprintf("cannot load float %q: %s", in, err))
 	}
-	f, _ := newFloat.SetPrec(100).Float64()
 	return f
 }
 
 func strToBigInt(in string) *big.Int {
-	bi := &big.Int{}
-	_, success := bi.SetString(in, 10)
-	if !success {
+	bi, err := parseBigInt([]byte(in))
+	if err != nil {
 		panic(fmt.Sprintf("cannot load int %q", in))
 	}
 	return bi
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"bytes"
+	"sort"
+)
+
+// MergeDryRun computes the effect of merging `kvPartialStore` into the store, without
+// mutating it. It returns, sorted, the keys the merge would add, those whose value it
+// would change and those it would remove, or the error the merge would fail with.
+func (b *baseStore) MergeDryRun(kvPartialStore *PartialKV) (added, changed, removed []string, err error) {
+	overlay := newOverlayKV(b.kv)
+
+	config := *b.Config
+	config.memoryBudget = nil // the dry run must not be accounted for
+	scratch := *b
+	scratch.Config = &config
+	scratch.kv = overlay
+	scratch.deltas = nil
+	scratch.budgetCounter = nil
+
+	if err := scratch.Merge(kvPartialStore); err != nil {
+		return nil, nil, nil, err
+	}
+
+	for k, v := range overlay.writes {
+		prev, found := b.kv.Get(k)
+		switch {
+		case !found:
+			added = append(added, k)
+		case !bytes.Equal(prev, v):
+			changed = append(changed, k)
+		}
+	}
+	for k := range overlay.deleted {
+		if _, found := b.kv.Get(k); found {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed, nil
+}
+
+// overlayKV records the writes and deletions made on top of a backend it only reads.
+type overlayKV struct {
+	base    kvBackend
+	writes  map[string][]byte
+	deleted map[string]bool
+}
+
+func newOverlayKV(base kvBackend) *overlayKV {
+	return &overlayKV{
+		base:    base,
+		writes:  make(map[string][]byte),
+		deleted: make(map[string]bool),
+	}
+}
+
+func (o *overlayKV) Get(key string) ([]byte, bool) {
+	if o.deleted[key] {
+		return nil, false
+	}
+	if val, found := o.writes[key]; found {
+		return val, true
+	}
+	return o.base.Get(key)
+}
+
+func (o *overlayKV) Set(key string, value []byte) {
+	o.writes[key] = value
+	delete(o.deleted, key)
+}
+
+func (o *overlayKV) Delete(key string) {
+	delete(o.writes, key)
+	o.deleted[key] = true
+}
+
+func (o *overlayKV) Len() int {
+	count := o.base.Len()
+	for k := range o.writes {
+		if _, found := o.base.Get(k); !found {
+			count++
+		}
+	}
+	for k := range o.deleted {
+		if _, found := o.base.Get(k); found {
+			count--
+		}
+	}
+	return count
+}
+
+func (o *overlayKV) Iterate(f func(key string, value []byte) error) error {
+	entries := o.Map()
+	for k, v := range entries {
+		if err := f(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *overlayKV) Map() map[string][]byte {
+	out := make(map[string][]byte, o.base.Len()+len(o.writes))
+	for k, v := range o.base.Map() {
+		if !o.deleted[k] {
+			out[k] = v
+		}
+	}
+	for k, v := range o.writes {
+		out[k] = v
+	}
+	return out
+}
+
+func (o *overlayKV) Commit() error { return nil }
+func (o *overlayKV) Close() error  { return nil }
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_MergeDryRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		prev            map[string][]byte
+		latest          map[string][]byte
+		policy          pbsubstreams.Module_KindStore_UpdatePolicy
+		valueType       string
+		deletedPrefixes []string
+		deletedKeys     []string
+		expectAdded     []string
+		expectChanged   []string
+		expectRemoved   []string
+	}{
+		{
+			name:            "set with deleted prefixes and keys",
+			prev:            map[string][]byte{"t:1": []byte("baz"), "t:2": []byte("same"), "p:3": []byte("lol"), "d:4": []byte("gone")},
+			latest:          map[string][]byte{"t:1": []byte("bar"), "t:2": []byte("same"), "t:5": []byte("new")},
+			policy:          pbsubstreams.Module_KindStore_UPDATE_POLICY_SET,
+			valueType:       manifest.OutputValueTypeString,
+			deletedPrefixes: []string{"p:"},
+			deletedKeys:     []string{"d:4", "absent"},
+			expectAdded:     []string{"t:5"},
+			expectChanged:   []string{"t:1"},
+			expectRemoved:   []string{"d:4", "p:3"},
+		},
+		{
+			name:          "set if not exists",
+			prev:          map[string][]byte{"one": []byte("baz")},
+			latest:        map[string][]byte{"one": []byte("foo"), "two": []byte("bar")},
+			policy:        pbsubstreams.Module_KindStore_UPDATE_POLICY_SET_IF_NOT_EXISTS,
+			valueType:     manifest.OutputValueTypeString,
+			expectAdded:   []string{"two"},
+			expectChanged: nil,
+		},
+		{
+			name:          "add int64",
+			prev:          map[string][]byte{"one": []byte("1"), "two": []byte("2")},
+			latest:        map[string][]byte{"one": []byte("0"), "two": []byte("3"), "three": []byte("3")},
+			policy:        pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD,
+			valueType:     manifest.OutputValueTypeInt64,
+			expectAdded:   []string{"three"},
+			expectChanged: []string{"two"},
+		},
+		{
+			name:          "max bigint",
+			prev:          map[string][]byte{"one": []byte("10"), "two": []byte("2")},
+			latest:        map[string][]byte{"one": []byte("5"), "two": []byte("20")},
+			policy:        pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX,
+			valueType:     manifest.OutputValueTypeBigInt,
+			expectChanged: []string{"two"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prev := newStore(copyKV(test.prev), test.policy, test.valueType)
+			latest := newPartialStore(copyKV(test.latest), test.policy, test.valueType, test.deletedPrefixes)
+			for _, key := range test.deletedKeys {
+				latest.addDeletedKey(key)
+			}
+
+			added, changed, removed, err := prev.MergeDryRun(latest)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectAdded, added)
+			assert.Equal(t, test.expectChanged, changed)
+			assert.Equal(t, test.expectRemoved, removed)
+			assert.Equal(t, test.prev, prev.kv.Map(), "dry run must not mutate the store")
+			assert.Nil(t, prev.deltas)
+
+			require.NoError(t, prev.Merge(latest))
+			actualAdded, actualChanged, actualRemoved := diffKV(test.prev, prev.kv.Map())
+			assert.Equal(t, actualAdded, added, "added keys match the actual merge")
+			assert.Equal(t, actualChanged, changed, "changed keys match the actual merge")
+			assert.Equal(t, actualRemoved, removed, "removed keys match the actual merge")
+		})
+	}
+}
+
+func TestStore_MergeDryRunIncompatible(t *testing.T) {
+	prev := newStore(map[string][]byte{"one": []byte("1")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString)
+	latest := newPartialStore(map[string][]byte{}, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeInt64, nil)
+
+	_, _, _, err := prev.MergeDryRun(latest)
+	assert.ErrorContains(t, err, "incompatible update policies")
+}
+
+func copyKV(in map[string][]byte) map[string][]byte {
+	out := make(map[string][]byte, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func diffKV(before, after map[string][]byte) (added, changed, removed []string) {
+	for k, v := range after {
+		prev, found := before[k]
+		switch {
+		case !found:
+			added = append(added, k)
+		case !bytes.Equal(prev, v):
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, found := after[k]; !found {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"math/big"
 	"testing"
 
 	"go.uber.org/zap"
@@ -319,6 +320,67 @@ func TestStore_Merge(t *testing.T) {
 				"three": []byte("30.1"),
 			},
 		},
+		{
+			name: "bit_or_int",
+			latest: newPartialStore(map[string][]byte{
+				"one": []byte("5"),
+				"two": []byte("2"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, manifest.OutputValueTypeInt64, nil),
+			prev: newStore(map[string][]byte{
+				"one":   []byte("3"),
+				"three": []byte("8"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, manifest.OutputValueTypeInt64),
+			expectedError: false,
+			expectedKV: map[string][]byte{
+				"one":   []byte("7"),
+				"two":   []byte("2"),
+				"three": []byte("8"),
+			},
+		},
+		{
+			name: "bit_or_big_int",
+			latest: newPartialStore(map[string][]byte{
+				"one": []byte("18446744073709551616"),
+				"two": []byte("2"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, manifest.OutputValueTypeBigInt, nil),
+			prev: newStore(map[string][]byte{
+				"one":   []byte("1"),
+				"three": []byte("8"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, manifest.OutputValueTypeBigInt),
+			expectedError: false,
+			expectedKV: map[string][]byte{
+				"one":   []byte("18446744073709551617"),
+				"two":   []byte("2"),
+				"three": []byte("8"),
+			},
+		},
+		{
+			name: "bit_and_int",
+			latest: newPartialStore(map[string][]byte{
+				"one": []byte("6"),
+				"two": []byte("2"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, manifest.OutputValueTypeInt64, nil),
+			prev: newStore(map[string][]byte{
+				"one":   []byte("3"),
+				"three": []byte("8"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, manifest.OutputValueTypeInt64),
+			expectedError: false,
+			expectedKV: map[string][]byte{
+				"one":   []byte("2"),
+				"two":   []byte("2"),
+				"three": []byte("8"),
+			},
+		},
+		{
+			name: "bit_or_float",
+			latest: newPartialStore(map[string][]byte{
+				"one": []byte("1.5"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, manifest.OutputValueTypeFloat64, nil),
+			prev: newStore(map[string][]byte{
+				"one": []byte("2.5"),
+			}, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, manifest.OutputValueTypeFloat64),
+			expectedError: true,
+		},
 		{
 			name: "delete key prefixes",
 			latest: newPartialStore(
@@ -336,53 +398,76 @@ func TestStore_Merge(t *testing.T) {
 		},
 	}
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			if test.latest.updatePolicy == pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND {
-				test.latest.appendLimit = 20
-				test.prev.appendLimit = 20
-			}
+	// Spilled runs go first, they copy the test stores instead of mutating them.
+	for _, backend := range []string{"spill", "memory"} {
+		for _, test := range tests {
+			t.Run(backend+"/"+test.name, func(t *testing.T) {
+				prev, latest := test.prev, test.latest
+				if backend == "spill" {
+					prev = &FullKV{baseStore: withSpillKV(t, prev.baseStore)}
+					latest = &PartialKV{baseStore: withSpillKV(t, latest.baseStore), DeletedPrefixes: latest.DeletedPrefixes, seen: latest.seen}
+				}
 
-			err := test.prev.Merge(test.latest)
-			if test.expectedError {
-				require.Error(t, err)
-				return
-			} else {
-				require.NoError(t, err)
-			}
+				if latest.updatePolicy == pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND {
+					latest.appendLimit = 20
+					prev.appendLimit = 20
+				}
 
-			for k, v := range test.prev.kv {
-				if test.latest.valueType == manifest.OutputValueTypeBigDecimal {
-					actual, _ := foundOrZeroBigFloat(v, true).Float64()
-					expected, _ := foundOrZeroBigFloat(test.expectedKV[k], true).Float64()
-					assert.InDelta(t, actual, expected, 0.01)
+				err := prev.Merge(latest)
+				if test.expectedError {
+					require.Error(t, err)
+					return
 				} else {
-					expected := string(test.expectedKV[k])
-					actual := string(v)
-					assert.Equal(t, expected, actual)
+					require.NoError(t, err)
 				}
-			}
 
-			for k, v := range test.expectedKV {
-				if test.latest.valueType == manifest.OutputValueTypeBigDecimal {
-					actual, _ := foundOrZeroBigFloat(v, true).Float64()
-					expected, _ := foundOrZeroBigFloat(test.prev.kv[k], true).Float64()
-					assert.InDelta(t, actual, expected, 0.01)
-				} else {
-					expected := string(test.prev.kv[k])
-					actual := string(v)
-					assert.Equal(t, expected, actual)
+				for k, v := range prev.kv.Map() {
+					if latest.valueType == manifest.OutputValueTypeBigDecimal {
+						actual, _ := bytesToBigFloat(v).Float64()
+						expected, _ := bytesToBigFloat(test.expectedKV[k]).Float64()
+						assert.InDelta(t, actual, expected, 0.01)
+					} else {
+						expected := string(test.expectedKV[k])
+						actual := string(v)
+						assert.Equal(t, expected, actual)
+					}
 				}
-				assert.Nil(t, test.prev.deltas, "merge should not keep leftover deltas")
-				assert.Zero(t, test.prev.lastOrdinal, "merge should not keep non-zero lastOrdinal")
-			}
-		})
+
+				for k, v := range test.expectedKV {
+					if latest.valueType == manifest.OutputValueTypeBigDecimal {
+						actual, _ := bytesToBigFloat(v).Float64()
+						expected, _ := bytesToBigFloat(prev.kv.Map()[k]).Float64()
+						assert.InDelta(t, actual, expected, 0.01)
+					} else {
+						expected := string(prev.kv.Map()[k])
+						actual := string(v)
+						assert.Equal(t, expected, actual)
+					}
+					assert.Nil(t, prev.deltas, "merge should not keep leftover deltas")
+					assert.Zero(t, prev.lastOrdinal, "merge should not keep non-zero lastOrdinal")
+				}
+			})
+		}
 	}
 }
 
+// withSpillKV returns a copy of `b` with its state held by a spill backend.
+func withSpillKV(t *testing.T, b *baseStore) *baseStore {
+	kv := newSpillKV(t.TempDir(), "test", nil)
+	for k, v := range b.kv.Map() {
+		kv.Set(k, v)
+	}
+	require.NoError(t, kv.Commit())
+	t.Cleanup(func() { kv.Close() })
+
+	out := *b
+	out.kv = kv
+	return &out
+}
+
 func newPartialStore(kv map[string][]byte, updatePolicy pbsubstreams.Module_KindStore_UpdatePolicy, valueType string, deletedPrefixes []string) *PartialKV {
 	b := &baseStore{
-		kv: kv,
+		kv: memoryKV(kv),
 		Config: &Config{
 			updatePolicy: updatePolicy,
 			valueType:    valueType,
@@ -394,7 +479,7 @@ func newPartialStore(kv map[string][]byte, updatePolicy pbsubstreams.Module_Kind
 
 func newStore(kv map[string][]byte, updatePolicy pbsubstreams.Module_KindStore_UpdatePolicy, valueType string) *FullKV {
 	b := &baseStore{
-		kv: kv,
+		kv: memoryKV(kv),
 		Config: &Config{
 			updatePolicy: updatePolicy,
 			valueType:    valueType,
@@ -403,3 +488,81 @@ func newStore(kv map[string][]byte, updatePolicy pbsubstreams.Module_KindStore_U
 	}
 	return &FullKV{baseStore: b}
 }
+
+func TestStore_MergeInvalidNumericValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    pbsubstreams.Module_KindStore_UpdatePolicy
+		valueType string
+		prev      map[string][]byte
+		latest    map[string][]byte
+	}{
+		{"sum int64 in full store", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeInt64, map[string][]byte{"key": []byte("abc")}, map[string][]byte{"key": []byte("1")}},
+		{"sum float64 in partial", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeFloat64, map[string][]byte{"key": []byte("1.0")}, map[string][]byte{"key": []byte("abc")}},
+		{"sum bigint in full store", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeBigInt, map[string][]byte{"key": []byte("abc")}, map[string][]byte{"key": []byte("1")}},
+		{"sum bigdecimal in partial", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeBigDecimal, nil, map[string][]byte{"key": []byte("abc")}},
+		{"max int64 in full store", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, manifest.OutputValueTypeInt64, map[string][]byte{"key": []byte("abc")}, map[string][]byte{"key": []byte("1")}},
+		{"min float64 in full store", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, manifest.OutputValueTypeFloat64, map[string][]byte{"key": []byte("abc")}, map[string][]byte{"key": []byte("1.0")}},
+		{"min bigint in partial", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, manifest.OutputValueTypeBigInt, nil, map[string][]byte{"key": []byte("abc")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prev := newStore(test.prev, test.policy, test.valueType)
+			latest := newPartialStore(test.latest, test.policy, test.valueType, nil)
+
+			err := prev.Merge(latest)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), `"key"`)
+			assert.Contains(t, err.Error(), `"abc"`)
+			assert.Contains(t, err.Error(), test.valueType)
+		})
+	}
+}
+
+func TestStore_MergeBigFloatPrecision(t *testing.T) {
+	merge := func(bits uint) (string, error) {
+		prev := newStore(map[string][]byte{"sum": []byte("1000000000000000000000000000000")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeBigFloat)
+		prev.SetBigFloatPrecision(bits)
+		latest := newPartialStore(map[string][]byte{"sum": []byte("1")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeBigFloat, nil)
+		latest.SetBigFloatPrecision(bits)
+
+		if err := prev.Merge(latest); err != nil {
+			return "", err
+		}
+		val, _ := prev.kv.Get("sum")
+		return string(val), nil
+	}
+
+	rounded, err := merge(53)
+	require.NoError(t, err)
+	assert.Equal(t, "1e+30", rounded)
+
+	exact, err := merge(256)
+	require.NoError(t, err)
+	sum, err := parseBigFloatWithPrecision([]byte(exact), 256)
+	require.NoError(t, err)
+	expected, _ := new(big.Int).SetString("1000000000000000000000000000001", 10)
+	actual, accuracy := sum.Int(nil)
+	assert.Equal(t, big.Exact, accuracy)
+	assert.Equal(t, expected, actual)
+}
+
+func TestStore_MergeBigFloatPrecisionMismatch(t *testing.T) {
+	prev := newStore(map[string][]byte{"max": []byte("1.5")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, manifest.OutputValueTypeBigFloat)
+	prev.SetBigFloatPrecision(256)
+	latest := newPartialStore(map[string][]byte{"max": []byte("2.5")}, pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, manifest.OutputValueTypeBigFloat, nil)
+
+	assert.EqualError(t, prev.Merge(latest), "incompatible bigfloat precisions: cannot merge 256 bits and 0 bits")
+
+	// a partial written with another precision is refused even with the same configuration
+	latest.SetBigFloatPrecision(256)
+	recorded := uint(128)
+	latest.loadedBigFloatPrecision = &recorded
+	assert.EqualError(t, prev.Merge(latest), "incompatible bigfloat precisions: cannot merge 256 bits and 128 bits")
+
+	recorded = 256
+	require.NoError(t, prev.Merge(latest))
+	val, _ := prev.kv.Get("max")
+	assert.Equal(t, "2.5", string(val))
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/manifest"
+)
+
+// ValueConverter converts a value of a store to a new value type. It reports the
+// conversions losing information as `lossy`.
+type ValueConverter func(key string, value []byte) (converted []byte, lossy bool, err error)
+
+// MigrateSnapshot rewrites the full snapshot `file` of the store `from` as a snapshot of
+// the store `to`, at the same block boundary, converting each value with `convert`. The
+// keys reserved for internal use are copied as is. The original snapshot is left intact,
+// `to` must write to another location, for example the module hash of the store with
+// its new value type.
+//
+// The migration fails on the first lossy conversion, unless `force` is set.
+func MigrateSnapshot(ctx context.Context, from, to *Config, file *FileInfo, convert ValueConverter, force bool, logger *zap.Logger) (*FileInfo, error) {
+	if file.Partial {
+		return nil, fmt.Errorf("cannot migrate partial snapshot %s, only full ones", file.Filename)
+	}
+	if from.objStore.ObjectURL(from.snapshotKey(file)) == to.objStore.ObjectURL(to.snapshotKey(file)) {
+		return nil, fmt.Errorf("migrating snapshot %s would overwrite it, the migrated store must be saved elsewhere", file.Filename)
+	}
+
+	source := from.NewFullKV(logger)
+	if err := source.Load(ctx, file); err != nil {
+		return nil, fmt.Errorf("loading snapshot to migrate: %w", err)
+	}
+	defer source.kv.Close()
+
+	target := to.NewFullKV(logger)
+	lossyCount := 0
+	err := source.IterateWithReserved(func(key string, value []byte) error {
+		if from.isSystemKey(key) {
+			target.setNewKV(key, value)
+			return nil
+		}
+
+		converted, lossy, err := convert(key, value)
+		if err != nil {
+			return fmt.Errorf("converting key %q: %w", key, err)
+		}
+		if lossy {
+			if !force {
+				return fmt.Errorf("converting key %q: value %q cannot be converted to %s without loss", key, value, to.valueType)
+			}
+			lossyCount++
+		}
+		target.setNewKV(key, converted)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrating store %q from %s to %s: %w", from.name, from.valueType, to.valueType, err)
+	}
+
+	migrated, writer, err := target.Save(file.Range.ExclusiveEndBlock)
+	if err != nil {
+		return nil, fmt.Errorf("saving migrated snapshot: %w", err)
+	}
+	if err := writer.Write(ctx); err != nil {
+		return nil, fmt.Errorf("writing migrated snapshot: %w", err)
+	}
+
+	logger.Info("store snapshot migrated",
+		zap.String("store", from.name),
+		zap.String("from_value_type", from.valueType),
+		zap.String("to_value_type", to.valueType),
+		zap.String("snapshot", migrated.Filename),
+		zap.Uint64("key_count", target.Length()),
+		zap.Int("lossy_conversions", lossyCount),
+	)
+	return migrated, nil
+}
+
+// NumericConverter converts values between the numeric value types (`int64`, `float64`,
+// `bigint`, `bigfloat` and `bigdecimal`). A conversion is lossy when the converted value
+// is not exactly the original number, for example a fractional number converted to an
+// integer type, which is truncated.
+func NumericConverter(fromValueType, toValueType string) (ValueConverter, error) {
+	for _, valueType := range []string{fromValueType, toValueType} {
+		if !isNumericValueType(valueType) {
+			return nil, fmt.Errorf("value type %q is not numeric", valueType)
+		}
+	}
+
+	return func(key string, value []byte) ([]byte, bool, error) {
+		if _, err := compareNumeric(fromValueType, value, value); err != nil {
+			return nil, false, err
+		}
+		number, err := decimal.NewFromString(string(value))
+		if err != nil {
+			return nil, false, fmt.Errorf("value %q is not a valid %s: %w", value, fromValueType, err)
+		}
+
+		converted, err := formatNumber(number, toValueType)
+		if err != nil {
+			return nil, false, err
+		}
+		back, err := decimal.NewFromString(string(converted))
+		if err != nil {
+			return nil, false, fmt.Errorf("converted value %q is not a valid number: %w", converted, err)
+		}
+		return converted, !back.Equal(number), nil
+	}, nil
+}
+
+func isNumericValueType(valueType string) bool {
+	switch strings.ToLower(valueType) {
+	case manifest.OutputValueTypeInt64,
+		manifest.OutputValueTypeFloat64,
+		manifest.OutputValueTypeBigInt,
+		manifest.OutputValueTypeBigFloat,
+		manifest.OutputValueTypeBigDecimal:
+		return true
+	}
+	return false
+}
+
+// formatNumber encodes `number` as a value of `valueType`, truncating it for integer types.
+func formatNumber(number decimal.Decimal, valueType string) ([]byte, error) {
+	switch strings.ToLower(valueType) {
+	case manifest.OutputValueTypeInt64:
+		integer := number.Truncate(0).BigInt()
+		if !integer.IsInt64() {
+			return nil, fmt.Errorf("number %s overflows int64", number)
+		}
+		return []byte(strconv.FormatInt(integer.Int64(), 10)), nil
+	case manifest.OutputValueTypeBigInt:
+		return []byte(number.Truncate(0).BigInt().String()), nil
+	case manifest.OutputValueTypeFloat64:
+		f, _ := number.Float64()
+		return floatToBytes(f), nil
+	case manifest.OutputValueTypeBigFloat:
+		f, _, err := big.ParseFloat(number.String(), 10, 100, big.ToNearestEven)
+		if err != nil {
+			return nil, err
+		}
+		return bigFloatToBytes(f), nil
+	case manifest.OutputValueTypeBigDecimal:
+		return []byte(number.String()), nil
+	}
+	return nil, fmt.Errorf("value type %q is not numeric", valueType)
+}
+
+// compareNumeric compares `a` with `b`, both decoded as numbers of `valueType`, returning
+// -1, 0 or +1 when `a` is respectively smaller, equal or larger.
+func compareNumeric(valueType string, a, b []byte) (int, error) {
+	invalid := func(value []byte, err error) error {
+		return fmt.Errorf("value %q is not a valid %s: %w", value, valueType, err)
+	}
+
+	switch strings.ToLower(valueType) {
+	case manifest.OutputValueTypeInt64:
+		left, err := parseInt64(a)
+		if err != nil {
+			return 0, invalid(a, err)
+		}
+		right, err := parseInt64(b)
+		if err != nil {
+			return 0, invalid(b, err)
+		}
+		return cmp.Compare(left, right), nil
+
+	case manifest.OutputValueTypeFloat64:
+		left, err := parseFloat64(a)
+		if err != nil {
+			return 0, invalid(a, err)
+		}
+		right, err := parseFloat64(b)
+		if err != nil {
+			return 0, invalid(b, err)
+		}
+		return cmp.Compare(left, right), nil
+
+	case manifest.OutputValueTypeBigInt:
+		left, err := parseBigInt(a)
+		if err != nil {
+			return 0, invalid(a, err)
+		}
+		right, err := parseBigInt(b)
+		if err != nil {
+			return 0, invalid(b, err)
+		}
+		return left.Cmp(right), nil
+
+	case manifest.OutputValueTypeBigFloat:
+		left, err := parseBigFloat(a)
+		if err != nil {
+			return 0, invalid(a, err)
+		}
+		right, err := parseBigFloat(b)
+		if err != nil {
+			return 0, invalid(b, err)
+		}
+		return left.Cmp(right), nil
+
+	case manifest.OutputValueTypeBigDecimal:
+		left, err := decimal.NewFromString(string(a))
+		if err != nil {
+			return 0, invalid(a, err)
+		}
+		right, err := decimal.NewFromString(string(b))
+		if err != nil {
+			return 0, invalid(b, err)
+		}
+		return left.Cmp(right), nil
+	}
+	return 0, fmt.Errorf("values of type %q are not numbers", valueType)
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestMigrateSnapshot_Float64ToBigDecimal(t *testing.T) {
+	ctx := context.Background()
+	baseStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+
+	from, err := NewConfig("prices", 0, "prices.float64.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "float64", baseStore, "")
+	require.NoError(t, err)
+	to, err := NewConfig("prices", 0, "prices.bigdecimal.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bigdecimal", baseStore, "")
+	require.NoError(t, err)
+
+	original := from.NewFullKV(zap.NewNop())
+	original.Set(0, "eth", "1234.5")
+	original.Set(0, "btc", "0.1")
+	original.Set(0, "tiny", "1e-7")
+	file, writer, err := original.Save(100)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	convert, err := NumericConverter("float64", "bigdecimal")
+	require.NoError(t, err)
+	migrated, err := MigrateSnapshot(ctx, from, to, file, convert, false, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), migrated.Range.ExclusiveEndBlock)
+
+	loaded := to.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, migrated))
+	for key, expected := range map[string]string{"eth": "1234.5", "btc": "0.1", "tiny": "0.0000001"} {
+		val, found := loaded.GetLast(key)
+		require.True(t, found, key)
+		assert.Equal(t, expected, string(val), key)
+	}
+
+	intact := from.NewFullKV(zap.NewNop())
+	require.NoError(t, intact.Load(ctx, file))
+	val, _ := intact.GetLast("tiny")
+	assert.Equal(t, "1e-7", string(val), "the original snapshot is left intact")
+
+	_, err = MigrateSnapshot(ctx, from, from, file, convert, false, zap.NewNop())
+	assert.ErrorContains(t, err, "would overwrite it")
+}
+
+func TestMigrateSnapshot_LossyConversion(t *testing.T) {
+	ctx := context.Background()
+	baseStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+
+	from, err := NewConfig("amounts", 0, "amounts.float64.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "float64", baseStore, "")
+	require.NoError(t, err)
+	to, err := NewConfig("amounts", 0, "amounts.int64.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", baseStore, "")
+	require.NoError(t, err)
+
+	original := from.NewFullKV(zap.NewNop())
+	original.Set(0, "whole", "10")
+	original.Set(0, "fraction", "2.5")
+	file, writer, err := original.Save(100)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	convert, err := NumericConverter("float64", "int64")
+	require.NoError(t, err)
+	_, err = MigrateSnapshot(ctx, from, to, file, convert, false, zap.NewNop())
+	assert.ErrorContains(t, err, `value "2.5" cannot be converted to int64 without loss`)
+
+	migrated, err := MigrateSnapshot(ctx, from, to, file, convert, true, zap.NewNop())
+	require.NoError(t, err)
+	loaded := to.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(ctx, migrated))
+	val, _ := loaded.GetLast("fraction")
+	assert.Equal(t, "2", string(val))
+}
@@ -2,10 +2,13 @@ package store
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"slices"
+	"strings"
 
-	"github.com/streamingfast/substreams/storage/store/marshaller"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
 )
 
 var _ Store = (*PartialKV)(nil)
@@ -15,6 +18,7 @@ type PartialKV struct {
 
 	initialBlock    uint64 // block at which we initialized this store
 	DeletedPrefixes []string
+	DeletedKeys     map[string]bool // keys deleted one by one, also deleted from the full store on merge
 
 	loadedFrom string
 	seen       map[string]bool
@@ -22,50 +26,75 @@ type PartialKV struct {
 
 func (p *PartialKV) Roll(lastBlock uint64) {
 	p.initialBlock = lastBlock
-	p.baseStore.kv = map[string][]byte{}
+	p.baseStore.kv.Close()
+	p.baseStore.kv = p.Config.newKV()
+	p.DeletedKeys = nil
+	p.totalSizeBytes = 0
+	p.trackSize()
 }
 
 func (p *PartialKV) InitialBlock() uint64 { return p.initialBlock }
 
 func (p *PartialKV) Load(ctx context.Context, file *FileInfo) error {
-	p.loadedFrom = file.Filename
-	p.logger.Debug("loading partial store state from file", zap.String("filename", file.Filename))
+	filename := p.snapshotKey(file)
+	p.loadedFrom = filename
+	p.logger.Debug("loading partial store state from file", zap.String("filename", filename))
 
-	data, err := loadStore(ctx, p.objStore, file.Filename)
+	data, err := loadStore(ctx, p.objStore, filename, p.loadRetries, p.loadBackoff)
 	if err != nil {
-		return fmt.Errorf("load partial store %s at %s: %w", p.name, file.Filename, err)
+		return fmt.Errorf("load partial store %s at %s: %w", p.name, filename, err)
+	}
+	data, err = p.openSnapshot(data)
+	if err != nil {
+		return fmt.Errorf("decrypt partial store %s at %s: %w", p.name, filename, err)
 	}
 
-	storeData, size, err := p.marshaller.Unmarshal(data)
+	var deletedKeys []byte
+	deletePrefixes, size, err := p.unmarshalState(data, func(key string, value []byte) bool {
+		if key != deletedKeysKey {
+			return false
+		}
+		deletedKeys = value
+		return true
+	})
 	if err != nil {
 		return fmt.Errorf("unmarshal store: %w", err)
 	}
-
-	p.kv = storeData.Kv
-	if p.kv == nil {
-		p.kv = map[string][]byte{}
+	keys, err := decodeDeletedKeys(deletedKeys)
+	if err != nil {
+		return fmt.Errorf("decoding deleted keys: %w", err)
 	}
 	p.totalSizeBytes = size
-	p.DeletedPrefixes = storeData.DeletePrefixes
+	p.DeletedPrefixes = deletePrefixes
+	p.DeletedKeys = nil
+	for _, key := range keys {
+		p.addDeletedKey(key)
+	}
+	p.trackSize()
 
-	p.logger.Debug("partial store loaded", zap.String("filename", file.Filename), zap.Int("key_count", len(p.kv)), zap.Uint64("data_size", size))
+	p.logger.Debug("partial store loaded", zap.String("filename", filename), zap.Int("key_count", p.kv.Len()), zap.Uint64("data_size", size))
 	return nil
 }
 
 func (p *PartialKV) Save(endBoundaryBlock uint64) (*FileInfo, *fileWriter, error) {
 	p.logger.Debug("writing partial store state", zap.Object("store", p))
 
-	stateData := &marshaller.StoreData{
-		Kv:             p.kv,
-		DeletePrefixes: p.DeletedPrefixes,
+	var header []kvEntry
+	if len(p.DeletedKeys) != 0 {
+		header = append(header, kvEntry{deletedKeysKey, encodeDeletedKeys(p.DeletedKeys)})
 	}
-
-	content, err := p.marshaller.Marshal(stateData)
+	p.DeletedPrefixes = coalescePrefixes(p.DeletedPrefixes)
+	content, err := p.marshalState(p.DeletedPrefixes, header...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("marshal partial data: %w", err)
 	}
+	content, err = p.sealSnapshot(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypt partial data: %w", err)
+	}
 
 	file := NewPartialFileInfo(p.name, p.initialBlock, endBoundaryBlock, p.traceID)
+	file.Filename = p.snapshotKey(file)
 	p.logger.Info("partial store save written", zap.String("file_name", file.Filename), zap.Stringer("block_range", file.Range))
 
 	fw := &fileWriter{
@@ -80,21 +109,111 @@ func (p *PartialKV) Save(endBoundaryBlock uint64) (*FileInfo, *fileWriter, error
 func (p *PartialKV) DeletePrefix(ord uint64, prefix string) {
 	p.baseStore.DeletePrefix(ord, prefix)
 
-	if !p.seen[prefix] {
-		p.DeletedPrefixes = append(p.DeletedPrefixes, prefix)
-		p.seen[prefix] = true
+	// redundant prefixes are only dropped by coalescePrefixes, on Save and Merge, or
+	// when they may exceed the cap
+	if p.seen[prefix] {
+		return
 	}
+	p.seen[prefix] = true
+	p.DeletedPrefixes = append(p.DeletedPrefixes, prefix)
+
+	if p.maxDeletedPrefixes != 0 && uint64(len(p.DeletedPrefixes)) > p.maxDeletedPrefixes {
+		p.DeletedPrefixes = coalescePrefixes(p.DeletedPrefixes)
+		if uint64(len(p.DeletedPrefixes)) > p.maxDeletedPrefixes {
+			panic(fmt.Sprintf("store %q deleted more than %d distinct key prefixes in a single segment", p.name, p.maxDeletedPrefixes))
+		}
+	}
+}
+
+// coalescePrefixes returns `prefixes` without duplicates nor the prefixes starting
+// with another one of them, deleting those changes nothing.
+func coalescePrefixes(prefixes []string) []string {
+	sorted := slices.Clone(prefixes)
+	slices.Sort(sorted)
+
+	// a prefix sorts right before the strings it starts
+	var out []string
+	for _, prefix := range sorted {
+		if len(out) > 0 && strings.HasPrefix(prefix, out[len(out)-1]) {
+			continue
+		}
+		out = append(out, prefix)
+	}
+	return out
+}
+
+// deletedKeysKey is the snapshot entry holding, in a partial, the keys deleted with
+// DeleteMany, see `encodeDeletedKeys`. Like the metadata, it is not part of the state.
+const deletedKeysKey = internalKeyPrefix + "deleted_keys"
+
+// DeleteMany deletes `keys` from the partial and records them, as the full store the
+// partial is merged into may hold them.
+func (p *PartialKV) DeleteMany(ord uint64, keys []string) {
+	p.baseStore.DeleteMany(ord, keys)
+
+	defer p.trackSize()
+	for _, key := range keys {
+		p.addDeletedKey(key)
+	}
+	if p.totalSizeBytes > p.totalSizeLimit {
+		panic(fmt.Sprintf("store %q became too big at %d, maximum size: %d", p.Name(), p.totalSizeBytes, p.totalSizeLimit))
+	}
+}
+
+// addDeletedKey records `key` as deleted, counting it in the size of the store.
+func (p *PartialKV) addDeletedKey(key string) {
+	if p.DeletedKeys[key] {
+		return
+	}
+	if p.DeletedKeys == nil {
+		p.DeletedKeys = make(map[string]bool)
+	}
+	p.DeletedKeys[key] = true
+	p.totalSizeBytes += uint64(len(key))
+}
+
+// encodeDeletedKeys serializes `keys`, sorted so a same set always gives the same
+// bytes, each one preceded by its length as an uvarint.
+func encodeDeletedKeys(keys map[string]bool) []byte {
+	sorted := maps.Keys(keys)
+	slices.Sort(sorted)
+
+	var out []byte
+	for _, key := range sorted {
+		out = binary.AppendUvarint(out, uint64(len(key)))
+		out = append(out, key...)
+	}
+	return out
+}
+
+// decodeDeletedKeys is the reverse of encodeDeletedKeys.
+func decodeDeletedKeys(in []byte) ([]string, error) {
+	var out []string
+	for len(in) > 0 {
+		length, n := binary.Uvarint(in)
+		if n <= 0 || uint64(len(in)-n) < length {
+			return nil, fmt.Errorf("truncated key after %d keys", len(out))
+		}
+		out = append(out, string(in[n:n+int(length)]))
+		in = in[n+int(length):]
+	}
+	return out, nil
+}
+
+func (p *PartialKV) Delete(ord uint64, key string) {
+	p.DeleteMany(ord, []string{key})
 }
 
 func (p *PartialKV) DeleteStore(ctx context.Context, file *FileInfo) (err error) {
-	zlog.Debug("deleting partial store file", zap.String("file_name", file.Filename))
+	filename := p.snapshotKey(file)
+	zlog.Debug("deleting partial store file", zap.String("file_name", filename))
 
-	if err = p.objStore.DeleteObject(ctx, file.Filename); err != nil {
-		zlog.Warn("deleting file", zap.String("file_name", file.Filename), zap.Error(err))
+	if err = p.objStore.DeleteObject(ctx, filename); err != nil {
+		zlog.Warn("deleting file", zap.String("file_name", filename), zap.Error(err))
 	}
 	return err
 }
 
 func (p *PartialKV) String() string {
-	return fmt.Sprintf("partialKV name %s moduleInitialBlock %d  keyCount %d deltasCount %d loadFrom %s", p.Name(), p.moduleInitialBlock, len(p.kv), len(p.deltas), p.loadedFrom)
+	return fmt.Sprintf("partialKV name %s moduleInitialBlock %d  keyCount %d deltasCount %d loadFrom %s", p.Name(), p.moduleInitialBlock, p.kv.Len(), len(p.deltas), p.loadedFrom)
 }
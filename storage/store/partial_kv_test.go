@@ -7,7 +7,10 @@ import (
 	"testing"
 
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	"github.com/streamingfast/substreams/storage/store/marshaller"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -24,7 +27,7 @@ func TestPartialKV_Save_Load_Empty_MapNotNil(t *testing.T) {
 
 	kvs := &PartialKV{
 		baseStore: &baseStore{
-			kv: map[string][]byte{},
+			kv: memoryKV{},
 
 			logger:     zap.NewNop(),
 			marshaller: marshaller.Default(),
@@ -44,7 +47,7 @@ func TestPartialKV_Save_Load_Empty_MapNotNil(t *testing.T) {
 
 	kvl := &PartialKV{
 		baseStore: &baseStore{
-			kv: map[string][]byte{},
+			kv: memoryKV{},
 
 			logger:     zap.NewNop(),
 			marshaller: marshaller.Default(),
@@ -60,3 +63,37 @@ func TestPartialKV_Save_Load_Empty_MapNotNil(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNilf(t, kvl.kv, "kvl.kv is nil")
 }
+
+func TestPartialKV_DeletePrefixCoalescesRedundantPrefixes(t *testing.T) {
+	p := &PartialKV{baseStore: newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, nil), seen: make(map[string]bool)}
+
+	p.DeletePrefix(1, "user:alice:")
+	p.DeletePrefix(2, "user:bob:")
+	p.DeletePrefix(3, "token:")
+	p.DeletePrefix(4, "user:")
+	p.DeletePrefix(5, "user:carol:")
+	p.DeletePrefix(6, "token:")
+
+	assert.Len(t, p.DeletedPrefixes, 5)
+	_, _, err := p.Save(10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token:", "user:"}, p.DeletedPrefixes)
+	assert.Equal(t, []string{"a", "b:"}, coalescePrefixes([]string{"b:1", "a", "b:", "ab", "b:", "b:1:x"}))
+}
+
+func TestPartialKV_DeletePrefixOverCap(t *testing.T) {
+	p := &PartialKV{baseStore: newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, nil), seen: make(map[string]bool)}
+	p.SetMaxDeletedPrefixes(2)
+
+	p.DeletePrefix(1, "a:")
+	p.DeletePrefix(2, "b:")
+	p.DeletePrefix(3, "b:1")
+	assert.PanicsWithValue(t, `store "test" deleted more than 2 distinct key prefixes in a single segment`, func() {
+		p.DeletePrefix(4, "c:")
+	})
+
+	full := newStore(map[string][]byte{}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString)
+	full.SetMaxDeletedPrefixes(2)
+	err := full.Merge(newPartialStore(map[string][]byte{}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, []string{"a:", "a:1", "b:", "c:"}))
+	assert.ErrorContains(t, err, "partial deletes 3 distinct key prefixes, more than the maximum of 2")
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// PartialLoader loads partial snapshots ahead of their merge, at most `concurrency` of
+// them being loaded, or loaded and waiting to be taken, in the background at any time.
+// Partials must still be merged in block order: `Prefetch` only overlaps their loading
+// with the merge of the previous ones, and `Take` hands them out in whatever order it
+// is called.
+//
+// A prefetched partial that is never taken keeps its slot until the context it was
+// prefetched with is done or the loader is closed.
+type PartialLoader struct {
+	config *Config
+	logger *zap.Logger
+	slots  chan struct{}
+
+	lock      sync.Mutex
+	pending   map[string]*pendingPartial
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type pendingPartial struct {
+	started  bool // set once the load began, in the background, or from `Take`
+	taken    bool // set once handed out by `Take`, which then releases the slot
+	released bool // set once released without being taken
+	done     chan struct{}
+	takenCh  chan struct{}
+	kv       *PartialKV
+	err      error
+}
+
+func NewPartialLoader(config *Config, concurrency uint64, logger *zap.Logger) *PartialLoader {
+	return &PartialLoader{
+		config:  config,
+		logger:  logger,
+		slots:   make(chan struct{}, concurrency),
+		pending: make(map[string]*pendingPartial),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Close releases the partials prefetched and not taken. Partials cannot be taken
+// from a closed loader.
+func (l *PartialLoader) Close() {
+	l.closeOnce.Do(func() { close(l.closed) })
+}
+
+// Prefetch starts loading `file` in the background as soon as a slot is free.
+func (l *PartialLoader) Prefetch(ctx context.Context, file *FileInfo) {
+	key := l.config.snapshotKey(file)
+
+	l.lock.Lock()
+	if _, found := l.pending[key]; found {
+		l.lock.Unlock()
+		return
+	}
+	p := &pendingPartial{done: make(chan struct{}), takenCh: make(chan struct{})}
+	l.pending[key] = p
+	l.lock.Unlock()
+
+	go func() {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		case <-l.closed:
+			return
+		}
+
+		l.lock.Lock()
+		if p.started {
+			// `Take` did not wait for us and loaded it itself
+			l.lock.Unlock()
+			<-l.slots
+			return
+		}
+		p.started = true
+		l.lock.Unlock()
+
+		p.kv, p.err = l.load(ctx, file)
+		close(p.done)
+
+		select {
+		case <-p.takenCh:
+			return
+		case <-ctx.Done():
+		case <-l.closed:
+		}
+
+		l.lock.Lock()
+		if p.taken {
+			l.lock.Unlock()
+			return
+		}
+		p.released = true
+		if l.pending[key] == p {
+			delete(l.pending, key)
+		}
+		l.lock.Unlock()
+
+		if p.kv != nil {
+			p.kv.kv.Close()
+			p.kv.Release()
+		}
+		<-l.slots
+	}()
+}
+
+// Take returns the partial store of `file`, waiting for its prefetch to complete. A
+// partial not prefetched, or whose prefetch is still waiting for a slot, is loaded
+// right away so that taking partials in order never waits on later ones.
+func (l *PartialLoader) Take(ctx context.Context, file *FileInfo) (*PartialKV, error) {
+	key := l.config.snapshotKey(file)
+
+	select {
+	case <-l.closed:
+		return nil, fmt.Errorf("partial loader closed")
+	default:
+	}
+
+	l.lock.Lock()
+	p, found := l.pending[key]
+	delete(l.pending, key)
+	if !found || !p.started {
+		if found {
+			p.started = true
+		}
+		l.lock.Unlock()
+		return l.load(ctx, file)
+	}
+	l.lock.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	l.lock.Lock()
+	if p.released {
+		l.lock.Unlock()
+		return nil, fmt.Errorf("partial %q released before being taken", file.Filename)
+	}
+	p.taken = true
+	close(p.takenCh)
+	l.lock.Unlock()
+
+	<-l.slots
+	return p.kv, p.err
+}
+
+func (l *PartialLoader) load(ctx context.Context, file *FileInfo) (*PartialKV, error) {
+	kv := l.config.NewPartialKV(file.Range.StartBlock, l.logger)
+	if err := kv.Load(ctx, file); err != nil {
+		return nil, err
+	}
+	return kv, nil
+}
+
+// MergePartials merges `partials`, which must be sorted by block, into `into`, loading
+// up to `concurrency` of them ahead of the one being merged. The result is the same as
+// merging them one after the other.
+func MergePartials(ctx context.Context, into *FullKV, partials []*FileInfo, concurrency uint64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	loader := NewPartialLoader(into.Config, concurrency, into.logger)
+	defer loader.Close()
+	if concurrency > 0 {
+		for _, partial := range partials {
+			loader.Prefetch(ctx, partial)
+		}
+	}
+
+	for _, partial := range partials {
+		partialKV, err := loader.Take(ctx, partial)
+		if err != nil {
+			return fmt.Errorf("loading partial %q: %w", partial.Filename, err)
+		}
+		err = into.Merge(partialKV)
+		partialKV.kv.Close()
+		partialKV.Release()
+		if err != nil {
+			return fmt.Errorf("merging partial %q: %w", partial.Filename, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestMergePartials(t *testing.T) {
+	ctx := context.Background()
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	objStore := config.objStore.(*dstore.MockStore)
+
+	var partials []*FileInfo
+	for i := 0; i < 6; i++ {
+		partial := config.NewPartialKV(uint64(i*10), zap.NewNop())
+		partial.Set(0, "last", fmt.Sprintf("%d", i))
+		partial.Set(0, fmt.Sprintf("key-%d", i), "value")
+		file, writer, err := partial.Save(uint64(i*10 + 10))
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+		partials = append(partials, file)
+	}
+
+	// the first partials are the slowest to load, so they complete after the next ones
+	var loading, maxLoading atomic.Int64
+	objStore.OpenObjectFunc = func(ctx context.Context, name string) (io.ReadCloser, error) {
+		current := loading.Add(1)
+		defer loading.Add(-1)
+		for {
+			previous := maxLoading.Load()
+			if current <= previous || maxLoading.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+
+		var delay time.Duration
+		for i, partial := range partials {
+			if partial.Filename == name {
+				delay = time.Duration(len(partials)-i) * 10 * time.Millisecond
+			}
+		}
+		time.Sleep(delay)
+		return io.NopCloser(bytes.NewReader(objStore.Files[name])), nil
+	}
+
+	serial := config.NewFullKV(zap.NewNop())
+	require.NoError(t, MergePartials(ctx, serial, partials, 0))
+	assert.Equal(t, int64(1), maxLoading.Load())
+
+	maxLoading.Store(0)
+	concurrent := config.NewFullKV(zap.NewNop())
+	require.NoError(t, MergePartials(ctx, concurrent, partials, 3))
+	assert.Greater(t, maxLoading.Load(), int64(1))
+	// plus the partial being taken, loaded right away when its prefetch did not start yet
+	assert.LessOrEqual(t, maxLoading.Load(), int64(3+1))
+
+	assert.Equal(t, serial.kv.Map(), concurrent.kv.Map())
+	last, found := concurrent.GetLast("last")
+	require.True(t, found)
+	assert.Equal(t, "5", string(last))
+	assert.Equal(t, serial.Checksum(), concurrent.Checksum())
+}
+
+func TestPartialLoader_ReleasesUntakenPartials(t *testing.T) {
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+
+	var files []*FileInfo
+	for i := 0; i < 2; i++ {
+		partial := config.NewPartialKV(uint64(i*10), zap.NewNop())
+		partial.Set(0, "key", "value")
+		file, writer, err := partial.Save(uint64(i*10 + 10))
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(context.Background()))
+		files = append(files, file)
+	}
+
+	loader := NewPartialLoader(config, 1, zap.NewNop())
+	slotsFree := func() bool { return len(loader.slots) == 0 }
+
+	// a prefetch never taken gives its slot back once its context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	loader.Prefetch(ctx, files[0])
+	require.Eventually(t, func() bool { return !slotsFree() }, time.Second, time.Millisecond)
+	cancel()
+	require.Eventually(t, slotsFree, time.Second, time.Millisecond)
+
+	// or once the loader is closed
+	loader.Prefetch(context.Background(), files[1])
+	require.Eventually(t, func() bool { return !slotsFree() }, time.Second, time.Millisecond)
+	loader.Close()
+	require.Eventually(t, slotsFree, time.Second, time.Millisecond)
+
+	_, err = loader.Take(context.Background(), files[1])
+	assert.Error(t, err)
+}
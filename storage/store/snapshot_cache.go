@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+// SnapshotCache keeps the content of full snapshots in memory, as read from storage,
+// so stores loading them skip the read. It is filled ahead of the requests by
+// `Preload` and holds at most `limit` bytes, snapshots that don't fit are not cached.
+type SnapshotCache struct {
+	limit uint64
+
+	lock    sync.Mutex
+	size    uint64
+	entries map[string][]byte // object URL => content
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewSnapshotCache(limit uint64) *SnapshotCache {
+	return &SnapshotCache{
+		limit:   limit,
+		entries: make(map[string][]byte),
+	}
+}
+
+// Hits returns the number of snapshot loads served from the cache.
+func (c *SnapshotCache) Hits() uint64 { return c.hits.Load() }
+
+// Misses returns the number of snapshot loads that had to read from storage.
+func (c *SnapshotCache) Misses() uint64 { return c.misses.Load() }
+
+// Size returns the number of bytes held by the cache.
+func (c *SnapshotCache) Size() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// get returns a copy of the cached content of `key`, the stores loading it may modify
+// the values they get out of it.
+func (c *SnapshotCache) get(key string) ([]byte, bool) {
+	c.lock.Lock()
+	data, found := c.entries[key]
+	c.lock.Unlock()
+
+	if !found {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return append([]byte(nil), data...), true
+}
+
+// add caches `data` under `key`, unless it would take the cache over its limit.
+func (c *SnapshotCache) add(key string, data []byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, found := c.entries[key]; found {
+		return true
+	}
+	if c.size+uint64(len(data)) > c.limit {
+		return false
+	}
+	c.entries[key] = data
+	c.size += uint64(len(data))
+	return true
+}
+
+// Preload reads into the cache the latest full snapshot of each of the stores with
+// the given module hashes, found in `baseStore` and named by `formatter`, nil for the
+// default naming. It stops early, without error, when `ctx` is done, and skips the
+// stores whose snapshot is missing or doesn't fit in the cache.
+func (c *SnapshotCache) Preload(ctx context.Context, baseStore dstore.Store, moduleHashes []string, formatter SnapshotKeyFormatter, logger *zap.Logger) error {
+	for _, moduleHash := range moduleHashes {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		config, err := NewConfig(moduleHash, 0, moduleHash, pbsubstreams.Module_KindStore_UPDATE_POLICY_UNSET, "", baseStore, "")
+		if err != nil {
+			return fmt.Errorf("configuring store %s: %w", moduleHash, err)
+		}
+		config.SetSnapshotKeyFormatter(formatter)
+
+		files, err := config.ListSnapshotFiles(ctx, math.MaxUint64)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("listing snapshots of store %s: %w", moduleHash, err)
+		}
+
+		var latest *FileInfo
+		for _, file := range files {
+			if !file.Partial && (latest == nil || file.Range.ExclusiveEndBlock > latest.Range.ExclusiveEndBlock) {
+				latest = file
+			}
+		}
+		if latest == nil {
+			logger.Info("no snapshot to preload", zap.String("module_hash", moduleHash))
+			continue
+		}
+
+		key := config.snapshotKey(latest)
+		data, err := loadStore(ctx, config.objStore, key, config.loadRetries, config.loadBackoff)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("loading snapshot %s of store %s: %w", key, moduleHash, err)
+		}
+		if !c.add(config.objStore.ObjectURL(key), data) {
+			logger.Info("snapshot does not fit in the cache, skipping", zap.String("module_hash", moduleHash), zap.String("snapshot", key), zap.Int("size", len(data)))
+			continue
+		}
+		logger.Info("snapshot preloaded", zap.String("module_hash", moduleHash), zap.String("snapshot", key), zap.Int("size", len(data)))
+	}
+	return nil
+}
+
+// readSnapshot returns the content of the full snapshot object `key`, from the snapshot
+// cache if the store has one holding it.
+func (c *Config) readSnapshot(ctx context.Context, key string) ([]byte, error) {
+	if c.snapshotCache != nil {
+		if data, found := c.snapshotCache.get(c.objStore.ObjectURL(key)); found {
+			return data, nil
+		}
+	}
+	return loadStore(ctx, c.objStore, key, c.loadRetries, c.loadBackoff)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestSnapshotCache_Preload(t *testing.T) {
+	ctx := context.Background()
+	baseStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+
+	newConfig := func() *Config {
+		config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", baseStore, "")
+		require.NoError(t, err)
+		return config
+	}
+
+	for _, endBlock := range []uint64{10, 20} {
+		saved := newConfig().NewFullKV(zap.NewNop())
+		saved.Set(0, "key", "value")
+		_, writer, err := saved.Save(endBlock)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+	}
+
+	cache := NewSnapshotCache(1024)
+	require.NoError(t, cache.Preload(ctx, baseStore, []string{"test.module.hash", "unknown.module.hash"}, nil, zap.NewNop()))
+	assert.NotZero(t, cache.Size())
+
+	config := newConfig()
+	config.SetSnapshotCache(cache)
+
+	latest := config.NewFullKV(zap.NewNop())
+	require.NoError(t, latest.Load(ctx, NewCompleteFileInfo("test", 0, 20)))
+	assert.Equal(t, uint64(1), cache.Hits())
+	val, found := latest.GetLast("key")
+	require.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+
+	// only the latest snapshot is preloaded
+	require.NoError(t, config.NewFullKV(zap.NewNop()).Load(ctx, NewCompleteFileInfo("test", 0, 10)))
+	assert.Equal(t, uint64(1), cache.Hits())
+	assert.Equal(t, uint64(1), cache.Misses())
+}
+
+func TestSnapshotCache_PreloadLimits(t *testing.T) {
+	ctx := context.Background()
+	baseStore, err := dstore.NewStore(t.TempDir(), "", "none", true)
+	require.NoError(t, err)
+
+	config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", baseStore, "")
+	require.NoError(t, err)
+	saved := config.NewFullKV(zap.NewNop())
+	saved.Set(0, "key", "value")
+	_, writer, err := saved.Save(10)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+
+	tooSmall := NewSnapshotCache(1)
+	require.NoError(t, tooSmall.Preload(ctx, baseStore, []string{"test.module.hash"}, nil, zap.NewNop()))
+	assert.Zero(t, tooSmall.Size())
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	canceled := NewSnapshotCache(1024)
+	require.NoError(t, canceled.Preload(canceledCtx, baseStore, []string{"test.module.hash"}, nil, zap.NewNop()))
+	assert.Zero(t, canceled.Size())
+}
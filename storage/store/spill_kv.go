@@ -0,0 +1,313 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var spillBucket = []byte("kv")
+
+// spillIterateBatchSize is the number of entries read from disk at once when iterating,
+// entries are handed to the iteration callback outside of any read transaction.
+const spillIterateBatchSize = 1000
+
+// spillMaxPendingWrites caps the writes buffered in memory between two commits, a
+// merge or a load writing the whole state commits once every that many writes.
+const spillMaxPendingWrites = 10_000
+
+// spillKV keeps the store's state in a local bolt database instead of memory. The
+// database is private to the store, created on the first commit and deleted when the
+// backend is closed, by the store itself or by `ConfigMap.Close`.
+//
+// Writes are buffered in memory and committed in a single transaction, once per
+// block, see `baseStore.Commit`. The first I/O error is kept and returned by every
+// later Commit and Iterate, the state on disk being incomplete from then on.
+type spillKV struct {
+	directory string
+	name      string
+	spills    *spillSet
+
+	db      *bolt.DB
+	path    string
+	count   int
+	pending map[string]spillWrite
+	err     error
+}
+
+type spillWrite struct {
+	value   []byte
+	deleted bool
+}
+
+func newSpillKV(directory, storeName string, spills *spillSet) *spillKV {
+	return &spillKV{
+		directory: directory,
+		name:      storeName,
+		spills:    spills,
+		pending:   make(map[string]spillWrite),
+	}
+}
+
+func (s *spillKV) open() error {
+	f, err := os.CreateTemp(s.directory, s.name+"-*.db")
+	if err != nil {
+		return fmt.Errorf("creating spill file: %w", err)
+	}
+	path := f.Name()
+	f.Close()
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{NoSync: true, NoFreelistSync: true})
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("opening spill database: %w", err)
+	}
+
+	s.db = db
+	s.path = path
+	if s.spills != nil {
+		s.spills.add(s)
+	}
+	return nil
+}
+
+func (s *spillKV) Get(key string) (out []byte, found bool) {
+	if write, found := s.pending[key]; found {
+		return write.value, !write.deleted
+	}
+	if s.db == nil {
+		return nil, false
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if val := tx.Bucket(spillBucket).Get([]byte(key)); val != nil {
+			out = copyBytes(val)
+			found = true
+		}
+		return nil
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("reading key %q: %w", key, err))
+		return nil, false
+	}
+	return
+}
+
+// exists is Get without copying the value out of the database.
+func (s *spillKV) exists(key string) (found bool) {
+	if write, found := s.pending[key]; found {
+		return !write.deleted
+	}
+	if s.db == nil {
+		return false
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(spillBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("reading key %q: %w", key, err))
+		return false
+	}
+	return
+}
+
+func (s *spillKV) Set(key string, value []byte) {
+	if !s.exists(key) {
+		s.count++
+	}
+	s.pending[key] = spillWrite{value: value}
+	s.commitIfFull()
+}
+
+func (s *spillKV) Delete(key string) {
+	if !s.exists(key) {
+		return
+	}
+	s.count--
+	s.pending[key] = spillWrite{deleted: true}
+	s.commitIfFull()
+}
+
+func (s *spillKV) commitIfFull() {
+	if len(s.pending) >= spillMaxPendingWrites {
+		// a failure is kept, and returned by the next commit
+		_ = s.Commit()
+	}
+}
+
+func (s *spillKV) Len() int { return s.count }
+
+// Commit writes the buffered writes to disk, in a single transaction.
+func (s *spillKV) Commit() error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if s.db == nil {
+		if err := s.open(); err != nil {
+			s.fail(err)
+			return s.err
+		}
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(spillBucket)
+		if err != nil {
+			return err
+		}
+		for k, write := range s.pending {
+			if write.deleted {
+				err = bucket.Delete([]byte(k))
+			} else {
+				err = bucket.Put([]byte(k), nonNil(write.value))
+			}
+			if err != nil {
+				return fmt.Errorf("writing key %q: %w", k, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.fail(err)
+		return s.err
+	}
+	clear(s.pending)
+	return nil
+}
+
+func (s *spillKV) fail(err error) {
+	if s.err == nil {
+		s.err = fmt.Errorf("spilled store %q: %w", s.name, err)
+	}
+}
+
+func (s *spillKV) Iterate(f func(key string, value []byte) error) error {
+	if err := s.Commit(); err != nil {
+		return err
+	}
+	if s.db == nil {
+		return nil
+	}
+
+	var after []byte
+	for {
+		var keys []string
+		var values [][]byte
+		err := s.db.View(func(tx *bolt.Tx) error {
+			cursor := tx.Bucket(spillBucket).Cursor()
+			k, v := cursor.First()
+			if after != nil {
+				k, v = cursor.Seek(after)
+				if k != nil && string(k) == string(after) {
+					k, v = cursor.Next()
+				}
+			}
+			for ; k != nil && len(keys) < spillIterateBatchSize; k, v = cursor.Next() {
+				keys = append(keys, string(k))
+				values = append(values, copyBytes(v))
+			}
+			return nil
+		})
+		if err != nil {
+			s.fail(fmt.Errorf("reading entries: %w", err))
+			return s.err
+		}
+
+		for i, k := range keys {
+			if err := f(k, values[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(keys) < spillIterateBatchSize {
+			return nil
+		}
+		after = []byte(keys[len(keys)-1])
+	}
+}
+
+// Map loads the whole state in memory, it is only meant for debugging and tests. An
+// I/O error is returned by the next Commit.
+func (s *spillKV) Map() map[string][]byte {
+	out := make(map[string][]byte, s.count)
+	_ = s.Iterate(func(key string, value []byte) error {
+		out[key] = value
+		return nil
+	})
+	return out
+}
+
+func (s *spillKV) Close() error {
+	s.pending = make(map[string]spillWrite)
+	s.count = 0
+	if s.db == nil {
+		return nil
+	}
+	if s.spills != nil {
+		s.spills.remove(s)
+	}
+	err := s.db.Close()
+	s.db = nil
+	os.Remove(s.path)
+	return err
+}
+
+// spillSet tracks the open spill backends of the stores of a request, so they can all
+// be closed once it ends, see `ConfigMap.Close`.
+type spillSet struct {
+	mu   sync.Mutex
+	open map[*spillKV]bool
+}
+
+func (s *spillSet) add(kv *spillKV) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.open == nil {
+		s.open = make(map[*spillKV]bool)
+	}
+	s.open[kv] = true
+}
+
+func (s *spillSet) remove(kv *spillKV) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.open, kv)
+}
+
+func (s *spillSet) closeAll() error {
+	s.mu.Lock()
+	kvs := make([]*spillKV, 0, len(s.open))
+	for kv := range s.open {
+		kvs = append(kvs, kv)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, kv := range kvs {
+		if err := kv.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing spilled store %q: %w", kv.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nonNil is required because bolt considers nil values as missing keys.
+func nonNil(v []byte) []byte {
+	if v == nil {
+		return []byte{}
+	}
+	return v
+}
+
+func copyBytes(v []byte) []byte {
+	out := make([]byte, len(v))
+	copy(out, v)
+	return out
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestSpillKV_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	config, err := NewConfig("test", 0, "test.module.hash", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+	configs := ConfigMap{"test": config}
+	configs.SetSpillDirectory(dir)
+
+	s := config.NewFullKV(zap.NewNop())
+	s.Set(1, "a", "1")
+	s.Set(2, "b", "2")
+	s.Set(3, "a", "3")
+
+	// the writes of the block are buffered until it is committed
+	spill := s.kv.(*spillKV)
+	assert.Nil(t, spill.db)
+	val, found := s.GetLast("a")
+	assert.True(t, found)
+	assert.Equal(t, []byte("3"), val)
+
+	require.NoError(t, s.Commit())
+	s.DeletePrefix(4, "b")
+	require.NoError(t, s.Commit())
+	require.NotNil(t, spill.db)
+	assert.Empty(t, spill.pending)
+	assert.Equal(t, 1, spill.Len())
+
+	file, writer, err := s.Save(100)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(context.Background()))
+
+	loaded := config.NewFullKV(zap.NewNop())
+	require.NoError(t, loaded.Load(context.Background(), file))
+	assert.Equal(t, map[string][]byte{"a": []byte("3")}, loaded.kv.Map())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	require.NoError(t, configs.Close())
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSpillKV_IOError(t *testing.T) {
+	kv := newSpillKV(filepath.Join(t.TempDir(), "missing"), "test", nil)
+
+	assert.NotPanics(t, func() {
+		kv.Set("a", []byte("1"))
+		kv.Delete("a")
+		kv.Set("b", []byte("2"))
+	})
+	assert.ErrorContains(t, kv.Commit(), `spilled store "test": creating spill file`)
+	assert.Error(t, kv.Iterate(func(key string, value []byte) error { return nil }))
+	assert.NoError(t, kv.Close())
+}
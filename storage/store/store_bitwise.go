@@ -0,0 +1,47 @@
+package store
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// BitOrInt64 combines `value` with the integer under `key` using a bitwise OR, an absent
+// key taking `value`, like the merge of `bit_or` stores.
+func (b *baseStore) BitOrInt64(ord uint64, key string, value int64) {
+	b.bitwiseInt64(ord, key, value, func(a, b int64) int64 { return a | b })
+}
+
+// BitAndInt64 is BitOrInt64 with a bitwise AND, for `bit_and` stores.
+func (b *baseStore) BitAndInt64(ord uint64, key string, value int64) {
+	b.bitwiseInt64(ord, key, value, func(a, b int64) int64 { return a & b })
+}
+
+// BitOrBigInt is BitOrInt64 for `bigint` stores.
+func (b *baseStore) BitOrBigInt(ord uint64, key string, value *big.Int) {
+	b.bitwiseBigInt(ord, key, value, func(a, b *big.Int) *big.Int { return new(big.Int).Or(a, b) })
+}
+
+// BitAndBigInt is BitAndInt64 for `bigint` stores.
+func (b *baseStore) BitAndBigInt(ord uint64, key string, value *big.Int) {
+	b.bitwiseBigInt(ord, key, value, func(a, b *big.Int) *big.Int { return new(big.Int).And(a, b) })
+}
+
+func (b *baseStore) bitwiseInt64(ord uint64, key string, value int64, op func(a, b int64) int64) {
+	result := value
+	if val, found := b.GetAt(ord, key); found {
+		if prev, err := strconv.ParseInt(string(val), 10, 64); err == nil {
+			result = op(prev, value)
+		}
+	}
+	b.mustSet(ord, key, []byte(strconv.FormatInt(result, 10)))
+}
+
+func (b *baseStore) bitwiseBigInt(ord uint64, key string, value *big.Int, op func(a, b *big.Int) *big.Int) {
+	result := value
+	if val, found := b.GetAt(ord, key); found {
+		if prev, ok := new(big.Int).SetString(string(val), 10); ok {
+			result = op(prev, value)
+		}
+	}
+	b.mustSet(ord, key, []byte(result.String()))
+}
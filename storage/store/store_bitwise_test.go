@@ -0,0 +1,30 @@
+package store
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStoreBitwise(t *testing.T) {
+	b := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, "int64", nil)
+
+	b.BitOrInt64(0, "or", 0b0101)
+	b.BitOrInt64(1, "or", 0b0011)
+	b.BitAndInt64(2, "and", 0b0101)
+	b.BitAndInt64(3, "and", 0b0011)
+	b.BitOrBigInt(4, "big_or", big.NewInt(0b0101))
+	b.BitOrBigInt(5, "big_or", big.NewInt(0b0011))
+	b.BitAndBigInt(6, "big_and", big.NewInt(0b0101))
+	b.BitAndBigInt(7, "big_and", big.NewInt(0b0011))
+
+	for key, expected := range map[string]string{"or": "7", "and": "1", "big_or": "7", "big_and": "1"} {
+		actual, found := b.GetLast(key)
+		require.True(t, found, key)
+		assert.Equal(t, expected, string(actual), key)
+	}
+}
@@ -21,7 +21,7 @@ func (b *baseStore) SetMaxBigInt(ord uint64, key string, value *big.Int) {
 			max = prev
 		}
 	}
-	b.set(ord, key, []byte(max.String()))
+	b.mustSet(ord, key, []byte(max.String()))
 }
 
 func (b *baseStore) SetMaxInt64(ord uint64, key string, value int64) {
@@ -37,7 +37,7 @@ func (b *baseStore) SetMaxInt64(ord uint64, key string, value int64) {
 			max = prev
 		}
 	}
-	b.set(ord, key, []byte(fmt.Sprintf("%d", max)))
+	b.mustSet(ord, key, []byte(fmt.Sprintf("%d", max)))
 }
 
 func (b *baseStore) SetMaxFloat64(ord uint64, key string, value float64) {
@@ -54,20 +54,20 @@ func (b *baseStore) SetMaxFloat64(ord uint64, key string, value float64) {
 			max = prev
 		}
 	}
-	b.set(ord, key, []byte(strconv.FormatFloat(max, 'g', 100, 64)))
+	b.mustSet(ord, key, []byte(strconv.FormatFloat(max, 'g', 100, 64)))
 }
 
 func (b *baseStore) SetMaxBigDecimal(ord uint64, key string, value decimal.Decimal) {
 	val, found := b.GetAt(ord, key)
 	if !found {
-		b.set(ord, key, []byte(value.String()))
+		b.mustSet(ord, key, []byte(value.String()))
 		return
 	}
 	prev, err := decimal.NewFromString(string(val))
 	prev.Truncate(34)
 	if err != nil || value.Cmp(prev) == 1 {
-		b.set(ord, key, []byte(value.String()))
+		b.mustSet(ord, key, []byte(value.String()))
 		return
 	}
-	b.set(ord, key, []byte(prev.String()))
+	b.mustSet(ord, key, []byte(prev.String()))
 }
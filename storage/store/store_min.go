@@ -21,7 +21,7 @@ func (b *baseStore) SetMinBigInt(ord uint64, key string, value *big.Int) {
 			min = prev
 		}
 	}
-	b.set(ord, key, []byte(min.String()))
+	b.mustSet(ord, key, []byte(min.String()))
 }
 
 func (b *baseStore) SetMinInt64(ord uint64, key string, value int64) {
@@ -37,7 +37,7 @@ func (b *baseStore) SetMinInt64(ord uint64, key string, value int64) {
 			min = prev
 		}
 	}
-	b.set(ord, key, []byte(fmt.Sprintf("%d", min)))
+	b.mustSet(ord, key, []byte(fmt.Sprintf("%d", min)))
 }
 
 func (b *baseStore) SetMinFloat64(ord uint64, key string, value float64) {
@@ -54,20 +54,20 @@ func (b *baseStore) SetMinFloat64(ord uint64, key string, value float64) {
 			min = prev
 		}
 	}
-	b.set(ord, key, []byte(strconv.FormatFloat(min, 'g', 100, 64)))
+	b.mustSet(ord, key, []byte(strconv.FormatFloat(min, 'g', 100, 64)))
 }
 
 func (b *baseStore) SetMinBigDecimal(ord uint64, key string, value decimal.Decimal) {
 	val, found := b.GetAt(ord, key)
 	if !found {
-		b.set(ord, key, []byte(value.String()))
+		b.mustSet(ord, key, []byte(value.String()))
 		return
 	}
 	prev, err := decimal.NewFromString(string(val))
 	prev.Truncate(34)
 	if err != nil || value.Cmp(prev) <= 0 {
-		b.set(ord, key, []byte(value.String()))
+		b.mustSet(ord, key, []byte(value.String()))
 		return
 	}
-	b.set(ord, key, []byte(prev.String()))
+	b.mustSet(ord, key, []byte(prev.String()))
 }
@@ -47,9 +47,9 @@ func TestStoreSetMinBigInt(t *testing.T) {
 	}
 
 	initTestStore := func(b *baseStore, key string, value *big.Int) {
-		b.kv = map[string][]byte{}
+		b.kv = memoryKV{}
 		if value != nil {
-			b.kv[key] = []byte(value.String())
+			b.kv.Set(key, []byte(value.String()))
 		}
 	}
 
@@ -113,9 +113,9 @@ func TestStoreSetMinInt64(t *testing.T) {
 	}
 
 	initTestStore := func(b *baseStore, key string, value *int64) {
-		b.kv = map[string][]byte{}
+		b.kv = memoryKV{}
 		if value != nil {
-			b.kv[key] = []byte(fmt.Sprintf("%d", *value))
+			b.kv.Set(key, []byte(fmt.Sprintf("%d", *value)))
 		}
 	}
 
@@ -180,9 +180,9 @@ func TestStoreSetMinFloat64(t *testing.T) {
 	}
 
 	initTestStore := func(b *baseStore, key string, value *float64) {
-		b.kv = map[string][]byte{}
+		b.kv = memoryKV{}
 		if value != nil {
-			b.kv[key] = []byte(strconv.FormatFloat(*value, 'g', 100, 64))
+			b.kv.Set(key, []byte(strconv.FormatFloat(*value, 'g', 100, 64)))
 		}
 	}
 
@@ -241,9 +241,9 @@ func TestStoreSetMinBigFloat(t *testing.T) {
 	}
 
 	initTestStore := func(b *baseStore, key string, value decimal.Decimal) {
-		b.kv = map[string][]byte{}
+		b.kv = memoryKV{}
 		if value != nilDecimal {
-			b.kv[key] = []byte(value.String())
+			b.kv.Set(key, []byte(value.String()))
 		}
 	}
 
@@ -20,7 +20,7 @@ func (b *baseStore) SumBigInt(ord uint64, key string, value *big.Int) {
 			sum.Add(prev, value)
 		}
 	}
-	b.set(ord, key, []byte(sum.String()))
+	b.mustSet(ord, key, []byte(sum.String()))
 }
 
 func (b *baseStore) SumInt64(ord uint64, key string, value int64) {
@@ -36,7 +36,7 @@ func (b *baseStore) SumInt64(ord uint64, key string, value int64) {
 			sum = prev + value
 		}
 	}
-	b.set(ord, key, []byte(strconv.FormatInt(sum, 10)))
+	b.mustSet(ord, key, []byte(strconv.FormatInt(sum, 10)))
 }
 
 func (b *baseStore) SumFloat64(ord uint64, key string, value float64) {
@@ -52,21 +52,21 @@ func (b *baseStore) SumFloat64(ord uint64, key string, value float64) {
 			sum = prev + value
 		}
 	}
-	b.set(ord, key, []byte(strconv.FormatFloat(sum, 'g', 100, 64)))
+	b.mustSet(ord, key, []byte(strconv.FormatFloat(sum, 'g', 100, 64)))
 }
 
 func (b *baseStore) SumBigDecimal(ord uint64, key string, value decimal.Decimal) {
 	v, found := b.GetAt(ord, key)
 	if !found {
-		b.set(ord, key, []byte(value.String()))
+		b.mustSet(ord, key, []byte(value.String()))
 		return
 	}
 	prev, err := decimal.NewFromString(string(v))
 	prev.Truncate(34)
 	if err != nil {
-		b.set(ord, key, []byte(value.String()))
+		b.mustSet(ord, key, []byte(value.String()))
 		return
 	}
 	sum := prev.Add(value)
-	b.set(ord, key, []byte(sum.String()))
+	b.mustSet(ord, key, []byte(sum.String()))
 }
@@ -39,7 +39,7 @@ func TestStoreSumBigInt(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			b := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_UNSET, "", nil)
 			if test.existingValue != nil {
-				b.kv[test.key] = test.existingValue
+				b.kv.Set(test.key, test.existingValue)
 				b.totalSizeBytes += uint64(len(test.key) + len(test.existingValue))
 			}
 
@@ -84,7 +84,7 @@ func TestStoreSumInt64(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			b := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_UNSET, "", nil)
 			if test.existingValue != nil {
-				b.kv[test.key] = test.existingValue
+				b.kv.Set(test.key, test.existingValue)
 				b.totalSizeBytes += uint64(len(test.key) + len(test.existingValue))
 			}
 
@@ -130,7 +130,7 @@ func TestStoreSumFloat64(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			b := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_UNSET, "", nil)
 			if test.existingValue != nil {
-				b.kv[test.key] = test.existingValue
+				b.kv.Set(test.key, test.existingValue)
 				b.totalSizeBytes += uint64(len(test.key) + len(test.existingValue))
 			}
 
@@ -1,8 +1,18 @@
 package store
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
 
 func (b *baseStore) Append(ord uint64, key string, value []byte) error {
+	if err := b.frozenError(); err != nil {
+		return err
+	}
+	if b.coalesceAppends {
+		return b.appendCoalesced(ord, key, value)
+	}
 	var newVal []byte
 	oldVal, found := b.GetAt(ord, key)
 	if !found {
@@ -18,7 +28,40 @@ func (b *baseStore) Append(ord uint64, key string, value []byte) error {
 		copy(newVal[0:], oldVal)
 		copy(newVal[len(oldVal):], value)
 	}
-	b.set(ord, key, newVal)
+	return b.set(ord, key, newVal)
+}
 
-	return nil
+func (b *baseStore) AppendUnique(ord uint64, key string, value []byte) (bool, error) {
+	oldVal, found := b.GetAt(ord, key)
+	if found {
+		elements, err := DecodeFramedElements(oldVal)
+		if err != nil {
+			return false, fmt.Errorf("key %q: %w", key, err)
+		}
+		for _, element := range elements {
+			if bytes.Equal(element, value) {
+				return false, nil
+			}
+		}
+	}
+
+	frame := binary.AppendUvarint(nil, uint64(len(value)))
+	frame = append(frame, value...)
+	if err := b.Append(ord, key, frame); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DecodeFramedElements splits a value written with AppendUnique into its elements.
+func DecodeFramedElements(value []byte) (out [][]byte, err error) {
+	for len(value) > 0 {
+		size, n := binary.Uvarint(value)
+		if n <= 0 || uint64(len(value)-n) < size {
+			return nil, fmt.Errorf("invalid framed element at offset %d", len(value))
+		}
+		out = append(out, value[n:n+int(size)])
+		value = value[n+int(size):]
+	}
+	return out, nil
 }
@@ -1,10 +1,13 @@
 package store
 
 import (
+	"fmt"
 	"testing"
 
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValueAppend(t *testing.T) {
@@ -57,3 +60,138 @@ func TestValueAppend(t *testing.T) {
 	}
 
 }
+
+func TestValueAppendUnique(t *testing.T) {
+	store := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "", nil)
+	store.appendLimit = 100
+
+	added, err := store.AppendUnique(0, "key", []byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = store.AppendUnique(1, "key", []byte("a"))
+	assert.NoError(t, err)
+	assert.False(t, added)
+
+	added, err = store.AppendUnique(2, "key", []byte("bc"))
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	val, found := store.GetLast("key")
+	assert.True(t, found)
+	elements, err := DecodeFramedElements(val)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("bc")}, elements)
+}
+
+func TestValueAppend_Coalesced(t *testing.T) {
+	// run writes a block appending many times to a hot key, among other writes
+	run := func(coalesce bool) *baseStore {
+		s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "string", nil)
+		s.appendLimit = 0
+		s.totalSizeLimit = 1_000_000
+		s.SetAppendCoalescing(coalesce)
+
+		require.NoError(t, s.Append(0, "hot", []byte("start;")))
+		s.GetDeltas()
+		s.Reset()
+
+		for i := 0; i < 1000; i++ {
+			ord := uint64(i)
+			require.NoError(t, s.Append(ord, "hot", []byte(fmt.Sprintf("%d;", i))))
+			if i%100 == 0 {
+				require.NoError(t, s.Append(ord, fmt.Sprintf("cold%d", i), []byte("x")))
+			}
+		}
+		first, found := s.GetFirst("hot")
+		require.True(t, found)
+		assert.Equal(t, []byte("start;"), first)
+		return s
+	}
+
+	sequential, coalesced := run(false), run(true)
+
+	expected, found := sequential.GetLast("hot")
+	require.True(t, found)
+	actual, found := coalesced.GetLast("hot")
+	require.True(t, found)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, sequential.kv.Map(), coalesced.kv.Map())
+	assert.Equal(t, sequential.totalSizeBytes, coalesced.totalSizeBytes)
+
+	deltas := coalesced.GetDeltas()
+	require.Len(t, deltas, 11)
+	hot := deltas[len(deltas)-1]
+	assert.Equal(t, &pbssinternal.StoreDelta{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 999, Key: "hot", OldValue: []byte("start;"), NewValue: expected}, hot)
+	for i := 1; i < len(deltas); i++ {
+		assert.LessOrEqual(t, deltas[i-1].Ordinal, deltas[i].Ordinal, "deltas must stay ordered")
+	}
+
+	// the block can still be undone
+	coalesced.ApplyDeltasReverse(deltas)
+	assert.Equal(t, map[string][]byte{"hot": []byte("start;")}, coalesced.kv.Map())
+}
+
+func TestValueAppend_CoalescedThenSet(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "string", nil)
+	s.SetAppendCoalescing(true)
+
+	require.NoError(t, s.Append(1, "key", []byte("a")))
+	require.NoError(t, s.Append(2, "key", []byte("b")))
+	s.Set(3, "key", "c")
+	require.NoError(t, s.Append(4, "key", []byte("d")))
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_CREATE, Ordinal: 2, Key: "key", NewValue: []byte("ab")},
+		{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 3, Key: "key", OldValue: []byte("ab"), NewValue: []byte("c")},
+		{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 4, Key: "key", OldValue: []byte("c"), NewValue: []byte("cd")},
+	}, s.GetDeltas())
+}
+
+func TestValueAppend_CoalescedReads(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "string", nil)
+	s.SetAppendCoalescing(true)
+
+	s.Set(1, "key", "a")
+	require.NoError(t, s.Append(2, "key", []byte("b")))
+	require.NoError(t, s.Append(3, "key", []byte("c")))
+
+	last, found := s.GetLast("key")
+	require.True(t, found)
+	assert.Equal(t, "abc", string(last))
+	assert.True(t, s.HasLast("key"))
+
+	for ord, expected := range map[uint64]string{1: "a", 2: "a", 3: "abc", 4: "abc"} {
+		val, found := s.GetAt(ord, "key")
+		require.True(t, found, ord)
+		assert.Equal(t, expected, string(val), ord)
+	}
+	_, found = s.GetAt(0, "key")
+	assert.False(t, found)
+	assert.True(t, s.HasAt(3, "key"))
+}
+
+func BenchmarkValueAppend_HotKey(b *testing.B) {
+	value := []byte("0123456789abcdef")
+	for _, coalesce := range []bool{false, true} {
+		b.Run(fmt.Sprintf("coalesce_%t", coalesce), func(b *testing.B) {
+			s := newTestBaseStore(b, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "bytes", nil)
+			s.appendLimit = 0
+			s.totalSizeLimit = 1 << 30
+			s.SetAppendCoalescing(coalesce)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.Reset()
+				s.kv = memoryKV{}
+				s.totalSizeBytes = 0
+				for ord := uint64(0); ord < 1000; ord++ {
+					if err := s.Append(ord, "hot", value); err != nil {
+						b.Fatal(err)
+					}
+				}
+				s.GetDeltas()
+			}
+		})
+	}
+}
@@ -4,33 +4,23 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/streamingfast/substreams/metrics"
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 )
 
-//func (s *baseStore) Del(ord uint64, key string) {
-//	s.bumpOrdinal(ord)
-//
-//	val, found := s.GetLast(key)
-//	if found {
-//		delta := &pbsubstreams.StoreDelta{
-//			Operation: pbsubstreams.StoreDelta_DELETE,
-//			Ordinal:   ord,
-//			Key:       key,
-//			OldValue:  val,
-//			NewValue:  nil,
-//		}
-//		s.ApplyDelta(delta)
-//		s.deltas = append(s.deltas, delta)
-//	}
-//}
+// Delete deletes `key`, emitting a DELETE delta if it is present.
+func (b *baseStore) Delete(ord uint64, key string) {
+	b.DeleteMany(ord, []string{key})
+}
 
 func (b *baseStore) DeletePrefix(ord uint64, prefix string) {
 	b.bumpOrdinal(ord)
+	b.flushAppends(prefix)
 
 	var deltas []*pbssinternal.StoreDelta
-	for key, val := range b.kv {
+	_ = b.kv.Iterate(func(key string, val []byte) error {
 		if !strings.HasPrefix(key, prefix) {
-			continue
+			return nil
 		}
 		delta := &pbssinternal.StoreDelta{
 			Operation: pbssinternal.StoreDelta_DELETE,
@@ -41,9 +31,42 @@ func (b *baseStore) DeletePrefix(ord uint64, prefix string) {
 		}
 		b.ApplyDelta(delta)
 		deltas = append(deltas, delta)
-	}
+		metrics.CountStoreDelta(b.name, delta.Operation)
+		return nil
+	})
 	sort.Slice(deltas, func(i, j int) bool {
 		return deltas[i].Key < deltas[j].Key
 	})
 	b.deltas = append(b.deltas, deltas...)
 }
+
+// DeleteMany deletes all of `keys` under a single ordinal. DELETE deltas are emitted,
+// sorted by key, for the keys present in the store only, absent keys are skipped.
+func (b *baseStore) DeleteMany(ord uint64, keys []string) {
+	b.bumpOrdinal(ord)
+
+	sorted := make([]string, len(keys))
+	copy(sorted, keys)
+	sort.Strings(sorted)
+
+	for i, key := range sorted {
+		if i > 0 && sorted[i-1] == key {
+			continue
+		}
+		b.flushAppend(key)
+		val, found := b.kv.Get(key)
+		if !found {
+			continue
+		}
+		delta := &pbssinternal.StoreDelta{
+			Operation: pbssinternal.StoreDelta_DELETE,
+			Ordinal:   ord,
+			Key:       key,
+			OldValue:  val,
+			NewValue:  nil,
+		}
+		b.ApplyDelta(delta)
+		b.deltas = append(b.deltas, delta)
+		metrics.CountStoreDelta(b.name, delta.Operation)
+	}
+}
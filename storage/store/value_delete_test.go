@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_DeleteMany(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	s.kv.Set("index:c", []byte("3"))
+	s.kv.Set("index:a", []byte("1"))
+	s.kv.Set("index:b", []byte("2"))
+	s.kv.Set("other", []byte("4"))
+
+	s.DeleteMany(1, []string{"index:c", "missing", "index:a", "index:c"})
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_DELETE, Ordinal: 1, Key: "index:a", OldValue: []byte("1")},
+		{Operation: pbssinternal.StoreDelta_DELETE, Ordinal: 1, Key: "index:c", OldValue: []byte("3")},
+	}, s.GetDeltas())
+	assert.Equal(t, map[string][]byte{
+		"index:b": []byte("2"),
+		"other":   []byte("4"),
+	}, s.kv.Map())
+}
+
+func TestPartialKV_DeleteMany_Merge(t *testing.T) {
+	full := newStore(map[string][]byte{
+		"a": []byte("1"),
+		"b": []byte("2"),
+		"c": []byte("3"),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString)
+
+	partial := newPartialStore(map[string][]byte{}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, nil)
+	partial.itemSizeLimit = 100
+	partial.totalSizeLimit = 1000
+	partial.Set(0, "c", "30")
+	// "a" was written before the partial's range, "c" is re-written after its deletion
+	partial.DeleteMany(1, []string{"a", "c"})
+	partial.Set(2, "c", "300")
+	assert.Len(t, partial.GetDeltas(), 3)
+
+	require.NoError(t, full.Merge(partial))
+	assert.Equal(t, map[string][]byte{
+		"b": []byte("2"),
+		"c": []byte("300"),
+	}, full.kv.Map())
+}
+
+func TestPartialKV_DeleteMany_SaveLoad(t *testing.T) {
+	config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, dstore.NewMockStore(nil), "")
+	require.NoError(t, err)
+
+	partial := config.NewPartialKV(0, zap.NewNop())
+	partial.Set(0, "kept", "1")
+	partial.DeleteMany(1, []string{"gone", "kept:other", "gone"})
+
+	// the deleted keys are recorded beside the state, not in it
+	assert.Equal(t, uint64(1), partial.Length())
+	assert.Equal(t, uint64(len("kept")+len("1")+len("gone")+len("kept:other")), partial.SizeBytes())
+
+	file, writer, err := partial.Save(10)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(context.Background()))
+
+	loaded := config.NewPartialKV(0, zap.NewNop())
+	require.NoError(t, loaded.Load(context.Background(), file))
+	assert.Equal(t, map[string]bool{"gone": true, "kept:other": true}, loaded.DeletedKeys)
+	assert.Equal(t, map[string][]byte{"kept": []byte("1")}, loaded.kv.Map())
+}
+
+func TestStore_DeleteAbsentAndPresent(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	s.Set(0, "present", "value")
+	s.Reset()
+	size := s.SizeBytes()
+
+	s.DeletePrefix(1, "absent")
+	s.DeleteMany(1, []string{"absent"})
+	assert.Empty(t, s.GetDeltas(), "deleting an absent key emits no delta")
+	assert.Equal(t, size, s.SizeBytes())
+
+	s.DeleteMany(2, []string{"present"})
+	expected := &pbssinternal.StoreDelta{Operation: pbssinternal.StoreDelta_DELETE, Ordinal: 2, Key: "present", OldValue: []byte("value")}
+	assert.Equal(t, []*pbssinternal.StoreDelta{expected}, s.GetDeltas())
+	assert.False(t, s.HasLast("present"))
+	assert.Zero(t, s.SizeBytes())
+
+	// downstream consumers may apply the same DELETE again
+	s.ApplyDelta(expected)
+	assert.False(t, s.HasLast("present"))
+	assert.Zero(t, s.SizeBytes())
+}
+
+func TestStore_Delete(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	s.kv.Set("a", []byte("1"))
+	s.kv.Set("b", []byte("2"))
+
+	s.Delete(1, "a")
+	s.Delete(2, "missing")
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_DELETE, Ordinal: 1, Key: "a", OldValue: []byte("1")},
+	}, s.GetDeltas())
+	assert.Equal(t, map[string][]byte{"b": []byte("2")}, s.kv.Map())
+}
+
+func TestStore_DeletePrefix(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	s.kv.Set("pair:2", []byte("2"))
+	s.kv.Set("pair:1", []byte("1"))
+	s.kv.Set("pairs", []byte("3"))
+	s.kv.Set("token:1", []byte("4"))
+
+	s.DeletePrefix(1, "pair:")
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_DELETE, Ordinal: 1, Key: "pair:1", OldValue: []byte("1")},
+		{Operation: pbssinternal.StoreDelta_DELETE, Ordinal: 1, Key: "pair:2", OldValue: []byte("2")},
+	}, s.GetDeltas())
+	assert.Equal(t, map[string][]byte{
+		"pairs":   []byte("3"),
+		"token:1": []byte("4"),
+	}, s.kv.Map())
+}
+
+func TestPartialKV_Delete_Merge(t *testing.T) {
+	full := newStore(map[string][]byte{
+		"a":      []byte("1"),
+		"pair:1": []byte("2"),
+		"pair:2": []byte("3"),
+		"z":      []byte("4"),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString)
+
+	partial := newPartialStore(map[string][]byte{}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, nil)
+	partial.itemSizeLimit = 100
+	partial.totalSizeLimit = 1000
+	partial.Delete(1, "a")
+	partial.DeletePrefix(2, "pair:")
+	assert.Equal(t, []string{"pair:"}, partial.DeletedPrefixes)
+
+	require.NoError(t, full.Merge(partial))
+	assert.Equal(t, map[string][]byte{"z": []byte("4")}, full.kv.Map())
+}
@@ -24,7 +24,10 @@ func (b *baseStore) GetFirst(key string) ([]byte, bool) {
 
 	}
 
-	val, found := b.kv[key]
+	if val, found, buffered := b.appendedFirst(key); buffered {
+		return val, found
+	}
+	val, found := b.kv.Get(key)
 	return val, found
 }
 
@@ -46,11 +49,18 @@ func (b *baseStore) HasFirst(key string) bool {
 
 	}
 
-	_, found := b.kv[key]
+	if _, found, buffered := b.appendedFirst(key); buffered {
+		return found
+	}
+	_, found := b.kv.Get(key)
 	return found
 }
 
 func (b *baseStore) GetLast(key string) ([]byte, bool) {
+	if buf, buffered := b.appendBuffers[key]; buffered {
+		return buf.value, true
+	}
+
 	for i := len(b.deltas) - 1; i >= 0; i-- {
 		delta := b.deltas[i]
 		if delta.Key != key {
@@ -67,11 +77,15 @@ func (b *baseStore) GetLast(key string) ([]byte, bool) {
 		}
 	}
 
-	val, found := b.kv[key]
+	val, found := b.kv.Get(key)
 	return val, found
 }
 
 func (b *baseStore) HasLast(key string) bool {
+	if _, buffered := b.appendBuffers[key]; buffered {
+		return true
+	}
+
 	for i := len(b.deltas) - 1; i >= 0; i-- {
 		delta := b.deltas[i]
 		if delta.Key != key {
@@ -88,13 +102,23 @@ func (b *baseStore) HasLast(key string) bool {
 		}
 	}
 
-	_, found := b.kv[key]
+	_, found := b.kv.Get(key)
 	return found
 }
 
-// GetAt returns the key for the state that includes the processing of `ord`.
+// GetAt returns the key for the state that includes the processing of `ord`. When the
+// appends to the key are coalesced, `ord` falling between the first and the last append
+// of the block returns the value from before the first one.
 func (b *baseStore) GetAt(ord uint64, key string) (out []byte, found bool) {
-	out, found = b.GetLast(key)
+	if buf, buffered := b.appendBuffers[key]; buffered {
+		if ord >= buf.ordinal {
+			return buf.value, true
+		}
+		// the deltas of the key all come before its first append
+		out, found = buf.oldValue, buf.oldFound
+	} else {
+		out, found = b.GetLast(key)
+	}
 
 	for i := len(b.deltas) - 1; i >= 0; i-- {
 		delta := b.deltas[i]
@@ -122,6 +146,9 @@ func (b *baseStore) GetAt(ord uint64, key string) (out []byte, found bool) {
 
 // HasAt returns true if the key exists for the state that includes the processing of `ord`.
 func (b *baseStore) HasAt(ord uint64, key string) bool {
+	if buf, buffered := b.appendBuffers[key]; buffered && ord >= buf.ordinal {
+		return true
+	}
 	_, found := b.GetFirst(key)
 
 	for i := len(b.deltas) - 1; i >= 0; i-- {
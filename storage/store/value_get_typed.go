@@ -0,0 +1,97 @@
+package store
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/streamingfast/substreams/manifest"
+)
+
+// GetAsInt64 returns the last value of `key` decoded as an `int64`, 0 when not found. It
+// fails when the store does not hold `int64` values or when the value does not decode.
+func (b *baseStore) GetAsInt64(key string) (int64, bool, error) {
+	value, found, err := b.getTyped(key, manifest.OutputValueTypeInt64)
+	if err != nil || !found {
+		return 0, found, err
+	}
+	out, err := parseInt64(value)
+	if err != nil {
+		return 0, true, b.decodeError(key, err)
+	}
+	return out, true, nil
+}
+
+// GetAsBigInt returns the last value of `key` decoded as a `bigint`, 0 when not found. It
+// fails when the store does not hold `bigint` values or when the value does not decode.
+func (b *baseStore) GetAsBigInt(key string) (*big.Int, bool, error) {
+	value, found, err := b.getTyped(key, manifest.OutputValueTypeBigInt)
+	if err != nil || !found {
+		return new(big.Int), found, err
+	}
+	out, err := parseBigInt(value)
+	if err != nil {
+		return new(big.Int), true, b.decodeError(key, err)
+	}
+	return out, true, nil
+}
+
+// GetBigInt returns the last value of `key` decoded as a `bigint`, nil and false when not
+// found. Unlike GetAsBigInt, it panics when the store does not hold `bigint` values or
+// when the value does not decode, like writes do on invalid keys.
+func (b *baseStore) GetBigInt(key string) (*big.Int, bool) {
+	out, found, err := b.GetAsBigInt(key)
+	if err != nil {
+		panic(err.Error())
+	}
+	if !found {
+		return nil, false
+	}
+	return out, true
+}
+
+// GetAsFloat64 returns the last value of `key` decoded as a `float64`, 0 when not found. It
+// fails when the store does not hold `float64` values or when the value does not decode.
+func (b *baseStore) GetAsFloat64(key string) (float64, bool, error) {
+	value, found, err := b.getTyped(key, manifest.OutputValueTypeFloat64)
+	if err != nil || !found {
+		return 0, found, err
+	}
+	out, err := parseFloat64(value)
+	if err != nil {
+		return 0, true, b.decodeError(key, err)
+	}
+	return out, true, nil
+}
+
+// GetAsBigFloat returns the last value of `key` decoded as a `bigfloat`, with the precision
+// used by merges, 0 when not found. It fails when the store does not hold `bigfloat`
+// values or when the value does not decode.
+func (b *baseStore) GetAsBigFloat(key string) (*big.Float, bool, error) {
+	prec := uint(defaultBigFloatPrecision)
+	if b.bigFloatPrecision != 0 {
+		prec = b.bigFloatPrecision
+	}
+
+	value, found, err := b.getTyped(key, manifest.OutputValueTypeBigFloat)
+	if err != nil || !found {
+		return new(big.Float).SetPrec(prec), found, err
+	}
+	out, err := parseBigFloatWithPrecision(value, prec)
+	if err != nil {
+		return new(big.Float).SetPrec(prec), true, b.decodeError(key, err)
+	}
+	return out, true, nil
+}
+
+func (b *baseStore) getTyped(key string, valueType string) ([]byte, bool, error) {
+	if strings.ToLower(b.valueType) != valueType {
+		return nil, false, fmt.Errorf("store %q holds %q values, they cannot be read as %q", b.name, b.valueType, valueType)
+	}
+	value, found := b.GetLast(key)
+	return value, found, nil
+}
+
+func (b *baseStore) decodeError(key string, err error) error {
+	return fmt.Errorf("decoding %q value of key %q in store %q: %w", b.valueType, key, b.name, err)
+}
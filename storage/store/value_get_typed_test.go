@@ -0,0 +1,140 @@
+package store
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestValueGetTyped_Int64(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", nil)
+	s.Set(0, "a", "-42")
+	s.Set(1, "bad", "1.5")
+
+	val, found, err := s.GetAsInt64("a")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(-42), val)
+
+	val, found, err = s.GetAsInt64("missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, int64(0), val)
+
+	_, found, err = s.GetAsInt64("bad")
+	assert.True(t, found)
+	assert.EqualError(t, err, `decoding "int64" value of key "bad" in store "test": strconv.ParseInt: parsing "1.5": invalid syntax`)
+
+	_, _, err = s.GetAsBigInt("a")
+	assert.EqualError(t, err, `store "test" holds "int64" values, they cannot be read as "bigint"`)
+}
+
+func TestValueGetTyped_BigInt(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bigint", nil)
+	s.Set(0, "a", "123456789012345678901234567890")
+
+	val, found, err := s.GetAsBigInt("a")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "123456789012345678901234567890", val.String())
+
+	val, found, err = s.GetAsBigInt("missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 0, val.Sign())
+
+	_, _, err = s.GetAsInt64("a")
+	assert.Error(t, err)
+}
+
+func TestValueGetTyped_Float64(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "float64", nil)
+	s.Set(0, "a", "1.5")
+	s.Set(1, "bad", "abc")
+
+	val, found, err := s.GetAsFloat64("a")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1.5, val)
+
+	val, found, err = s.GetAsFloat64("missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, float64(0), val)
+
+	_, found, err = s.GetAsFloat64("bad")
+	assert.True(t, found)
+	assert.Error(t, err)
+
+	_, _, err = s.GetAsBigFloat("a")
+	assert.Error(t, err)
+}
+
+func TestValueGetTyped_BigFloat(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bigfloat", nil)
+	s.Set(0, "a", "10.000000000000000000001")
+
+	val, found, err := s.GetAsBigFloat("a")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint(100), val.Prec())
+	assert.Equal(t, 0, val.Cmp(strToBigFloat("10.000000000000000000001")))
+
+	val, found, err = s.GetAsBigFloat("missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 0, val.Cmp(new(big.Float)))
+
+	_, _, err = s.GetAsFloat64("a")
+	assert.Error(t, err)
+}
+
+func TestValueGetTyped_ReadsLastValue(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", nil)
+	s.Set(0, "a", "1")
+	s.Set(1, "a", "2")
+
+	val, _, err := s.GetAsInt64("a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), val)
+
+	s.DeletePrefix(2, "a")
+	_, found, err := s.GetAsInt64("a")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestValueGetTyped_GetSetBigInt(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bigint", nil)
+
+	val, found := s.GetBigInt("absent")
+	assert.False(t, found)
+	assert.Nil(t, val)
+
+	negative, _ := new(big.Int).SetString("-98765432109876543210", 10)
+	s.SetBigInt(0, "zero", new(big.Int))
+	s.SetBigInt(1, "negative", negative)
+
+	val, found = s.GetBigInt("zero")
+	require.True(t, found)
+	assert.Equal(t, 0, val.Sign())
+
+	val, found = s.GetBigInt("negative")
+	require.True(t, found)
+	assert.Equal(t, 0, negative.Cmp(val))
+	raw, _ := s.GetLast("negative")
+	assert.Equal(t, "-98765432109876543210", string(raw))
+
+	s.Set(2, "bad", "1.5")
+	assert.Panics(t, func() { s.GetBigInt("bad") })
+
+	int64Store := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", nil)
+	assert.PanicsWithValue(t, `store "test" holds "int64" values, "a" cannot be written as "bigint"`, func() {
+		int64Store.SetBigInt(0, "a", big.NewInt(1))
+	})
+	assert.Panics(t, func() { int64Store.GetBigInt("a") })
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/streamingfast/substreams/manifest"
+)
+
+// Increment adds `value` to the integer under `key`, an absent key counting as zero,
+// and records the change as a CREATE or UPDATE delta at `ord`. It panics when the
+// store's value type is not `int64` or `bigint`, or when the current value doesn't
+// parse as one.
+func (b *baseStore) Increment(ord uint64, key string, value int64) {
+	b.addInteger(ord, key, big.NewInt(value))
+}
+
+// Decrement subtracts `value` from the integer under `key`, see Increment.
+func (b *baseStore) Decrement(ord uint64, key string, value int64) {
+	// negated as a big.Int, -value overflows for math.MinInt64
+	b.addInteger(ord, key, new(big.Int).Neg(big.NewInt(value)))
+}
+
+func (b *baseStore) addInteger(ord uint64, key string, value *big.Int) {
+	valueType := strings.ToLower(b.valueType)
+	if valueType != manifest.OutputValueTypeInt64 && valueType != manifest.OutputValueTypeBigInt {
+		panic(fmt.Sprintf("key %q: cannot increment or decrement values of type %q, only %q and %q", key, b.valueType, manifest.OutputValueTypeInt64, manifest.OutputValueTypeBigInt))
+	}
+
+	sum := new(big.Int).Set(value)
+	if prev, found := b.GetAt(ord, key); found {
+		prevInt, err := parseBigInt(prev)
+		if err != nil {
+			panic(fmt.Sprintf("key %q: current value is not a valid %s: %s", key, b.valueType, err))
+		}
+		sum.Add(sum, prevInt)
+	}
+
+	if valueType == manifest.OutputValueTypeInt64 {
+		if !sum.IsInt64() {
+			panic(fmt.Sprintf("key %q: %s overflows int64", key, sum))
+		}
+		b.mustSet(ord, key, []byte(strconv.FormatInt(sum.Int64(), 10)))
+		return
+	}
+	b.mustSet(ord, key, []byte(sum.String()))
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_IncrementDecrement(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeInt64, nil)
+
+	s.Increment(1, "count", 5)
+	s.Increment(2, "count", 3)
+	s.Decrement(3, "count", 10)
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_CREATE, Ordinal: 1, Key: "count", NewValue: []byte("5")},
+		{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 2, Key: "count", OldValue: []byte("5"), NewValue: []byte("8")},
+		{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 3, Key: "count", OldValue: []byte("8"), NewValue: []byte("-2")},
+	}, s.GetDeltas())
+	assert.Equal(t, uint64(3), s.lastOrdinal)
+
+	val, found := s.GetLast("count")
+	require.True(t, found)
+	assert.Equal(t, []byte("-2"), val)
+
+	assert.Panics(t, func() { s.Increment(2, "count", 1) }, "ordinal lower than the previous one")
+}
+
+func TestStore_IncrementDecrementBigInt(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeBigInt, nil)
+	s.SetBytes(0, "count", []byte("9223372036854775807"))
+	s.Reset()
+
+	s.Increment(1, "count", 1)
+	s.Decrement(2, "count", -9223372036854775808)
+
+	deltas := s.GetDeltas()
+	require.Len(t, deltas, 2)
+	assert.Equal(t, []byte("9223372036854775808"), deltas[0].NewValue)
+	assert.Equal(t, []byte("9223372036854775808"), deltas[1].OldValue)
+	assert.Equal(t, []byte("18446744073709551616"), deltas[1].NewValue)
+}
+
+func TestStore_IncrementInvalid(t *testing.T) {
+	float := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeFloat64, nil)
+	assert.Panics(t, func() { float.Increment(0, "count", 1) })
+
+	int64Store := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, manifest.OutputValueTypeInt64, nil)
+	int64Store.SetBytes(0, "count", []byte("9223372036854775807"))
+	assert.Panics(t, func() { int64Store.Increment(1, "count", 1) }, "overflows int64")
+	assert.Panics(t, func() { int64Store.Decrement(1, internalKeyPrefix+"count", 1) }, "reserved prefix")
+}
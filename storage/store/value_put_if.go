@@ -0,0 +1,39 @@
+package store
+
+import "fmt"
+
+// PutIfGreater writes `value`, a number of the store's value type, under `key` if the key
+// is absent or holds a smaller number, emitting a CREATE or UPDATE delta. It returns
+// whether `value` was written, keeping under each key the largest value ever written.
+func (b *baseStore) PutIfGreater(ord uint64, key string, value []byte) (bool, error) {
+	return b.putIfCompared(ord, key, value, func(order int) bool { return order > 0 })
+}
+
+// PutIfLess writes `value`, a number of the store's value type, under `key` if the key
+// is absent or holds a larger number, emitting a CREATE or UPDATE delta. It returns
+// whether `value` was written, keeping under each key the smallest value ever written.
+func (b *baseStore) PutIfLess(ord uint64, key string, value []byte) (bool, error) {
+	return b.putIfCompared(ord, key, value, func(order int) bool { return order < 0 })
+}
+
+// putIfCompared writes `value` under `key` if the key is absent, or if `writes` is true
+// for the order of `value` relative to the current value.
+func (b *baseStore) putIfCompared(ord uint64, key string, value []byte, writes func(order int) bool) (bool, error) {
+	existing, found := b.GetAt(ord, key)
+	if !found {
+		// compared with itself only to validate it
+		existing = value
+	}
+	order, err := compareNumeric(b.valueType, value, existing)
+	if err != nil {
+		return false, fmt.Errorf("store %q key %q: %w", b.name, key, err)
+	}
+	if found && !writes(order) {
+		return false, nil
+	}
+
+	if err := b.set(ord, key, value); err != nil {
+		return false, fmt.Errorf("store %q: %w", b.name, err)
+	}
+	return true, nil
+}
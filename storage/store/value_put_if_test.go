@@ -0,0 +1,107 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_PutIfGreater(t *testing.T) {
+	tests := []struct {
+		name          string
+		valueType     string
+		existing      string
+		value         string
+		expectWritten bool
+		expectValue   string
+	}{
+		{"first write", "int64", "", "5", true, "5"},
+		{"greater int64", "int64", "5", "7", true, "7"},
+		{"smaller int64 no change", "int64", "5", "-2", false, "5"},
+		{"equal int64 no change", "int64", "5", "5", false, "5"},
+		{"greater bigint", "bigint", "99999999999999999999", "100000000000000000000", true, "100000000000000000000"},
+		{"smaller float64 no change", "float64", "1.5", "1.25", false, "1.5"},
+		{"greater bigdecimal", "bigdecimal", "1.10", "1.2", true, "1.2"},
+		{"smaller bigfloat no change", "bigfloat", "10", "9.99", false, "10"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, test.valueType, nil)
+			if test.existing != "" {
+				s.SetBytes(0, "key", []byte(test.existing))
+				s.Reset()
+			}
+
+			written, err := s.PutIfGreater(1, "key", []byte(test.value))
+			require.NoError(t, err)
+			assert.Equal(t, test.expectWritten, written)
+
+			val, found := s.GetLast("key")
+			require.True(t, found)
+			assert.Equal(t, test.expectValue, string(val))
+			if !test.expectWritten {
+				assert.Empty(t, s.GetDeltas())
+			}
+		})
+	}
+}
+
+func TestStore_PutIfLess(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", nil)
+
+	written, err := s.PutIfLess(1, "key", []byte("10"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	written, err = s.PutIfLess(2, "key", []byte("12"))
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	written, err = s.PutIfLess(3, "key", []byte("3"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_CREATE, Ordinal: 1, Key: "key", NewValue: []byte("10")},
+		{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 3, Key: "key", OldValue: []byte("10"), NewValue: []byte("3")},
+	}, s.GetDeltas())
+}
+
+func TestStore_PutIfInvalidValues(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", nil)
+	_, err := s.PutIfGreater(1, "key", []byte("abc"))
+	assert.ErrorContains(t, err, `value "abc" is not a valid int64`)
+	assert.False(t, s.HasLast("key"))
+
+	s = newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	_, err = s.PutIfLess(1, "key", []byte("1"))
+	assert.ErrorContains(t, err, `values of type "string" are not numbers`)
+}
+
+func TestStore_PutIfAnyPolicy(t *testing.T) {
+	for _, policy := range []pbsubstreams.Module_KindStore_UpdatePolicy{
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_SET,
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_SET_IF_NOT_EXISTS,
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD,
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN,
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX,
+	} {
+		s := newTestBaseStore(t, policy, "int64", nil)
+
+		written, err := s.PutIfGreater(1, "key", []byte("5"))
+		require.NoError(t, err, policy)
+		assert.True(t, written, policy)
+		written, err = s.PutIfGreater(2, "key", []byte("9"))
+		require.NoError(t, err, policy)
+		assert.True(t, written, policy)
+
+		val, _ := s.GetLast("key")
+		assert.Equal(t, "9", string(val), policy)
+		assert.Len(t, s.GetDeltas(), 2, policy)
+	}
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Values of `scored` stores are an 8 bytes big-endian int64 score followed by an
+// arbitrary payload. MIN and MAX stores of that type compare on the score only, and
+// keep the payload that goes with the lowest or highest score.
+//
+// `versioned` values share that layout, see `EncodeVersionedValue`.
+const prefixLength = 8
+
+// encodePrefixed encodes `payload` after its 8 bytes big-endian `prefix`.
+func encodePrefixed(prefix uint64, payload []byte) []byte {
+	out := make([]byte, prefixLength+len(payload))
+	binary.BigEndian.PutUint64(out, prefix)
+	copy(out[prefixLength:], payload)
+	return out
+}
+
+// decodePrefixed splits a value encoded with `encodePrefixed` into its prefix and
+// payload, `kind` naming the values in errors.
+func decodePrefixed(kind string, in []byte) (prefix uint64, payload []byte, err error) {
+	if len(in) < prefixLength {
+		return 0, nil, fmt.Errorf("%s value too short: %d bytes, expected at least %d", kind, len(in), prefixLength)
+	}
+	return binary.BigEndian.Uint64(in), in[prefixLength:], nil
+}
+
+// setPrefixed sets `payload` under `key` unless the value already there has a prefix
+// `replaces` keeps over `prefix`.
+func (b *baseStore) setPrefixed(ord uint64, key string, kind string, prefix uint64, payload []byte, replaces func(existing, candidate uint64) bool) {
+	if val, found := b.GetAt(ord, key); found {
+		if prev, _, err := decodePrefixed(kind, val); err == nil && !replaces(prev, prefix) {
+			return
+		}
+	}
+	b.mustSet(ord, key, encodePrefixed(prefix, payload))
+}
+
+// mergePrefixed keeps, for each key of `partialKV`, the value whose prefix is kept
+// by `replaces`, the full store's value being kept on equal prefixes.
+func (b *baseStore) mergePrefixed(partialKV kvEntries, kind string, replaces func(existing, candidate uint64) bool) error {
+	return partialKV(func(k string, v []byte) error {
+		candidate, _, err := decodePrefixed(kind, v)
+		if err != nil {
+			return fmt.Errorf("key %q in partial: %w", k, err)
+		}
+		existingVal, found := b.kv.Get(k)
+		if !found {
+			b.setNewKV(k, v)
+			return nil
+		}
+		existing, _, err := decodePrefixed(kind, existingVal)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", k, err)
+		}
+		if replaces(existing, candidate) {
+			b.setKV(k, v)
+		}
+		return nil
+	})
+}
+
+// EncodeScoredValue encodes `payload` along with its `score`, see `SetMaxScored`.
+func EncodeScoredValue(score int64, payload []byte) []byte {
+	return encodePrefixed(uint64(score), payload)
+}
+
+// DecodeScoredValue splits a value encoded with `EncodeScoredValue` into its score and payload.
+func DecodeScoredValue(in []byte) (score int64, payload []byte, err error) {
+	prefix, payload, err := decodePrefixed("scored", in)
+	return int64(prefix), payload, err
+}
+
+// SetMaxScored keeps, under `key`, the payload with the highest score seen so far.
+// On equal scores, the existing payload is kept.
+func (b *baseStore) SetMaxScored(ord uint64, key string, score int64, payload []byte) {
+	b.setScored(ord, key, score, payload, func(existing, candidate int64) bool { return candidate > existing })
+}
+
+// SetMinScored keeps, under `key`, the payload with the lowest score seen so far.
+// On equal scores, the existing payload is kept.
+func (b *baseStore) SetMinScored(ord uint64, key string, score int64, payload []byte) {
+	b.setScored(ord, key, score, payload, func(existing, candidate int64) bool { return candidate < existing })
+}
+
+func (b *baseStore) setScored(ord uint64, key string, score int64, payload []byte, replaces func(existing, candidate int64) bool) {
+	b.setPrefixed(ord, key, "scored", uint64(score), payload, scoreComparison(replaces))
+}
+
+// mergeScored keeps, for each key of `partialKV`, the value whose score is kept
+// by `replaces`, the full store's value being kept on equal scores.
+func (b *baseStore) mergeScored(partialKV kvEntries, replaces func(existing, candidate int64) bool) error {
+	return b.mergePrefixed(partialKV, "scored", scoreComparison(replaces))
+}
+
+// scoreComparison compares the prefixes of scored values as the int64 scores they are.
+func scoreComparison(replaces func(existing, candidate int64) bool) func(existing, candidate uint64) bool {
+	return func(existing, candidate uint64) bool { return replaces(int64(existing), int64(candidate)) }
+}
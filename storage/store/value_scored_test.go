@@ -0,0 +1,70 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestScoredValue_Encoding(t *testing.T) {
+	score, payload, err := DecodeScoredValue(EncodeScoredValue(-12, []byte("payload")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-12), score)
+	assert.Equal(t, []byte("payload"), payload)
+
+	_, _, err = DecodeScoredValue([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestStore_SetMaxScored(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, manifest.OutputValueTypeScored, nil)
+
+	s.SetMaxScored(0, "owner", 100, []byte("zzz"))
+	// the payload compares lower, but its score is higher
+	s.SetMaxScored(1, "owner", 200, []byte("aaa"))
+	s.SetMaxScored(2, "owner", 150, []byte("zzz"))
+
+	val, found := s.GetLast("owner")
+	require.True(t, found)
+	score, payload, err := DecodeScoredValue(val)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), score)
+	assert.Equal(t, []byte("aaa"), payload)
+
+	s.SetMinScored(3, "first", 200, []byte("aaa"))
+	s.SetMinScored(4, "first", 100, []byte("zzz"))
+	val, _ = s.GetLast("first")
+	assert.Equal(t, EncodeScoredValue(100, []byte("zzz")), val)
+}
+
+func TestStore_MergeScored(t *testing.T) {
+	full := newStore(map[string][]byte{
+		"a": EncodeScoredValue(100, []byte("zzz")),
+		"b": EncodeScoredValue(300, []byte("aaa")),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, manifest.OutputValueTypeScored)
+	partial := newPartialStore(map[string][]byte{
+		"a": EncodeScoredValue(200, []byte("aaa")),
+		"b": EncodeScoredValue(200, []byte("zzz")),
+		"c": EncodeScoredValue(1, []byte("new")),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, manifest.OutputValueTypeScored, nil)
+
+	require.NoError(t, full.Merge(partial))
+	assert.Equal(t, map[string][]byte{
+		"a": EncodeScoredValue(200, []byte("aaa")),
+		"b": EncodeScoredValue(300, []byte("aaa")),
+		"c": EncodeScoredValue(1, []byte("new")),
+	}, full.kv.Map())
+
+	minFull := newStore(map[string][]byte{
+		"a": EncodeScoredValue(100, []byte("aaa")),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, manifest.OutputValueTypeScored)
+	minPartial := newPartialStore(map[string][]byte{
+		"a": EncodeScoredValue(50, []byte("zzz")),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, manifest.OutputValueTypeScored, nil)
+	require.NoError(t, minFull.Merge(minPartial))
+	assert.Equal(t, EncodeScoredValue(50, []byte("zzz")), minFull.kv.Map()["a"])
+}
@@ -2,40 +2,99 @@ package store
 
 import (
 	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/shopspring/decimal"
+
+	"github.com/streamingfast/substreams/manifest"
+	"github.com/streamingfast/substreams/metrics"
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 )
 
+// internalKeyPrefix is reserved for keys written by the system itself, such as merge metadata.
+const internalKeyPrefix = "__!__"
+
+// DefaultReservedKeyPrefix is the key prefix modules cannot write under unless the store's
+// config sets another one, see `Config.SetReservedKeyPrefix`.
+const DefaultReservedKeyPrefix = internalKeyPrefix
+
+// isSystemKey reports whether `key` is one written by the system rather than by the
+// module. When modules are allowed to write under internalKeyPrefix, only the prefixes
+// the system actually uses tell them apart.
+func (c *Config) isSystemKey(key string) bool {
+	if c.reservedKeyPrefix == internalKeyPrefix {
+		return strings.HasPrefix(key, internalKeyPrefix)
+	}
+	return strings.HasPrefix(key, metadataKeyPrefix) || key == deletedKeysKey
+}
+
+// PutBytesIfNotExists writes `value` under `key` unless the key is present, failing
+// when `key` cannot be written by a module.
+func (b *baseStore) PutBytesIfNotExists(ord uint64, key string, value []byte) error {
+	return b.setIfNotExists(ord, key, value)
+}
+
+// Deprecated: use PutBytesIfNotExists, which returns an error instead of panicking on
+// keys that cannot be written.
 func (b *baseStore) SetBytesIfNotExists(ord uint64, key string, value []byte) {
-	b.setIfNotExists(ord, key, value)
+	b.mustSetIfNotExists(ord, key, value)
 }
 
 func (b *baseStore) SetIfNotExists(ord uint64, key string, value string) {
-	b.setIfNotExists(ord, key, []byte(value))
+	b.mustSetIfNotExists(ord, key, []byte(value))
+}
+
+// PutBytes writes `value` under `key`, failing when `key` cannot be written by a module.
+func (b *baseStore) PutBytes(ord uint64, key string, value []byte) error {
+	return b.set(ord, key, value)
 }
 
+// Deprecated: use PutBytes, which returns an error instead of panicking on keys that
+// cannot be written.
 func (b *baseStore) SetBytes(ord uint64, key string, value []byte) {
-	b.set(ord, key, value)
+	b.mustSet(ord, key, value)
 }
 
 func (b *baseStore) Set(ord uint64, key string, value string) {
-	b.set(ord, key, []byte(value))
+	b.mustSet(ord, key, []byte(value))
 }
 
-func (b *baseStore) set(ord uint64, key string, value []byte) {
-	// FIXME(abourget): these should return an error up the stack instead, would bubble up
-	// in the wasm/module.go and fail the query, with proper error propagation.
-	if strings.HasPrefix(key, "__!__") {
-		panic("key prefix __!__ is reserved for internal system use.")
+// SetBigInt writes `value` under `key`, in the decimal notation of `bigint` values. It
+// panics when the store does not hold `bigint` values.
+func (b *baseStore) SetBigInt(ord uint64, key string, value *big.Int) {
+	if strings.ToLower(b.valueType) != manifest.OutputValueTypeBigInt {
+		panic(fmt.Sprintf("store %q holds %q values, %q cannot be written as %q", b.name, b.valueType, key, manifest.OutputValueTypeBigInt))
 	}
-	if uint64(len(value)) > b.itemSizeLimit {
-		panic(fmt.Sprintf("key %q attempted to write %d bytes (capped at %d)", key, len(value), b.itemSizeLimit))
+	b.mustSet(ord, key, []byte(value.String()))
+}
+
+// mustSet is `set` for the writes that cannot fail, panicking on invalid keys.
+func (b *baseStore) mustSet(ord uint64, key string, value []byte) {
+	if err := b.set(ord, key, value); err != nil {
+		panic(err.Error())
 	}
+}
 
-	if len(key) == 0 {
-		panic(fmt.Sprintf("invalid key"))
+func (b *baseStore) mustSetIfNotExists(ord uint64, key string, value []byte) {
+	if err := b.setIfNotExists(ord, key, value); err != nil {
+		panic(err.Error())
+	}
+}
+
+// set writes `value` under `key`, returning an error when `key` cannot be written by a
+// module. Values over the size limit, or of the wrong type when validated, still panic.
+func (b *baseStore) set(ord uint64, key string, value []byte) error {
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+	b.flushAppend(key)
+	if uint64(len(value)) > b.itemSizeLimit {
+		panic(fmt.Sprintf("key %q attempted to write %d bytes (capped at %d)", key, len(value), b.itemSizeLimit))
 	}
+	b.checkValueType(key, value)
 
 	b.bumpOrdinal(ord)
 
@@ -64,13 +123,21 @@ func (b *baseStore) set(ord uint64, key string, value []byte) {
 
 	b.ApplyDelta(delta)
 	b.deltas = append(b.deltas, delta)
+	metrics.CountStoreDelta(b.name, delta.Operation)
+	return nil
 }
 
-func (b *baseStore) setIfNotExists(ord uint64, key string, value []byte) {
+func (b *baseStore) setIfNotExists(ord uint64, key string, value []byte) error {
+	b.checkNotFrozen()
+	if err := b.checkKey(key); err != nil {
+		return err
+	}
+	b.flushAppend(key)
 	_, found := b.GetLast(key)
 	if found {
-		return
+		return nil
 	}
+	b.checkValueType(key, value)
 
 	b.bumpOrdinal(ord)
 
@@ -87,4 +154,77 @@ func (b *baseStore) setIfNotExists(ord uint64, key string, value []byte) {
 
 	b.ApplyDelta(delta)
 	b.deltas = append(b.deltas, delta)
+	metrics.CountStoreDelta(b.name, delta.Operation)
+	return nil
+}
+
+// maxKeyLengthInErrors caps how much of an offending key is quoted in error messages.
+const maxKeyLengthInErrors = 64
+
+// checkKey fails when `key` cannot be written by a module: empty keys, keys using the
+// reserved prefix, if any, and system keys are always refused, invalid UTF-8 and
+// over-long keys only when key validation is enabled.
+func (b *baseStore) checkKey(key string) error {
+	if len(key) == 0 {
+		return fmt.Errorf("invalid key, must be at least 1 character")
+	}
+	if b.reservedKeyPrefix != "" && strings.HasPrefix(key, b.reservedKeyPrefix) {
+		return fmt.Errorf("key %s uses the prefix %s reserved for internal system use", truncatedKey(key), b.reservedKeyPrefix)
+	}
+	if b.isSystemKey(key) {
+		return fmt.Errorf("key %s collides with the keys written by the system", truncatedKey(key))
+	}
+	if !b.validateKeys {
+		return nil
+	}
+	if b.maxKeyLength != 0 && uint64(len(key)) > b.maxKeyLength {
+		return fmt.Errorf("key %s is %d bytes long (capped at %d)", truncatedKey(key), len(key), b.maxKeyLength)
+	}
+	if !utf8.ValidString(key) {
+		return fmt.Errorf("key %s is not valid UTF-8", truncatedKey(key))
+	}
+	return nil
+}
+
+// truncatedKey quotes `key` for error messages, only keeping its beginning when it is long.
+func truncatedKey(key string) string {
+	if len(key) <= maxKeyLengthInErrors {
+		return strconv.Quote(key)
+	}
+	return strconv.Quote(key[:maxKeyLengthInErrors]) + "..."
+}
+
+// checkValueType panics when value validation is enabled and `value` does not parse
+// as the store's value type, so the module fails at the offending write.
+func (b *baseStore) checkValueType(key string, value []byte) {
+	if !b.validateValueType {
+		return
+	}
+	if err := validateValueType(b.valueType, value); err != nil {
+		panic(fmt.Sprintf("key %q: invalid value %q for value type %q: %s", key, value, b.valueType, err))
+	}
+}
+
+func validateValueType(valueType string, value []byte) error {
+	switch strings.ToLower(valueType) {
+	case manifest.OutputValueTypeInt64:
+		_, err := strconv.ParseInt(string(value), 10, 64)
+		return err
+	case manifest.OutputValueTypeFloat64:
+		_, err := strconv.ParseFloat(string(value), 64)
+		return err
+	case manifest.OutputValueTypeBigInt:
+		if _, ok := new(big.Int).SetString(string(value), 10); !ok {
+			return fmt.Errorf("not a base 10 integer")
+		}
+	case manifest.OutputValueTypeBigFloat:
+		if _, _, err := big.ParseFloat(string(value), 10, 100, big.ToNearestEven); err != nil {
+			return err
+		}
+	case manifest.OutputValueTypeBigDecimal:
+		if _, err := decimal.NewFromString(string(value)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestValueSet_ValueTypeValidation(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64", nil)
+
+	// validation is disabled by default, any value is accepted
+	s.Set(0, "unchecked", "abc")
+
+	s.SetValueTypeValidation(true)
+	s.Set(1, "good", "-42")
+	assert.PanicsWithValue(t, `key "bad": invalid value "abc" for value type "int64": strconv.ParseInt: parsing "abc": invalid syntax`, func() {
+		s.Set(2, "bad", "abc")
+	})
+	assert.Panics(t, func() {
+		s.SetBytesIfNotExists(3, "bad", []byte("1.5"))
+	})
+
+	_, found := s.GetLast("bad")
+	assert.False(t, found)
+	val, found := s.GetLast("good")
+	assert.True(t, found)
+	assert.Equal(t, []byte("-42"), val)
+}
+
+func TestValueSet_KeyValidation(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+
+	longKey := strings.Repeat("k", 100)
+	invalidKey := "bad\xffkey"
+
+	// reserved keys are always refused, other checks are disabled by default
+	assert.Panics(t, func() { s.SetIfNotExists(0, "__!__reserved", "value") })
+	s.Set(0, longKey, "value")
+	s.Set(1, invalidKey, "value")
+
+	s.SetKeyValidation(true, 80)
+	assert.PanicsWithValue(t, `key "`+strings.Repeat("k", 64)+`"... is 101 bytes long (capped at 80)`, func() {
+		s.Set(2, longKey+"2", "value")
+	})
+	assert.PanicsWithValue(t, `key "bad\xffkey2" is not valid UTF-8`, func() {
+		s.SetIfNotExists(3, invalidKey+"2", "value")
+	})
+	s.Set(4, strings.Repeat("é", 40), "value")
+
+	_, found := s.GetLast(longKey + "2")
+	assert.False(t, found)
+	_, found = s.GetLast(invalidKey + "2")
+	assert.False(t, found)
+}
+
+func TestValueSet_ReservedKeyPrefix(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	s.SetReservedKeyPrefix("sys/")
+
+	assert.PanicsWithValue(t, `key "sys/key" uses the prefix sys/ reserved for internal system use`, func() { s.Set(0, "sys/key", "value") })
+	assert.Panics(t, func() { s.SetIfNotExists(0, "sys/key", "value") })
+	// the system's own keys stay out of reach
+	assert.Panics(t, func() { s.Set(0, metadataKey, "value") })
+
+	s.Set(1, "__!__business", "value")
+	val, found := s.GetLast("__!__business")
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+
+	var keys []string
+	require.NoError(t, s.Iterate(func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"__!__business"}, keys)
+
+	s.SetReservedKeyPrefix("")
+	s.Set(2, "sys/key", "value")
+	assert.Panics(t, func() { s.Set(3, deletedKeysKey, "value") })
+
+	appendStore := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "string", nil)
+	appendStore.SetReservedKeyPrefix("sys/")
+	assert.ErrorContains(t, appendStore.Append(0, "sys/key", []byte("a")), "reserved for internal system use")
+	require.NoError(t, appendStore.Append(1, "__!__key", []byte("a")))
+}
+
+func TestValueSet_PutBytesInvalidKey(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+
+	assert.NotPanics(t, func() {
+		assert.EqualError(t, s.PutBytes(0, "__!__reserved", []byte("value")), `key "__!__reserved" uses the prefix __!__ reserved for internal system use`)
+		assert.EqualError(t, s.PutBytesIfNotExists(0, "", []byte("value")), "invalid key, must be at least 1 character")
+		assert.ErrorContains(t, s.Append(0, "__!__reserved", []byte("value")), "reserved for internal system use")
+	})
+	assert.Empty(t, s.GetDeltas())
+
+	require.NoError(t, s.PutBytes(1, "key", []byte("value")))
+	require.NoError(t, s.PutBytesIfNotExists(2, "key", []byte("other")))
+	val, found := s.GetLast("key")
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func Test_validateValueType(t *testing.T) {
+	assert.NoError(t, validateValueType("bigint", []byte("123456789012345678901234567890")))
+	assert.Error(t, validateValueType("bigint", []byte("1.5")))
+	assert.NoError(t, validateValueType("float64", []byte("1.5")))
+	assert.NoError(t, validateValueType("bigdecimal", []byte("1.5")))
+	assert.Error(t, validateValueType("bigfloat", []byte("one")))
+	assert.NoError(t, validateValueType("string", []byte("anything")))
+}
+
+func TestStore_SetBytes(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bytes", nil)
+
+	value := []byte{0x01, 0x02}
+	s.SetBytes(1, "key", value)
+	value[0] = 0xff // the store keeps its own copy
+
+	val, found := s.GetLast("key")
+	assert.True(t, found)
+	assert.Equal(t, []byte{0x01, 0x02}, val)
+
+	s.SetBytes(2, "key", []byte{0x03})
+	val, found = s.GetLast("key")
+	assert.True(t, found)
+	assert.Equal(t, []byte{0x03}, val)
+
+	assert.Equal(t, []*pbssinternal.StoreDelta{
+		{Operation: pbssinternal.StoreDelta_CREATE, Ordinal: 1, Key: "key", NewValue: []byte{0x01, 0x02}},
+		{Operation: pbssinternal.StoreDelta_UPDATE, Ordinal: 2, Key: "key", OldValue: []byte{0x01, 0x02}, NewValue: []byte{0x03}},
+	}, s.GetDeltas())
+}
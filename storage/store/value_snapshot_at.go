@@ -0,0 +1,51 @@
+package store
+
+import (
+	"maps"
+
+	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
+)
+
+// SnapshotAtOrdinal returns a copy of the whole state as it was once the writes at `ord`
+// of the current block were processed, undoing the deltas of later ordinals. It is meant
+// for debugging, the reserved keys are left out. The appends still buffered are not
+// flushed, which would change the deltas of the block.
+func (b *baseStore) SnapshotAtOrdinal(ord uint64) map[string][]byte {
+	deltas := b.deltas
+
+	out := maps.Clone(b.kv.Map())
+	if out == nil {
+		out = make(map[string][]byte)
+	}
+	for i := len(deltas) - 1; i >= 0; i-- {
+		delta := deltas[i]
+		if delta.Ordinal <= ord {
+			break
+		}
+		switch delta.Operation {
+		case pbssinternal.StoreDelta_UPDATE, pbssinternal.StoreDelta_DELETE:
+			out[delta.Key] = delta.OldValue
+		case pbssinternal.StoreDelta_CREATE:
+			delete(out, delta.Key)
+		}
+	}
+
+	// the deltas of the keys with buffered appends come before their first append
+	for key, buf := range b.appendBuffers {
+		if ord >= buf.ordinal {
+			continue
+		}
+		if val, found := b.GetAt(ord, key); found {
+			out[key] = val
+		} else {
+			delete(out, key)
+		}
+	}
+
+	for key := range out {
+		if b.isSystemKey(key) {
+			delete(out, key)
+		}
+	}
+	return out
+}
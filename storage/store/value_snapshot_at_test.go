@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_SnapshotAtOrdinal(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", nil)
+	s.Set(0, "kept", "previous block")
+	s.Set(0, "updated", "previous block")
+	s.Set(0, "deleted", "previous block")
+	s.Reset()
+
+	s.Set(10, "created", "1")
+	s.Set(20, "updated", "2")
+	s.Set(20, "created", "2")
+	s.Delete(30, "deleted")
+	s.Set(40, "late", "4")
+
+	assert.Equal(t, map[string][]byte{
+		"kept":    []byte("previous block"),
+		"updated": []byte("previous block"),
+		"deleted": []byte("previous block"),
+	}, s.SnapshotAtOrdinal(5))
+
+	assert.Equal(t, map[string][]byte{
+		"kept":    []byte("previous block"),
+		"updated": []byte("previous block"),
+		"deleted": []byte("previous block"),
+		"created": []byte("1"),
+	}, s.SnapshotAtOrdinal(15))
+
+	assert.Equal(t, map[string][]byte{
+		"kept":    []byte("previous block"),
+		"updated": []byte("2"),
+		"deleted": []byte("previous block"),
+		"created": []byte("2"),
+	}, s.SnapshotAtOrdinal(25))
+
+	assert.Equal(t, map[string][]byte{
+		"kept":    []byte("previous block"),
+		"updated": []byte("2"),
+		"created": []byte("2"),
+		"late":    []byte("4"),
+	}, s.SnapshotAtOrdinal(40))
+
+	// the store itself is left untouched
+	val, found := s.GetLast("late")
+	assert.True(t, found)
+	assert.Equal(t, []byte("4"), val)
+	assert.Len(t, s.GetDeltas(), 5)
+}
+
+func TestStore_SnapshotAtOrdinalCoalescedAppends(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, "string", nil)
+	s.SetAppendCoalescing(true)
+
+	s.Set(10, "key", "a")
+	require.NoError(t, s.Append(20, "key", []byte("b")))
+	require.NoError(t, s.Append(30, "key", []byte("c")))
+
+	assert.Equal(t, map[string][]byte{}, s.SnapshotAtOrdinal(5))
+	assert.Equal(t, map[string][]byte{"key": []byte("a")}, s.SnapshotAtOrdinal(25))
+	assert.Equal(t, map[string][]byte{"key": []byte("abc")}, s.SnapshotAtOrdinal(30))
+
+	// the appends are still buffered, the block's deltas are unchanged
+	assert.Len(t, s.deltas, 1)
+	assert.Len(t, s.appendBuffers, 1)
+}
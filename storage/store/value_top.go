@@ -0,0 +1,79 @@
+package store
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// TopN returns the `n` keys holding the largest values, in descending order of value,
+// keys holding equal values being sorted lexicographically. Values are compared as
+// numbers of the store's value type, keeping only `n` candidates at a time rather than
+// sorting the whole store, and never more than the store's scan limit. It panics when
+// the store does not hold numbers or when one of its values does not decode.
+func (b *baseStore) TopN(n int) []*KV {
+	if n <= 0 {
+		return nil
+	}
+	if b.scanLimit > 0 && uint64(n) > b.scanLimit {
+		n = int(b.scanLimit)
+	}
+
+	top := &topKVs{valueType: b.valueType}
+	_ = b.kv.Iterate(func(key string, value []byte) error {
+		if b.isSystemKey(key) {
+			return nil
+		}
+
+		candidate := &KV{Key: key, Value: value}
+		if top.Len() < n {
+			// compared with itself only to validate it
+			if _, err := compareNumeric(b.valueType, value, value); err != nil {
+				panic(fmt.Sprintf("store %q key %q: %s", b.name, key, err))
+			}
+			heap.Push(top, candidate)
+			return nil
+		}
+
+		order, err := compareNumeric(b.valueType, value, top.entries[0].Value)
+		if err != nil {
+			panic(fmt.Sprintf("store %q key %q: %s", b.name, key, err))
+		}
+		if order > 0 || (order == 0 && key < top.entries[0].Key) {
+			top.entries[0] = candidate
+			heap.Fix(top, 0)
+		}
+		return nil
+	})
+
+	out := top.entries
+	sort.Slice(out, func(i, j int) bool { return top.Less(j, i) })
+	return out
+}
+
+// topKVs is a min-heap of entries whose values were validated, the smallest value, and
+// on equal values the largest key, at its root.
+type topKVs struct {
+	valueType string
+	entries   []*KV
+}
+
+func (h *topKVs) Len() int { return len(h.entries) }
+
+func (h *topKVs) Less(i, j int) bool {
+	order, _ := compareNumeric(h.valueType, h.entries[i].Value, h.entries[j].Value)
+	if order == 0 {
+		return h.entries[i].Key > h.entries[j].Key
+	}
+	return order < 0
+}
+
+func (h *topKVs) Swap(i, j int) { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *topKVs) Push(x any) { h.entries = append(h.entries, x.(*KV)) }
+
+func (h *topKVs) Pop() any {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestStore_TopN(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeInt64, nil)
+	for key, value := range map[string]string{"alice": "12", "bob": "-3", "carol": "40", "dave": "7", "erin": "12", "frank": "2"} {
+		s.Set(0, key, value)
+	}
+	s.kv.Set(internalKeyPrefix+"meta", []byte("999"))
+
+	assert.Equal(t, []*KV{
+		{Key: "carol", Value: []byte("40")},
+		{Key: "alice", Value: []byte("12")},
+		{Key: "erin", Value: []byte("12")},
+	}, s.TopN(3))
+
+	assert.Len(t, s.TopN(10), 6)
+	assert.Nil(t, s.TopN(0))
+}
+
+func TestStore_TopNNotNumeric(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeString, nil)
+	s.Set(0, "key", "value")
+
+	assert.Panics(t, func() { s.TopN(3) })
+}
+
+func TestStore_TopNScanLimit(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeInt64, nil)
+	s.SetScanLimit(2)
+	for key, value := range map[string]string{"alice": "12", "bob": "-3", "carol": "40"} {
+		s.Set(0, key, value)
+	}
+
+	assert.Equal(t, []*KV{
+		{Key: "carol", Value: []byte("40")},
+		{Key: "alice", Value: []byte("12")},
+	}, s.TopN(10))
+}
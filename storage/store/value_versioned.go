@@ -0,0 +1,33 @@
+package store
+
+// Values of `versioned` stores are the 8 bytes big-endian number of the block the value
+// was written for followed by an arbitrary payload, the layout of `scored` values. Those
+// stores keep, for each key, the value of the highest block, whatever the order writes
+// come in.
+
+// EncodeVersionedValue encodes `payload` along with the `blockNum` it was written for,
+// see `SetIfNewerBlock`.
+func EncodeVersionedValue(blockNum uint64, payload []byte) []byte {
+	return encodePrefixed(blockNum, payload)
+}
+
+// DecodeVersionedValue splits a value encoded with `EncodeVersionedValue` into its block
+// number and payload.
+func DecodeVersionedValue(in []byte) (blockNum uint64, payload []byte, err error) {
+	return decodePrefixed("versioned", in)
+}
+
+// SetIfNewerBlock sets `payload` under `key` only if `blockNum` is greater than the
+// block of the value already there, stale writes coming out of order are discarded.
+// This compares the blocks the values were written for, not the values themselves.
+func (b *baseStore) SetIfNewerBlock(ord uint64, key string, blockNum uint64, payload []byte) {
+	b.setPrefixed(ord, key, "versioned", blockNum, payload, newerBlock)
+}
+
+// mergeVersioned keeps, for each key of `partialKV`, the value of the highest block,
+// the full store's value being kept on equal blocks.
+func (b *baseStore) mergeVersioned(partialKV kvEntries) error {
+	return b.mergePrefixed(partialKV, "versioned", newerBlock)
+}
+
+func newerBlock(existing, candidate uint64) bool { return candidate > existing }
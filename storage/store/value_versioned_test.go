@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestVersionedValue_Encoding(t *testing.T) {
+	blockNum, payload, err := DecodeVersionedValue(EncodeVersionedValue(12, []byte("payload")))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), blockNum)
+	assert.Equal(t, []byte("payload"), payload)
+
+	_, _, err = DecodeVersionedValue([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestStore_SetIfNewerBlock(t *testing.T) {
+	s := newTestBaseStore(t, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeVersioned, nil)
+
+	s.SetIfNewerBlock(0, "price", 20, []byte("newer"))
+	// written later, but for an older block: discarded
+	s.SetIfNewerBlock(1, "price", 10, []byte("older"))
+	s.SetIfNewerBlock(2, "price", 20, []byte("same block"))
+
+	val, found := s.GetLast("price")
+	require.True(t, found)
+	assert.Equal(t, EncodeVersionedValue(20, []byte("newer")), val)
+
+	// unlike a MAX, the payload is not compared
+	s.SetIfNewerBlock(3, "price", 21, []byte("aaa"))
+	val, _ = s.GetLast("price")
+	assert.Equal(t, EncodeVersionedValue(21, []byte("aaa")), val)
+	assert.Len(t, s.GetDeltas(), 2)
+}
+
+func TestStore_MergeVersioned(t *testing.T) {
+	full := newStore(map[string][]byte{
+		"a": EncodeVersionedValue(100, []byte("full")),
+		"b": EncodeVersionedValue(100, []byte("full")),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeVersioned)
+	partial := newPartialStore(map[string][]byte{
+		"a": EncodeVersionedValue(50, []byte("partial")),
+		"b": EncodeVersionedValue(150, []byte("partial")),
+		"c": EncodeVersionedValue(1, []byte("new")),
+	}, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, manifest.OutputValueTypeVersioned, nil)
+
+	require.NoError(t, full.Merge(partial))
+	assert.Equal(t, map[string][]byte{
+		"a": EncodeVersionedValue(100, []byte("full")),
+		"b": EncodeVersionedValue(150, []byte("partial")),
+		"c": EncodeVersionedValue(1, []byte("new")),
+	}, full.kv.Map())
+}
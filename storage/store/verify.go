@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// VerifySnapshotReplay rebuilds the full snapshot `file` by merging, in order, the
+// partial snapshots found in storage on top of the latest full snapshot preceding it
+// (or an empty store when there is none), then compares the result with `file` loaded
+// directly. The service deletes the partials once merged, so it only applies to the
+// ones kept around, the service's own audit running before they are deleted, see
+// `Config.SetSnapshotAudit`. It returns the divergent keys, sorted, with the snapshot's value on the left
+// and the replayed one on the right; no diffs means the snapshot can be trusted. An error
+// is returned when the partials needed to replay up to `file` are missing.
+func VerifySnapshotReplay(ctx context.Context, config *Config, file *FileInfo) ([]*SnapshotDiff, error) {
+	if file.Partial {
+		return nil, fmt.Errorf("cannot verify partial snapshot %q, only full snapshots can be replayed", file.Filename)
+	}
+
+	files, err := config.ListSnapshotFiles(ctx, file.Range.ExclusiveEndBlock)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshot files: %w", err)
+	}
+
+	base, partials, err := replayFiles(config, files, file.Range.ExclusiveEndBlock)
+	if err != nil {
+		return nil, err
+	}
+	return NewSnapshotAudit(config, file, base, partials).Run(ctx)
+}
+
+// replayFiles picks, among `files`, the latest full snapshot ending before `endBlock`,
+// nil when there is none, and the partials that follow it up to `endBlock`.
+func replayFiles(config *Config, files []*FileInfo, endBlock uint64) (base *FileInfo, partials []*FileInfo, err error) {
+	cursor := config.moduleInitialBlock
+	for _, f := range files {
+		if f.Partial || f.Range.StartBlock != config.moduleInitialBlock || f.Range.ExclusiveEndBlock >= endBlock {
+			continue
+		}
+		if base == nil || f.Range.ExclusiveEndBlock > base.Range.ExclusiveEndBlock {
+			base = f
+		}
+	}
+	if base != nil {
+		cursor = base.Range.ExclusiveEndBlock
+	}
+
+	for cursor < endBlock {
+		partial := findPartialStartingAt(files, cursor)
+		if partial == nil {
+			return nil, nil, fmt.Errorf("cannot replay store %q: no partial snapshot starting at block %d", config.name, cursor)
+		}
+		partials = append(partials, partial)
+		cursor = partial.Range.ExclusiveEndBlock
+	}
+	if cursor != endBlock {
+		return nil, nil, fmt.Errorf("cannot replay store %q: partials end at block %d, past block %d", config.name, cursor, endBlock)
+	}
+	return base, partials, nil
+}
+
+// replay builds the state of the store by merging `partials`, in order, on top of the
+// full snapshot `base`, nil for an empty store.
+func replay(ctx context.Context, config *Config, base *FileInfo, partials []*FileInfo, logger *zap.Logger) (*FullKV, error) {
+	replayed := config.NewFullKV(logger)
+	if base != nil {
+		if err := replayed.Load(ctx, base); err != nil {
+			replayed.kv.Close()
+			return nil, fmt.Errorf("loading base snapshot: %w", err)
+		}
+	}
+	if err := MergePartials(ctx, replayed, partials, config.mergeConcurrency); err != nil {
+		replayed.kv.Close()
+		return nil, err
+	}
+	return replayed, nil
+}
+
+func findPartialStartingAt(files []*FileInfo, startBlock uint64) *FileInfo {
+	for _, f := range files {
+		if f.Partial && f.Range.StartBlock == startBlock {
+			return f
+		}
+	}
+	return nil
+}
+
+// SnapshotAudit verifies a full snapshot produced by merging partials against the
+// replay, from storage, of these partials on top of the full snapshot they were merged
+// on. It has to run before the partials are deleted.
+type SnapshotAudit struct {
+	config   *Config
+	file     *FileInfo
+	base     *FileInfo
+	partials []*FileInfo
+}
+
+// NewSnapshotAudit prepares the audit of `file`, produced by merging `partials`, in
+// order, on top of the full snapshot `base`, nil when they start at the module's
+// initial block.
+func NewSnapshotAudit(config *Config, file, base *FileInfo, partials []*FileInfo) *SnapshotAudit {
+	return &SnapshotAudit{
+		config:   config,
+		file:     file,
+		base:     base,
+		partials: partials,
+	}
+}
+
+// Run replays the partials and returns the keys of the snapshot that differ from the
+// replayed state, sorted, with the snapshot's value on the left and the replayed one on
+// the right.
+func (a *SnapshotAudit) Run(ctx context.Context) ([]*SnapshotDiff, error) {
+	replayed, err := replay(ctx, a.config, a.base, a.partials, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+	defer replayed.kv.Close()
+
+	direct, err := loadSnapshotKV(ctx, a.config, a.file)
+	if err != nil {
+		return nil, fmt.Errorf("loading snapshot: %w", err)
+	}
+	return compareKVs(direct, replayed.kv.Map(), a.config.isSystemKey), nil
+}
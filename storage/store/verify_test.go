@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestVerifySnapshotReplay(t *testing.T) {
+	ctx := context.Background()
+
+	// newStore writes partials [0, 10) and [10, 20), a full snapshot up to 10 and
+	// the full snapshot up to 20 to verify, with content `full`.
+	newStore := func(full map[string]string) (*Config, *FileInfo) {
+		config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+
+		writePartial := func(start, end uint64, kvs map[string]string) {
+			s := config.NewPartialKV(start, zap.NewNop())
+			for k, v := range kvs {
+				s.Set(0, k, v)
+			}
+			_, writer, err := s.Save(end)
+			require.NoError(t, err)
+			require.NoError(t, writer.Write(ctx))
+		}
+		writeFull := func(end uint64, kvs map[string]string) *FileInfo {
+			s := config.NewFullKV(zap.NewNop())
+			for k, v := range kvs {
+				s.kv.Set(k, []byte(v))
+			}
+			file, writer, err := s.Save(end)
+			require.NoError(t, err)
+			require.NoError(t, writer.Write(ctx))
+			return file
+		}
+
+		writePartial(0, 10, map[string]string{"a": "1", "b": "2"})
+		writePartial(10, 20, map[string]string{"b": "3", "c": "4"})
+		writeFull(10, map[string]string{"a": "1", "b": "2"})
+		return config, writeFull(20, full)
+	}
+
+	config, file := newStore(map[string]string{"a": "1", "b": "3", "c": "4"})
+	diffs, err := VerifySnapshotReplay(ctx, config, file)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 0)
+
+	config, corrupted := newStore(map[string]string{"a": "1", "b": "2", "c": "4", "d": "5"})
+	diffs, err = VerifySnapshotReplay(ctx, config, corrupted)
+	require.NoError(t, err)
+	assert.Equal(t, []*SnapshotDiff{
+		{Key: "b", Left: []byte("2"), Right: []byte("3")},
+		{Key: "d", Left: []byte("5"), MissingRight: true},
+	}, diffs)
+
+	s := config.NewFullKV(zap.NewNop())
+	missing, writer, err := s.Save(30)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(ctx))
+	_, err = VerifySnapshotReplay(ctx, config, missing)
+	assert.EqualError(t, err, `cannot replay store "test": no partial snapshot starting at block 20`)
+}
+
+func TestSnapshotAudit(t *testing.T) {
+	ctx := context.Background()
+
+	// newAudit writes the partial [0, 10) and the full snapshot up to 10, supposedly
+	// produced from it, with content `full`.
+	newAudit := func(full map[string]string) *SnapshotAudit {
+		config, err := NewConfig("test", 0, "abc", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string", dstore.NewMockStore(nil), "")
+		require.NoError(t, err)
+
+		partial := config.NewPartialKV(0, zap.NewNop())
+		partial.Set(0, "a", "1")
+		partialFile, writer, err := partial.Save(10)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+
+		s := config.NewFullKV(zap.NewNop())
+		for k, v := range full {
+			s.kv.Set(k, []byte(v))
+		}
+		file, writer, err := s.Save(10)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(ctx))
+		return NewSnapshotAudit(config, file, nil, []*FileInfo{partialFile})
+	}
+
+	diffs, err := newAudit(map[string]string{"a": "1"}).Run(ctx)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 0)
+
+	// the snapshot is checked against the partial, not against the state it was saved from
+	diffs, err = newAudit(map[string]string{"a": "corrupted"}).Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*SnapshotDiff{{Key: "a", Left: []byte("corrupted"), Right: []byte("1")}}, diffs)
+}
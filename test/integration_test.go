@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/dauth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -390,6 +391,39 @@ func TestOneStoreOneMap(t *testing.T) {
 	}
 }
 
+func TestOneStoreOneMapWithoutStoreSnapshots(t *testing.T) {
+	tests := []struct {
+		name        string
+		startBlock  int64
+		linearBlock uint64
+		stopBlock   uint64
+		production  bool
+	}{
+		{"dev_mode", 25, 25, 29, false},
+		{"prod_mode", 25, 27, 38, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reference := newTestRun(t, test.startBlock, test.linearBlock, test.stopBlock, "assert_test_store_add_i64")
+			reference.ProductionMode = test.production
+			require.NoError(t, reference.Run(t, test.name+"_reference"))
+			require.NotEmpty(t, listFiles(t, reference.TempDir))
+
+			run := newTestRun(t, test.startBlock, test.linearBlock, test.stopBlock, "assert_test_store_add_i64")
+			run.ProductionMode = test.production
+			run.ParallelSubrequests = 5
+			run.DisableStoreSnapshots = true
+			require.NoError(t, run.Run(t, test.name))
+
+			mapOutput := run.MapOutput("assert_test_store_add_i64")
+			assert.Contains(t, mapOutput, `assert_test_store_add_i64: 0801`)
+			assert.Equal(t, reference.MapOutput("assert_test_store_add_i64"), mapOutput)
+			assert.Equal(t, int(test.stopBlock)-int(test.startBlock), strings.Count(mapOutput, "\n"))
+			assert.Empty(t, listFiles(t, run.TempDir))
+		})
+	}
+}
+
 func TestStoreDeletePrefix(t *testing.T) {
 	run := newTestRun(t, 30, 41, 41, "assert_test_store_delete_prefix")
 	run.BlockProcessedCallback = func(ctx *execContext) {
@@ -407,6 +441,18 @@ func TestAllAssertions(t *testing.T) {
 	// Relies on `assert_all_test` having modInit == 1, so
 	run := newTestRun(t, 1, 31, 31, "assert_all_test")
 
+	// the assertions read the deltas of the stores in write order, which only the
+	// uncached stores keep for their deltas sharing an ordinal
+	var stores []string
+	for _, mod := range run.Package.Modules.Modules {
+		if mod.GetKindStore() != nil {
+			stores = append(stores, mod.Name)
+		}
+	}
+	run.Context = dauth.WithTrustedHeaders(context.Background(), dauth.TrustedHeaders{
+		"x-sf-substreams-uncached-modules": strings.Join(stores, ","),
+	})
+
 	require.NoError(t, run.Run(t, "assert_all_test"))
 
 	//assert.Len(t, listFiles(t, run.TempDir), 90) // All these .kv files on disk
@@ -47,6 +47,7 @@ type testRun struct {
 	BlockProcessedCallback blockProcessedCallBack
 	LinearHandoffBlockNum  uint64 // defaults to the request's StopBlock, so no linear handoff, only backprocessing
 	ProductionMode         bool
+	DisableStoreSnapshots  bool
 	// PreWork can be done to perform tier2 work in advance, to simulate when
 	// pre-existing data is available in different conditions
 	PreWork testPreWork
@@ -142,7 +143,7 @@ func (f *testRun) Run(t *testing.T, testName string) error {
 		f.PreWork(t, f, workerFactory)
 	}
 
-	if err := processRequest(t, ctx, request, workerFactory, newBlockGenerator, responseCollector, false, f.BlockProcessedCallback, testTempDir, f.ParallelSubrequests, f.LinearHandoffBlockNum); err != nil {
+	if err := processRequest(t, ctx, request, workerFactory, newBlockGenerator, responseCollector, false, f.BlockProcessedCallback, testTempDir, f.ParallelSubrequests, f.LinearHandoffBlockNum, f.DisableStoreSnapshots); err != nil {
 		return fmt.Errorf("running test: %w", err)
 	}
 
@@ -278,6 +279,7 @@ func processRequest(
 	testTempDir string,
 	parallelSubrequests uint64,
 	linearHandoffBlockNum uint64,
+	disableStoreSnapshots bool,
 ) error {
 	t.Helper()
 
@@ -302,6 +304,7 @@ func processRequest(
 		"tag",
 		workerFactory,
 	)
+	runtimeConfig.DisableStoreSnapshots = disableStoreSnapshots
 	svc := service.TestNewService(runtimeConfig, linearHandoffBlockNum, tr.StreamFactory)
 	return svc.TestBlocks(ctx, isSubRequest, request, responseCollector.Collect)
 }
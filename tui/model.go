@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"time"
+
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
 )
 
@@ -8,12 +10,18 @@ func newModel(ui *TUI) model {
 	return model{
 		StagesProgress: updatedRanges{},
 		ui:             ui,
+		blocks:         ui.blocks,
+		flushInterval:  ui.flushInterval,
 	}
 }
 
 type model struct {
 	ui *TUI
 
+	blocks        *blockBuffer
+	flushInterval time.Duration
+	BlockRenders  int
+
 	StagesProgress updatedRanges
 	StagesModules  []string
 	SlowJobs       []string
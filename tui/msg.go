@@ -1,6 +1,9 @@
 package tui
 
 import (
+	"strings"
+	"sync"
+
 	tea "github.com/charmbracelet/bubbletea"
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
 )
@@ -29,8 +32,42 @@ func (ui *TUI) send(msg tea.Msg) {
 	}
 }
 
+// BlockMessage is the rendering of a block to print, see `TUI.SetFlushInterval`.
 type BlockMessage string
 
+// flushBlocks prints the block messages received since the last frame.
+type flushBlocks struct{}
+
+// blockBuffer accumulates the block messages until the next frame. It is shared by the
+// model copies and with the TUI, which prints what is left once the program is gone.
+type blockBuffer struct {
+	lock      sync.Mutex
+	pending   []string
+	scheduled bool
+}
+
+// add buffers `text` and returns true if a flush must be scheduled.
+func (b *blockBuffer) add(text string) (schedule bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.pending = append(b.pending, text)
+	if b.scheduled {
+		return false
+	}
+	b.scheduled = true
+	return true
+}
+
+// take returns the buffered block messages, in order, and empties the buffer.
+func (b *blockBuffer) take() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	out := strings.TrimSuffix(strings.Join(b.pending, ""), "\n")
+	b.pending = nil
+	b.scheduled = false
+	return out
+}
+
 type SessionInitMessage struct {
 	TraceID string
 }
@@ -21,7 +21,7 @@ func (ui *TUI) decoratedBlockScopedData(
 	debugMapOutputs []*pbsubstreamsrpc.MapModuleOutput,
 	debugStoreOutputs []*pbsubstreamsrpc.StoreModuleOutput,
 	clock *pbsubstreams.Clock,
-) error {
+) (out string) {
 	var s []string
 
 	for _, out := range append([]*pbsubstreamsrpc.MapModuleOutput{output}, debugMapOutputs...) {
@@ -63,9 +63,9 @@ func (ui *TUI) decoratedBlockScopedData(
 	}
 
 	if len(s) != 0 {
-		fmt.Println(strings.Join(s, ""))
+		out = strings.Join(s, "") + "\n"
 	}
-	return nil
+	return out
 }
 
 func cachedValues(name string) string {
@@ -165,6 +165,20 @@ func (ui *TUI) jsonBlockScopedData(
 	return nil
 }
 
+// failureSummaryText renders, per module, the number of blocks on which it failed
+// and the sampled reasons.
+func failureSummaryText(summary *pbsubstreamsrpc.FailureSummary) string {
+	var s strings.Builder
+	s.WriteString("Module failures:\n")
+	for _, failures := range summary.Modules {
+		fmt.Fprintf(&s, "  %s: failed on %d blocks, from #%d to #%d\n", failures.Module, failures.Count, failures.FirstFailedBlock, failures.LastFailedBlock)
+		for _, reason := range failures.SampleReasons {
+			fmt.Fprintf(&s, "    - %s\n", reason)
+		}
+	}
+	return s.String()
+}
+
 func (ui *TUI) decoratedSnapshotData(output *pbsubstreamsrpc.InitialSnapshotData) error {
 	var s []string
 	if output != nil && len(output.Deltas) != 0 {
@@ -356,13 +370,16 @@ type ModuleWrap struct {
 func decodeAsString(in []byte) []byte { return []byte(fmt.Sprintf("%q", string(in))) }
 func decodeAsHex(in []byte) string    { return "(hex) " + hex.EncodeToString(in) }
 
-func printClock(block *pbsubstreamsrpc.BlockScopedData) {
-	fmt.Printf("----------- BLOCK #%s (%s) ---------------\n", humanize.Comma(int64(block.Clock.Number)), block.Clock.Id)
+func decoratedClock(block *pbsubstreamsrpc.BlockScopedData) (out string) {
+	if block.LiveTransition {
+		out = fmt.Sprintf("----------- LIVE FROM BLOCK #%s, blocks may now be reorged ---------------\n", humanize.Comma(int64(block.Clock.Number)))
+	}
+	return out + fmt.Sprintf("----------- BLOCK #%s (%s) ---------------\n", humanize.Comma(int64(block.Clock.Number)), block.Clock.Id)
 }
 
-func printUndo(lastGoodClock *pbsubstreams.BlockRef, cursor string) {
-	fmt.Printf("----------- BLOCK UNDO UP TO #%s (0x%s) ---------------\n", humanize.Comma(int64(lastGoodClock.Number)), lastGoodClock.Id)
-	fmt.Printf("\nNext cursor: %s\n", cursor)
+func decoratedUndo(lastGoodClock *pbsubstreams.BlockRef, cursor string) string {
+	return fmt.Sprintf("----------- BLOCK UNDO UP TO #%s (0x%s) ---------------\n", humanize.Comma(int64(lastGoodClock.Number)), lastGoodClock.Id) +
+		fmt.Sprintf("\nNext cursor: %s\n", cursor)
 }
 func printUndoJSON(lastGoodClock *pbsubstreams.BlockRef, cursor string) {
 	fmt.Printf("{\"undo_until\":{\"num\":%d,\"id\":%s\",\"next_cursor\":\"%s\"}\n", lastGoodClock.Number, lastGoodClock.Id, cursor)
@@ -37,6 +37,9 @@ type TUI struct {
 	prog          *tea.Program
 	seenFirstData bool
 
+	flushInterval time.Duration // see `SetFlushInterval`
+	blocks        *blockBuffer
+
 	msgDescs       map[string]*desc.MessageDescriptor
 	decodeMsgTypes map[string]func(in []byte) string
 	msgTypes       map[string]string // Replace by calls to GetFullyQualifiedName() on the `msgDescs`
@@ -51,11 +54,19 @@ func New(req *pbsubstreamsrpc.Request, pkg *pbsubstreams.Package, outputStreamNa
 		decodeMsgTypes:    map[string]func(in []byte) string{},
 		msgTypes:          map[string]string{},
 		msgDescs:          map[string]*desc.MessageDescriptor{},
+		blocks:            &blockBuffer{},
 	}
 
 	return ui
 }
 
+// SetFlushInterval makes the blocks be printed in batches, at most once per `interval`,
+// above the progress which keeps running. With a zero interval, the terminal is released
+// to print each block as it comes, which flickers on fast streams.
+func (ui *TUI) SetFlushInterval(interval time.Duration) {
+	ui.flushInterval = interval
+}
+
 func (ui *TUI) Init(outputMode string) error {
 	if err := ui.configureOutputMode(outputMode); err != nil {
 		return err
@@ -136,6 +147,7 @@ func (ui *TUI) Cancel() {
 	if err != nil {
 		err = fmt.Errorf("releasing terminal: %w", err)
 	}
+	ui.printPendingBlocks()
 
 	ui.shutter.Shutdown(err)
 }
@@ -144,8 +156,7 @@ func (ui *TUI) IncomingMessage(ctx context.Context, resp *pbsubstreamsrpc.Respon
 	switch m := resp.Message.(type) {
 	case *pbsubstreamsrpc.Response_BlockUndoSignal:
 		if ui.outputMode == OutputModeTUI {
-			printUndo(m.BlockUndoSignal.LastValidBlock, m.BlockUndoSignal.LastValidCursor)
-			ui.ensureTerminalUnlocked()
+			ui.printBlock(decoratedUndo(m.BlockUndoSignal.LastValidBlock, m.BlockUndoSignal.LastValidCursor))
 		} else {
 			printUndoJSON(m.BlockUndoSignal.LastValidBlock, m.BlockUndoSignal.LastValidCursor)
 		}
@@ -157,21 +168,18 @@ func (ui *TUI) IncomingMessage(ctx context.Context, resp *pbsubstreamsrpc.Respon
 			}
 		}
 
-		if ui.outputMode == OutputModeTUI {
-			printClock(m.BlockScopedData)
-		}
 		if m.BlockScopedData == nil {
 			return nil
 		}
 		ui.seenFirstData = true
 		if ui.outputMode == OutputModeTUI {
-			ui.ensureTerminalUnlocked()
-			return ui.decoratedBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock)
+			ui.printBlock(decoratedClock(m.BlockScopedData) + ui.decoratedBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock))
+			return nil
 		} else {
 			return ui.jsonBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock)
 		}
 	case *pbsubstreamsrpc.Response_Progress:
-		if !ui.seenFirstData {
+		if !ui.seenFirstData || ui.flushInterval != 0 {
 			if ui.outputMode == OutputModeTUI {
 				ui.ensureTerminalLocked()
 				ui.prog.Send(m.Progress)
@@ -198,12 +206,31 @@ func (ui *TUI) IncomingMessage(ctx context.Context, resp *pbsubstreamsrpc.Respon
 			fmt.Printf("TraceID: %s\n", m.Session.TraceId)
 		}
 
+	case *pbsubstreamsrpc.Response_FailureSummary:
+		if ui.outputMode == OutputModeTUI {
+			ui.ensureTerminalUnlocked()
+		}
+		fmt.Print(failureSummaryText(m.FailureSummary))
+
 	default:
 		fmt.Println("Unsupported response", m)
 	}
 	return nil
 }
 
+// printBlock prints the rendering of a block. With a flush interval, the program keeps
+// the terminal and prints the blocks in batches, otherwise the terminal is released
+// and the block printed right away.
+func (ui *TUI) printBlock(text string) {
+	if ui.flushInterval == 0 {
+		ui.ensureTerminalUnlocked()
+		fmt.Print(text)
+		return
+	}
+	ui.ensureTerminalLocked()
+	ui.send(BlockMessage(text))
+}
+
 func (ui *TUI) ensureTerminalUnlocked() {
 	if ui.prog == nil {
 		return
@@ -212,6 +239,15 @@ func (ui *TUI) ensureTerminalUnlocked() {
 	ui.prog.Kill()
 	ui.prog = nil
 	time.Sleep(10 * time.Millisecond)
+	ui.printPendingBlocks()
+}
+
+// printPendingBlocks prints the blocks not yet flushed by the program, once it no longer
+// holds the terminal.
+func (ui *TUI) printPendingBlocks() {
+	if pending := ui.blocks.take(); pending != "" {
+		fmt.Println(pending)
+	}
 }
 
 func (ui *TUI) ensureTerminalLocked() {
@@ -237,6 +273,7 @@ func (ui *TUI) CleanUpTerminal() {
 		if err := ui.prog.ReleaseTerminal(); err != nil {
 			fmt.Println("failed releasing terminal:", err)
 		}
+		ui.printPendingBlocks()
 	}
 }
 
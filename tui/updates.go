@@ -53,6 +53,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.BackprocessingCompleteAtBlock = uint64(m.Request.StartBlockNum)
 		}
 		return m, nil
+	case BlockMessage:
+		if m.blocks.add(string(msg)) {
+			return m, tea.Tick(m.flushInterval, func(time.Time) tea.Msg { return flushBlocks{} })
+		}
+		return m, nil
+	case flushBlocks:
+		pending := m.blocks.take()
+		if pending == "" {
+			return m, nil
+		}
+		m.BlockRenders++
+		return m, tea.Println(pending)
 	case *pbsubstreamsrpc.Response_Session:
 		m.TraceID = msg.Session.TraceId
 		m.BackprocessingCompleteAtBlock = msg.Session.ResolvedStartBlock
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_BlockMessagesCoalesced(t *testing.T) {
+	ui := New(nil, nil, nil)
+	ui.SetFlushInterval(100 * time.Millisecond)
+	var m tea.Model = newModel(ui)
+
+	var expected []string
+	var scheduled int
+	for frame := 0; frame < 2; frame++ {
+		for i := 0; i < 50; i++ {
+			text := fmt.Sprintf("block %d\n", frame*50+i)
+			expected = append(expected, text)
+
+			var cmd tea.Cmd
+			m, cmd = m.Update(BlockMessage(text))
+			if cmd != nil {
+				scheduled++
+			}
+		}
+		assert.Equal(t, frame+1, scheduled, "a single flush is scheduled per frame")
+
+		var cmd tea.Cmd
+		m, cmd = m.Update(flushBlocks{})
+		require.NotNil(t, cmd)
+	}
+	assert.Equal(t, 2, m.(model).BlockRenders, "100 blocks are printed in 2 renders")
+
+	// a flush without blocks renders nothing
+	m, cmd := m.Update(flushBlocks{})
+	assert.Nil(t, cmd)
+	assert.Equal(t, 2, m.(model).BlockRenders)
+
+	// blocks are kept in order until flushed
+	for _, text := range expected {
+		m, _ = m.Update(BlockMessage(text))
+	}
+	assert.Equal(t, strings.TrimSuffix(strings.Join(expected, ""), "\n"), ui.blocks.take())
+}
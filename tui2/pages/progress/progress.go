@@ -93,6 +93,9 @@ func (p *Progress) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		p.bars.Init()
 	case *pbsubstreamsrpc.BlockScopedData:
 		p.dataPayloads += 1
+		if msg.(*pbsubstreamsrpc.BlockScopedData).LiveTransition {
+			p.state = "Live"
+		}
 	case *pbsubstreamsrpc.ModulesProgress:
 		msg := msg.(*pbsubstreamsrpc.ModulesProgress)
 		newBars := make([]*ranges.Bar, len(msg.Stages))
@@ -18,6 +18,12 @@ func NewBlockScopedDataResponse(in *pbsubstreamsrpc.BlockScopedData) *pbsubstrea
 	}
 }
 
+func NewStreamStartResponse(in *pbsubstreamsrpc.StreamStart) *pbsubstreamsrpc.Response {
+	return &pbsubstreamsrpc.Response{
+		Message: &pbsubstreamsrpc.Response_StreamStart{StreamStart: in},
+	}
+}
+
 func NewSnapshotData(in *pbsubstreamsrpc.InitialSnapshotData) *pbsubstreamsrpc.Response {
 	return &pbsubstreamsrpc.Response{
 		Message: &pbsubstreamsrpc.Response_DebugSnapshotData{DebugSnapshotData: in},
@@ -1,12 +1,15 @@
 package wasm
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/streamingfast/substreams/metrics"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
@@ -85,6 +88,23 @@ func (c *Call) Err() error {
 func (c *Call) Output() []byte {
 	return c.returnValue
 }
+
+// Seed returns a deterministic seed for the module's pseudo-random choices, exposed
+// to modules as the `env::seed` host function. It is derived from the module name and
+// the block only, so a module re-run on the same block, in any request, draws the same
+// values and its outputs remain cacheable. Modules must not rely on any true source
+// of randomness, which would make their cached outputs diverge from a re-execution.
+func (c *Call) Seed() uint64 {
+	h := sha256.New()
+	h.Write([]byte(c.ModuleName))
+	h.Write([]byte{0})
+	if c.Clock != nil {
+		h.Write([]byte(c.Clock.Id))
+		h.Write(binary.BigEndian.AppendUint64(nil, c.Clock.Number))
+	}
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
 func (c *Call) SetReturnValue(msg []byte) {
 	c.returnValue = make([]byte, len(msg))
 	copy(c.returnValue, msg)
@@ -117,36 +137,77 @@ func (c *Call) ReachedLogsMaxByteCount() bool {
 }
 
 func (c *Call) DoSet(ord uint64, key string, value []byte) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateSimple("set", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, key)
-	c.outputStore.SetBytes(ord, key, value)
+	if err := c.outputStore.PutBytes(ord, key, value); err != nil {
+		c.ReturnError(fmt.Errorf("setting store key: %w", err))
+	}
 }
 func (c *Call) DoSetIfNotExists(ord uint64, key string, value []byte) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateSimple("set_if_not_exists", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET_IF_NOT_EXISTS, key)
-	c.outputStore.SetBytesIfNotExists(ord, key, value)
+	if err := c.outputStore.PutBytesIfNotExists(ord, key, value); err != nil {
+		c.ReturnError(fmt.Errorf("setting store key: %w", err))
+	}
+}
+// DoPutIfGreater and DoPutIfLess are valid whatever the update policy, the store's
+// value type being a number.
+func (c *Call) DoPutIfGreater(ord uint64, key string, value []byte) (written bool) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.traceStateWrites("put_if_greater", key)
+	written, err := c.outputStore.PutIfGreater(ord, key, value)
+	if err != nil {
+		c.ReturnError(fmt.Errorf("put_if_greater: %w", err))
+	}
+	return written
+}
+func (c *Call) DoPutIfLess(ord uint64, key string, value []byte) (written bool) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.traceStateWrites("put_if_less", key)
+	written, err := c.outputStore.PutIfLess(ord, key, value)
+	if err != nil {
+		c.ReturnError(fmt.Errorf("put_if_less: %w", err))
+	}
+	return written
 }
 func (c *Call) DoAppend(ord uint64, key string, value []byte) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateSimple("append", pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, key)
 	if err := c.outputStore.Append(ord, key, value); err != nil {
 		c.ReturnError(fmt.Errorf("appending to store: %w", err))
 	}
 }
 func (c *Call) DoDeletePrefix(ord uint64, prefix string) {
-	defer c.stats.RecordModuleWasmStoreDeletePrefix(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreDeletePrefix(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.traceStateWrites("delete_prefix", prefix)
 	c.outputStore.DeletePrefix(ord, prefix)
 }
+
+// DoDelete deletes a key from the output store, whatever its update policy, like
+// DoDeletePrefix. Only the modules having no output store cannot delete.
+func (c *Call) DoDelete(ord uint64, key string) {
+	c.validateAnyPolicy("delete", key)
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.outputStore.Delete(ord, key)
+}
 func (c *Call) DoAddBigInt(ord uint64, key string, value string) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("add_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigint", key)
 
 	toAdd, _ := new(big.Int).SetString(value, 10)
 	c.outputStore.SumBigInt(ord, key, toAdd)
 }
 func (c *Call) DoAddBigDecimal(ord uint64, key string, value string) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithTwoValueTypes("add_bigdecimal", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigdecimal", "bigfloat", key)
 
 	toAdd, err := decimal.NewFromString(string(value))
@@ -156,33 +217,69 @@ func (c *Call) DoAddBigDecimal(ord uint64, key string, value string) {
 	c.outputStore.SumBigDecimal(ord, key, toAdd.Truncate(34))
 }
 func (c *Call) DoAddInt64(ord uint64, key string, value int64) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("add_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64", key)
 	c.outputStore.SumInt64(ord, key, value)
 }
 func (c *Call) DoAddFloat64(ord uint64, key string, value float64) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("add_float64", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "float64", key)
 	c.outputStore.SumFloat64(ord, key, value)
 }
+func (c *Call) DoIncrement(ord uint64, key string, value int64) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithTwoValueTypes("increment", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64", "bigint", key)
+	c.outputStore.Increment(ord, key, value)
+}
+func (c *Call) DoDecrement(ord uint64, key string, value int64) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithTwoValueTypes("decrement", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64", "bigint", key)
+	c.outputStore.Decrement(ord, key, value)
+}
 func (c *Call) DoSetMinInt64(ord uint64, key string, value int64) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("set_min_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "int64", key)
 	c.outputStore.SetMinInt64(ord, key, value)
 }
+func (c *Call) DoSetMinScored(ord uint64, key string, score int64, payload []byte) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("set_min_scored", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "scored", key)
+	c.outputStore.SetMinScored(ord, key, score, payload)
+}
+func (c *Call) DoSetMaxScored(ord uint64, key string, score int64, payload []byte) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("set_max_scored", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "scored", key)
+	c.outputStore.SetMaxScored(ord, key, score, payload)
+}
+func (c *Call) DoSetIfNewerBlock(ord uint64, key string, blockNum uint64, payload []byte) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("set_if_newer_block", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "versioned", key)
+	c.outputStore.SetIfNewerBlock(ord, key, blockNum, payload)
+}
 func (c *Call) DoSetMinBigInt(ord uint64, key string, value string) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("set_min_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "bigint", key)
 	toSet, _ := new(big.Int).SetString(value, 10)
 	c.outputStore.SetMinBigInt(ord, key, toSet)
 }
 func (c *Call) DoSetMinFloat64(ord uint64, key string, value float64) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("set_min_float64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "float64", key)
 	c.outputStore.SetMinFloat64(ord, key, value)
 }
 func (c *Call) DoSetMinBigDecimal(ord uint64, key string, value string) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithTwoValueTypes("set_min_bigdecimal", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "bigdecimal", "bigfloat", key)
 	toAdd, err := decimal.NewFromString(value)
 	if err != nil {
@@ -191,24 +288,28 @@ func (c *Call) DoSetMinBigDecimal(ord uint64, key string, value string) {
 	c.outputStore.SetMinBigDecimal(ord, key, toAdd.Truncate(34))
 }
 func (c *Call) DoSetMaxInt64(ord uint64, key string, value int64) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("set_max_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "int64", key)
 	c.outputStore.SetMaxInt64(ord, key, value)
 }
 func (c *Call) DoSetMaxBigInt(ord uint64, key string, value string) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("set_max_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "bigint", key)
 	toSet, _ := new(big.Int).SetString(value, 10)
 	c.outputStore.SetMaxBigInt(ord, key, toSet)
 
 }
 func (c *Call) DoSetMaxFloat64(ord uint64, key string, value float64) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithValueType("set_max_float64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "float64", key)
 	c.outputStore.SetMaxFloat64(ord, key, value)
 }
 func (c *Call) DoSetMaxBigDecimal(ord uint64, key string, value string) {
-	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
 	c.validateWithTwoValueTypes("set_max_bigdecimal", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "bigdecimal", "bigfloat", key)
 	toAdd, err := decimal.NewFromString(value)
 	if err != nil {
@@ -217,8 +318,42 @@ func (c *Call) DoSetMaxBigDecimal(ord uint64, key string, value string) {
 	c.outputStore.SetMaxBigDecimal(ord, key, toAdd.Truncate(34))
 }
 
+func (c *Call) DoBitOrInt64(ord uint64, key string, value int64) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("bit_or_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, "int64", key)
+	c.outputStore.BitOrInt64(ord, key, value)
+}
+func (c *Call) DoBitOrBigInt(ord uint64, key string, value string) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("bit_or_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, "bigint", key)
+	c.outputStore.BitOrBigInt(ord, key, c.parseBigInt(value))
+}
+func (c *Call) DoBitAndInt64(ord uint64, key string, value int64) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("bit_and_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, "int64", key)
+	c.outputStore.BitAndInt64(ord, key, value)
+}
+func (c *Call) DoBitAndBigInt(ord uint64, key string, value string) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(now)) }()
+	c.validateWithValueType("bit_and_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, "bigint", key)
+	c.outputStore.BitAndBigInt(ord, key, c.parseBigInt(value))
+}
+
+func (c *Call) parseBigInt(value string) *big.Int {
+	out, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		c.ReturnError(fmt.Errorf("parsing bigint: invalid value %q", value))
+	}
+	return out
+}
+
 func (c *Call) DoGetAt(storeIndex int, ord uint64, key string) (value []byte, found bool) {
-	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
 	c.validateStoreIndex(storeIndex, "get_at")
 	readStore := c.inputStores[storeIndex]
 	c.traceStateReads("get_at", storeIndex, found, key)
@@ -226,7 +361,8 @@ func (c *Call) DoGetAt(storeIndex int, ord uint64, key string) (value []byte, fo
 }
 
 func (c *Call) DoHasAt(storeIndex int, ord uint64, key string) (found bool) {
-	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
 	c.validateStoreIndex(storeIndex, "has_at")
 	readStore := c.inputStores[storeIndex]
 	c.traceStateReads("has_at", storeIndex, found, key)
@@ -234,7 +370,8 @@ func (c *Call) DoHasAt(storeIndex int, ord uint64, key string) (found bool) {
 }
 
 func (c *Call) DoGetFirst(storeIndex int, key string) (value []byte, found bool) {
-	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
 	c.validateStoreIndex(storeIndex, "get_first")
 	readStore := c.inputStores[storeIndex]
 	c.traceStateReads("get_first", storeIndex, found, key)
@@ -242,7 +379,8 @@ func (c *Call) DoGetFirst(storeIndex int, key string) (value []byte, found bool)
 }
 
 func (c *Call) DoHasFirst(storeIndex int, key string) (found bool) {
-	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
 	c.validateStoreIndex(storeIndex, "has_first")
 	readStore := c.inputStores[storeIndex]
 	c.traceStateReads("has_first", storeIndex, found, key)
@@ -250,7 +388,8 @@ func (c *Call) DoHasFirst(storeIndex int, key string) (found bool) {
 }
 
 func (c *Call) DoGetLast(storeIndex int, key string) (value []byte, found bool) {
-	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
 	c.validateStoreIndex(storeIndex, "get_last")
 	readStore := c.inputStores[storeIndex]
 	c.traceStateReads("get_last", storeIndex, found, key)
@@ -258,13 +397,40 @@ func (c *Call) DoGetLast(storeIndex int, key string) (value []byte, found bool)
 }
 
 func (c *Call) DoHasLast(storeIndex int, key string) (found bool) {
-	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
 	c.validateStoreIndex(storeIndex, "has_last")
 	readStore := c.inputStores[storeIndex]
 	c.traceStateReads("has_last", storeIndex, found, key)
 	return readStore.HasLast(key)
 }
 
+// DoGetTopN returns the `n` keys of an input store holding the largest values, see
+// `store.TopReader`, encoded as the protobuf message `repeated Entry entries = 1`, each
+// `Entry` being `string key = 1; bytes value = 2`. `found` is false when the store is
+// empty.
+func (c *Call) DoGetTopN(storeIndex int, n int) (entries []byte, found bool) {
+	now := time.Now()
+	defer func() { c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(now)) }()
+	c.validateStoreIndex(storeIndex, "get_top_n")
+	readStore, ok := c.inputStores[storeIndex].(store.TopReader)
+	if !ok {
+		c.ReturnError(fmt.Errorf("\"get_top_n\" failed: store %d cannot rank its keys", storeIndex))
+	}
+	top := readStore.TopN(n)
+	c.traceStateReads("get_top_n", storeIndex, len(top) != 0, "")
+	for _, kv := range top {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, kv.Key)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, kv.Value)
+		entries = protowire.AppendTag(entries, 1, protowire.BytesType)
+		entries = protowire.AppendBytes(entries, entry)
+	}
+	return entries, len(top) != 0
+}
+
 func (c *Call) validateStoreIndex(storeIndex int, stateFunc string) {
 	if storeIndex+1 > len(c.inputStores) {
 		c.ReturnError(fmt.Errorf("%q failed: invalid store index %d, %d stores declared", stateFunc, storeIndex, len(c.inputStores)))
@@ -278,6 +444,13 @@ func (c *Call) validateSimple(stateFunc string, updatePolicy pbsubstreams.Module
 	c.traceStateWrites(stateFunc, key)
 }
 
+func (c *Call) validateAnyPolicy(stateFunc string, key string) {
+	if c.updatePolicy == pbsubstreams.Module_KindStore_UPDATE_POLICY_UNSET || c.outputStore == nil {
+		c.returnInvalidPolicy(stateFunc, "any updatePolicy")
+	}
+	c.traceStateWrites(stateFunc, key)
+}
+
 func (c *Call) validateWithValueType(stateFunc string, updatePolicy pbsubstreams.Module_KindStore_UpdatePolicy, valueType string, key string) {
 	if c.updatePolicy != updatePolicy || c.valueType != valueType {
 		c.returnInvalidPolicy(stateFunc, fmt.Sprintf(`updatePolicy == %q and valueType == %q`, policyMap[updatePolicy], valueType))
@@ -325,4 +498,6 @@ var policyMap = map[pbsubstreams.Module_KindStore_UpdatePolicy]string{
 	pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN:               "min",
 	pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX:               "max",
 	pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND:            "append",
+	pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR:            "bit_or",
+	pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND:           "bit_and",
 }
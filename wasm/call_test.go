@@ -6,6 +6,7 @@ import (
 	"github.com/streamingfast/dstore"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/streamingfast/substreams/metrics"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
@@ -88,6 +89,46 @@ func Test_CallStoreOps(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"bit_or_int64 golden path",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_OR, "int64"),
+			func(c *Call) {
+				c.DoBitOrInt64(0, "key", 1)
+			},
+			true,
+		},
+		{
+			"bit_or_int64 wrong policy",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, "int64"),
+			func(c *Call) {
+				c.DoBitOrInt64(0, "key", 1)
+			},
+			false,
+		},
+		{
+			"bit_and_bigint golden path",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, "bigint"),
+			func(c *Call) {
+				c.DoBitAndBigInt(0, "key", "1")
+			},
+			true,
+		},
+		{
+			"bit_and_bigint wrong type",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, "int64"),
+			func(c *Call) {
+				c.DoBitAndBigInt(0, "key", "1")
+			},
+			false,
+		},
+		{
+			"bit_and_bigint invalid value",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_BIT_AND, "bigint"),
+			func(c *Call) {
+				c.DoBitAndBigInt(0, "key", "not a number")
+			},
+			false,
+		},
 		{
 			"add_bigint golden path",
 			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigint"),
@@ -184,6 +225,38 @@ func Test_CallStoreOps(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"increment golden path",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64"),
+			func(c *Call) {
+				c.DoIncrement(0, "key", 1)
+			},
+			true,
+		},
+		{
+			"decrement golden path bigint",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigint"),
+			func(c *Call) {
+				c.DoDecrement(0, "key", 1)
+			},
+			true,
+		},
+		{
+			"increment wrong type",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "float64"),
+			func(c *Call) {
+				c.DoIncrement(0, "key", 1)
+			},
+			false,
+		},
+		{
+			"decrement wrong policy",
+			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64"),
+			func(c *Call) {
+				c.DoDecrement(0, "key", 1)
+			},
+			false,
+		},
 		{
 			"add_float64 golden path",
 			newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "float64"),
@@ -497,3 +570,99 @@ func expectPanic(t *testing.T, shouldPanic bool, c *Call, f func(c *Call)) {
 		assert.Panics(t, func() { f(c) })
 	}
 }
+
+func TestCall_Seed(t *testing.T) {
+	clock := &pbsubstreams.Clock{Id: "00000010a", Number: 10}
+
+	seed := NewCall(clock, "map_random", "map_random", nil, nil).Seed()
+	again := NewCall(&pbsubstreams.Clock{Id: "00000010a", Number: 10}, "map_random", "map_random", nil, nil).Seed()
+	assert.Equal(t, seed, again, "same block and module should yield the same seed")
+
+	otherBlock := NewCall(&pbsubstreams.Clock{Id: "00000011a", Number: 11}, "map_random", "map_random", nil, nil).Seed()
+	assert.NotEqual(t, seed, otherBlock)
+
+	otherModule := NewCall(clock, "map_other", "map_other", nil, nil).Seed()
+	assert.NotEqual(t, seed, otherModule)
+}
+
+func TestCall_DoDelete(t *testing.T) {
+	c := newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string")
+	c.DoSet(0, "key", []byte("value"))
+	c.DoSet(0, "key:other", []byte("value"))
+
+	c.DoDelete(1, "key")
+	assert.False(t, c.outputStore.HasLast("key"))
+	assert.True(t, c.outputStore.HasLast("key:other"), "not a prefix deletion")
+}
+
+func TestCall_DoDeletePolicies(t *testing.T) {
+	for policy, valueType := range map[pbsubstreams.Module_KindStore_UpdatePolicy]string{
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_SET_IF_NOT_EXISTS: "string",
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD:               "int64",
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX:               "int64",
+		pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND:            "string",
+	} {
+		c := newTestCall(policy, valueType)
+		assert.NotPanics(t, func() { c.DoDelete(1, "key") }, policyMap[policy])
+	}
+
+	c := &Call{ModuleName: "map_test", stats: metrics.NewReqStats(&metrics.Config{}, zap.NewNop())}
+	assert.PanicsWithError(t, `module "map_test": invalid store operation "delete", only valid for stores with any updatePolicy`, func() {
+		c.DoDelete(1, "key")
+	})
+}
+
+func TestCall_DoSetInvalidKey(t *testing.T) {
+	c := newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string")
+	c.ModuleName = "store_test"
+
+	// the runtimes turn the error raised by the host function into a failure of the call
+	assert.PanicsWithError(t, `module "store_test": setting store key: key "__!__reserved" uses the prefix __!__ reserved for internal system use`, func() {
+		c.DoSet(0, "__!__reserved", []byte("value"))
+	})
+}
+
+func TestCall_DoGetTopN(t *testing.T) {
+	c := newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64")
+	for key, value := range map[string]string{"alice": "12", "bob": "-3", "carol": "40"} {
+		c.outputStore.SetBytes(0, key, []byte(value))
+	}
+	c.inputStores = append(c.inputStores, c.outputStore)
+
+	entries, found := c.DoGetTopN(0, 2)
+	assert.True(t, found)
+
+	var keys, values []string
+	for len(entries) > 0 {
+		_, _, n := protowire.ConsumeTag(entries)
+		entry, m := protowire.ConsumeBytes(entries[n:])
+		entries = entries[n+m:]
+
+		_, _, n = protowire.ConsumeTag(entry)
+		key, m := protowire.ConsumeString(entry[n:])
+		entry = entry[n+m:]
+		_, _, n = protowire.ConsumeTag(entry)
+		value, _ := protowire.ConsumeBytes(entry[n:])
+		keys, values = append(keys, key), append(values, string(value))
+	}
+	assert.Equal(t, []string{"carol", "alice"}, keys)
+	assert.Equal(t, []string{"40", "12"}, values)
+
+	empty := newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "int64")
+	empty.inputStores = append(empty.inputStores, empty.outputStore)
+	_, found = empty.DoGetTopN(0, 2)
+	assert.False(t, found)
+}
+
+func TestCall_DoPutIf(t *testing.T) {
+	c := newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64")
+	assert.True(t, c.DoPutIfGreater(0, "key", []byte("5")))
+	assert.False(t, c.DoPutIfGreater(1, "key", []byte("3")))
+	assert.True(t, c.DoPutIfLess(2, "key", []byte("3")))
+
+	value, _ := c.outputStore.GetLast("key")
+	assert.Equal(t, "3", string(value))
+
+	notNumeric := newTestCall(pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "string")
+	assert.Panics(t, func() { notNumeric.DoPutIfGreater(0, "key", []byte("5")) })
+}
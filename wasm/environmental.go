@@ -0,0 +1,23 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// IsEnvironmentalError tells if `err`, returned by the execution of a module, comes from
+// the environment running it rather than from the module's code and inputs, so running
+// it again may succeed. Only cancellations and I/O failures are: any other error, traps
+// and the errors raised by host functions through `Call.ReturnError` included, happens
+// again on the same block and inputs.
+func IsEnvironmentalError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pathErr *fs.PathError
+	var syscallErr *os.SyscallError
+	return errors.As(err, &pathErr) || errors.As(err, &syscallErr)
+}
@@ -0,0 +1,19 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsEnvironmentalError(t *testing.T) {
+	assert.True(t, IsEnvironmentalError(fmt.Errorf("call: %w", context.Canceled)))
+	assert.True(t, IsEnvironmentalError(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+	assert.True(t, IsEnvironmentalError(fmt.Errorf("module %q: %w", "mod", &fs.PathError{Op: "read", Path: "/spill/mod", Err: fs.ErrClosed})))
+
+	assert.False(t, IsEnvironmentalError(fmt.Errorf("call: integer divide by zero")))
+	assert.False(t, IsEnvironmentalError(fmt.Errorf("module %q: invalid key", "mod")))
+}
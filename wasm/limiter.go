@@ -0,0 +1,38 @@
+package wasm
+
+import (
+	"context"
+)
+
+// InstantiationLimiter bounds the number of wasm modules being instantiated at once by
+// the registries sharing it. A burst of requests then waits for its turn instead of
+// compiling all of its modules together. A nil limiter does not limit anything.
+type InstantiationLimiter struct {
+	slots chan struct{}
+}
+
+// NewInstantiationLimiter returns a limiter letting `limit` instantiations run
+// concurrently, or nil when `limit` is 0.
+func NewInstantiationLimiter(limit uint64) *InstantiationLimiter {
+	if limit == 0 {
+		return nil
+	}
+	return &InstantiationLimiter{
+		slots: make(chan struct{}, limit),
+	}
+}
+
+// acquire waits for a free slot, until `ctx` is done, and returns the function giving
+// it back.
+func (l *InstantiationLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
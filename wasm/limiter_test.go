@@ -0,0 +1,96 @@
+package wasm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockingModuleFactory struct {
+	running    atomic.Int32
+	maxRunning atomic.Int32
+	unblock    chan struct{}
+}
+
+func (f *blockingModuleFactory) NewModule(ctx context.Context, code []byte, registry *Registry) (Module, error) {
+	running := f.running.Add(1)
+	defer f.running.Add(-1)
+	for {
+		max := f.maxRunning.Load()
+		if running <= max || f.maxRunning.CompareAndSwap(max, running) {
+			break
+		}
+	}
+	<-f.unblock
+	return nil, nil
+}
+
+func TestRegistry_InstantiationLimiter(t *testing.T) {
+	factory := &blockingModuleFactory{unblock: make(chan struct{})}
+	RegisterModuleFactory("test-blocking", factory)
+	defer delete(runtimes, "test-blocking")
+
+	limiter := NewInstantiationLimiter(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		registry := NewRegistryWithRuntime("test-blocking", nil, 0)
+		registry.SetInstantiationLimiter(limiter)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := registry.NewModule(context.Background(), nil)
+			assert.NoError(t, err)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return factory.running.Load() == 2 }, time.Second, time.Millisecond)
+	// the others are queued, not running
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(2), factory.running.Load())
+
+	close(factory.unblock)
+	wg.Wait()
+	assert.Equal(t, int32(2), factory.maxRunning.Load())
+}
+
+func TestRegistry_InstantiationLimiterContextDone(t *testing.T) {
+	factory := &blockingModuleFactory{unblock: make(chan struct{})}
+	RegisterModuleFactory("test-blocking", factory)
+	defer delete(runtimes, "test-blocking")
+
+	limiter := NewInstantiationLimiter(1)
+	registry := NewRegistryWithRuntime("test-blocking", nil, 0)
+	registry.SetInstantiationLimiter(limiter)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := registry.NewModule(context.Background(), nil)
+		assert.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return factory.running.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := registry.NewModule(ctx, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(factory.unblock)
+	<-done
+}
+
+func TestNewInstantiationLimiter_noLimit(t *testing.T) {
+	limiter := NewInstantiationLimiter(0)
+	assert.Nil(t, limiter)
+
+	release, err := limiter.acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
@@ -18,6 +18,7 @@ type Registry struct {
 	maxFuel              uint64
 	runtimeStack         ModuleFactory
 	instanceCacheEnabled bool
+	instantiationLimiter *InstantiationLimiter
 }
 
 func (r *Registry) registerWASMExtension(namespace string, importName string, ext WASMExtension) {
@@ -45,7 +46,19 @@ func (r *Registry) registerWASMExtension(namespace string, importName string, ex
 func (r *Registry) MaxFuel() uint64            { return r.maxFuel }
 func (r *Registry) InstanceCacheEnabled() bool { return r.instanceCacheEnabled }
 
+// SetInstantiationLimiter makes NewModule wait for a slot of `limiter`, which is usually
+// shared with the registries of the other requests.
+func (r *Registry) SetInstantiationLimiter(limiter *InstantiationLimiter) {
+	r.instantiationLimiter = limiter
+}
+
 func (r *Registry) NewModule(ctx context.Context, wasmCode []byte) (Module, error) {
+	release, err := r.instantiationLimiter.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("waiting to instantiate wasm module: %w", err)
+	}
+	defer release()
+
 	return r.runtimeStack.NewModule(ctx, wasmCode, r)
 }
 
@@ -96,6 +96,14 @@ func (i *instance) newImports() error {
 		return fmt.Errorf("registering output import: %w", err)
 	}
 
+	if err = linker.FuncWrap("env", "seed",
+		func() int64 {
+			return int64(i.CurrentCall.Seed())
+		},
+	); err != nil {
+		return fmt.Errorf("registering seed import: %w", err)
+	}
+
 	return nil
 }
 
@@ -115,13 +123,22 @@ func (i *instance) registerStateImports(linker *wasmtime.Linker) error {
 	functions := map[string]interface{}{}
 	functions["set"] = i.set
 	functions["set_if_not_exists"] = i.setIfNotExists
+	functions["put_if_greater"] = i.putIfGreater
+	functions["put_if_less"] = i.putIfLess
 	functions["append"] = i.append
 	functions["delete_prefix"] = i.deletePrefix
+	functions["delete"] = i.delete
 	functions["add_bigint"] = i.addBigInt
 	functions["add_bigdecimal"] = i.addBigDecimal
 	functions["add_bigfloat"] = i.addBigDecimal
 	functions["add_int64"] = i.addInt64
+	functions["increment"] = i.increment
+	functions["decrement"] = i.decrement
 	functions["add_float64"] = i.addFloat64
+	functions["bit_or_int64"] = i.bitOrInt64
+	functions["bit_or_bigint"] = i.bitOrBigInt
+	functions["bit_and_int64"] = i.bitAndInt64
+	functions["bit_and_bigint"] = i.bitAndBigInt
 	functions["set_min_int64"] = i.setMinInt64
 	functions["set_min_bigint"] = i.setMinBigint
 	functions["set_min_float64"] = i.setMinFloat64
@@ -132,12 +149,16 @@ func (i *instance) registerStateImports(linker *wasmtime.Linker) error {
 	functions["set_max_float64"] = i.setMaxFloat64
 	functions["set_max_bigdecimal"] = i.setMaxBigDecimal
 	functions["set_max_bigfloat"] = i.setMaxBigDecimal
+	functions["set_max_scored"] = i.setMaxScored
+	functions["set_min_scored"] = i.setMinScored
+	functions["set_if_newer_block"] = i.setIfNewerBlock
 	functions["get_at"] = i.getAt
 	functions["get_first"] = i.getFirst
 	functions["get_last"] = i.getLast
 	functions["has_at"] = i.hasAt
 	functions["has_first"] = i.hasFirst
 	functions["has_last"] = i.hasLast
+	functions["get_top_n"] = i.getTopN
 
 	for n, f := range functions {
 		if err := linker.FuncWrap("state", n, f); err != nil {
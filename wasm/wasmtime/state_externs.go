@@ -10,6 +10,18 @@ func (i *instance) set(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
 	i.CurrentCall.DoSet(uint64(ord), key, value)
 }
 
+func (i *instance) putIfGreater(ord int64, keyPtr, keyLength, valPtr, valLength int32) int32 {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	value := i.Heap.ReadBytes(valPtr, valLength)
+	return returnIfFound(i.CurrentCall.DoPutIfGreater(uint64(ord), key, value))
+}
+
+func (i *instance) putIfLess(ord int64, keyPtr, keyLength, valPtr, valLength int32) int32 {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	value := i.Heap.ReadBytes(valPtr, valLength)
+	return returnIfFound(i.CurrentCall.DoPutIfLess(uint64(ord), key, value))
+}
+
 func (i *instance) setIfNotExists(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
 	key := i.Heap.ReadString(keyPtr, keyLength)
 	value := i.Heap.ReadBytes(valPtr, valLength)
@@ -27,12 +39,39 @@ func (i *instance) deletePrefix(ord int64, keyPtr, keyLength int32) {
 	i.CurrentCall.DoDeletePrefix(uint64(ord), prefix)
 }
 
+func (i *instance) delete(ord int64, keyPtr, keyLength int32) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	i.CurrentCall.DoDelete(uint64(ord), key)
+}
+
 func (i *instance) addBigInt(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
 	key := i.Heap.ReadString(keyPtr, keyLength)
 	value := i.Heap.ReadString(valPtr, valLength)
 	i.CurrentCall.DoAddBigInt(uint64(ord), key, value)
 }
 
+func (i *instance) bitOrInt64(ord int64, keyPtr, keyLength int32, value int64) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	i.CurrentCall.DoBitOrInt64(uint64(ord), key, value)
+}
+
+func (i *instance) bitOrBigInt(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	value := i.Heap.ReadString(valPtr, valLength)
+	i.CurrentCall.DoBitOrBigInt(uint64(ord), key, value)
+}
+
+func (i *instance) bitAndInt64(ord int64, keyPtr, keyLength int32, value int64) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	i.CurrentCall.DoBitAndInt64(uint64(ord), key, value)
+}
+
+func (i *instance) bitAndBigInt(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	value := i.Heap.ReadString(valPtr, valLength)
+	i.CurrentCall.DoBitAndBigInt(uint64(ord), key, value)
+}
+
 func (i *instance) addBigDecimal(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
 	key := i.Heap.ReadString(keyPtr, keyLength)
 	value := i.Heap.ReadString(valPtr, valLength)
@@ -44,6 +83,16 @@ func (i *instance) addInt64(ord int64, keyPtr, keyLength int32, value int64) {
 	i.CurrentCall.DoAddInt64(uint64(ord), key, value)
 }
 
+func (i *instance) increment(ord int64, keyPtr, keyLength int32, value int64) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	i.CurrentCall.DoIncrement(uint64(ord), key, value)
+}
+
+func (i *instance) decrement(ord int64, keyPtr, keyLength int32, value int64) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	i.CurrentCall.DoDecrement(uint64(ord), key, value)
+}
+
 func (i *instance) addFloat64(ord int64, keyPtr, keyLength int32, value float64) {
 	key := i.Heap.ReadString(keyPtr, keyLength)
 	i.CurrentCall.DoAddFloat64(uint64(ord), key, value)
@@ -76,6 +125,24 @@ func (i *instance) setMaxInt64(ord int64, keyPtr, keyLength int32, value int64)
 	i.CurrentCall.DoSetMaxInt64(uint64(ord), key, value)
 }
 
+func (i *instance) setMaxScored(ord int64, keyPtr, keyLength int32, score int64, payloadPtr, payloadLength int32) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	payload := i.Heap.ReadBytes(payloadPtr, payloadLength)
+	i.CurrentCall.DoSetMaxScored(uint64(ord), key, score, payload)
+}
+
+func (i *instance) setIfNewerBlock(ord int64, keyPtr, keyLength int32, blockNum int64, payloadPtr, payloadLength int32) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	payload := i.Heap.ReadBytes(payloadPtr, payloadLength)
+	i.CurrentCall.DoSetIfNewerBlock(uint64(ord), key, uint64(blockNum), payload)
+}
+
+func (i *instance) setMinScored(ord int64, keyPtr, keyLength int32, score int64, payloadPtr, payloadLength int32) {
+	key := i.Heap.ReadString(keyPtr, keyLength)
+	payload := i.Heap.ReadBytes(payloadPtr, payloadLength)
+	i.CurrentCall.DoSetMinScored(uint64(ord), key, score, payload)
+}
+
 func (i *instance) setMaxBigInt(ord int64, keyPtr, keyLength, valPtr, valLength int32) {
 	key := i.Heap.ReadString(keyPtr, keyLength)
 	value := i.Heap.ReadString(valPtr, valLength)
@@ -129,6 +196,11 @@ func (i *instance) hasLast(storeIndex int32, keyPtr, keyLength int32) int32 {
 	return returnIfFound(found)
 }
 
+func (i *instance) getTopN(storeIndex, n, outputPtr int32) int32 {
+	entries, found := i.CurrentCall.DoGetTopN(int(storeIndex), int(n))
+	return writeToHeapIfFound(i, outputPtr, entries, found)
+}
+
 func writeToHeapIfFound(i *instance, outputPtr int32, value []byte, found bool) int32 {
 	if !found {
 		return 0
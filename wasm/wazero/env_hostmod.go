@@ -36,4 +36,14 @@ var envFuncs = []funcs{
 			call.SetReturnValue(msg)
 		}),
 	},
+	{
+		"seed",
+		[]parm{},
+		[]parm{i64},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			call := wasm.FromContext(ctx)
+
+			stack[0] = call.Seed()
+		}),
+	},
 }
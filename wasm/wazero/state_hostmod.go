@@ -37,6 +37,34 @@ var stateFuncs = []funcs{
 			call.DoSet(ord, key, value)
 		}),
 	},
+	{
+		"put_if_greater",
+		[]parm{i64, i32, i32, i32, i32},
+		[]parm{i32},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := readBytesFromStack(mod, stack[3:])
+			call := wasm.FromContext(ctx)
+
+			written := call.DoPutIfGreater(ord, key, value)
+			setStack0Bool(stack, written)
+		}),
+	},
+	{
+		"put_if_less",
+		[]parm{i64, i32, i32, i32, i32},
+		[]parm{i32},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := readBytesFromStack(mod, stack[3:])
+			call := wasm.FromContext(ctx)
+
+			written := call.DoPutIfLess(ord, key, value)
+			setStack0Bool(stack, written)
+		}),
+	},
 	{
 		"set_if_not_exists",
 		[]parm{i64, i32, i32, i32, i32},
@@ -75,6 +103,18 @@ var stateFuncs = []funcs{
 			call.DoDeletePrefix(ord, prefix)
 		}),
 	},
+	{
+		"delete",
+		[]parm{i64, i32, i32},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			call := wasm.FromContext(ctx)
+
+			call.DoDelete(ord, key)
+		}),
+	},
 	{
 		"add_bigint",
 		[]parm{i64, i32, i32, i32, i32},
@@ -114,6 +154,84 @@ var stateFuncs = []funcs{
 			call.DoAddInt64(ord, key, value)
 		}),
 	},
+	{
+		"increment",
+		[]parm{i64, i32, i32, i64},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := int64(stack[3])
+			call := wasm.FromContext(ctx)
+
+			call.DoIncrement(ord, key, value)
+		}),
+	},
+	{
+		"decrement",
+		[]parm{i64, i32, i32, i64},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := int64(stack[3])
+			call := wasm.FromContext(ctx)
+
+			call.DoDecrement(ord, key, value)
+		}),
+	},
+	{
+		"bit_or_int64",
+		[]parm{i64, i32, i32, i64},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := int64(stack[3])
+			call := wasm.FromContext(ctx)
+
+			call.DoBitOrInt64(ord, key, value)
+		}),
+	},
+	{
+		"bit_or_bigint",
+		[]parm{i64, i32, i32, i32, i32},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := readStringFromStack(mod, stack[3:])
+			call := wasm.FromContext(ctx)
+
+			call.DoBitOrBigInt(ord, key, value)
+		}),
+	},
+	{
+		"bit_and_int64",
+		[]parm{i64, i32, i32, i64},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := int64(stack[3])
+			call := wasm.FromContext(ctx)
+
+			call.DoBitAndInt64(ord, key, value)
+		}),
+	},
+	{
+		"bit_and_bigint",
+		[]parm{i64, i32, i32, i32, i32},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			value := readStringFromStack(mod, stack[3:])
+			call := wasm.FromContext(ctx)
+
+			call.DoBitAndBigInt(ord, key, value)
+		}),
+	},
 	{
 		"add_float64",
 		[]parm{i64, i32, i32, f64},
@@ -232,6 +350,48 @@ var stateFuncs = []funcs{
 			call.DoSetMaxBigDecimal(ord, key, value)
 		}),
 	},
+	{
+		"set_max_scored",
+		[]parm{i64, i32, i32, i64, i32, i32},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			score := int64(stack[3])
+			payload := readBytesFromStack(mod, stack[4:])
+			call := wasm.FromContext(ctx)
+
+			call.DoSetMaxScored(ord, key, score, payload)
+		}),
+	},
+	{
+		"set_if_newer_block",
+		[]parm{i64, i32, i32, i64, i32, i32},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			blockNum := stack[3]
+			payload := readBytesFromStack(mod, stack[4:])
+			call := wasm.FromContext(ctx)
+
+			call.DoSetIfNewerBlock(ord, key, blockNum, payload)
+		}),
+	},
+	{
+		"set_min_scored",
+		[]parm{i64, i32, i32, i64, i32, i32},
+		[]parm{},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			ord := stack[0]
+			key := readStringFromStack(mod, stack[1:])
+			score := int64(stack[3])
+			payload := readBytesFromStack(mod, stack[4:])
+			call := wasm.FromContext(ctx)
+
+			call.DoSetMinScored(ord, key, score, payload)
+		}),
+	},
 
 	// Getter functions
 
@@ -321,6 +481,21 @@ var stateFuncs = []funcs{
 			setStack0Bool(stack, found)
 		}),
 	},
+	{
+		"get_top_n",
+		[]parm{i32, i32, i32},
+		[]parm{i32},
+		api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			storeIndex := uint32(stack[0])
+			n := uint32(stack[1])
+			outputPtr := uint32(stack[2])
+			call := wasm.FromContext(ctx)
+			inst := instanceFromContext(ctx)
+
+			entries, found := call.DoGetTopN(int(storeIndex), int(n))
+			setStackAndOutput(ctx, stack, call, found, inst, outputPtr, entries)
+		}),
+	},
 }
 
 func setStackAndOutput(ctx context.Context, stack []uint64, call *wasm.Call, found bool, inst *instance, outputPtr uint32, value []byte) {